@@ -0,0 +1,28 @@
+// Command fixtures writes or verifies the cross-language wire fixtures of
+// pkg/fixtures.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/badlogic/pi-go/pkg/fixtures"
+)
+
+func main() {
+	dir := flag.String("dir", "pkg/fixtures/testdata", "fixture directory")
+	check := flag.Bool("check", false, "verify the fixtures instead of writing them")
+	flag.Parse()
+
+	var err error
+	if *check {
+		err = fixtures.Verify(*dir)
+	} else {
+		err = fixtures.Write(*dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
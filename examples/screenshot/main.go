@@ -0,0 +1,118 @@
+// Command screenshot shows a tool that returns an image. The agent loop
+// keeps the image inside the tool result for APIs that accept it there
+// (Anthropic, Bedrock, Google) and moves it into a user message after the
+// tool results for the OpenAI APIs. A dummy provider reports where the
+// image arrived.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ScreenshotArgs are the arguments of the screenshot tool.
+type ScreenshotArgs struct {
+	Width  int `json:"width" jsonschema:"description=Viewport width in pixels,minimum=1,maximum=1920"`
+	Height int `json:"height" jsonschema:"description=Viewport height in pixels,minimum=1,maximum=1080"`
+}
+
+// screenshotTool stands in for a browser or desktop capture: it renders a
+// PNG and returns it next to a short text description.
+func screenshotTool() agent.AgentTool {
+	return agent.NewTool("screenshot", "Capture the current screen as a PNG image.",
+		func(ctx context.Context, args ScreenshotArgs) (agent.AgentToolResult, error) {
+			img := image.NewRGBA(image.Rect(0, 0, args.Width, args.Height))
+			for y := 0; y < args.Height; y++ {
+				for x := 0; x < args.Width; x++ {
+					img.Set(x, y, color.RGBA{R: uint8(x * 255 / args.Width), G: uint8(y * 255 / args.Height), B: 128, A: 255})
+				}
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			return agent.AgentToolResult{Content: []ai.Content{
+				ai.NewTextContent(fmt.Sprintf("Screenshot %dx%d", args.Width, args.Height)),
+				ai.NewImageContent(base64.StdEncoding.EncodeToString(buf.Bytes()), "image/png"),
+			}}, nil
+		})
+}
+
+// dummyStream asks for a screenshot, then describes where the image was
+// found in the context it received.
+func dummyStream(model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+	stream := ai.NewAssistantMessageEventStream()
+	msg := &ai.AssistantMessage{
+		Role:       ai.RoleAssistant,
+		Api:        model.Api,
+		Provider:   model.Provider,
+		Model:      model.ID,
+		StopReason: ai.StopReasonStop,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	last := llmCtx.Messages[len(llmCtx.Messages)-1]
+	if last.User != nil && len(llmCtx.Messages) == 1 {
+		msg.Content = []ai.Content{ai.NewToolCallContent("call_1", "screenshot", map[string]any{"width": 64, "height": 48})}
+		msg.StopReason = ai.StopReasonToolUse
+	} else {
+		where := "nowhere"
+		for _, m := range llmCtx.Messages {
+			switch {
+			case m.ToolResult != nil && hasImage(m.ToolResult.Content):
+				where = "inside the tool result"
+			case m.User != nil && hasImage(m.User.Content):
+				where = "in a user message after the tool result"
+			}
+		}
+		msg.Content = []ai.Content{ai.NewTextContent("I received the screenshot " + where + ".")}
+	}
+	go func() {
+		stream.Push(ai.AssistantMessageEvent{Type: ai.EventStart, Partial: msg})
+		stream.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+	}()
+	return stream
+}
+
+func hasImage(content []ai.Content) bool {
+	for _, c := range content {
+		if c.Image != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	models := []*ai.Model{
+		{ID: "claude-sonnet-4-5", Api: ai.ApiAnthropicMessages, Provider: ai.ProviderAnthropic, Input: []string{"text", "image"}},
+		{ID: "gpt-4o", Api: ai.ApiOpenAICompletions, Provider: ai.ProviderOpenAI, Input: []string{"text", "image"}},
+	}
+	for _, model := range models {
+		a := agent.NewAgent(agent.AgentOptions{StreamFn: dummyStream})
+		a.SetModel(model)
+		a.SetTools([]agent.AgentTool{screenshotTool()})
+		a.Subscribe(func(e agent.AgentEvent) {
+			if e.Type == agent.MessageEventEnd && e.Message.Assistant != nil {
+				for _, c := range e.Message.Assistant.Content {
+					if c.Text != nil {
+						fmt.Printf("%s: %s\n", model.ID, c.Text.Text)
+					}
+				}
+			}
+		})
+		if err := a.Prompt("Take a screenshot."); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		a.WaitForIdle()
+	}
+}
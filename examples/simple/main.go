@@ -49,7 +49,7 @@ func main() {
 		ContextWindow: 200000,
 		MaxTokens:     8192,
 	}
-	ai.RegisterModel(model)
+	ai.RegisterModel(model, "")
 
 	// 3. Create an agent
 	a := agent.NewAgent(agent.AgentOptions{
@@ -2,7 +2,10 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"sync"
 	"time"
 
@@ -23,16 +26,48 @@ func DefaultConvertToLLM(messages []AgentMessage) ([]ai.Message, error) {
 
 // AgentOptions configures an Agent.
 type AgentOptions struct {
-	InitialState     *AgentState
-	ConvertToLLM     func([]AgentMessage) ([]ai.Message, error)
-	TransformContext func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
-	SteeringMode     string // "all" or "one-at-a-time"
-	FollowUpMode     string // "all" or "one-at-a-time"
-	StreamFn         StreamFn
-	SessionID        string
-	GetApiKey        func(provider string) (string, error)
-	ThinkingBudgets  *ai.ThinkingBudgets
-	MaxRetryDelayMs  *int
+	InitialState       *AgentState
+	ConvertToLLM       func([]AgentMessage) ([]ai.Message, error)
+	TransformContext   func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
+	SteeringMode       string             // "all" or "one-at-a-time"
+	FollowUpMode       string             // "all" or "one-at-a-time"
+	SteeringToolPolicy SteeringToolPolicy // tool calls on steering: skip the rest (default), finish all, or abort running ones too
+	StreamFn           StreamFn
+	StreamCtxFn        StreamCtxFn // preferred over StreamFn; receives the abort context
+	SessionID          string
+	GetApiKey          func(provider string) (string, error)
+	ThinkingBudgets    *ai.ThinkingBudgets
+	MaxRetryDelayMs    *int
+	Registry           *ai.Registry
+	Language           *LanguageOptions // enables language detection on the first prompt
+	PostProcessors     []MessagePostProcessor
+	TurnAnalyzer       TurnAnalyzer        // tags user messages with intents as they enter the conversation
+	ErrorReporter      ErrorReporter       // receives a redacted bundle when a run ends in error
+	ImageCaptioner     *ai.ImageCaptioner  // describes images for text-only models
+	TraceTurns         int                 // recent turns kept for ExplainTurn; 0 disables
+	Pipeline           *TransformPipeline  // named context transform stages, run after TransformContext
+	Continuation       *ContinuationPolicy // nudges the model to continue unfinished tasks
+	ToolConcurrency    int                 // max concurrent Parallelizable tool calls; <= 1 is sequential
+	OnToolApproval     ToolApprovalFunc    // asked before each tool call runs; see AgentLoopConfig
+	ContextMonitor     *ContextMonitor     // fires when context usage crosses fill thresholds
+	DefaultToolTimeout time.Duration       // bounds tool calls without their own Timeout; 0 disables
+	ToolInterceptors   []ToolInterceptor   // wrap every tool's Execute; see AgentLoopConfig
+	MaxToolRetries     int                 // consecutive turns of recoverable tool errors before the run fails; 0 is unlimited
+	MaxTurns           int                 // LLM calls per run; 0 is unlimited
+	MaxToolCalls       int                 // tool calls per run; 0 is unlimited
+	ResumeDisconnects  int                 // resumes of a response cut off by a dropped connection; 0 disables
+	Budget             *Budget             // caps the cost and tokens of each run
+	SessionBudget      *Budget             // caps the cost and tokens of the session; see SessionUsage
+	CoerceArguments    *ai.CoerceOptions   // repairs mistyped tool arguments before validation
+	ValidationPolicy   *ValidationPolicy   // intervenes when a tool's arguments keep failing validation
+	ToolCache          *ToolCache          // serves repeated calls of Cacheable tools; shared by all runs
+	ConvertCache       *ConvertCache       // converts messages for the LLM with memoization; replaces ConvertToLLM
+	Compaction         *CompactionPolicy   // summarizes older turns near the context window
+	ImageModeration    *ai.ImageModerator  // blocks, blurs or flags unsafe images in the context
+	Locale             string              // language of built-in strings; "" follows the detected Language, else English
+	Catalog            Catalog             // overrides or extends DefaultCatalog
+	Priority           ai.Priority         // request priority for ai.Limiter; background runs yield to interactive ones
+	TrustSavedModels   bool                // lets Restore use a session's own model definition when the registry lacks it
 }
 
 // Agent manages a conversation loop with an LLM.
@@ -43,21 +78,66 @@ type Agent struct {
 
 	listeners      map[int]func(AgentEvent)
 	nextListenerID int
+	outbox         []AgentEvent // events waiting for delivery; see publish
+	deliveryMu     sync.Mutex   // held by the goroutine delivering the outbox
 
 	abortCancel context.CancelFunc
 	abortCtx    context.Context
 
-	convertToLLM     func([]AgentMessage) ([]ai.Message, error)
-	transformContext  func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
-	steeringQueue    []AgentMessage
-	followUpQueue    []AgentMessage
-	steeringMode     string
-	followUpMode     string
-	StreamFn         StreamFn
-	sessionID        string
-	GetApiKey        func(provider string) (string, error)
-	thinkingBudgets  *ai.ThinkingBudgets
-	maxRetryDelayMs  *int
+	convertToLLM       func([]AgentMessage) ([]ai.Message, error)
+	transformContext   func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
+	steeringQueue      []AgentMessage
+	followUpQueue      []AgentMessage
+	steeringMode       string
+	steeringToolPolicy SteeringToolPolicy
+	followUpMode       string
+	StreamFn           StreamFn
+	StreamCtxFn        StreamCtxFn
+	sessionID          string
+	GetApiKey          func(provider string) (string, error)
+	thinkingBudgets    *ai.ThinkingBudgets
+	maxRetryDelayMs    *int
+	registry           *ai.Registry
+	language           *LanguageOptions
+	postProcessors     []MessagePostProcessor
+	turnAnalyzer       TurnAnalyzer
+	errorReporter      ErrorReporter
+	imageCaptioner     *ai.ImageCaptioner
+	recentEvents       []ReportEvent
+	reports            sync.WaitGroup // error reports being delivered
+	warnedModels       map[string]bool // deprecation warnings already emitted
+	traceTurns         int
+	pipeline           *TransformPipeline
+	turnTraces         []*TurnTrace
+	nextTurnIndex      int
+	continuation       *ContinuationPolicy
+	toolConcurrency    int
+	onToolApproval     ToolApprovalFunc
+	toolApprovals      toolApprovals // always-allow grants, kept across runs
+	contextMonitor     *ContextMonitor
+	defaultToolTimeout time.Duration
+	toolInterceptors   []ToolInterceptor
+	maxToolRetries     int
+	maxTurns           int
+	maxToolCalls       int
+	resumeDisconnects  int
+	budget             *Budget
+	sessionBudget      *Budget
+	sessionUsage       ai.Usage // spent by the session, for SessionBudget
+	coerceArguments    *ai.CoerceOptions
+	validationPolicy   *ValidationPolicy
+	locks              *ResourceLocks
+	toolCache          *ToolCache
+	compaction         *CompactionPolicy
+	imageModeration    *ai.ImageModerator
+	locale             string
+	catalog            Catalog
+	spectator          bool // created by NewSpectator: tools are never offered
+	priority           ai.Priority
+	trustSavedModels   bool
+	toolsets           []*Toolset
+	closers            []io.Closer     // resources released by Close
+	requestIDs         map[string]bool // idempotency keys accepted this session
 
 	running chan struct{} // closed when current run completes
 }
@@ -70,6 +150,7 @@ func NewAgent(opts AgentOptions) *Agent {
 			PendingToolCalls: map[string]struct{}{},
 		},
 		listeners:       map[int]func(AgentEvent){},
+		warnedModels:    map[string]bool{},
 		convertToLLM:    DefaultConvertToLLM,
 		steeringMode:    "one-at-a-time",
 		followUpMode:    "one-at-a-time",
@@ -84,6 +165,9 @@ func NewAgent(opts AgentOptions) *Agent {
 	if opts.ConvertToLLM != nil {
 		a.convertToLLM = opts.ConvertToLLM
 	}
+	if opts.ConvertCache != nil {
+		a.convertToLLM = opts.ConvertCache.Convert
+	}
 	if opts.TransformContext != nil {
 		a.transformContext = opts.TransformContext
 	}
@@ -96,10 +180,42 @@ func NewAgent(opts AgentOptions) *Agent {
 	if opts.StreamFn != nil {
 		a.StreamFn = opts.StreamFn
 	}
+	a.steeringToolPolicy = opts.SteeringToolPolicy
+	a.StreamCtxFn = opts.StreamCtxFn
 	a.sessionID = opts.SessionID
 	a.GetApiKey = opts.GetApiKey
 	a.thinkingBudgets = opts.ThinkingBudgets
 	a.maxRetryDelayMs = opts.MaxRetryDelayMs
+	a.registry = opts.Registry
+	a.language = opts.Language
+	a.postProcessors = opts.PostProcessors
+	a.turnAnalyzer = opts.TurnAnalyzer
+	a.errorReporter = opts.ErrorReporter
+	a.imageCaptioner = opts.ImageCaptioner
+	a.traceTurns = opts.TraceTurns
+	a.pipeline = opts.Pipeline
+	a.continuation = opts.Continuation
+	a.toolConcurrency = opts.ToolConcurrency
+	a.onToolApproval = opts.OnToolApproval
+	a.contextMonitor = opts.ContextMonitor
+	a.defaultToolTimeout = opts.DefaultToolTimeout
+	a.toolInterceptors = opts.ToolInterceptors
+	a.maxToolRetries = opts.MaxToolRetries
+	a.maxTurns = opts.MaxTurns
+	a.maxToolCalls = opts.MaxToolCalls
+	a.resumeDisconnects = opts.ResumeDisconnects
+	a.budget = opts.Budget
+	a.sessionBudget = opts.SessionBudget
+	a.coerceArguments = opts.CoerceArguments
+	a.validationPolicy = opts.ValidationPolicy
+	a.locks = NewResourceLocks()
+	a.toolCache = opts.ToolCache
+	a.compaction = opts.Compaction
+	a.imageModeration = opts.ImageModeration
+	a.locale = opts.Locale
+	a.catalog = opts.Catalog
+	a.priority = opts.Priority
+	a.trustSavedModels = opts.TrustSavedModels
 
 	return a
 }
@@ -111,6 +227,16 @@ func (a *Agent) State() AgentState {
 	return a.state
 }
 
+// SessionUsage returns the spend of the session, which SessionBudget is
+// checked against: every run's LLM calls, compaction summaries and
+// sub-agents, and ReplayTurn calls. Reset starts it at zero and Restore at
+// the restored session's Usage.
+func (a *Agent) SessionUsage() ai.Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sessionUsage
+}
+
 // Subscribe registers a listener. Returns an unsubscribe function.
 func (a *Agent) Subscribe(fn func(AgentEvent)) func() {
 	a.mu.Lock()
@@ -132,11 +258,41 @@ func (a *Agent) SetSystemPrompt(v string) {
 	a.state.SystemPrompt = v
 }
 
-// SetModel sets the model.
+// SetModel sets the model and clears any alias set by SetModelAlias.
 func (a *Agent) SetModel(m *ai.Model) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.state.Model = m
+	a.state.ModelAlias = ""
+}
+
+// SetPriority sets the request priority used from the next run on.
+func (a *Agent) SetPriority(p ai.Priority) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.priority = p
+}
+
+// SetLocale sets the language of built-in strings used from the next run
+// on; "" follows the detected language.
+func (a *Agent) SetLocale(locale string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.locale = locale
+}
+
+// localeLocked returns the configured locale, or the detected language.
+func (a *Agent) localeLocked() string {
+	if a.locale != "" {
+		return a.locale
+	}
+	return a.state.Language
+}
+
+// Locks returns the conversation's resource locks, shared by all runs, so
+// that application code can coordinate with its tools.
+func (a *Agent) Locks() *ResourceLocks {
+	return a.locks
 }
 
 // SetThinkingLevel sets the thinking level.
@@ -236,6 +392,34 @@ func (a *Agent) WaitForIdle() {
 	}
 }
 
+// AddCloser registers a resource (such as a tool server connection) to be
+// closed by Close.
+func (a *Agent) AddCloser(c io.Closer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closers = append(a.closers, c)
+}
+
+// Close aborts any running prompt, waits for it and for pending error
+// reports to finish, and closes the resources registered with AddCloser in
+// reverse order.
+func (a *Agent) Close() error {
+	a.Abort()
+	a.WaitForIdle()
+	a.reports.Wait()
+	a.mu.Lock()
+	closers := a.closers
+	a.closers = nil
+	a.mu.Unlock()
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Reset clears the agent state.
 func (a *Agent) Reset() {
 	a.mu.Lock()
@@ -245,29 +429,53 @@ func (a *Agent) Reset() {
 	a.state.StreamMessage = nil
 	a.state.PendingToolCalls = map[string]struct{}{}
 	a.state.Error = ""
+	a.state.Language = ""
+	a.sessionUsage = ai.Usage{}
+	a.turnTraces = nil
+	a.nextTurnIndex = 0
+	a.requestIDs = nil
+	a.toolApprovals.reset()
+	a.contextMonitor.Reset()
 	a.steeringQueue = nil
 	a.followUpQueue = nil
 }
 
 // Prompt sends a text prompt to the agent.
 func (a *Agent) Prompt(text string, images ...ai.ImageContent) error {
+	return a.runLoop(context.Background(), promptMessages(text, images), false)
+}
+
+// PromptContext is Prompt with a context for the run: its values reach
+// context transforms (e.g. WithStageOverrides), and cancelling it aborts
+// the run like Abort.
+func (a *Agent) PromptContext(ctx context.Context, text string, images ...ai.ImageContent) error {
+	return a.runLoop(ctx, promptMessages(text, images), false)
+}
+
+// promptMessages builds the user message for Prompt.
+func promptMessages(text string, images []ai.ImageContent) []AgentMessage {
 	content := []ai.Content{ai.NewTextContent(text)}
 	for _, img := range images {
 		content = append(content, ai.Content{Image: &img})
 	}
-	msgs := []AgentMessage{
+	return []AgentMessage{
 		NewAgentMessageFromMessage(ai.Message{User: &ai.UserMessage{
 			Role:      ai.RoleUser,
 			Content:   content,
 			Timestamp: time.Now().UnixMilli(),
 		}}),
 	}
-	return a.runLoop(msgs, false)
 }
 
 // PromptMessages sends agent messages as a prompt.
 func (a *Agent) PromptMessages(msgs []AgentMessage) error {
-	return a.runLoop(msgs, false)
+	return a.runLoop(context.Background(), msgs, false)
+}
+
+// PromptMessagesContext is PromptMessages with a context for the run; see
+// PromptContext.
+func (a *Agent) PromptMessagesContext(ctx context.Context, msgs []AgentMessage) error {
+	return a.runLoop(ctx, msgs, false)
 }
 
 // Continue resumes from the current context.
@@ -288,16 +496,16 @@ func (a *Agent) Continue() error {
 		// Try steering queue first.
 		steering := a.dequeueSteeringMessages()
 		if len(steering) > 0 {
-			return a.runLoop(steering, true)
+			return a.runLoop(context.Background(), steering, true)
 		}
 		followUp := a.dequeueFollowUpMessages()
 		if len(followUp) > 0 {
-			return a.runLoop(followUp, false)
+			return a.runLoop(context.Background(), followUp, false)
 		}
 		return fmt.Errorf("cannot continue from message role: assistant")
 	}
 
-	return a.runLoop(nil, false)
+	return a.runLoop(context.Background(), nil, false)
 }
 
 func (a *Agent) dequeueSteeringMessages() []AgentMessage {
@@ -336,12 +544,13 @@ func (a *Agent) dequeueFollowUpMessages() []AgentMessage {
 	return out
 }
 
-func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) error {
+func (a *Agent) runLoop(ctx context.Context, messages []AgentMessage, skipInitialSteeringPoll bool) error {
 	a.mu.Lock()
 	if a.state.IsStreaming {
 		a.mu.Unlock()
 		return fmt.Errorf("agent is already processing a prompt")
 	}
+	a.refreshAliasModelLocked()
 	model := a.state.Model
 	if model == nil {
 		a.mu.Unlock()
@@ -349,20 +558,29 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 	}
 
 	a.running = make(chan struct{})
-	a.abortCtx, a.abortCancel = context.WithCancel(context.Background())
+	a.abortCtx, a.abortCancel = context.WithCancel(ai.WithPriority(ctx, a.priority))
 	a.state.IsStreaming = true
 	a.state.StreamMessage = nil
 	a.state.Error = ""
+	a.recentEvents = nil
 
 	reasoning := a.state.ThinkingLevel
 	if reasoning == ai.ThinkingOff {
 		reasoning = ""
 	}
 
+	systemPrompt := a.state.SystemPrompt
+	if a.language != nil {
+		if a.state.Language == "" && len(a.state.Messages) == 0 {
+			a.state.Language = a.language.detect(firstUserText(messages))
+		}
+		systemPrompt = a.language.localizeSystemPrompt(systemPrompt, a.state.Language)
+	}
+
 	agentCtx := AgentContext{
-		SystemPrompt: a.state.SystemPrompt,
+		SystemPrompt: systemPrompt,
 		Messages:     append([]AgentMessage{}, a.state.Messages...),
-		Tools:        a.state.Tools,
+		Tools:        a.runToolsLocked(),
 	}
 
 	skipSteering := skipInitialSteeringPoll
@@ -377,8 +595,11 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 			ThinkingBudgets: a.thinkingBudgets,
 		},
 		Model:        model,
+		Registry:     a.registry,
+		StreamCtxFn:  a.StreamCtxFn,
 		ConvertToLLM: a.convertToLLM,
 		TransformContext: a.transformContext,
+		Pipeline:         a.pipeline,
 		GetApiKey:    a.GetApiKey,
 		GetSteeringMessages: func() ([]AgentMessage, error) {
 			if skipSteering {
@@ -390,6 +611,36 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 		GetFollowUpMessages: func() ([]AgentMessage, error) {
 			return a.dequeueFollowUpMessages(), nil
 		},
+		SteeringToolPolicy: a.steeringToolPolicy,
+		ImageCaptioner:     a.imageCaptioner,
+		PostProcessors:     a.postProcessors,
+		Continuation:       a.continuation,
+		ToolConcurrency:    a.toolConcurrency,
+		OnToolApproval:     a.onToolApproval,
+		toolApprovals:      &a.toolApprovals,
+		ContextMonitor:     a.contextMonitor,
+		DefaultToolTimeout: a.defaultToolTimeout,
+		ToolInterceptors:   a.toolInterceptors,
+		MaxToolRetries:     a.maxToolRetries,
+		MaxTurns:           a.maxTurns,
+		MaxToolCalls:       a.maxToolCalls,
+		ResumeDisconnects:  a.resumeDisconnects,
+		Budget:             a.budget,
+		SessionBudget:      a.sessionBudget,
+		SessionUsage:       a.sessionUsage,
+		meter:              &usageMeter{onCharge: a.chargeSession},
+		CoerceArguments:    a.coerceArguments,
+		ValidationPolicy:   a.validationPolicy,
+		Locks:              a.locks,
+		ToolCache:          a.toolCache,
+		Compaction:         a.compaction,
+		ImageModeration:    a.imageModeration,
+		TurnAnalyzer:       a.turnAnalyzer,
+		Locale:             a.localeLocked(),
+		Catalog:            a.catalog,
+	}
+	if a.traceTurns > 0 {
+		config.OnTurnTrace = a.recordTurnTrace
 	}
 	// Fix: don't use system prompt as API key
 	config.SimpleStreamOptions.StreamOptions.ApiKey = ""
@@ -397,9 +648,20 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 		config.SimpleStreamOptions.StreamOptions.SessionID = a.sessionID
 	}
 
-	ctx := a.abortCtx
+	ctx = a.abortCtx
+	warning := ""
+	if !a.warnedModels[model.Provider+"/"+model.ID] {
+		warning = ai.RegistryOrDefault(a.registry).DeprecationWarning(model, time.Now())
+		if warning != "" {
+			a.warnedModels[model.Provider+"/"+model.ID] = true
+		}
+	}
 	a.mu.Unlock()
 
+	if warning != "" {
+		a.publish(AgentEvent{Type: WarningEvent, Warning: warning}, false)
+	}
+
 	var stream *AgentEventStream
 	if messages != nil {
 		stream = AgentLoop(ctx, messages, agentCtx, config, a.StreamFn)
@@ -418,6 +680,21 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 	// Process events in background.
 	go func() {
 		defer func() {
+			if v := recover(); v != nil {
+				// applyEvent panicked (listener panics are contained
+				// by emit). Keep the loop from blocking on an unread stream and
+				// stop it; the run ends with the panic as its error.
+				go func() {
+					for range stream.Events() {
+					}
+				}()
+				a.mu.Lock()
+				a.state.Error = ai.RecoverPanic(v).Error()
+				if a.abortCancel != nil {
+					a.abortCancel()
+				}
+				a.mu.Unlock()
+			}
 			a.mu.Lock()
 			a.state.IsStreaming = false
 			a.state.StreamMessage = nil
@@ -430,43 +707,132 @@ func (a *Agent) runLoop(messages []AgentMessage, skipInitialSteeringPoll bool) e
 		}()
 
 		for event := range stream.Events() {
-			a.mu.Lock()
-			switch event.Type {
-			case MessageEventStart:
-				a.state.StreamMessage = event.Message
-			case MessageEventUpdate:
-				a.state.StreamMessage = event.Message
-			case MessageEventEnd:
-				a.state.StreamMessage = nil
-				a.state.Messages = append(a.state.Messages, *event.Message)
-			case ToolExecutionEventStart:
-				a.state.PendingToolCalls[event.ToolCallID] = struct{}{}
-			case ToolExecutionEventEnd:
-				delete(a.state.PendingToolCalls, event.ToolCallID)
-			case TurnEventEnd:
-				if event.Message != nil && event.Message.Assistant != nil {
-					if event.Message.Assistant.ErrorMessage != "" {
-						a.state.Error = event.Message.Assistant.ErrorMessage
-					}
-				}
-			case AgentEventEnd:
-				a.state.IsStreaming = false
-				a.state.StreamMessage = nil
+			a.publish(a.applyEvent(event), true)
+		}
+	}()
+
+	return nil
+}
+
+// applyEvent updates agent state for an event from the loop and returns
+// the event to deliver to listeners. A committed message gets its ID on a
+// copy: the loop still holds the original.
+func (a *Agent) applyEvent(event AgentEvent) AgentEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.errorReporter != nil && event.Type != MessageEventUpdate {
+		a.recentEvents = append(a.recentEvents, reportEventOf(event))
+		if n := len(a.recentEvents); n > maxReportEvents {
+			a.recentEvents = a.recentEvents[n-maxReportEvents:]
+		}
+	}
+	switch event.Type {
+	case MessageEventStart:
+		a.state.StreamMessage = event.Message
+	case MessageEventUpdate:
+		a.state.StreamMessage = event.Message
+	case MessageEventEnd:
+		a.state.StreamMessage = nil
+		if event.Message.ID == "" {
+			m := *event.Message
+			m.ID = NewMessageID()
+			event.Message = &m
+		}
+		a.state.Messages = append(a.state.Messages, *event.Message)
+		if event.Message.Assistant != nil {
+			a.sessionUsage = ai.AddUsage(a.sessionUsage, event.Message.Assistant.Usage)
+		}
+	case ToolExecutionEventStart:
+		a.state.PendingToolCalls[event.ToolCallID] = struct{}{}
+	case ToolExecutionEventEnd:
+		delete(a.state.PendingToolCalls, event.ToolCallID)
+	case TurnEventEnd:
+		if event.Message != nil && event.Message.Assistant != nil {
+			if event.Message.Assistant.ErrorMessage != "" {
+				a.state.Error = event.Message.Assistant.ErrorMessage
 			}
-			a.mu.Unlock()
+			if event.Message.Assistant.StopReason == ai.StopReasonError && a.errorReporter != nil {
+				report := newErrorReport(a.sessionID, a.state, event.Message.Assistant, a.recentEvents)
+				a.reports.Add(1)
+				go a.sendReport(report)
+			}
+		}
+	case AgentEventEnd:
+		a.state.IsStreaming = false
+		a.state.StreamMessage = nil
+	}
+	return event
+}
+
+// chargeSession adds spend outside the conversation's assistant messages
+// to the session usage.
+func (a *Agent) chargeSession(u ai.Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessionUsage = ai.AddUsage(a.sessionUsage, u)
+}
 
-			// Emit to listeners.
+// publish queues an event for the listeners and delivers the queue, so
+// that listeners see one event at a time, in order, whichever goroutine
+// produced it. If another goroutine is delivering, a blocking publish
+// (the run's) waits for it and delivers what is left; otherwise, e.g. for
+// a listener calling back into the agent, that goroutine delivers the
+// event.
+func (a *Agent) publish(event AgentEvent, block bool) {
+	a.mu.Lock()
+	a.outbox = append(a.outbox, event)
+	a.mu.Unlock()
+	for {
+		if block {
+			a.deliveryMu.Lock()
+		} else if !a.deliveryMu.TryLock() {
+			return
+		}
+		for {
 			a.mu.Lock()
-			listeners := make([]func(AgentEvent), 0, len(a.listeners))
-			for _, fn := range a.listeners {
-				listeners = append(listeners, fn)
+			if len(a.outbox) == 0 {
+				a.mu.Unlock()
+				break
 			}
+			e := a.outbox[0]
+			a.outbox = a.outbox[1:]
 			a.mu.Unlock()
-			for _, fn := range listeners {
-				fn(event)
-			}
+			a.emit(e)
 		}
-	}()
+		a.deliveryMu.Unlock()
+		// An event queued after the outbox was found empty but before the
+		// unlock would be stranded; deliver it.
+		a.mu.Lock()
+		empty := len(a.outbox) == 0
+		a.mu.Unlock()
+		if empty {
+			return
+		}
+		block = false
+	}
+}
 
-	return nil
+// emit delivers an event to all listeners. Call it through publish.
+func (a *Agent) emit(event AgentEvent) {
+	a.mu.Lock()
+	listeners := make([]func(AgentEvent), 0, len(a.listeners))
+	for _, fn := range a.listeners {
+		listeners = append(listeners, fn)
+	}
+	a.mu.Unlock()
+	for _, fn := range listeners {
+		deliver(fn, event)
+	}
+}
+
+// deliver calls a listener, logging a panic instead of propagating it so
+// that the other listeners and later events are still delivered.
+func deliver(fn func(AgentEvent), event AgentEvent) {
+	defer func() {
+		if v := recover(); v != nil {
+			p := ai.RecoverPanic(v)
+			log.Printf("agent: %s listener panicked: %v\n%s", event.Type, p.Value, p.Stack)
+		}
+	}()
+	fn(event)
 }
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// SetModelAlias selects the model alias currently resolves to for provider
+// and remembers the alias, so that when operators re-point it the agent
+// follows on its next run. Pin the alias to stay on one version.
+func (a *Agent) SetModelAlias(provider ai.Provider, alias string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	registry := ai.RegistryOrDefault(a.registry)
+	id := registry.ResolveAlias(provider, alias)
+	if pinned, ok := a.state.AliasPins[alias]; ok {
+		id = pinned
+	}
+	m := registry.GetModel(provider, id)
+	if m == nil {
+		return fmt.Errorf("alias %q does not resolve to a registered %s model", alias, provider)
+	}
+	a.state.Model = m
+	a.state.ModelAlias = alias
+	return nil
+}
+
+// PinAlias fixes alias to modelID for this session regardless of later
+// re-pointing. An empty modelID pins the alias to what it resolves to now.
+func (a *Agent) PinAlias(alias, modelID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if modelID == "" {
+		provider := ai.Provider("")
+		if a.state.Model != nil {
+			provider = a.state.Model.Provider
+		}
+		modelID = ai.RegistryOrDefault(a.registry).ResolveAlias(provider, alias)
+	}
+	if a.state.AliasPins == nil {
+		a.state.AliasPins = map[string]string{}
+	}
+	a.state.AliasPins[alias] = modelID
+}
+
+// UnpinAlias lets alias follow the registry again.
+func (a *Agent) UnpinAlias(alias string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.state.AliasPins, alias)
+}
+
+// refreshAliasModelLocked re-resolves state.ModelAlias before a run. The
+// current model is kept if the alias no longer resolves.
+func (a *Agent) refreshAliasModelLocked() {
+	if a.state.ModelAlias == "" || a.state.Model == nil {
+		return
+	}
+	registry := ai.RegistryOrDefault(a.registry)
+	provider := a.state.Model.Provider
+	id, ok := a.state.AliasPins[a.state.ModelAlias]
+	if !ok {
+		id = registry.ResolveAlias(provider, a.state.ModelAlias)
+	}
+	if m := registry.GetModel(provider, id); m != nil {
+		a.state.Model = m
+	}
+}
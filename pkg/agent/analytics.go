@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// TurnIntent classifies a user turn.
+type TurnIntent string
+
+const (
+	IntentQuestion    TurnIntent = "question"
+	IntentCommand     TurnIntent = "command"
+	IntentFeedback    TurnIntent = "feedback"
+	IntentFrustration TurnIntent = "frustration"
+	IntentOther       TurnIntent = "other"
+)
+
+// MetadataIntents is the AgentMessage.Metadata key holding []TurnIntent.
+const MetadataIntents = "intents"
+
+// TurnAnalyzer tags the text of a user turn with intents.
+type TurnAnalyzer interface {
+	Analyze(ctx context.Context, text string) ([]TurnIntent, error)
+}
+
+// HeuristicAnalyzer classifies turns with keyword and punctuation rules.
+type HeuristicAnalyzer struct{}
+
+var (
+	questionPattern    = regexp.MustCompile(`(?i)(\?\s*$|^(who|what|when|where|why|how|which|can|could|would|is|are|does|do|should)\b)`)
+	commandPattern     = regexp.MustCompile(`(?i)^(please\s+)?(add|create|make|write|fix|remove|delete|update|change|run|show|list|find|implement|refactor|explain|generate|build|rename|move)\b`)
+	feedbackPattern    = regexp.MustCompile(`(?i)\b(thanks|thank you|great|perfect|nice|awesome|looks good|lgtm|well done|that works)\b`)
+	frustrationPattern = regexp.MustCompile(`(?i)(\b(wrong|again|still (not|doesn't|broken)|not what i|useless|stop|ugh|wtf|come on|i said|doesn't work|didn't work)\b|!{2,}|\?{2,})`)
+)
+
+// Analyze returns every matching intent, or IntentOther.
+func (HeuristicAnalyzer) Analyze(ctx context.Context, text string) ([]TurnIntent, error) {
+	text = strings.TrimSpace(text)
+	var out []TurnIntent
+	if frustrationPattern.MatchString(text) {
+		out = append(out, IntentFrustration)
+	}
+	if feedbackPattern.MatchString(text) {
+		out = append(out, IntentFeedback)
+	}
+	if questionPattern.MatchString(text) {
+		out = append(out, IntentQuestion)
+	}
+	if commandPattern.MatchString(text) {
+		out = append(out, IntentCommand)
+	}
+	if len(out) == 0 {
+		out = append(out, IntentOther)
+	}
+	return out, nil
+}
+
+// ModelAnalyzer classifies turns with a (typically small, cheap) model. The
+// call is cancelled with the run and charged to its budgets.
+type ModelAnalyzer struct {
+	Model    *ai.Model
+	StreamFn StreamFn
+}
+
+const analyzerPrompt = `Classify the user's message. Reply with a comma-separated list of labels from: question, command, feedback, frustration, other. Reply with labels only.`
+
+// Analyze asks the model for labels and keeps the recognised ones.
+func (m ModelAnalyzer) Analyze(ctx context.Context, text string) ([]TurnIntent, error) {
+	if m.StreamFn == nil || m.Model == nil {
+		return nil, fmt.Errorf("model analyzer: model and stream function are required")
+	}
+	s := abortableStreamFn(m.StreamFn)(ctx, m.Model, ai.Context{
+		SystemPrompt: analyzerPrompt,
+		Messages:     []ai.Message{ai.NewUserMessage(text)},
+	}, &ai.SimpleStreamOptions{})
+	res := s.Result()
+	if res == nil {
+		return nil, fmt.Errorf("model analyzer: no response")
+	}
+	ChargeUsage(ctx, res.Usage)
+	if res.StopReason == ai.StopReasonError || res.StopReason == ai.StopReasonAborted {
+		return nil, fmt.Errorf("model analyzer: %s", res.ErrorMessage)
+	}
+	var reply strings.Builder
+	for _, c := range res.Content {
+		if c.Text != nil {
+			reply.WriteString(c.Text.Text)
+		}
+	}
+	var out []TurnIntent
+	for _, label := range strings.Split(reply.String(), ",") {
+		switch intent := TurnIntent(strings.ToLower(strings.TrimSpace(label))); intent {
+		case IntentQuestion, IntentCommand, IntentFeedback, IntentFrustration, IntentOther:
+			out = append(out, intent)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, IntentOther)
+	}
+	return out, nil
+}
+
+// tagIntents annotates the user messages in msgs with analyzer intents as
+// they enter the conversation, returning msgs with the tagged messages
+// copied. Analysis failures leave a message untagged.
+func tagIntents(ctx context.Context, analyzer TurnAnalyzer, msgs []AgentMessage) []AgentMessage {
+	var out []AgentMessage
+	for i, m := range msgs {
+		if m.User == nil {
+			continue
+		}
+		intents, err := analyzer.Analyze(ctx, firstUserText(msgs[i:i+1]))
+		if err != nil {
+			continue
+		}
+		if out == nil {
+			out = append([]AgentMessage{}, msgs...)
+		}
+		m.Metadata = maps.Clone(m.Metadata)
+		m.SetMetadata(MetadataIntents, intents)
+		out[i] = m
+	}
+	if out == nil {
+		return msgs
+	}
+	return out
+}
+
+// Intents returns the intents recorded on a message, if any.
+func (m AgentMessage) Intents() []TurnIntent {
+	v, _ := m.Metadata[MetadataIntents].([]TurnIntent)
+	return v
+}
+
+// IntentCounts tallies the intents of all user turns in the session.
+func (a *Agent) IntentCounts() map[TurnIntent]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := map[TurnIntent]int{}
+	for _, m := range a.state.Messages {
+		for _, in := range m.Intents() {
+			out[in]++
+		}
+	}
+	return out
+}
+
+// MessagesWithIntent returns the user turns tagged with intent.
+func (a *Agent) MessagesWithIntent(intent TurnIntent) []AgentMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []AgentMessage
+	for _, m := range a.state.Messages {
+		for _, in := range m.Intents() {
+			if in == intent {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return out
+}
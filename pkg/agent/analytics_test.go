@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestIntentsTagQueuedMessagesAndSurviveJSON(t *testing.T) {
+	a := NewAgent(AgentOptions{StreamFn: replyStream(ai.NewTextContent("ok")), TurnAnalyzer: HeuristicAnalyzer{}})
+	a.SetModel(&ai.Model{ID: "test"})
+	a.FollowUp(NewAgentMessageFromMessage(ai.NewUserMessage("thanks, looks good")))
+	if err := a.Prompt("what is this?"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+
+	var users []AgentMessage
+	for _, m := range a.State().Messages {
+		if m.User != nil {
+			users = append(users, m)
+		}
+	}
+	if len(users) != 2 {
+		t.Fatalf("got %d user messages, want prompt and follow-up", len(users))
+	}
+	want := [][]TurnIntent{{IntentQuestion}, {IntentFeedback}}
+	for i, m := range users {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded AgentMessage
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if got := decoded.Intents(); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("message %d: intents after JSON = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestModelAnalyzerHonoursContext(t *testing.T) {
+	hang := func(*ai.Model, ai.Context, *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		return ai.NewAssistantMessageEventStream() // never answers
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := ModelAnalyzer{Model: &ai.Model{ID: "small"}, StreamFn: hang}.Analyze(ctx, "hi")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("cancelled analysis returned no error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Analyze ignored the cancelled context")
+	}
+}
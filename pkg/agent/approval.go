@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ApprovalDecision is the answer to a tool approval request.
+type ApprovalDecision int
+
+const (
+	// ApprovalDeny rejects the call; the model receives an error result.
+	ApprovalDeny ApprovalDecision = iota
+	// ApprovalAllow runs this call.
+	ApprovalAllow
+	// ApprovalAlwaysAllow runs this call and every later call of the same
+	// tool without asking again.
+	ApprovalAlwaysAllow
+)
+
+func (d ApprovalDecision) String() string {
+	switch d {
+	case ApprovalAllow:
+		return "allow"
+	case ApprovalAlwaysAllow:
+		return "always-allow"
+	default:
+		return "deny"
+	}
+}
+
+// ToolApprovalFunc decides whether a validated tool call may run. It may
+// block (e.g. waiting for a user) and is called concurrently for
+// Parallelizable tools.
+type ToolApprovalFunc func(ctx context.Context, toolCall ai.ToolCall) (ApprovalDecision, error)
+
+// toolApprovals remembers tools granted ApprovalAlwaysAllow.
+type toolApprovals struct {
+	mu      sync.Mutex
+	allowed map[string]bool
+}
+
+func (t *toolApprovals) isAllowed(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.allowed[name]
+}
+
+func (t *toolApprovals) allow(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.allowed == nil {
+		t.allowed = map[string]bool{}
+	}
+	t.allowed[name] = true
+}
+
+func (t *toolApprovals) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.allowed = nil
+}
+
+// approve asks fn about tc unless its tool was already always-allowed,
+// emitting ToolApprovalRequestedEvent first. A non-nil error result means
+// the call must not run.
+func (t *toolApprovals) approve(ctx context.Context, config *AgentLoopConfig, tc ai.ToolCall, stream *AgentEventStream) error {
+	fn := config.OnToolApproval
+	if fn == nil || t.isAllowed(tc.Name) {
+		return nil
+	}
+	stream.Push(AgentEvent{Type: ToolApprovalRequestedEvent, ToolCallID: tc.ID, ToolName: tc.Name, Args: tc.Arguments})
+	decision, err := fn(ctx, tc)
+	if err != nil {
+		return fmt.Errorf("approval for tool %s failed: %w", tc.Name, err)
+	}
+	switch decision {
+	case ApprovalAllow:
+		return nil
+	case ApprovalAlwaysAllow:
+		t.allow(tc.Name)
+		return nil
+	default:
+		return errors.New(config.text(MsgToolDenied, tc.Name))
+	}
+}
+
+// SetToolApproval sets the approval callback for subsequent runs; nil
+// disables approval.
+func (a *Agent) SetToolApproval(fn ToolApprovalFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onToolApproval = fn
+}
+
+// ResetToolApprovals forgets tools granted ApprovalAlwaysAllow, so they are
+// asked about again.
+func (a *Agent) ResetToolApprovals() {
+	a.toolApprovals.reset()
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Budget caps the spend of a run or session. Zero fields are unlimited.
+type Budget struct {
+	MaxCost   float64 `json:"maxCost,omitempty"`   // USD, as computed from the model's pricing
+	MaxTokens int     `json:"maxTokens,omitempty"` // total tokens, input and output
+}
+
+// BudgetScope names what a Budget applies to.
+type BudgetScope string
+
+const (
+	BudgetRun     BudgetScope = "run"
+	BudgetSession BudgetScope = "session"
+)
+
+// BudgetExceeded reports the budget that stopped a run.
+type BudgetExceeded struct {
+	Scope  BudgetScope `json:"scope"`
+	Budget Budget      `json:"budget"`
+	Usage  ai.Usage    `json:"usage"` // spent in Scope when the run stopped
+}
+
+// usageTokens returns u's total tokens, summing the parts when the
+// provider did not report a total.
+func usageTokens(u ai.Usage) int {
+	if u.TotalTokens > 0 {
+		return u.TotalTokens
+	}
+	return u.Input + u.Output + u.CacheRead + u.CacheWrite
+}
+
+// exhausted reports whether u has used up b; nil is unlimited.
+func (b *Budget) exhausted(u ai.Usage) bool {
+	if b == nil {
+		return false
+	}
+	return b.MaxCost > 0 && u.Cost.Total >= b.MaxCost || b.MaxTokens > 0 && usageTokens(u) >= b.MaxTokens
+}
+
+// usageMeter sums the spend of a run that no assistant message in the
+// conversation records: compaction summaries, failed fallback attempts and
+// usage charged with ChargeUsage or ai.ReportUsage, such as sub-agents,
+// ModelAnalyzer, image captions and image moderation.
+type usageMeter struct {
+	mu       sync.Mutex
+	usage    ai.Usage
+	onCharge func(ai.Usage) // set by Agent to keep its session usage current
+}
+
+func (m *usageMeter) charge(u ai.Usage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.usage = ai.AddUsage(m.usage, u)
+	m.mu.Unlock()
+	if m.onCharge != nil {
+		m.onCharge(u)
+	}
+}
+
+func (m *usageMeter) total() ai.Usage {
+	if m == nil {
+		return ai.Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// withMeter returns ctx billing to config's usage meter, creating it if
+// needed.
+func withMeter(ctx context.Context, config *AgentLoopConfig) context.Context {
+	if config.meter == nil {
+		config.meter = &usageMeter{}
+	}
+	return ai.WithUsageHook(ctx, config.meter.charge)
+}
+
+// ChargeUsage bills u to the run whose tool call or summarization ctx
+// belongs to, counting it toward the run's Budget and SessionBudget. Tools
+// that call models themselves, such as NewSubAgentTool, use it so that
+// their spend cannot bypass the budgets; helpers in package ai report
+// through ai.ReportUsage, which is the same. Outside a run it does nothing.
+func ChargeUsage(ctx context.Context, u ai.Usage) {
+	ai.ReportUsage(ctx, u)
+}
+
+// remainingCostKey carries, in the ctx of a run's tool calls, a function
+// returning the cost the run may still spend.
+type remainingCostKey struct{}
+
+// withRemainingCost returns ctx reporting what the run of config and
+// newMessages has left under its cost budgets, for remainingCost.
+func withRemainingCost(ctx context.Context, config *AgentLoopConfig, newMessages *[]AgentMessage) context.Context {
+	return context.WithValue(ctx, remainingCostKey{}, func() float64 {
+		run := ai.AddUsage(TotalUsage(*newMessages), config.meter.total())
+		left := math.Inf(1)
+		if b := config.Budget; b != nil && b.MaxCost > 0 {
+			left = min(left, b.MaxCost-run.Cost.Total)
+		}
+		if b := config.SessionBudget; b != nil && b.MaxCost > 0 {
+			left = min(left, b.MaxCost-config.SessionUsage.Cost.Total-run.Cost.Total)
+		}
+		return left
+	})
+}
+
+// remainingCost returns the cost the run of ctx may still spend before its
+// Budget or SessionBudget stops it: +Inf without a cost budget or outside a
+// run.
+func remainingCost(ctx context.Context) float64 {
+	if f, ok := ctx.Value(remainingCostKey{}).(func() float64); ok {
+		return f()
+	}
+	return math.Inf(1)
+}
+
+// checkBudgets returns the budget a run has used up, if any. The run's
+// assistant messages and the usage charged to its meter count toward
+// Budget; SessionBudget also counts SessionUsage, the spend before the run.
+func checkBudgets(config *AgentLoopConfig, newMessages []AgentMessage) *BudgetExceeded {
+	run := ai.AddUsage(TotalUsage(newMessages), config.meter.total())
+	if config.Budget.exhausted(run) {
+		return &BudgetExceeded{Scope: BudgetRun, Budget: *config.Budget, Usage: run}
+	}
+	session := ai.AddUsage(config.SessionUsage, run)
+	if config.SessionBudget.exhausted(session) {
+		return &BudgetExceeded{Scope: BudgetSession, Budget: *config.SessionBudget, Usage: session}
+	}
+	return nil
+}
+
+// overBudget ends the run, inside its current turn, when a budget is used
+// up.
+func overBudget(config *AgentLoopConfig, stream *AgentEventStream, newMessages *[]AgentMessage) bool {
+	e := checkBudgets(config, *newMessages)
+	if e == nil {
+		return false
+	}
+	stream.Push(AgentEvent{Type: BudgetExceededEvent, BudgetExceeded: e})
+	endAtLimit(config, e.text(config), true, stream, newMessages)
+	return true
+}
+
+// text is the explanation ending the run.
+func (e *BudgetExceeded) text(config *AgentLoopConfig) string {
+	if e.Budget.MaxCost > 0 && e.Usage.Cost.Total >= e.Budget.MaxCost {
+		return config.text(MsgCostBudget, e.Budget.MaxCost)
+	}
+	return config.text(MsgTokenBudget, e.Budget.MaxTokens)
+}
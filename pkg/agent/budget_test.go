@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// usageStream replies "other" and reports tokens of usage.
+func usageStream(tokens int) StreamFn {
+	return func(model *ai.Model, _ ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonStop,
+			Content: []ai.Content{ai.NewTextContent("other")}, Usage: ai.Usage{Input: tokens, TotalTokens: tokens}}
+		out := ai.NewAssistantMessageEventStream()
+		go out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+		return out
+	}
+}
+
+func TestBudgetCountsModelAnalyzerSpend(t *testing.T) {
+	s := &toolTurnsStream{turns: 5}
+	a := NewAgent(AgentOptions{
+		StreamFn:     s.stream,
+		TurnAnalyzer: ModelAnalyzer{Model: &ai.Model{ID: "small"}, StreamFn: usageStream(1000)},
+		Budget:       &Budget{MaxTokens: 100},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	var exceeded *BudgetExceeded
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == BudgetExceededEvent {
+			exceeded = e.BudgetExceeded
+		}
+	})
+
+	if err := a.Prompt("read"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if exceeded == nil || exceeded.Scope != BudgetRun || exceeded.Usage.TotalTokens != 1000 {
+		t.Fatalf("budget_exceeded = %+v", exceeded)
+	}
+	if len(s.contexts) != 0 {
+		t.Errorf("made %d model calls after the analyzer used up the budget", len(s.contexts))
+	}
+	if got := a.SessionUsage().TotalTokens; got != 1000 {
+		t.Errorf("session usage %d tokens, want 1000", got)
+	}
+}
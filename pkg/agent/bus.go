@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BusMessageKind selects which agent queue a bus message is delivered to.
+type BusMessageKind string
+
+const (
+	BusSteering BusMessageKind = "steering"
+	BusFollowUp BusMessageKind = "followUp"
+)
+
+// BusMessage is a steering or follow-up message addressed to a session.
+type BusMessage struct {
+	SessionID string         `json:"sessionId"`
+	Kind      BusMessageKind `json:"kind"`
+	Message   AgentMessage   `json:"message"`
+}
+
+// MessageBus delivers steering and follow-up messages to whichever replica
+// owns a session. LocalBus covers the single-process case; package
+// pkg/bus has implementations backed by Redis and NATS.
+type MessageBus interface {
+	// Publish sends a message to all subscribers of msg.SessionID.
+	Publish(ctx context.Context, msg BusMessage) error
+	// Subscribe registers fn for messages addressed to sessionID.
+	// The returned function removes the subscription.
+	Subscribe(ctx context.Context, sessionID string, fn func(BusMessage)) (func(), error)
+}
+
+// LocalBus is an in-process MessageBus.
+type LocalBus struct {
+	mu     sync.RWMutex
+	subs   map[string]map[int]func(BusMessage)
+	nextID int
+}
+
+// NewLocalBus creates an empty in-process bus.
+func NewLocalBus() *LocalBus {
+	return &LocalBus{subs: map[string]map[int]func(BusMessage){}}
+}
+
+// Publish delivers msg synchronously to every subscriber of its session.
+func (b *LocalBus) Publish(ctx context.Context, msg BusMessage) error {
+	b.mu.RLock()
+	fns := make([]func(BusMessage), 0, len(b.subs[msg.SessionID]))
+	for _, fn := range b.subs[msg.SessionID] {
+		fns = append(fns, fn)
+	}
+	b.mu.RUnlock()
+	for _, fn := range fns {
+		fn(msg)
+	}
+	return nil
+}
+
+// Subscribe registers fn for messages addressed to sessionID.
+func (b *LocalBus) Subscribe(ctx context.Context, sessionID string, fn func(BusMessage)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	if b.subs[sessionID] == nil {
+		b.subs[sessionID] = map[int]func(BusMessage){}
+	}
+	b.subs[sessionID][id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[sessionID], id)
+		if len(b.subs[sessionID]) == 0 {
+			delete(b.subs, sessionID)
+		}
+	}, nil
+}
+
+// AttachBus subscribes the agent to steering and follow-up messages for its
+// session ID. Returns a function that detaches the agent from the bus.
+func (a *Agent) AttachBus(ctx context.Context, bus MessageBus) (func(), error) {
+	a.mu.Lock()
+	sessionID := a.sessionID
+	a.mu.Unlock()
+	if sessionID == "" {
+		return nil, fmt.Errorf("agent has no session ID")
+	}
+	return bus.Subscribe(ctx, sessionID, func(m BusMessage) {
+		switch m.Kind {
+		case BusSteering:
+			a.Steer(m.Message)
+		case BusFollowUp:
+			a.FollowUp(m.Message)
+		}
+	})
+}
+
+// PublishSteer sends a steering message to the agent owning sessionID.
+func PublishSteer(ctx context.Context, bus MessageBus, sessionID string, m AgentMessage) error {
+	return bus.Publish(ctx, BusMessage{SessionID: sessionID, Kind: BusSteering, Message: m})
+}
+
+// PublishFollowUp sends a follow-up message to the agent owning sessionID.
+func PublishFollowUp(ctx context.Context, bus MessageBus, sessionID string, m AgentMessage) error {
+	return bus.Publish(ctx, BusMessage{SessionID: sessionID, Kind: BusFollowUp, Message: m})
+}
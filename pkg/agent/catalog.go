@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageKey identifies a built-in string that the agent writes into the
+// conversation, e.g. as a tool result.
+type MessageKey string
+
+const (
+	MsgToolNotFound      MessageKey = "tool_not_found" // tool name
+	MsgToolSkipped       MessageKey = "tool_skipped"
+	MsgToolInterrupted   MessageKey = "tool_interrupted"
+	MsgToolDenied        MessageKey = "tool_denied"       // tool name
+	MsgToolTimedOut      MessageKey = "tool_timed_out"    // tool name, timeout
+	MsgToolWithheld      MessageKey = "tool_withheld"     // tool name, error
+	MsgToolRetry         MessageKey = "tool_retry"        // retry, max retries
+	MsgToolProvisional   MessageKey = "tool_provisional"  // tool name, ProvisionalAfter
+	MsgToolFinalOutput   MessageKey = "tool_final_output" // tool name, call ID
+	MsgToolFinalFailed   MessageKey = "tool_final_failed" // tool name, call ID, error
+	MsgToolNotReplayed   MessageKey = "tool_not_replayed" // tool name
+	MsgImageOmitted      MessageKey = "image_omitted"
+	MsgImageBlocked      MessageKey = "image_blocked" // flagged categories
+	MsgCompactionSummary MessageKey = "compaction_summary"
+	MsgContinue          MessageKey = "continue"
+	MsgTurnLimit         MessageKey = "turn_limit"      // MaxTurns
+	MsgToolCallLimit     MessageKey = "tool_call_limit" // MaxToolCalls
+	MsgToolOverLimit     MessageKey = "tool_over_limit" // MaxToolCalls
+	MsgCostBudget        MessageKey = "cost_budget"     // Budget.MaxCost
+	MsgTokenBudget       MessageKey = "token_budget"    // Budget.MaxTokens
+	MsgSubAgentTurns     MessageKey = "subagent_turns"  // MaxTurns
+	MsgSubAgentCost      MessageKey = "subagent_cost"   // MaxCost
+	MsgSubAgentLast      MessageKey = "subagent_last"
+	MsgRemainingTasks    MessageKey = "remaining_tasks"
+	MsgValidationStrict  MessageKey = "validation_strict"  // tool name, failures, schema
+	MsgValidationExample MessageKey = "validation_example" // tool name, failures, example
+	MsgValidationAskErr  MessageKey = "validation_ask_err" // error
+	MsgValidationNoFix   MessageKey = "validation_no_fix"  // tool name
+	MsgValidationInvalid MessageKey = "validation_invalid" // error
+)
+
+// Catalog holds translations of the built-in strings by locale. Templates
+// take the same fmt arguments as the English originals, in the order noted
+// on the keys; use explicit argument indexes (%[2]s) to reorder them.
+type Catalog map[string]map[MessageKey]string
+
+// DefaultCatalog holds English and the built-in translations.
+var DefaultCatalog = Catalog{
+	"en": {
+		MsgToolNotFound:      "Tool %s not found",
+		MsgToolSkipped:       "Skipped due to queued user message.",
+		MsgToolInterrupted:   "Cancelled due to queued user message.",
+		MsgToolDenied:        "the user denied permission to run tool %s",
+		MsgToolTimedOut:      "tool %s timed out after %s and was cancelled",
+		MsgToolWithheld:      "The result of tool %s was withheld: %v",
+		MsgToolRetry:         "Correct the tool call and try again (retry %d of %d).",
+		MsgToolProvisional:   "[Provisional: %s is still running after %s; this is its output so far. The final output will follow in a later message.]",
+		MsgToolFinalOutput:   "Final output of tool %s (call %s), replacing its provisional result:",
+		MsgToolFinalFailed:   "Tool %s (call %s) failed after its provisional result: %s",
+		MsgToolNotReplayed:   "Tool %s was not run during replay and no recorded call used these arguments.",
+		MsgImageOmitted:      "(image omitted: not supported by model)",
+		MsgImageBlocked:      "[Image withheld by moderation: %s]",
+		MsgCompactionSummary: "The earlier part of this conversation was summarized to save context:",
+		MsgContinue:          DefaultNudge,
+		MsgRemainingTasks:    "Remaining tasks:",
+		MsgTurnLimit:         "Stopped: this run reached its limit of %d turns.",
+		MsgToolCallLimit:     "Stopped: this run reached its limit of %d tool calls.",
+		MsgToolOverLimit:     "Not run: the run reached its limit of %d tool calls.",
+		MsgCostBudget:        "Stopped: the cost budget of $%.2f is used up.",
+		MsgTokenBudget:       "Stopped: the budget of %d tokens is used up.",
+		MsgSubAgentTurns:     "The sub-agent reached its limit of %d turns before finishing the task.",
+		MsgSubAgentCost:      "The sub-agent reached its cost limit of $%.4f before finishing the task.",
+		MsgSubAgentLast:      "Its last message was:",
+		MsgValidationStrict:  "The arguments for %s failed validation %d times in a row. Strict schema mode is now on for this tool: the arguments must match this JSON schema exactly, with every required property and no others:\n%s",
+		MsgValidationExample: "The arguments for %s failed validation %d times in a row. Here is an example of valid arguments; call the tool again following this structure with your own values:\n%s",
+		MsgValidationAskErr:  "Asking for corrected arguments failed: %v",
+		MsgValidationNoFix:   "No corrected arguments were provided for %s; do not call it again with the same arguments.",
+		MsgValidationInvalid: "The corrected arguments are also invalid: %v",
+	},
+	"de": {
+		MsgToolNotFound:      "Werkzeug %s nicht gefunden",
+		MsgToolSkipped:       "Übersprungen wegen einer wartenden Nachricht des Benutzers.",
+		MsgToolInterrupted:   "Abgebrochen wegen einer wartenden Nachricht des Benutzers.",
+		MsgToolDenied:        "der Benutzer hat die Ausführung von Werkzeug %s abgelehnt",
+		MsgToolTimedOut:      "Werkzeug %s hat das Zeitlimit von %s überschritten und wurde abgebrochen",
+		MsgToolWithheld:      "Das Ergebnis von Werkzeug %s wurde zurückgehalten: %v",
+		MsgToolRetry:         "Korrigiere den Werkzeugaufruf und versuche es erneut (Versuch %d von %d).",
+		MsgToolProvisional:   "[Vorläufig: %s läuft nach %s noch; dies ist die bisherige Ausgabe. Die endgültige Ausgabe folgt in einer späteren Nachricht.]",
+		MsgToolFinalOutput:   "Endgültige Ausgabe von Werkzeug %s (Aufruf %s), ersetzt das vorläufige Ergebnis:",
+		MsgToolFinalFailed:   "Werkzeug %s (Aufruf %s) ist nach dem vorläufigen Ergebnis fehlgeschlagen: %s",
+		MsgToolNotReplayed:   "Werkzeug %s wurde bei der Wiederholung nicht ausgeführt, und kein aufgezeichneter Aufruf verwendete diese Argumente.",
+		MsgImageOmitted:      "(Bild ausgelassen: vom Modell nicht unterstützt)",
+		MsgImageBlocked:      "[Bild von der Moderation zurückgehalten: %s]",
+		MsgCompactionSummary: "Der frühere Teil dieses Gesprächs wurde zusammengefasst, um Kontext zu sparen:",
+		MsgContinue:          "Du hast aufgehört, bevor die Aufgabe erledigt war. Arbeite weiter, bis sie abgeschlossen ist.",
+		MsgRemainingTasks:    "Offene Aufgaben:",
+		MsgTurnLimit:         "Angehalten: Dieser Lauf hat sein Limit von %d Runden erreicht.",
+		MsgToolCallLimit:     "Angehalten: Dieser Lauf hat sein Limit von %d Werkzeugaufrufen erreicht.",
+		MsgToolOverLimit:     "Nicht ausgeführt: Der Lauf hat sein Limit von %d Werkzeugaufrufen erreicht.",
+		MsgCostBudget:        "Angehalten: Das Kostenbudget von $%.2f ist aufgebraucht.",
+		MsgTokenBudget:       "Angehalten: Das Budget von %d Tokens ist aufgebraucht.",
+		MsgSubAgentTurns:     "Der Sub-Agent hat sein Limit von %d Runden erreicht, bevor die Aufgabe erledigt war.",
+		MsgSubAgentCost:      "Der Sub-Agent hat sein Kostenlimit von $%.4f erreicht, bevor die Aufgabe erledigt war.",
+		MsgSubAgentLast:      "Seine letzte Nachricht war:",
+		MsgValidationStrict:  "Die Argumente für %s sind %d-mal hintereinander an der Validierung gescheitert. Für dieses Werkzeug gilt jetzt der strikte Schemamodus: Die Argumente müssen genau diesem JSON-Schema entsprechen, mit allen Pflichtfeldern und keinen weiteren:\n%s",
+		MsgValidationExample: "Die Argumente für %s sind %d-mal hintereinander an der Validierung gescheitert. Hier ist ein Beispiel für gültige Argumente; rufe das Werkzeug erneut nach diesem Aufbau mit deinen eigenen Werten auf:\n%s",
+		MsgValidationAskErr:  "Die Abfrage korrigierter Argumente ist fehlgeschlagen: %v",
+		MsgValidationNoFix:   "Für %s wurden keine korrigierten Argumente angegeben; rufe es nicht noch einmal mit denselben Argumenten auf.",
+		MsgValidationInvalid: "Auch die korrigierten Argumente sind ungültig: %v",
+	},
+	"es": {
+		MsgToolNotFound:      "No se encontró la herramienta %s",
+		MsgToolSkipped:       "Omitido por un mensaje del usuario en cola.",
+		MsgToolInterrupted:   "Cancelado por un mensaje del usuario en cola.",
+		MsgToolDenied:        "el usuario denegó el permiso para ejecutar la herramienta %s",
+		MsgToolTimedOut:      "la herramienta %s superó el tiempo límite de %s y fue cancelada",
+		MsgToolWithheld:      "Se retuvo el resultado de la herramienta %s: %v",
+		MsgToolRetry:         "Corrige la llamada a la herramienta e inténtalo de nuevo (intento %d de %d).",
+		MsgToolProvisional:   "[Provisional: %s sigue en ejecución después de %s; esta es su salida hasta ahora. La salida final llegará en un mensaje posterior.]",
+		MsgToolFinalOutput:   "Salida final de la herramienta %s (llamada %s), que reemplaza su resultado provisional:",
+		MsgToolFinalFailed:   "La herramienta %s (llamada %s) falló después de su resultado provisional: %s",
+		MsgToolNotReplayed:   "La herramienta %s no se ejecutó durante la repetición y ninguna llamada registrada usó estos argumentos.",
+		MsgImageOmitted:      "(imagen omitida: el modelo no la admite)",
+		MsgImageBlocked:      "[Imagen retenida por la moderación: %s]",
+		MsgCompactionSummary: "La parte anterior de esta conversación se resumió para ahorrar contexto:",
+		MsgContinue:          "Te detuviste antes de completar la tarea. Sigue trabajando hasta terminarla.",
+		MsgRemainingTasks:    "Tareas pendientes:",
+		MsgTurnLimit:         "Detenido: esta ejecución alcanzó su límite de %d turnos.",
+		MsgToolCallLimit:     "Detenido: esta ejecución alcanzó su límite de %d llamadas a herramientas.",
+		MsgToolOverLimit:     "No ejecutada: la ejecución alcanzó su límite de %d llamadas a herramientas.",
+		MsgCostBudget:        "Detenido: se agotó el presupuesto de costo de $%.2f.",
+		MsgTokenBudget:       "Detenido: se agotó el presupuesto de %d tokens.",
+		MsgSubAgentTurns:     "El subagente alcanzó su límite de %d turnos antes de terminar la tarea.",
+		MsgSubAgentCost:      "El subagente alcanzó su límite de costo de $%.4f antes de terminar la tarea.",
+		MsgSubAgentLast:      "Su último mensaje fue:",
+		MsgValidationStrict:  "Los argumentos de %s fallaron la validación %d veces seguidas. El modo de esquema estricto está activado para esta herramienta: los argumentos deben ajustarse exactamente a este esquema JSON, con todas las propiedades obligatorias y ninguna otra:\n%s",
+		MsgValidationExample: "Los argumentos de %s fallaron la validación %d veces seguidas. Este es un ejemplo de argumentos válidos; vuelve a llamar a la herramienta siguiendo esta estructura con tus propios valores:\n%s",
+		MsgValidationAskErr:  "Falló la solicitud de argumentos corregidos: %v",
+		MsgValidationNoFix:   "No se proporcionaron argumentos corregidos para %s; no vuelvas a llamarla con los mismos argumentos.",
+		MsgValidationInvalid: "Los argumentos corregidos también son inválidos: %v",
+	},
+	"fr": {
+		MsgToolNotFound:      "Outil %s introuvable",
+		MsgToolSkipped:       "Ignoré en raison d'un message utilisateur en attente.",
+		MsgToolInterrupted:   "Annulé en raison d'un message utilisateur en attente.",
+		MsgToolDenied:        "l'utilisateur a refusé l'exécution de l'outil %s",
+		MsgToolTimedOut:      "l'outil %s a dépassé le délai de %s et a été annulé",
+		MsgToolWithheld:      "Le résultat de l'outil %s a été retenu : %v",
+		MsgToolRetry:         "Corrige l'appel d'outil et réessaie (tentative %d sur %d).",
+		MsgToolProvisional:   "[Provisoire : %s est toujours en cours après %s ; voici sa sortie jusqu'à présent. La sortie finale suivra dans un message ultérieur.]",
+		MsgToolFinalOutput:   "Sortie finale de l'outil %s (appel %s), qui remplace son résultat provisoire :",
+		MsgToolFinalFailed:   "L'outil %s (appel %s) a échoué après son résultat provisoire : %s",
+		MsgToolNotReplayed:   "L'outil %s n'a pas été exécuté lors de la relecture et aucun appel enregistré n'utilisait ces arguments.",
+		MsgImageOmitted:      "(image omise : non prise en charge par le modèle)",
+		MsgImageBlocked:      "[Image retenue par la modération : %s]",
+		MsgCompactionSummary: "La première partie de cette conversation a été résumée pour économiser du contexte :",
+		MsgContinue:          "Tu t'es arrêté avant la fin de la tâche. Continue jusqu'à ce qu'elle soit terminée.",
+		MsgRemainingTasks:    "Tâches restantes :",
+		MsgTurnLimit:         "Arrêté : cette exécution a atteint sa limite de %d tours.",
+		MsgToolCallLimit:     "Arrêté : cette exécution a atteint sa limite de %d appels d'outils.",
+		MsgToolOverLimit:     "Non exécuté : l'exécution a atteint sa limite de %d appels d'outils.",
+		MsgCostBudget:        "Arrêté : le budget de coût de %.2f $ est épuisé.",
+		MsgTokenBudget:       "Arrêté : le budget de %d tokens est épuisé.",
+		MsgSubAgentTurns:     "Le sous-agent a atteint sa limite de %d tours avant de terminer la tâche.",
+		MsgSubAgentCost:      "Le sous-agent a atteint sa limite de coût de %.4f $ avant de terminer la tâche.",
+		MsgSubAgentLast:      "Son dernier message était :",
+		MsgValidationStrict:  "Les arguments de %s ont échoué à la validation %d fois de suite. Le mode de schéma strict est maintenant activé pour cet outil : les arguments doivent correspondre exactement à ce schéma JSON, avec toutes les propriétés requises et aucune autre :\n%s",
+		MsgValidationExample: "Les arguments de %s ont échoué à la validation %d fois de suite. Voici un exemple d'arguments valides ; rappelle l'outil en suivant cette structure avec tes propres valeurs :\n%s",
+		MsgValidationAskErr:  "La demande d'arguments corrigés a échoué : %v",
+		MsgValidationNoFix:   "Aucun argument corrigé n'a été fourni pour %s ; ne le rappelle pas avec les mêmes arguments.",
+		MsgValidationInvalid: "Les arguments corrigés sont également invalides : %v",
+	},
+}
+
+// Text formats key for locale. A regional locale ("pt-BR") falls back to
+// its language ("pt"), then to English; at each step keys missing from c
+// are taken from DefaultCatalog.
+func (c Catalog) Text(locale string, key MessageKey, args ...any) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, lang)
+	}
+	candidates = append(candidates, "en")
+	for _, l := range candidates {
+		for _, cat := range []Catalog{c, DefaultCatalog} {
+			if tmpl, ok := cat[l][key]; ok {
+				if len(args) == 0 {
+					return tmpl
+				}
+				return fmt.Sprintf(tmpl, args...)
+			}
+		}
+	}
+	return string(key)
+}
+
+// text formats key in the run's locale.
+func (c *AgentLoopConfig) text(key MessageKey, args ...any) string {
+	return c.Catalog.Text(c.Locale, key, args...)
+}
@@ -0,0 +1,13 @@
+package agent
+
+import "testing"
+
+func TestDefaultCatalogTranslatesEveryKey(t *testing.T) {
+	for locale, texts := range DefaultCatalog {
+		for key := range DefaultCatalog["en"] {
+			if _, ok := texts[key]; !ok {
+				t.Errorf("%s lacks %s", locale, key)
+			}
+		}
+	}
+}
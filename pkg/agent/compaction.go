@@ -0,0 +1,343 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/textsplit"
+)
+
+// MetadataCompaction marks a compaction summary message. Its value is the
+// index, in the conversation, of the first message the summary does not
+// cover; see CompactedView.
+const MetadataCompaction = "compaction"
+
+// defaultCompactionPrompt instructs the summarization model.
+const defaultCompactionPrompt = `Summarize the conversation transcript below so that it can replace the transcript in the context of an assistant continuing the work. Keep the user's goals and constraints, decisions made, facts learned (file names, values, errors), tool results still relevant, and open tasks. Be concise; do not add commentary.`
+
+// CompactionPolicy summarizes older turns once the conversation nears the
+// model's context window. The summary is appended to the conversation as a
+// CompactionSummary message marked with MetadataCompaction, and from then
+// on the model sees the summary, as a user message, followed by the recent
+// messages it does not cover. The original messages stay in the history.
+type CompactionPolicy struct {
+	// Threshold is the fraction of Model.ContextWindow at which to compact
+	// (default 0.8).
+	Threshold float64
+
+	// KeepRecent is the fraction of the context window kept verbatim from
+	// the most recent messages (default 0.25).
+	KeepRecent float64
+
+	// Model summarizes; nil uses the run's model. A cheaper model with a
+	// large enough window is usually the better choice.
+	Model *ai.Model
+
+	// Prompt is the system prompt of the summarization call.
+	Prompt string
+
+	// Summarize replaces the summarization call, e.g. to use another
+	// service. It receives the messages being replaced (starting with the
+	// previous summary, if any); report model usage with ChargeUsage.
+	Summarize func(ctx context.Context, messages []AgentMessage) (string, error)
+}
+
+// Compaction describes one compaction. It is emitted as a CompactionEvent.
+type Compaction struct {
+	Summary      string `json:"summary"`
+	Replaced     int    `json:"replaced"`  // messages the summary replaces in the model's view
+	FirstKept    int    `json:"firstKept"` // conversation index of the first message kept verbatim
+	TokensBefore int    `json:"tokensBefore"`
+	TokensAfter  int    `json:"tokensAfter"`
+	Model        string `json:"model,omitempty"` // summarization model ID
+}
+
+// CompactionSummary is the Custom payload of a compaction summary. It is
+// not an LLM message, so the summary never becomes the conversation's
+// latest user turn (for Continue, language detection, ...); CompactedView
+// presents it to the model as a user message.
+type CompactionSummary struct {
+	Text string `json:"text"` // as shown to the model, with its introduction
+}
+
+func init() {
+	RegisterCustomType("compaction", CompactionSummary{})
+}
+
+func isCompactionSummary(m AgentMessage) bool {
+	_, ok := m.Custom.(CompactionSummary)
+	return ok
+}
+
+// summaryView returns the user message the model sees for a summary.
+// Summaries written as user messages by earlier versions are returned
+// unchanged.
+func summaryView(m AgentMessage) AgentMessage {
+	s, ok := m.Custom.(CompactionSummary)
+	if !ok {
+		return m
+	}
+	v := NewAgentMessageFromMessage(ai.NewUserMessage(s.Text))
+	v.ID, v.Metadata = m.ID, m.Metadata
+	return v
+}
+
+// CompactedView returns the messages the model sees: after the latest
+// compaction summary, the summary followed by the other messages from its
+// first kept index on. Without a summary msgs is returned unchanged.
+func CompactedView(msgs []AgentMessage) []AgentMessage {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		k, ok := compactionIndex(msgs[i])
+		if !ok {
+			continue
+		}
+		if k < 0 || k > i {
+			return msgs
+		}
+		view := make([]AgentMessage, 0, len(msgs)-k)
+		view = append(view, summaryView(msgs[i]))
+		for _, m := range msgs[k:] {
+			if _, summary := compactionIndex(m); !summary {
+				view = append(view, m)
+			}
+		}
+		return view
+	}
+	return msgs
+}
+
+// compactionIndex returns the first kept index recorded on a summary.
+func compactionIndex(m AgentMessage) (int, bool) {
+	v, ok := m.Metadata[MetadataCompaction].(int)
+	return v, ok
+}
+
+// EstimateContextTokens estimates how many tokens agentCtx occupies in
+// model's context window, as the loop would send it (see CompactedView).
+// A nil convert uses DefaultConvertToLLM.
+func EstimateContextTokens(model *ai.Model, agentCtx AgentContext, convert func([]AgentMessage) ([]ai.Message, error)) int {
+	if convert == nil {
+		convert = DefaultConvertToLLM
+	}
+	msgs, err := convert(slices.Clone(CompactedView(agentCtx.Messages)))
+	if err != nil {
+		return 0
+	}
+	llmCtx := ai.Context{SystemPrompt: agentCtx.SystemPrompt, Messages: msgs}
+	for _, t := range agentCtx.Tools {
+		llmCtx.Tools = append(llmCtx.Tools, t.LLMTool())
+	}
+	return ai.CountTokens(model, llmCtx)
+}
+
+// maybeCompact compacts agentCtx when it is above the policy's threshold.
+// The summary is appended to agentCtx and newMessages; failures are
+// reported as warnings and leave the context as it was. It reports whether
+// it summarized, successfully or not, so that the caller can check the
+// budgets the summarization was charged to.
+func (p *CompactionPolicy) maybeCompact(ctx context.Context, agentCtx *AgentContext, config *AgentLoopConfig, stream *AgentEventStream, streamFn StreamFn, newMessages *[]AgentMessage) bool {
+	model := config.Model
+	if p == nil || model == nil || model.ContextWindow <= 0 {
+		return false
+	}
+	threshold, keepRecent := p.Threshold, p.KeepRecent
+	if threshold <= 0 {
+		threshold = 0.8
+	}
+	if keepRecent <= 0 {
+		keepRecent = 0.25
+	}
+	before := EstimateContextTokens(model, *agentCtx, config.ConvertToLLM)
+	if float64(before) < threshold*float64(model.ContextWindow) {
+		return false
+	}
+
+	// The view starts at the previous summary, if any; find the cut in the
+	// conversation that keeps about keepRecent of the window, at a turn
+	// boundary so that tool calls stay with their results.
+	msgs := agentCtx.Messages
+	start := 0
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if k, ok := compactionIndex(msgs[i]); ok {
+			start = k
+			break
+		}
+	}
+	budget := int(keepRecent * float64(model.ContextWindow))
+	boundaries := turnBoundaries(msgs, start)
+	cut, kept := -1, 0
+	for i := len(msgs) - 1; i > start; i-- {
+		if _, ok := compactionIndex(msgs[i]); ok {
+			continue
+		}
+		kept += messageTokens(model, msgs[i])
+		if kept > budget {
+			break
+		}
+		if boundaries[i] {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return false
+	}
+	replaced := compactedRange(msgs, cut)
+
+	summaryModel := model
+	if p.Model != nil {
+		summaryModel = p.Model
+	}
+	var summary string
+	var err error
+	if p.Summarize != nil {
+		summary, err = p.Summarize(ctx, replaced)
+	} else {
+		summary, err = p.summarize(ctx, summaryModel, replaced, config, streamFn)
+	}
+	if err != nil {
+		stream.Push(AgentEvent{Type: WarningEvent, Warning: fmt.Sprintf("context compaction failed: %v", err)})
+		return true
+	}
+
+	am := AgentMessage{Custom: CompactionSummary{Text: config.text(MsgCompactionSummary) + "\n\n<summary>\n" + strings.TrimSpace(summary) + "\n</summary>"}}
+	am.SetMetadata(MetadataCompaction, cut)
+	stream.Push(AgentEvent{Type: MessageEventStart, Message: &am})
+	stream.Push(AgentEvent{Type: MessageEventEnd, Message: &am})
+	agentCtx.Messages = append(agentCtx.Messages, am)
+	*newMessages = append(*newMessages, am)
+
+	stream.Push(AgentEvent{Type: CompactionEvent, Compaction: &Compaction{
+		Summary:      summary,
+		Replaced:     len(replaced),
+		FirstKept:    cut,
+		TokensBefore: before,
+		TokensAfter:  EstimateContextTokens(model, *agentCtx, config.ConvertToLLM),
+		Model:        summaryModel.ID,
+	}})
+	return true
+}
+
+// compactedRange returns the messages of the current view that come
+// before the conversation index cut: the previous summary, if any, and the
+// messages between its first kept index and cut.
+func compactedRange(msgs []AgentMessage, cut int) []AgentMessage {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if k, ok := compactionIndex(msgs[i]); ok && k >= 0 && k <= cut {
+			out := []AgentMessage{summaryView(msgs[i])}
+			for _, m := range msgs[k:cut] {
+				if _, summary := compactionIndex(m); !summary {
+					out = append(out, m)
+				}
+			}
+			return out
+		}
+	}
+	return slices.Clone(msgs[:cut])
+}
+
+// turnBoundaries reports, for each message from start on, whether the
+// conversation may be cut before it: at a user message, or at an assistant
+// message once every earlier tool call has its result. Agents that work
+// through many tool turns after one prompt thus still have cut points.
+func turnBoundaries(msgs []AgentMessage, start int) []bool {
+	boundaries := make([]bool, len(msgs))
+	pending := map[string]bool{}
+	for i := start; i < len(msgs); i++ {
+		m := msgs[i]
+		switch {
+		case m.User != nil:
+			boundaries[i] = true
+			clear(pending)
+		case m.Assistant != nil:
+			boundaries[i] = len(pending) == 0
+			for _, c := range m.Assistant.Content {
+				if c.ToolCall != nil {
+					pending[c.ToolCall.ID] = true
+				}
+			}
+		case m.ToolResult != nil:
+			delete(pending, m.ToolResult.ToolCallID)
+		}
+	}
+	return boundaries
+}
+
+func messageTokens(model *ai.Model, m AgentMessage) int {
+	llm, err := DefaultConvertToLLM([]AgentMessage{m})
+	if err != nil {
+		return 0
+	}
+	return ai.CountTokens(model, ai.Context{Messages: llm})
+}
+
+// summarize asks model for a summary of msgs through the loop's stream
+// function.
+func (p *CompactionPolicy) summarize(ctx context.Context, model *ai.Model, msgs []AgentMessage, config *AgentLoopConfig, streamFn StreamFn) (string, error) {
+	sf, err := loopStreamFn(config, streamFn)
+	if err != nil {
+		return "", err
+	}
+	prompt := p.Prompt
+	if prompt == "" {
+		prompt = defaultCompactionPrompt
+	}
+	opts := config.SimpleStreamOptions
+	opts.Reasoning, opts.Temperature, opts.MaxTokens = "", nil, nil
+	if config.GetApiKey != nil {
+		if key, err := config.GetApiKey(model.Provider); err == nil {
+			opts.ApiKey = key
+		}
+	}
+
+	// A transcript too large for the summary model is summarized in parts
+	// of at most half its window, leaving room for the prompt and answer.
+	transcript := RenderTranscript(msgs)
+	parts := []string{transcript}
+	if model.ContextWindow > 0 && ai.EstimateTokens(transcript) > model.ContextWindow/2 {
+		parts = parts[:0]
+		for _, c := range textsplit.ByTokens(transcript, textsplit.Options{MaxTokens: model.ContextWindow / 2}) {
+			parts = append(parts, c.Text)
+		}
+	}
+	summaries := make([]string, 0, len(parts))
+	for _, part := range parts {
+		llmCtx := ai.Context{
+			SystemPrompt: prompt,
+			Messages:     []ai.Message{ai.NewUserMessage("<transcript>\n" + part + "</transcript>")},
+		}
+		summary, err := p.summarizePart(ctx, sf, model, llmCtx, opts, config)
+		if err != nil {
+			return "", err
+		}
+		summaries = append(summaries, summary)
+	}
+	return strings.Join(summaries, "\n\n"), nil
+}
+
+// summarizePart runs one summarization call.
+func (p *CompactionPolicy) summarizePart(ctx context.Context, sf StreamCtxFn, model *ai.Model, llmCtx ai.Context, opts ai.SimpleStreamOptions, config *AgentLoopConfig) (string, error) {
+	response := sf(ctx, model, llmCtx, ai.FitMaxTokens(model, llmCtx, &opts))
+	for range response.Events() {
+	}
+	msg := response.Result()
+	if msg == nil {
+		return "", fmt.Errorf("summarization produced no response")
+	}
+	config.meter.charge(msg.Usage)
+	if msg.StopReason == ai.StopReasonError || msg.StopReason == ai.StopReasonAborted {
+		return "", fmt.Errorf("summarization failed: %s", msg.ErrorMessage)
+	}
+	var sb strings.Builder
+	for _, c := range msg.Content {
+		if c.Text != nil {
+			sb.WriteString(c.Text.Text)
+		}
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return "", fmt.Errorf("summarization returned no text")
+	}
+	return sb.String(), nil
+}
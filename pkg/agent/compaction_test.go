@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// toolTurnsStream answers with a tool call until it has made turns calls,
+// then with a final text. It records the context of every call.
+type toolTurnsStream struct {
+	mu       sync.Mutex
+	turns    int
+	contexts []ai.Context
+}
+
+func (s *toolTurnsStream) stream(model *ai.Model, llmCtx ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+	s.mu.Lock()
+	s.contexts = append(s.contexts, llmCtx)
+	n := len(s.contexts)
+	s.mu.Unlock()
+
+	msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonStop}
+	if n <= s.turns {
+		msg.StopReason = ai.StopReasonToolUse
+		msg.Content = []ai.Content{ai.NewToolCallContent(fmt.Sprintf("call_%d", n), "read", map[string]any{})}
+	} else {
+		msg.Content = []ai.Content{ai.NewTextContent("done")}
+	}
+	out := ai.NewAssistantMessageEventStream()
+	partial := *msg
+	go func() {
+		out.Push(ai.AssistantMessageEvent{Type: ai.EventStart, Partial: &partial})
+		out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+	}()
+	return out
+}
+
+func TestCompactionCutsAtTurnBoundaries(t *testing.T) {
+	type noArgs struct{}
+	page := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	s := &toolTurnsStream{turns: 12}
+	a := NewAgent(AgentOptions{
+		StreamFn: s.stream,
+		Compaction: &CompactionPolicy{
+			Summarize: func(ctx context.Context, msgs []AgentMessage) (string, error) {
+				return fmt.Sprintf("summary of %d messages", len(msgs)), nil
+			},
+		},
+	})
+	a.SetModel(&ai.Model{ID: "test", ContextWindow: 4000})
+	a.SetTools([]AgentTool{NewTool("read", "Read a page.", func(ctx context.Context, _ noArgs) (AgentToolResult, error) {
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent(page)}}, nil
+	})})
+	var compactions []Compaction
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == CompactionEvent {
+			compactions = append(compactions, *e.Compaction)
+		}
+	})
+
+	if err := a.Prompt("read the pages"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	st := a.State()
+	if st.Error != "" {
+		t.Fatalf("run failed: %s", st.Error)
+	}
+
+	if len(compactions) == 0 {
+		t.Fatal("no compaction although the context outgrew the threshold")
+	}
+	first := compactions[0]
+	if first.TokensAfter >= first.TokensBefore {
+		t.Errorf("compaction did not shrink the context: %d -> %d tokens", first.TokensBefore, first.TokensAfter)
+	}
+	if st.Messages[first.FirstKept].Assistant == nil {
+		t.Errorf("first kept message is %q, want an assistant message", st.Messages[first.FirstKept].Role())
+	}
+
+	// The summary is not a user turn in the history...
+	users := 0
+	for _, m := range st.Messages {
+		if m.User != nil {
+			users++
+		}
+		if _, ok := m.Metadata[MetadataCompaction]; ok && !isCompactionSummary(m) {
+			t.Errorf("summary stored as %q message, want a CompactionSummary", m.Role())
+		}
+	}
+	if users != 1 {
+		t.Errorf("history has %d user messages, want only the prompt", users)
+	}
+
+	// ...but the model sees it as one, followed by complete tool turns.
+	last := s.contexts[len(s.contexts)-1].Messages
+	if last[0].User == nil || !strings.Contains(last[0].User.Content[0].Text.Text, "<summary>") {
+		t.Fatalf("model context does not start with the summary: %+v", last[0])
+	}
+	if last[1].Assistant == nil {
+		t.Errorf("summary is followed by %q, want assistant", last[1].Role())
+	}
+	calls := map[string]bool{}
+	for _, m := range last {
+		if m.Assistant != nil {
+			for _, c := range m.Assistant.Content {
+				if c.ToolCall != nil {
+					calls[c.ToolCall.ID] = true
+				}
+			}
+		}
+		if m.ToolResult != nil && !calls[m.ToolResult.ToolCallID] {
+			t.Errorf("tool result %s sent without its call", m.ToolResult.ToolCallID)
+		}
+	}
+}
+
+func TestContinueSkipsCompactionSummary(t *testing.T) {
+	msgs := []AgentMessage{
+		NewAgentMessageFromMessage(ai.NewUserMessage("hi")),
+		NewAgentMessageFromMessage(ai.Message{Assistant: &ai.AssistantMessage{Role: ai.RoleAssistant, StopReason: ai.StopReasonStop}}),
+		{Custom: CompactionSummary{Text: "summary"}, Metadata: map[string]any{MetadataCompaction: 1}},
+	}
+	_, err := AgentLoopContinue(context.Background(), AgentContext{Messages: msgs}, AgentLoopConfig{}, nil)
+	if err == nil {
+		t.Fatal("continued from an assistant message hidden behind a summary")
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ContextUsage reports how full the model's context window is after a
+// turn. It is emitted as a ContextUsageEvent.
+type ContextUsage struct {
+	Tokens        int     `json:"tokens"`
+	ContextWindow int     `json:"contextWindow"`
+	Fraction      float64 `json:"fraction"`            // Tokens / ContextWindow
+	Estimated     bool    `json:"estimated,omitempty"` // counted locally instead of reported by the provider
+	Crossed       float64 `json:"crossed,omitempty"`   // highest threshold crossed by this turn, if any
+}
+
+// DefaultContextThresholds are used when ContextMonitor.Thresholds is nil.
+var DefaultContextThresholds = []float64{0.70, 0.85, 0.95}
+
+// ContextMonitor calls OnThreshold when context usage rises past one of
+// its thresholds, so apps can warn users or compact before the window
+// overflows. A threshold fires again only after usage has dropped below it
+// (e.g. after compaction). Share one monitor per conversation.
+type ContextMonitor struct {
+	Thresholds  []float64 // fractions of the context window; nil uses DefaultContextThresholds
+	OnThreshold func(usage ContextUsage, threshold float64)
+
+	mu   sync.Mutex
+	last float64 // fraction seen after the previous turn
+}
+
+// observe records u and sets u.Crossed to the highest threshold crossed
+// since the previous observation, calling OnThreshold if one was.
+func (m *ContextMonitor) observe(u *ContextUsage) {
+	if m == nil {
+		return
+	}
+	thresholds := m.Thresholds
+	if thresholds == nil {
+		thresholds = DefaultContextThresholds
+	}
+	m.mu.Lock()
+	for _, t := range thresholds {
+		if m.last < t && u.Fraction >= t {
+			u.Crossed = max(u.Crossed, t)
+		}
+	}
+	m.last = u.Fraction
+	m.mu.Unlock()
+	if u.Crossed > 0 && m.OnThreshold != nil {
+		m.OnThreshold(*u, u.Crossed)
+	}
+}
+
+// Reset forgets the last observed usage, e.g. when the conversation is
+// cleared.
+func (m *ContextMonitor) Reset() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = 0
+}
+
+// measureContext computes the context usage after a turn. The provider's
+// reported usage for the response is preferred, plus an estimate for the
+// tool results appended after it; without usage the whole context is
+// counted locally. ok is false when the model's window is unknown.
+func measureContext(config *AgentLoopConfig, agentCtx *AgentContext, reply *ai.AssistantMessage, toolResults []ai.ToolResultMessage) (ContextUsage, bool) {
+	model := config.Model
+	if model == nil || model.ContextWindow <= 0 {
+		return ContextUsage{}, false
+	}
+	u := ContextUsage{ContextWindow: model.ContextWindow}
+	usage := reply.Usage
+	if total := usage.Input + usage.Output + usage.CacheRead + usage.CacheWrite; total > 0 {
+		u.Tokens = max(total, usage.TotalTokens)
+		u.Estimated = usage.Estimated
+		if len(toolResults) > 0 {
+			msgs := make([]ai.Message, len(toolResults))
+			for i := range toolResults {
+				msgs[i] = ai.Message{ToolResult: &toolResults[i]}
+			}
+			u.Tokens += ai.CountTokens(model, ai.Context{Messages: msgs})
+			u.Estimated = true
+		}
+	} else {
+		msgs, err := config.ConvertToLLM(slices.Clone(CompactedView(agentCtx.Messages)))
+		if err != nil {
+			return ContextUsage{}, false
+		}
+		llmCtx := ai.Context{SystemPrompt: agentCtx.SystemPrompt, Messages: msgs}
+		for _, t := range agentCtx.Tools {
+			llmCtx.Tools = append(llmCtx.Tools, t.LLMTool())
+		}
+		u.Tokens = ai.CountTokens(model, llmCtx)
+		u.Estimated = true
+	}
+	u.Fraction = float64(u.Tokens) / float64(model.ContextWindow)
+	return u, true
+}
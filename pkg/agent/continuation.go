@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// MetadataSynthetic is the AgentMessage.Metadata key set to true on user
+// messages injected by the agent rather than typed by a person.
+const MetadataSynthetic = "synthetic"
+
+// DefaultNudge is the message injected when a ContinuationPolicy has no
+// more specific text.
+const DefaultNudge = "You stopped before the task was complete. Continue working until it is done."
+
+// ContinuationPolicy keeps unattended runs going: when the model stops
+// while the task is not yet complete, a synthetic user message nudges it to
+// continue, up to MaxNudges times per run. It is opt-in; a policy with
+// neither Pending nor Judge never nudges.
+type ContinuationPolicy struct {
+	// Pending returns the tasks that remain open, e.g. from a todo tool.
+	// The run counts as incomplete while it returns any, and the nudge
+	// lists them.
+	Pending func() []string
+
+	// Judge decides from the conversation whether the task is complete. It
+	// may return the nudge text to use; "" uses Nudge. Judge errors end the
+	// run normally.
+	Judge func(ctx context.Context, messages []AgentMessage) (done bool, nudge string, err error)
+
+	// MaxNudges caps the nudges per run (default 3).
+	MaxNudges int
+
+	// Nudge is the default nudge text (default DefaultNudge, or its
+	// translation for the run's Locale).
+	Nudge string
+}
+
+// next returns the nudge to inject after the model stopped, or nil when the
+// run should end. nudges counts the nudges injected so far.
+func (p *ContinuationPolicy) next(ctx context.Context, config *AgentLoopConfig, messages []AgentMessage, nudges *int) *AgentMessage {
+	if p == nil || ctx.Err() != nil {
+		return nil
+	}
+	limit := p.MaxNudges
+	if limit <= 0 {
+		limit = 3
+	}
+	if *nudges >= limit {
+		return nil
+	}
+
+	text := ""
+	switch {
+	case p.Pending != nil:
+		pending := p.Pending()
+		if len(pending) == 0 {
+			return nil
+		}
+		text = p.nudgeText(config) + "\n\n" + config.text(MsgRemainingTasks) + "\n- " + strings.Join(pending, "\n- ")
+	case p.Judge != nil:
+		done, nudge, err := p.Judge(ctx, messages)
+		if err != nil || done {
+			return nil
+		}
+		text = nudge
+		if text == "" {
+			text = p.nudgeText(config)
+		}
+	default:
+		return nil
+	}
+
+	*nudges++
+	m := NewAgentMessageFromMessage(ai.Message{User: &ai.UserMessage{
+		Role:      ai.RoleUser,
+		Content:   []ai.Content{ai.NewTextContent(text)},
+		Timestamp: time.Now().UnixMilli(),
+	}})
+	m.SetMetadata(MetadataSynthetic, true)
+	return &m
+}
+
+func (p *ContinuationPolicy) nudgeText(config *AgentLoopConfig) string {
+	if p.Nudge != "" {
+		return p.Nudge
+	}
+	return config.text(MsgContinue)
+}
+
+// IsSynthetic reports whether m was injected by the agent.
+func (m AgentMessage) IsSynthetic() bool {
+	v, _ := m.Metadata[MetadataSynthetic].(bool)
+	return v
+}
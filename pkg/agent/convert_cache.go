@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// MessageConverter converts a single AgentMessage to zero or more LLM messages.
+type MessageConverter func(m AgentMessage) ([]ai.Message, error)
+
+// ConvertCache memoizes a MessageConverter by message ID so that each turn
+// only converts new or edited messages. A message counts as edited when its
+// underlying message pointer or Custom value changed; callers
+// that mutate messages in place must call Invalidate. Messages without an
+// ID are always converted. Entries for messages no longer in the history
+// are dropped on every call, so the cache always mirrors the latest snapshot.
+type ConvertCache struct {
+	convert MessageConverter
+
+	mu      sync.Mutex
+	entries map[string]*convertEntry
+	order   []*convertEntry // entries by position in the last call
+	gen     uint64
+	hits    int
+	misses  int
+}
+
+type convertEntry struct {
+	id         string
+	user       *ai.UserMessage
+	assistant  *ai.AssistantMessage
+	toolResult *ai.ToolResultMessage
+	custom     any
+	out        []ai.Message
+	gen        uint64 // the last Convert that saw the message
+}
+
+// NewConvertCache wraps convert; nil converts like DefaultConvertToLLM.
+func NewConvertCache(convert MessageConverter) *ConvertCache {
+	if convert == nil {
+		convert = func(m AgentMessage) ([]ai.Message, error) {
+			return DefaultConvertToLLM([]AgentMessage{m})
+		}
+	}
+	return &ConvertCache{convert: convert, entries: map[string]*convertEntry{}}
+}
+
+func (e *convertEntry) matches(m AgentMessage) bool {
+	if e.user != m.User || e.assistant != m.Assistant || e.toolResult != m.ToolResult {
+		return false
+	}
+	if e.custom == nil && m.Custom == nil {
+		return true
+	}
+	return sameCustom(e.custom, m.Custom)
+}
+
+// sameCustom reports whether two Custom values are equal. Values that
+// cannot be compared, such as structs holding maps in interface fields,
+// count as edited.
+func sameCustom(a, b any) (same bool) {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// Convert has the ConvertToLLM signature.
+func (c *ConvertCache) Convert(messages []AgentMessage) ([]ai.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	seen := 0
+	order := make([]*convertEntry, len(messages))
+	out := make([]ai.Message, 0, len(messages))
+	for i, m := range messages {
+		var e *convertEntry
+		if m.ID != "" {
+			// Histories mostly grow at the end, so look at the same
+			// position of the previous call before the map.
+			if i < len(c.order) && c.order[i] != nil && c.order[i].id == m.ID {
+				e = c.order[i]
+			} else {
+				e = c.entries[m.ID]
+			}
+		}
+		if e != nil && e.matches(m) {
+			c.hits++
+			if e.gen != c.gen {
+				e.gen = c.gen
+				seen++
+			}
+			order[i] = e
+			out = append(out, e.out...)
+			continue
+		}
+		c.misses++
+		converted, err := c.convert(m)
+		if err != nil {
+			return nil, err
+		}
+		if m.ID != "" {
+			if e == nil || e.gen != c.gen {
+				seen++
+			}
+			order[i] = &convertEntry{
+				id:         m.ID,
+				user:       m.User,
+				assistant:  m.Assistant,
+				toolResult: m.ToolResult,
+				custom:     m.Custom,
+				out:        converted,
+				gen:        c.gen,
+			}
+			c.entries[m.ID] = order[i]
+		}
+		out = append(out, converted...)
+	}
+	c.order = order
+	// Drop messages that left the history.
+	if len(c.entries) > seen {
+		for id, e := range c.entries {
+			if e.gen != c.gen {
+				delete(c.entries, id)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Invalidate forgets the conversion of one message.
+func (c *ConvertCache) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[id]; e != nil {
+		e.id = "" // no longer found by position either
+	}
+	delete(c.entries, id)
+}
+
+// Reset forgets all conversions.
+func (c *ConvertCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*convertEntry{}
+	c.order = nil
+}
+
+// Stats returns the number of cached and fresh conversions so far.
+func (c *ConvertCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
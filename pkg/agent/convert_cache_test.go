@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// note is a Custom message type that is comparable by type but may hold
+// uncomparable values.
+type note struct{ Value any }
+
+func history(n int) []AgentMessage {
+	msgs := make([]AgentMessage, n)
+	for i := range msgs {
+		if i%2 == 0 {
+			msgs[i] = NewAgentMessageFromMessage(ai.NewUserMessage(fmt.Sprintf("question %d", i)))
+		} else {
+			msgs[i] = NewAgentMessageFromMessage(ai.Message{Assistant: &ai.AssistantMessage{
+				Role: ai.RoleAssistant, Content: []ai.Content{ai.NewTextContent(fmt.Sprintf("answer %d", i))},
+			}})
+		}
+		msgs[i].ID = fmt.Sprintf("m%d", i)
+	}
+	return msgs
+}
+
+func TestConvertCacheReusesAndDetectsEdits(t *testing.T) {
+	c := NewConvertCache(nil)
+	msgs := append(history(4), AgentMessage{ID: "n", Custom: note{Value: map[string]any{"k": 1}}})
+	if _, err := c.Convert(msgs); err != nil {
+		t.Fatal(err)
+	}
+	// The second call must not panic comparing the uncomparable note.
+	msgs[1] = NewAgentMessageFromMessage(ai.NewUserMessage("edited"))
+	msgs[1].ID = "m1"
+	out, err := c.Convert(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := c.Stats(); hits != 3 || misses != 7 {
+		t.Errorf("hits %d, misses %d; want 3, 7", hits, misses)
+	}
+	want, _ := DefaultConvertToLLM(msgs)
+	if len(out) != len(want) || out[1].User == nil {
+		t.Errorf("converted %d messages (second %+v), want %d with the edit", len(out), out[1], len(want))
+	}
+	c.Invalidate("m0")
+	c.Convert(msgs[1:]) // shifted: found through the map, not by position
+	if hits, misses := c.Stats(); hits != 6 || misses != 8 {
+		t.Errorf("after invalidate and shift: hits %d, misses %d; want 6, 8", hits, misses)
+	}
+}
+
+// BenchmarkConvertToLLM converts a 5k-message session the way a turn
+// does, with a converter that rewrites every message as custom converters
+// typically do.
+func BenchmarkConvertToLLM(b *testing.B) {
+	msgs := history(5000)
+	rewrite := func(m AgentMessage) ([]ai.Message, error) {
+		out := m.Message
+		if a := m.Assistant; a != nil {
+			clone := *a
+			clone.Content = make([]ai.Content, len(a.Content))
+			for i, c := range a.Content {
+				clone.Content[i] = c
+				if c.Text != nil {
+					clone.Content[i] = ai.NewTextContent(strings.TrimSpace(c.Text.Text))
+				}
+			}
+			out.Assistant = &clone
+		}
+		return []ai.Message{out}, nil
+	}
+	b.Run("uncached", func(b *testing.B) {
+		for range b.N {
+			var out []ai.Message
+			for _, m := range msgs {
+				converted, err := rewrite(m)
+				if err != nil {
+					b.Fatal(err)
+				}
+				out = append(out, converted...)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		c := NewConvertCache(rewrite)
+		for range b.N {
+			if _, err := c.Convert(msgs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// maxReportEvents is how many recent events an error report keeps.
+const maxReportEvents = 50
+
+// reportTimeout bounds the delivery of one error report.
+const reportTimeout = 30 * time.Second
+
+// ErrorReport is a redacted diagnostic bundle assembled when a run ends with
+// StopReasonError. It never contains message content or credentials.
+type ErrorReport struct {
+	Time         string            `json:"time"`
+	SessionID    string            `json:"sessionId,omitempty"`
+	Provider     string            `json:"provider"`
+	Api          ai.Api            `json:"api"`
+	Model        string            `json:"model"`
+	ErrorMessage string            `json:"errorMessage"`
+	Request      RequestSummary    `json:"request"`
+	RecentEvents []ReportEvent     `json:"recentEvents"`
+	Versions     map[string]string `json:"versions"`
+}
+
+// RequestSummary describes the failed request without its content.
+type RequestSummary struct {
+	SystemPromptChars int              `json:"systemPromptChars"`
+	MessageCount      int              `json:"messageCount"`
+	Tools             []string         `json:"tools,omitempty"`
+	ThinkingLevel     ai.ThinkingLevel `json:"thinkingLevel"`
+}
+
+// ReportEvent is a content-free record of an agent event.
+type ReportEvent struct {
+	Type       AgentEventType `json:"type"`
+	Timestamp  int64          `json:"timestamp"` // Unix ms
+	ToolName   string         `json:"toolName,omitempty"`
+	IsError    bool           `json:"isError,omitempty"`
+	StopReason ai.StopReason  `json:"stopReason,omitempty"`
+}
+
+// ErrorReporter delivers error reports.
+type ErrorReporter interface {
+	Report(ctx context.Context, report ErrorReport) error
+}
+
+// DirReporter writes each report as a JSON file in Dir.
+type DirReporter struct {
+	Dir string
+}
+
+// WebhookReporter POSTs each report as JSON to URL.
+type WebhookReporter struct {
+	URL       string
+	Transport ai.Transport // nil uses the default transport
+}
+
+// Report posts the report and fails on non-2xx responses.
+func (w WebhookReporter) Report(ctx context.Context, report ErrorReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ai.GetTransport(w.Transport).Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// secretPatterns match credentials that providers sometimes echo in errors.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)bearer\s+[a-z0-9._\-]+`),
+	regexp.MustCompile(`\b(sk|pk|rk|xai|gsk|hf)[-_][A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{20,}`),
+	regexp.MustCompile(`(?i)\b(api[_-]?key|access[_-]?token|auth[_-]?token|secret|password)(["']?\s*[:=]\s*["']?)[^\s"',}]+`),
+}
+
+// RedactSecrets replaces credential-looking substrings with "[REDACTED]".
+func RedactSecrets(s string) string {
+	for i, p := range secretPatterns {
+		if i == len(secretPatterns)-1 {
+			s = p.ReplaceAllString(s, "${1}${2}[REDACTED]")
+			continue
+		}
+		s = p.ReplaceAllString(s, "[REDACTED]")
+	}
+	return s
+}
+
+// buildVersions collects Go and module versions for reports.
+func buildVersions() map[string]string {
+	v := map[string]string{
+		"go":   runtime.Version(),
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v["main"] = info.Main.Path + "@" + info.Main.Version
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/badlogic/pi-go" {
+				v["pi-go"] = dep.Version
+			}
+		}
+	}
+	return v
+}
+
+// reportEventOf summarizes an event for inclusion in a report.
+func reportEventOf(e AgentEvent) ReportEvent {
+	re := ReportEvent{Type: e.Type, Timestamp: time.Now().UnixMilli(), ToolName: e.ToolName, IsError: e.IsError}
+	if e.Message != nil && e.Message.Assistant != nil {
+		re.StopReason = e.Message.Assistant.StopReason
+	}
+	return re
+}
+
+// sendReport delivers an error report in the background; a failure is
+// surfaced to listeners as a WarningEvent.
+func (a *Agent) sendReport(report ErrorReport) {
+	defer a.reports.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	if err := a.errorReporter.Report(ctx, report); err != nil {
+		a.publish(AgentEvent{Type: WarningEvent, Warning: fmt.Sprintf("error report failed: %v", err)}, false)
+	}
+}
+
+// newErrorReport assembles a report for a failed assistant message.
+func newErrorReport(sessionID string, state AgentState, msg *ai.AssistantMessage, events []ReportEvent) ErrorReport {
+	tools := make([]string, len(state.Tools))
+	for i, t := range state.Tools {
+		tools[i] = t.Name
+	}
+	return ErrorReport{
+		Time:         time.Now().UTC().Format(time.RFC3339),
+		SessionID:    sessionID,
+		Provider:     msg.Provider,
+		Api:          msg.Api,
+		Model:        msg.Model,
+		ErrorMessage: RedactSecrets(msg.ErrorMessage),
+		Request: RequestSummary{
+			SystemPromptChars: len(state.SystemPrompt),
+			MessageCount:      len(state.Messages),
+			Tools:             tools,
+			ThinkingLevel:     state.ThinkingLevel,
+		},
+		RecentEvents: append([]ReportEvent{}, events...),
+		Versions:     buildVersions(),
+	}
+}
@@ -0,0 +1,13 @@
+//go:build js
+
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+// Report fails under js, which has no file system; use WebhookReporter.
+func (d DirReporter) Report(ctx context.Context, report ErrorReport) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,25 @@
+//go:build !js
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Report writes error-<timestamp>.json.
+func (d DirReporter) Report(ctx context.Context, report ErrorReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("error-%d.json", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(d.Dir, name), data, 0o644)
+}
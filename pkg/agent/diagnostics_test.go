@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+type reporterFunc func(context.Context, ErrorReport) error
+
+func (f reporterFunc) Report(ctx context.Context, r ErrorReport) error { return f(ctx, r) }
+
+func failingStream(model *ai.Model, _ ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+	msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonError, ErrorMessage: "boom"}
+	out := ai.NewAssistantMessageEventStream()
+	go out.Push(ai.AssistantMessageEvent{Type: ai.EventError, Reason: msg.StopReason, Error: msg})
+	return out
+}
+
+func TestErrorReportsAreAwaitedAndFailuresWarned(t *testing.T) {
+	var mu sync.Mutex
+	var reports []ErrorReport
+	a := NewAgent(AgentOptions{
+		StreamFn: failingStream,
+		ErrorReporter: reporterFunc(func(_ context.Context, r ErrorReport) error {
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, r)
+			return errors.New("unreachable")
+		}),
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	var warnings []string
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == WarningEvent {
+			mu.Lock()
+			warnings = append(warnings, e.Warning)
+			mu.Unlock()
+		}
+	})
+
+	for range 2 {
+		if err := a.Prompt("hi"); err != nil {
+			t.Fatal(err)
+		}
+		a.WaitForIdle()
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 2 {
+		t.Fatalf("Close returned with %d of 2 reports delivered", len(reports))
+	}
+	if first, second := len(reports[0].RecentEvents), len(reports[1].RecentEvents); first != second {
+		t.Errorf("second report has %d recent events, first %d; events leaked across runs", second, first)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "unreachable") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
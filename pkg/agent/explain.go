@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// TurnTrace records exactly what one LLM call of the agent loop sent and
+// received, for answering "why did it do that".
+type TurnTrace struct {
+	Index          int // turn number within the agent's lifetime, from 0
+	StartedAt      time.Time
+	Duration       time.Duration
+	RequestedModel *ai.Model // model configured for the turn
+	Model          *ai.Model // model that answered (differs after fallback)
+
+	// Transformed is the agent context after TransformContext; Context is
+	// what was sent to the model after ConvertToLLM and image handling.
+	Transformed []AgentMessage
+	Context     ai.Context
+	Options     ai.SimpleStreamOptions // ApiKey and header values redacted
+
+	Events   []ai.AssistantMessageEvent
+	Response *ai.AssistantMessage
+}
+
+func newTurnTrace(transformed []AgentMessage, llmCtx ai.Context, opts ai.SimpleStreamOptions, model *ai.Model) *TurnTrace {
+	if opts.ApiKey != "" {
+		opts.ApiKey = "[REDACTED]"
+	}
+	// Header values often carry credentials too (Authorization, custom API
+	// key headers); the names are kept for debugging.
+	if len(opts.Headers) > 0 {
+		headers := make(map[string]string, len(opts.Headers))
+		for k := range opts.Headers {
+			headers[k] = "[REDACTED]"
+		}
+		opts.Headers = headers
+	}
+	return &TurnTrace{
+		StartedAt:      time.Now(),
+		RequestedModel: model,
+		Model:          model,
+		Transformed:    append([]AgentMessage{}, transformed...),
+		Context:        llmCtx,
+		Options:        opts,
+	}
+}
+
+// String renders a compact human-readable explanation of the turn.
+func (t *TurnTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "turn %d at %s (%s)\n", t.Index, t.StartedAt.Format(time.RFC3339), t.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "model: %s/%s", t.Model.Provider, t.Model.ID)
+	if t.Model != t.RequestedModel {
+		fmt.Fprintf(&sb, " (fallback from %s)", t.RequestedModel.ID)
+	}
+	sb.WriteString("\n")
+	if t.Options.Reasoning != "" {
+		fmt.Fprintf(&sb, "reasoning: %s\n", t.Options.Reasoning)
+	}
+	fmt.Fprintf(&sb, "context: %d agent messages → %d LLM messages, %d tools, system prompt %d chars\n",
+		len(t.Transformed), len(t.Context.Messages), len(t.Context.Tools), len(t.Context.SystemPrompt))
+	counts := map[ai.AssistantMessageEventType]int{}
+	for _, e := range t.Events {
+		counts[e.Type]++
+	}
+	fmt.Fprintf(&sb, "events: %d", len(t.Events))
+	for _, typ := range []ai.AssistantMessageEventType{ai.EventTextDelta, ai.EventThinkingDelta, ai.EventToolCallEnd, ai.EventError} {
+		if n := counts[typ]; n > 0 {
+			fmt.Fprintf(&sb, ", %s×%d", typ, n)
+		}
+	}
+	sb.WriteString("\n")
+	if r := t.Response; r != nil {
+		fmt.Fprintf(&sb, "stop: %s", r.StopReason)
+		if r.ErrorMessage != "" {
+			fmt.Fprintf(&sb, " (%s)", r.ErrorMessage)
+		}
+		fmt.Fprintf(&sb, ", usage: %d in / %d out\n", r.Usage.Input, r.Usage.Output)
+	}
+	return sb.String()
+}
+
+// recordTurnTrace stores a trace, keeping the most recent traceTurns.
+func (a *Agent) recordTurnTrace(t *TurnTrace) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t.Index = a.nextTurnIndex
+	a.nextTurnIndex++
+	a.turnTraces = append(a.turnTraces, t)
+	if over := len(a.turnTraces) - a.traceTurns; over > 0 {
+		a.turnTraces = append([]*TurnTrace{}, a.turnTraces[over:]...)
+	}
+}
+
+// ExplainTurn returns the trace of turn index; negative indices count back
+// from the latest turn (-1 is the last). Requires AgentOptions.TraceTurns.
+func (a *Agent) ExplainTurn(index int) (*TurnTrace, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.traceTurns <= 0 {
+		return nil, fmt.Errorf("turn tracing is disabled (set AgentOptions.TraceTurns)")
+	}
+	if index < 0 {
+		index += a.nextTurnIndex
+	}
+	for _, t := range a.turnTraces {
+		if t.Index == index {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("turn %d is not available", index)
+}
@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestTurnTraceRedactsCredentials(t *testing.T) {
+	opts := ai.SimpleStreamOptions{}
+	opts.ApiKey = "sk-secret"
+	opts.Headers = map[string]string{"Authorization": "Bearer secret", "X-Api-Key": "secret"}
+
+	trace := newTurnTrace(nil, ai.Context{}, opts, &ai.Model{ID: "test"})
+	if trace.Options.ApiKey != "[REDACTED]" {
+		t.Errorf("ApiKey = %q", trace.Options.ApiKey)
+	}
+	for k, v := range trace.Options.Headers {
+		if v != "[REDACTED]" {
+			t.Errorf("header %s = %q", k, v)
+		}
+	}
+	if len(trace.Options.Headers) != 2 {
+		t.Errorf("headers = %v, want both names kept", trace.Options.Headers)
+	}
+	if opts.Headers["Authorization"] != "Bearer secret" {
+		t.Error("redaction modified the caller's headers")
+	}
+}
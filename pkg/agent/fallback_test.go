@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestDeprecatedModelIsUsedNotSkipped(t *testing.T) {
+	reg := ai.NewRegistry()
+	primary := &ai.Model{ID: "old", Provider: "p", Deprecated: true, FallbackIDs: []string{"new"}}
+	reg.RegisterModel(primary, "test")
+	reg.RegisterModel(&ai.Model{ID: "new", Provider: "p"}, "test")
+
+	var called []string
+	reply := replyStream(ai.NewTextContent("ok"))
+	a := NewAgent(AgentOptions{
+		Registry: reg,
+		StreamFn: func(model *ai.Model, c ai.Context, o *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			called = append(called, model.ID)
+			return reply(model, c, o)
+		},
+	})
+	a.SetModel(primary)
+	if err := a.Prompt("hi"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if len(called) != 1 || called[0] != "old" {
+		t.Errorf("called %v, want the deprecated primary", called)
+	}
+}
+
+// imageCount returns the number of images in c's messages.
+func imageCount(c ai.Context) int {
+	n := 0
+	for _, m := range c.Messages {
+		if m.User != nil {
+			for _, part := range m.User.Content {
+				if part.Image != nil {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+func TestFallbackStartsAtOnceAndGetsItsOwnContext(t *testing.T) {
+	const failAfter = 100 * time.Millisecond
+	reg := ai.NewRegistry()
+	primary := &ai.Model{ID: "text", Provider: "p", Input: []string{"text"}, FallbackIDs: []string{"vision"}}
+	reg.RegisterModel(primary, "test")
+	reg.RegisterModel(&ai.Model{ID: "vision", Provider: "p", Input: []string{"text", "image"}}, "test")
+
+	var mu sync.Mutex
+	images := map[string]int{}
+	a := NewAgent(AgentOptions{
+		Registry: reg,
+		StreamFn: func(model *ai.Model, c ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			mu.Lock()
+			images[model.ID] = imageCount(c)
+			mu.Unlock()
+			out := ai.NewAssistantMessageEventStream()
+			partial := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID}
+			go func() {
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventStart, Partial: partial})
+				if model.ID == "text" {
+					time.Sleep(failAfter)
+					msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonError, ErrorMessage: "overloaded"}
+					out.Push(ai.AssistantMessageEvent{Type: ai.EventError, Reason: msg.StopReason, Error: msg})
+					return
+				}
+				msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonStop, Content: []ai.Content{ai.NewTextContent("a cat")}}
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventTextStart, Partial: partial})
+				delta := *msg
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventTextDelta, Delta: "a cat", Partial: &delta})
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+			}()
+			return out
+		},
+	})
+	a.SetModel(primary)
+	var starts int
+	var firstStart time.Time
+	var ttft float64
+	a.Subscribe(func(e AgentEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case e.Type == MessageEventStart && e.Message.Assistant != nil:
+			if starts++; starts == 1 {
+				firstStart = time.Now()
+			}
+		case e.Type == TurnStageTimingEvent && e.TurnStageTiming.Stage == TurnStageTTFT:
+			ttft = e.TurnStageTiming.DurationMs
+		}
+	})
+	began := time.Now()
+	if err := a.Prompt("what is this?", ai.ImageContent{Data: "aGk=", MimeType: "image/png"}); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if starts != 1 {
+		t.Errorf("got %d assistant message starts, want 1", starts)
+	}
+	if d := firstStart.Sub(began); d >= failAfter {
+		t.Errorf("message start came after %s, held back until the fallback", d)
+	}
+	if ttft >= float64(failAfter/time.Millisecond) {
+		t.Errorf("TTFT %.0fms includes the failed attempt", ttft)
+	}
+	if images["text"] != 0 || images["vision"] != 1 {
+		t.Errorf("images sent per model = %v, want none to text and 1 to vision", images)
+	}
+	msgs := a.State().Messages
+	if last := msgs[len(msgs)-1].Assistant; last == nil || last.FallbackFrom != "text" {
+		t.Errorf("last message = %+v, want an answer falling back from text", msgs[len(msgs)-1])
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// FeedbackRating is a user's judgement of a message.
+type FeedbackRating int
+
+const (
+	FeedbackNegative FeedbackRating = -1
+	FeedbackPositive FeedbackRating = 1
+)
+
+// MetadataFeedback is the AgentMessage.Metadata key holding []Feedback.
+const MetadataFeedback = "feedback"
+
+// Feedback is a rating attached to a message.
+type Feedback struct {
+	MessageID string         `json:"messageId"`
+	Rating    FeedbackRating `json:"rating"`
+	Comment   string         `json:"comment,omitempty"`
+	Timestamp int64          `json:"timestamp"` // Unix ms
+}
+
+// RecordFeedback attaches a rating to a committed message, storing it in the
+// message's metadata (so it persists with the session) and emitting a
+// FeedbackEventRecorded event for telemetry sinks. The rating must be
+// FeedbackNegative or FeedbackPositive. The event is delivered in
+// order with the events of a running loop, never concurrently with them.
+func (a *Agent) RecordFeedback(messageID string, rating FeedbackRating, comment string) error {
+	if rating != FeedbackNegative && rating != FeedbackPositive {
+		return fmt.Errorf("invalid feedback rating %d", rating)
+	}
+	fb := Feedback{
+		MessageID: messageID,
+		Rating:    rating,
+		Comment:   comment,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	a.mu.Lock()
+	idx := -1
+	for i := range a.state.Messages {
+		if a.state.Messages[i].ID == messageID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		a.mu.Unlock()
+		return fmt.Errorf("message %q not found", messageID)
+	}
+	// Copy the metadata map: snapshots returned by State share it.
+	m := &a.state.Messages[idx]
+	md := make(map[string]any, len(m.Metadata)+1)
+	for k, v := range m.Metadata {
+		md[k] = v
+	}
+	existing, _ := md[MetadataFeedback].([]Feedback)
+	md[MetadataFeedback] = append(append([]Feedback{}, existing...), fb)
+	m.Metadata = md
+	msg := *m
+	a.mu.Unlock()
+
+	a.publish(AgentEvent{Type: FeedbackEventRecorded, Message: &msg, Feedback: &fb}, false)
+	return nil
+}
+
+// Feedback returns the feedback recorded on a message.
+func (m AgentMessage) Feedback() []Feedback {
+	v, _ := m.Metadata[MetadataFeedback].([]Feedback)
+	return v
+}
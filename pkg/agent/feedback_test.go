@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestFeedbackIsDeliveredInOrderWithTheRun(t *testing.T) {
+	a := NewAgent(AgentOptions{StreamFn: replyStream(ai.NewTextContent("ok"))})
+	a.SetModel(&ai.Model{ID: "test"})
+	if err := a.Prompt("hi"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	id := a.State().Messages[1].ID
+
+	var inside, overlaps, feedback atomic.Int32
+	a.Subscribe(func(e AgentEvent) {
+		if inside.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(time.Millisecond)
+		if e.Type == FeedbackEventRecorded {
+			feedback.Add(1)
+		}
+		inside.Add(-1)
+	})
+
+	if err := a.Prompt("again"); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if err := a.RecordFeedback(id, FeedbackPositive, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.WaitForIdle()
+	waitUntil := time.Now().Add(5 * time.Second)
+	for feedback.Load() < 5 && time.Now().Before(waitUntil) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := feedback.Load(); n != 5 {
+		t.Errorf("delivered %d feedback events, want 5", n)
+	}
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("listener ran concurrently with itself %d times", n)
+	}
+
+	data, err := json.Marshal(a.State().Messages[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m AgentMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if fb := m.Feedback(); len(fb) != 5 || fb[0].Rating != FeedbackPositive || fb[0].MessageID != id {
+		t.Errorf("feedback after JSON = %+v", fb)
+	}
+}
+
+func TestFeedbackRejectsUndefinedRatings(t *testing.T) {
+	a := NewAgent(AgentOptions{StreamFn: replyStream(ai.NewTextContent("ok"))})
+	a.SetModel(&ai.Model{ID: "test"})
+	if err := a.Prompt("hi"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	id := a.State().Messages[1].ID
+
+	for _, r := range []FeedbackRating{0, 2, -5} {
+		if err := a.RecordFeedback(id, r, ""); err == nil {
+			t.Errorf("rating %d was accepted", r)
+		}
+	}
+	if fb := a.State().Messages[1].Feedback(); len(fb) != 0 {
+		t.Errorf("feedback = %+v, want none recorded", fb)
+	}
+}
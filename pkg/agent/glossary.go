@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// MessagePostProcessor rewrites a final assistant message in place before
+// MessageEventEnd is emitted.
+type MessagePostProcessor func(msg *ai.AssistantMessage)
+
+// GlossaryTerm is a single preferred-terminology rule.
+type GlossaryTerm struct {
+	From          string // term to replace
+	To            string // preferred term
+	CaseSensitive bool   // match From exactly; otherwise match any case and adapt To
+	PartialWord   bool   // also match inside longer words
+}
+
+// Glossary enforces preferred terminology on assistant output.
+type Glossary struct {
+	terms    []GlossaryTerm
+	any      *regexp.Regexp   // finds the leftmost candidate of any term
+	patterns []*regexp.Regexp // each term, anchored at the candidate
+}
+
+// NewGlossary compiles the given terms. Longer terms are matched first so
+// that multi-word phrases win over their parts. Whole-word terms use
+// Unicode word boundaries, so accented and non-Latin words are respected.
+func NewGlossary(terms ...GlossaryTerm) *Glossary {
+	sorted := append([]GlossaryTerm(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].From) > len(sorted[j].From)
+	})
+	g := &Glossary{terms: sorted}
+	alts := make([]string, 0, len(sorted))
+	for _, t := range sorted {
+		expr := regexp.QuoteMeta(t.From)
+		if !t.CaseSensitive {
+			expr = `(?i:` + expr + `)`
+		}
+		alts = append(alts, expr)
+		g.patterns = append(g.patterns, regexp.MustCompile(`^`+expr))
+	}
+	if len(alts) > 0 {
+		g.any = regexp.MustCompile(strings.Join(alts, "|"))
+	}
+	return g
+}
+
+// NewGlossaryFromMap builds a case-insensitive, whole-word glossary. Terms
+// of equal length are tried in order of From, so keys that match the same
+// text (such as "Colour" and "colour") resolve the same way every time.
+func NewGlossaryFromMap(replacements map[string]string) *Glossary {
+	terms := make([]GlossaryTerm, 0, len(replacements))
+	for from, to := range replacements {
+		terms = append(terms, GlossaryTerm{From: from, To: to})
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].From < terms[j].From })
+	return NewGlossary(terms...)
+}
+
+// Apply returns text with every glossary term replaced in a single pass,
+// so a replacement is never itself rewritten by a later term. For
+// case-insensitive terms the replacement follows the match's casing:
+// ALL CAPS stays all caps and a capitalized match capitalizes To.
+func (g *Glossary) Apply(text string) string {
+	if g.any == nil {
+		return text
+	}
+	var out strings.Builder
+	last, i := 0, 0
+	for i < len(text) {
+		loc := g.any.FindStringIndex(text[i:])
+		if loc == nil {
+			break
+		}
+		start := i + loc[0]
+		term, n := g.matchAt(text, start)
+		if n == 0 {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			i = start + max(size, 1)
+			continue
+		}
+		match := text[start : start+n]
+		out.WriteString(text[last:start])
+		if term.CaseSensitive {
+			out.WriteString(term.To)
+		} else {
+			out.WriteString(matchCase(match, term.To))
+		}
+		last = start + n
+		i = last
+	}
+	if last == 0 {
+		return text
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// matchAt returns the first (longest) term matching text at start whose
+// word boundaries hold, and the length of the match.
+func (g *Glossary) matchAt(text string, start int) (GlossaryTerm, int) {
+	for i, p := range g.patterns {
+		loc := p.FindStringIndex(text[start:])
+		if loc == nil || loc[1] == 0 {
+			continue
+		}
+		t := g.terms[i]
+		end := start + loc[1]
+		if !t.PartialWord && !atWordBoundary(text, start, end) {
+			continue
+		}
+		return t, loc[1]
+	}
+	return GlossaryTerm{}, 0
+}
+
+// atWordBoundary reports whether text[start:end] is not glued to a
+// neighbouring word character. Edges of the match that are not word
+// characters themselves (e.g. the dot in ".NET") need no boundary.
+func atWordBoundary(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(last) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.M, r)
+}
+
+// PostProcessor returns a MessagePostProcessor applying the glossary to
+// every text block.
+func (g *Glossary) PostProcessor() MessagePostProcessor {
+	return func(msg *ai.AssistantMessage) {
+		for i, c := range msg.Content {
+			if c.Text != nil {
+				tc := *c.Text
+				tc.Text = g.Apply(tc.Text)
+				msg.Content[i] = ai.Content{Text: &tc}
+			}
+		}
+	}
+}
+
+func matchCase(match, repl string) string {
+	hasLetter := false
+	allUpper := true
+	for _, r := range match {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+			if !unicode.IsUpper(r) {
+				allUpper = false
+			}
+		}
+	}
+	if hasLetter && allUpper && utf8.RuneCountInString(match) > 1 {
+		return strings.ToUpper(repl)
+	}
+	first, _ := utf8.DecodeRuneInString(match)
+	if unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(repl)
+		return string(unicode.ToUpper(r)) + repl[size:]
+	}
+	return repl
+}
@@ -0,0 +1,35 @@
+package agent
+
+import "testing"
+
+func TestGlossaryApply(t *testing.T) {
+	g := NewGlossary(
+		GlossaryTerm{From: "café", To: "coffee shop"},
+		GlossaryTerm{From: "cat", To: "dog"},
+		GlossaryTerm{From: "dog", To: "wolf"},
+		GlossaryTerm{From: "Über", To: "Super", CaseSensitive: true},
+		GlossaryTerm{From: "ize", To: "ise", PartialWord: true},
+	)
+	for in, want := range map[string]string{
+		"the cat sat":          "the dog sat", // replacement is not rewritten by "dog"
+		"CAT and Cat":          "DOG and Dog", // case follows the match
+		"concatenate":          "concatenate", // whole word only
+		"the cafés and a café": "the cafés and a coffee shop",
+		"écat and caté":        "écat and caté", // non-ASCII letters are word characters
+		"Über über":            "Super über",    // case-sensitive
+		"organize":             "organise",      // partial word
+	} {
+		if got := g.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGlossaryFromMapIsDeterministic(t *testing.T) {
+	replacements := map[string]string{"colour": "color", "Colour": "hue", "COLOUR": "tint", "flavour": "flavor"}
+	for range 50 {
+		if got := NewGlossaryFromMap(replacements).Apply("colour"); got != "tint" {
+			t.Fatalf("Apply(colour) = %q, want the first key in order, COLOUR", got)
+		}
+	}
+}
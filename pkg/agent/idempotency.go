@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// MetadataRequestID is the AgentMessage.Metadata key holding the
+// client-generated idempotency key of the prompt that added the message.
+const MetadataRequestID = "requestId"
+
+// RequestID returns the idempotency key recorded on a message, if any.
+func (m AgentMessage) RequestID() string {
+	v, _ := m.Metadata[MetadataRequestID].(string)
+	return v
+}
+
+// PromptWithKey is Prompt with a client-generated idempotency key. If a
+// prompt with the same key was already accepted, it returns nil without
+// starting another run, so clients can safely retry after a network
+// failure. An empty key behaves like Prompt.
+func (a *Agent) PromptWithKey(key, text string, images ...ai.ImageContent) error {
+	return a.PromptMessagesWithKey(key, promptMessages(text, images))
+}
+
+// PromptMessagesWithKey is PromptMessages with an idempotency key; see
+// PromptWithKey. The key is recorded in the first message's metadata, so
+// deduplication also covers history restored with ReplaceMessages.
+func (a *Agent) PromptMessagesWithKey(key string, msgs []AgentMessage) error {
+	if key == "" {
+		return a.PromptMessages(msgs)
+	}
+	if !a.reserveRequestID(key) {
+		return nil
+	}
+	if len(msgs) > 0 {
+		msgs = append([]AgentMessage{}, msgs...)
+		msgs[0].SetMetadata(MetadataRequestID, key)
+	}
+	if err := a.runLoop(context.Background(), msgs, false); err != nil {
+		// The prompt was rejected, so a retry must be allowed to run.
+		a.mu.Lock()
+		delete(a.requestIDs, key)
+		a.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// HasRequestID reports whether a prompt with the given idempotency key has
+// been accepted.
+func (a *Agent) HasRequestID(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.hasRequestIDLocked(key)
+}
+
+// reserveRequestID records key, returning false if it was already present.
+func (a *Agent) reserveRequestID(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.hasRequestIDLocked(key) {
+		return false
+	}
+	if a.requestIDs == nil {
+		a.requestIDs = map[string]bool{}
+	}
+	a.requestIDs[key] = true
+	return true
+}
+
+func (a *Agent) hasRequestIDLocked(key string) bool {
+	if a.requestIDs[key] {
+		return true
+	}
+	for _, m := range a.state.Messages {
+		if m.RequestID() == key {
+			return true
+		}
+	}
+	return false
+}
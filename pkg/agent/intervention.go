@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ValidationAction is how the loop intervenes when the arguments of one
+// tool keep failing validation.
+type ValidationAction int
+
+const (
+	// ValidationStrict marks the tool Strict (see ai.Tool) for the rest of
+	// the run and shows the model the full parameter schema. The loop
+	// then stops coercing the tool's arguments and rejects properties the
+	// schema does not declare.
+	ValidationStrict ValidationAction = iota
+	// ValidationExample shows the model a worked example call: the tool's
+	// first Example, or one built from the schema with ai.ExampleValue.
+	ValidationExample
+	// ValidationAsk hands the failing call to ValidationPolicy.Ask (e.g. to
+	// let a user supply the arguments) and runs it with the answer.
+	ValidationAsk
+)
+
+func (a ValidationAction) String() string {
+	switch a {
+	case ValidationExample:
+		return "example"
+	case ValidationAsk:
+		return "ask"
+	default:
+		return "strict"
+	}
+}
+
+// ValidationPolicy intervenes when calls to one tool fail argument
+// validation several times in a row, instead of letting the model loop on
+// identical errors.
+type ValidationPolicy struct {
+	After  int // consecutive failures of one tool before intervening (default 2)
+	Action ValidationAction
+
+	// Ask answers ValidationAsk with corrected arguments for the call, or
+	// nil to let it fail. Without Ask, ValidationAsk shows an example.
+	Ask func(ctx context.Context, toolCall ai.ToolCall, validationErr error) (map[string]any, error)
+}
+
+func (p *ValidationPolicy) after() int {
+	if p.After > 0 {
+		return p.After
+	}
+	return 2
+}
+
+// validated records the outcome of validating a call to tool. On a
+// repeated failure it applies the policy, which may return corrected
+// arguments or an error carrying guidance for the model.
+func (r *toolRunner) validated(ctx context.Context, tool *AgentTool, tc ai.ToolCall, args map[string]any, err error) (map[string]any, error) {
+	policy := r.config.ValidationPolicy
+	r.mu.Lock()
+	if err == nil {
+		delete(r.invalid, tool.Name)
+		r.mu.Unlock()
+		return args, nil
+	}
+	if r.invalid == nil {
+		r.invalid = map[string]int{}
+	}
+	r.invalid[tool.Name]++
+	failures := r.invalid[tool.Name]
+	r.mu.Unlock()
+	if policy == nil || failures < policy.after() {
+		return nil, err
+	}
+
+	action := policy.Action
+	if action == ValidationAsk && policy.Ask == nil {
+		action = ValidationExample
+	}
+	switch action {
+	case ValidationStrict:
+		r.mu.Lock()
+		if r.strict == nil {
+			r.strict = map[string]bool{}
+		}
+		r.strict[tool.Name] = true
+		r.mu.Unlock()
+		schema := indentJSON(tool.Parameters)
+		return nil, fmt.Errorf("%w\n\n%s", err, r.config.text(MsgValidationStrict, tool.Name, failures, schema))
+	case ValidationAsk:
+		fixed, askErr := policy.Ask(ctx, tc, err)
+		if askErr != nil {
+			return nil, fmt.Errorf("%w\n\n%s", err, r.config.text(MsgValidationAskErr, askErr))
+		}
+		if fixed == nil {
+			return nil, fmt.Errorf("%w\n\n%s", err, r.config.text(MsgValidationNoFix, tool.Name))
+		}
+		tc.Arguments = fixed
+		args, retryErr := validateArguments(tool, tc)
+		if retryErr != nil {
+			return nil, fmt.Errorf("%w\n\n%s", err, r.config.text(MsgValidationInvalid, retryErr))
+		}
+		r.mu.Lock()
+		delete(r.invalid, tool.Name)
+		r.mu.Unlock()
+		return args, nil
+	default:
+		example := ai.ExampleValue(tool.Parameters)
+		if len(tool.Examples) > 0 {
+			example = tool.Examples[0]
+		}
+		data := indentJSON(example)
+		return nil, fmt.Errorf("%w\n\n%s", err, r.config.text(MsgValidationExample, tool.Name, failures, data))
+	}
+}
+
+// validateArguments checks a call's arguments against the tool's schema:
+// required properties, then types, enums and nested values. Strict tools
+// also may not have undeclared properties.
+func validateArguments(tool *AgentTool, tc ai.ToolCall) (map[string]any, error) {
+	args, err := ai.ValidateToolArguments(&tool.Tool, tc)
+	if err != nil {
+		return nil, err
+	}
+	schema := tool.Parameters
+	if tool.Strict {
+		schema = closedSchema(schema)
+	}
+	if err := ai.ValidateValue(schema, args); err != nil {
+		return nil, fmt.Errorf("validation failed for tool %q: %w", tc.Name, err)
+	}
+	return args, nil
+}
+
+// closedSchema returns a copy of schema with additionalProperties false on
+// every object it describes.
+func closedSchema(schema ai.ToolSchema) ai.ToolSchema {
+	if schema == nil {
+		return nil
+	}
+	out := make(ai.ToolSchema, len(schema)+1)
+	for k, v := range schema {
+		out[k] = v
+	}
+	if props, ok := schema["properties"].(map[string]any); ok {
+		closed := make(map[string]any, len(props))
+		for name, p := range props {
+			if p, ok := p.(map[string]any); ok {
+				closed[name] = closedSchema(p)
+			} else {
+				closed[name] = p
+			}
+		}
+		out["properties"] = closed
+		out["additionalProperties"] = false
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		out["items"] = closedSchema(items)
+	}
+	return out
+}
+
+// indentJSON formats v for the model, without HTML escaping.
+func indentJSON(v any) string {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// strictTools returns tools with Strict set on those switched to strict
+// schema mode, copying the slice only when needed.
+func (r *toolRunner) strictTools(tools []AgentTool) []AgentTool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.strict) == 0 {
+		return tools
+	}
+	out := make([]AgentTool, len(tools))
+	for i, t := range tools {
+		if r.strict[t.Name] {
+			t.Strict = true
+		}
+		out[i] = t
+	}
+	return out
+}
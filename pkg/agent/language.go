@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LanguageOptions enables detection of the user's language on the first
+// prompt and localizes the system prompt accordingly.
+type LanguageOptions struct {
+	// Detect returns an ISO 639-1 code for text, or "" if unknown.
+	// Defaults to DetectLanguage.
+	Detect func(text string) string
+
+	// SystemPrompts holds localized system prompt variants keyed by language
+	// code. When the detected language has a variant it replaces the
+	// configured system prompt.
+	SystemPrompts map[string]string
+
+	// Instruction builds the sentence appended to the system prompt when no
+	// variant exists. Defaults to "Respond in <language>.". Return "" to
+	// skip injection for a language.
+	Instruction func(lang string) string
+
+	// Default is the language assumed by the configured system prompt; no
+	// instruction is injected when it is detected. Defaults to "en".
+	Default string
+}
+
+// languageNames maps supported codes to English names for instructions.
+var languageNames = map[string]string{
+	"en": "English", "es": "Spanish", "fr": "French", "de": "German",
+	"it": "Italian", "pt": "Portuguese", "nl": "Dutch", "ru": "Russian",
+	"uk": "Ukrainian", "zh": "Chinese", "ja": "Japanese", "ko": "Korean",
+	"ar": "Arabic", "he": "Hebrew", "el": "Greek", "hi": "Hindi", "th": "Thai",
+}
+
+// LanguageName returns the English name of a language code, or the code.
+func LanguageName(code string) string {
+	if n, ok := languageNames[code]; ok {
+		return n
+	}
+	return code
+}
+
+// stopwords are frequent function words used to tell Latin-script languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "what", "how", "to", "of", "with", "this", "that", "can", "please"},
+	"es": {"el", "la", "los", "las", "que", "es", "y", "de", "por", "para", "cómo", "qué", "con", "una"},
+	"fr": {"le", "la", "les", "est", "et", "que", "des", "pour", "une", "vous", "avec", "dans", "pas", "comment"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "mit", "ein", "eine", "wie", "was", "für"},
+	"it": {"il", "lo", "gli", "che", "è", "di", "per", "una", "sono", "come", "non", "della", "con", "cosa"},
+	"pt": {"o", "os", "que", "é", "de", "não", "uma", "para", "com", "você", "como", "do", "da", "está"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "ik", "je", "met", "voor", "wat", "hoe"},
+}
+
+// DetectLanguage is a lightweight heuristic detector: non-Latin scripts are
+// identified by Unicode range, Latin-script languages by stopword frequency.
+// Returns "" when the text is too short or ambiguous.
+func DetectLanguage(text string) string {
+	scripts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				scripts["uk"] += 5
+			}
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Kana anywhere means Japanese even if Han dominates.
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > letters/2 {
+		return "ja"
+	}
+	if scripts["uk"] > scripts["ru"] {
+		return "uk"
+	}
+	best, bestCount := "", 0
+	for lang, n := range scripts {
+		if lang != "uk" && n > bestCount {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount > letters/2 {
+		return best
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < 3 {
+		return ""
+	}
+	scores := map[string]int{}
+	for _, w := range words {
+		for lang, list := range stopwords {
+			for _, sw := range list {
+				if w == sw {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, bestScore, tie := "", 0, false
+	for lang, n := range scores {
+		switch {
+		case n > bestScore:
+			best, bestScore, tie = lang, n, false
+		case n == bestScore:
+			tie = true
+		}
+	}
+	if bestScore < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// localizeSystemPrompt returns the system prompt to use for lang.
+func (o *LanguageOptions) localizeSystemPrompt(prompt, lang string) string {
+	if lang == "" {
+		return prompt
+	}
+	if v, ok := o.SystemPrompts[lang]; ok {
+		return v
+	}
+	def := o.Default
+	if def == "" {
+		def = "en"
+	}
+	if lang == def {
+		return prompt
+	}
+	instruction := "Respond in " + LanguageName(lang) + "."
+	if o.Instruction != nil {
+		instruction = o.Instruction(lang)
+	}
+	if instruction == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return instruction
+	}
+	return prompt + "\n\n" + instruction
+}
+
+func (o *LanguageOptions) detect(text string) string {
+	if o.Detect != nil {
+		return o.Detect(text)
+	}
+	return DetectLanguage(text)
+}
+
+// firstUserText returns the text of the first user message in msgs.
+func firstUserText(msgs []AgentMessage) string {
+	for _, m := range msgs {
+		if m.User == nil {
+			continue
+		}
+		var sb strings.Builder
+		for _, c := range m.User.Content {
+			if c.Text != nil {
+				sb.WriteString(c.Text.Text)
+				sb.WriteByte(' ')
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// SetLanguage overrides the detected language ("" re-enables detection on
+// the next prompt of an empty conversation).
+func (a *Agent) SetLanguage(lang string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.Language = lang
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LeaseStore grants time-limited exclusive ownership of a key. It is used to
+// ensure exactly one replica runs a given session's loop. LocalLeaseStore
+// covers a single process; bus.Redis shares leases between replicas.
+type LeaseStore interface {
+	// Acquire takes the lease if it is free or expired. Returns false if
+	// another owner currently holds it.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// Renew extends a lease held by owner. Returns false if the lease was lost.
+	Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// Release gives up a lease held by owner.
+	Release(ctx context.Context, key, owner string) error
+}
+
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+// LocalLeaseStore is an in-process LeaseStore.
+type LocalLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+// NewLocalLeaseStore creates an empty in-process lease store.
+func NewLocalLeaseStore() *LocalLeaseStore {
+	return &LocalLeaseStore{leases: map[string]lease{}}
+}
+
+// Acquire takes the lease if it is free, expired, or already held by owner.
+func (s *LocalLeaseStore) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if l, ok := s.leases[key]; ok && l.owner != owner && now.Before(l.expires) {
+		return false, nil
+	}
+	s.leases[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Renew extends the lease if owner still holds it.
+func (s *LocalLeaseStore) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	l, ok := s.leases[key]
+	if !ok || l.owner != owner || !now.Before(l.expires) {
+		return false, nil
+	}
+	s.leases[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Release drops the lease if owner holds it.
+func (s *LocalLeaseStore) Release(ctx context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[key]; ok && l.owner == owner {
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+// OwnershipOptions configures RunOwned.
+type OwnershipOptions struct {
+	Store     LeaseStore
+	SessionID string
+	Owner     string        // unique replica identifier
+	TTL       time.Duration // lease duration; renewed every TTL/3
+
+	// OnAcquired is called when this replica gains ownership of the session.
+	// It should resume the session's loop from persisted state. ctx is
+	// cancelled when ownership is lost or RunOwned returns; the lease is
+	// not taken again, nor released, until OnAcquired has returned.
+	OnAcquired func(ctx context.Context)
+
+	// OnLost is called when a held lease could not be renewed.
+	OnLost func()
+}
+
+// RunOwned competes for ownership of a session until ctx is cancelled.
+// While the lease is held it is renewed periodically; if renewal fails the
+// owner context is cancelled and the replica goes back to competing, so a
+// standby replica takes over when the current owner dies.
+func RunOwned(ctx context.Context, opts OwnershipOptions) error {
+	if opts.Store == nil {
+		return fmt.Errorf("ownership: no lease store")
+	}
+	if opts.SessionID == "" || opts.Owner == "" {
+		return fmt.Errorf("ownership: session ID and owner are required")
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 15 * time.Second
+	}
+	interval := opts.TTL / 3
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// held is closed when the OnAcquired call of the last ownership period
+	// has returned.
+	var ownerCancel context.CancelFunc
+	held := make(chan struct{})
+	close(held)
+	defer func() {
+		if ownerCancel != nil {
+			ownerCancel()
+		}
+		<-held
+		if ownerCancel != nil {
+			_ = opts.Store.Release(context.Background(), opts.SessionID, opts.Owner)
+		}
+	}()
+
+	for {
+		if ownerCancel == nil {
+			select {
+			case <-held:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			ok, err := opts.Store.Acquire(ctx, opts.SessionID, opts.Owner, opts.TTL)
+			if err == nil && ok {
+				ownerCtx, cancel := context.WithCancel(ctx)
+				ownerCancel = cancel
+				held = make(chan struct{})
+				go func(done chan struct{}) {
+					defer close(done)
+					if opts.OnAcquired != nil {
+						opts.OnAcquired(ownerCtx)
+					}
+				}(held)
+			}
+		} else {
+			ok, err := opts.Store.Renew(ctx, opts.SessionID, opts.Owner, opts.TTL)
+			if err != nil || !ok {
+				ownerCancel()
+				ownerCancel = nil
+				if opts.OnLost != nil {
+					opts.OnLost()
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLocalLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalLeaseStore()
+	if ok, _ := s.Acquire(ctx, "s1", "a", time.Minute); !ok {
+		t.Fatal("a could not acquire a free lease")
+	}
+	if ok, _ := s.Acquire(ctx, "s1", "b", time.Minute); ok {
+		t.Error("b acquired a lease held by a")
+	}
+	if ok, _ := s.Renew(ctx, "s1", "b", time.Minute); ok {
+		t.Error("b renewed a lease held by a")
+	}
+	if ok, _ := s.Renew(ctx, "s1", "a", time.Millisecond); !ok {
+		t.Error("a could not renew its lease")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := s.Acquire(ctx, "s1", "b", time.Minute); !ok {
+		t.Error("b could not acquire an expired lease")
+	}
+	s.Release(ctx, "s1", "a")
+	if ok, _ := s.Renew(ctx, "s1", "b", time.Minute); !ok {
+		t.Error("a released a lease it no longer held")
+	}
+}
+
+// flakyLeases loses every lease on its first renewal.
+type flakyLeases struct {
+	*LocalLeaseStore
+	mu      sync.Mutex
+	renewed bool
+}
+
+func (s *flakyLeases) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	first := !s.renewed
+	s.renewed = true
+	s.mu.Unlock()
+	if first {
+		return false, nil
+	}
+	return s.LocalLeaseStore.Renew(ctx, key, owner, ttl)
+}
+
+func TestRunOwnedWaitsForPreviousHolder(t *testing.T) {
+	var running, maxRunning, acquired atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := RunOwned(ctx, OwnershipOptions{
+		Store:     &flakyLeases{LocalLeaseStore: NewLocalLeaseStore()},
+		SessionID: "s1",
+		Owner:     "a",
+		TTL:       30 * time.Millisecond,
+		OnAcquired: func(ctx context.Context) {
+			acquired.Add(1)
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond) // slow to wind down
+			running.Add(-1)
+		},
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("RunOwned = %v, want the context's error", err)
+	}
+	if acquired.Load() < 2 {
+		t.Fatalf("lease acquired %d times, want it taken again after the loss", acquired.Load())
+	}
+	if maxRunning.Load() != 1 {
+		t.Errorf("%d OnAcquired calls ran at once, want 1", maxRunning.Load())
+	}
+	if running.Load() != 0 {
+		t.Error("RunOwned returned before OnAcquired did")
+	}
+}
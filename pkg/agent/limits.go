@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// RunLimit names a per-run limit of AgentLoopConfig.
+type RunLimit string
+
+const (
+	LimitTurns     RunLimit = "max_turns"
+	LimitToolCalls RunLimit = "max_tool_calls"
+	LimitCost      RunLimit = "max_cost" // SubAgentOptions.MaxCost
+)
+
+// RunLimitError ends a run that reached MaxTurns or MaxToolCalls. The run
+// ends with a synthetic assistant message with ai.StopReasonLimit
+// explaining the cutoff rather than with an error.
+type RunLimitError struct {
+	Limit RunLimit
+	Max   int
+}
+
+func (e *RunLimitError) Error() string {
+	if e.Limit == LimitTurns {
+		return fmt.Sprintf("run reached its limit of %d turns", e.Max)
+	}
+	return fmt.Sprintf("run reached its limit of %d tool calls", e.Max)
+}
+
+// text is the explanation ending the run.
+func (e *RunLimitError) text(config *AgentLoopConfig) string {
+	if e.Limit == LimitTurns {
+		return config.text(MsgTurnLimit, e.Max)
+	}
+	return config.text(MsgToolCallLimit, e.Max)
+}
+
+// limitMessage is the synthetic assistant message that ends a run cut off
+// by a run limit or budget.
+func limitMessage(config *AgentLoopConfig, text string) AgentMessage {
+	msg := &ai.AssistantMessage{
+		Role:       ai.RoleAssistant,
+		Content:    []ai.Content{ai.NewTextContent(text)},
+		StopReason: ai.StopReasonLimit,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	if m := config.Model; m != nil {
+		msg.Api, msg.Provider, msg.Model = m.Api, m.Provider, m.ID
+	}
+	am := NewAgentMessageFromMessage(ai.Message{Assistant: msg})
+	am.SetMetadata(MetadataSynthetic, true)
+	return am
+}
+
+// endAtLimit ends the run with a limit message saying text. inTurn closes
+// the turn that was started for the call the limit prevented.
+func endAtLimit(config *AgentLoopConfig, text string, inTurn bool, stream *AgentEventStream, newMessages *[]AgentMessage) {
+	am := limitMessage(config, text)
+	stream.Push(AgentEvent{Type: MessageEventStart, Message: &am})
+	stream.Push(AgentEvent{Type: MessageEventEnd, Message: &am})
+	*newMessages = append(*newMessages, am)
+	if inTurn {
+		stream.Push(AgentEvent{Type: TurnEventEnd, Message: &am})
+	}
+	stream.Push(AgentEvent{Type: AgentEventEnd, Messages: *newMessages})
+	stream.End(*newMessages)
+}
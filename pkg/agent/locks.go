@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ResourceLocks is a keyed mutex shared by the tool calls of a
+// conversation, so that parallel tool execution cannot interleave
+// side effects on one resource (a file path, a database row, ...). Tools
+// reach it through their context with LockResources. Locks are reentrant
+// per owner (one tool call), waits that would close a cycle fail with a
+// *DeadlockError, and waits longer than Timeout fail with a
+// *LockTimeoutError.
+type ResourceLocks struct {
+	// Timeout bounds each wait for a held key (default 30s); negative
+	// waits until the context is done.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	held    map[string]*heldLock
+	waiting map[string]string // owner → key it waits for
+}
+
+type heldLock struct {
+	owner    string
+	count    int
+	released chan struct{}
+}
+
+// NewResourceLocks creates an empty lock set.
+func NewResourceLocks() *ResourceLocks {
+	return &ResourceLocks{held: map[string]*heldLock{}, waiting: map[string]string{}}
+}
+
+// DeadlockError is returned when waiting for Key would deadlock: its
+// holder is, directly or transitively, waiting for a key Owner holds.
+type DeadlockError struct {
+	Key    string
+	Owner  string
+	Holder string
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf("lock %q: deadlock: held by %s, which is waiting for a lock held by %s", e.Key, e.Holder, e.Owner)
+}
+
+// LockTimeoutError is returned when Key stayed held longer than Timeout.
+type LockTimeoutError struct {
+	Key     string
+	Holder  string
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("lock %q: still held by %s after %s", e.Key, e.Holder, e.Timeout)
+}
+
+// Lock acquires keys for owner, in sorted order so that calls locking
+// overlapping sets cannot deadlock each other, and returns a function
+// releasing them. On error no key is held.
+func (l *ResourceLocks) Lock(ctx context.Context, owner string, keys ...string) (unlock func(), err error) {
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+	var acquired []string
+	release := func() {
+		for _, k := range acquired {
+			l.release(k)
+		}
+	}
+	for _, k := range keys {
+		if err := l.acquire(ctx, owner, k); err != nil {
+			release()
+			return nil, err
+		}
+		acquired = append(acquired, k)
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// Held returns the owner holding each locked key.
+func (l *ResourceLocks) Held() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]string, len(l.held))
+	for k, h := range l.held {
+		out[k] = h.owner
+	}
+	return out
+}
+
+func (l *ResourceLocks) acquire(ctx context.Context, owner, key string) error {
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		h := l.held[key]
+		if h == nil {
+			l.held[key] = &heldLock{owner: owner, count: 1, released: make(chan struct{})}
+			return nil
+		}
+		if h.owner == owner {
+			h.count++
+			return nil
+		}
+		if l.closesCycle(owner, h.owner) {
+			return &DeadlockError{Key: key, Owner: owner, Holder: h.owner}
+		}
+
+		l.waiting[owner] = key
+		l.mu.Unlock()
+		var err error
+		select {
+		case <-h.released:
+		case <-deadline:
+			err = &LockTimeoutError{Key: key, Holder: h.owner, Timeout: timeout}
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		l.mu.Lock()
+		delete(l.waiting, owner)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// closesCycle reports whether owner waiting on holder would complete a
+// cycle in the wait-for graph. l.mu must be held.
+func (l *ResourceLocks) closesCycle(owner, holder string) bool {
+	for seen := map[string]bool{}; !seen[holder]; {
+		if holder == owner {
+			return true
+		}
+		seen[holder] = true
+		key, ok := l.waiting[holder]
+		if !ok {
+			return false
+		}
+		h := l.held[key]
+		if h == nil {
+			return false
+		}
+		holder = h.owner
+	}
+	return false
+}
+
+func (l *ResourceLocks) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.held[key]
+	if h == nil {
+		return
+	}
+	if h.count--; h.count == 0 {
+		delete(l.held, key)
+		close(h.released)
+	}
+}
+
+type lockEnvKey struct{}
+
+type lockEnv struct {
+	locks *ResourceLocks
+	owner string
+}
+
+// WithResourceLocks returns a context through which LockResources locks
+// keys in locks on behalf of owner. The agent loop sets it for every tool
+// call, with the tool call ID as owner.
+func WithResourceLocks(ctx context.Context, locks *ResourceLocks, owner string) context.Context {
+	return context.WithValue(ctx, lockEnvKey{}, lockEnv{locks: locks, owner: owner})
+}
+
+// LockResources locks keys in the conversation's ResourceLocks for the
+// calling tool and returns the function releasing them, typically
+// deferred. Without a lock set in ctx (a tool run outside the agent loop)
+// it locks nothing.
+func LockResources(ctx context.Context, keys ...string) (unlock func(), err error) {
+	env, ok := ctx.Value(lockEnvKey{}).(lockEnv)
+	if !ok || env.locks == nil {
+		return func() {}, nil
+	}
+	return env.locks.Lock(ctx, env.owner, keys...)
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResourceLocksDetectDeadlock(t *testing.T) {
+	l := NewResourceLocks()
+	ctx := context.Background()
+	unlockA, err := l.Lock(ctx, "A", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockB, err := l.Lock(ctx, "B", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A waits for b while holding a ...
+	acquired := make(chan error, 1)
+	go func() {
+		unlock, err := l.Lock(ctx, "A", "b")
+		if err == nil {
+			unlock()
+		}
+		acquired <- err
+	}()
+	for waiting := ""; waiting != "b"; time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		waiting = l.waiting["A"]
+		l.mu.Unlock()
+	}
+
+	// ... so B waiting for a would close the cycle.
+	_, err = l.Lock(ctx, "B", "a")
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) || deadlock.Key != "a" || deadlock.Owner != "B" || deadlock.Holder != "A" {
+		t.Fatalf("B locking a = %v, want a *DeadlockError", err)
+	}
+
+	// B backs off and A gets b.
+	unlockB()
+	if err := <-acquired; err != nil {
+		t.Fatalf("A locking b after B released it = %v", err)
+	}
+	unlockA()
+	if held := l.Held(); len(held) != 0 {
+		t.Errorf("held after unlocking = %v", held)
+	}
+}
+
+func TestResourceLocksTimeOut(t *testing.T) {
+	l := NewResourceLocks()
+	l.Timeout = 20 * time.Millisecond
+	ctx := context.Background()
+	unlock, err := l.Lock(ctx, "A", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	// Reentrant for the holder.
+	again, err := l.Lock(ctx, "A", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again()
+
+	_, err = l.Lock(ctx, "B", "w", "x")
+	var timeout *LockTimeoutError
+	if !errors.As(err, &timeout) || timeout.Key != "x" || timeout.Holder != "A" || timeout.Timeout != l.Timeout {
+		t.Fatalf("B locking x = %v, want a *LockTimeoutError", err)
+	}
+	if held := l.Held(); len(held) != 1 || held["x"] != "A" {
+		t.Errorf("held after the timeout = %v, want only x by A", held)
+	}
+}
@@ -2,7 +2,10 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/badlogic/pi-go/pkg/ai"
@@ -20,8 +23,30 @@ func AgentLoop(
 	stream := NewAgentEventStream()
 
 	go func() {
-		newMessages := make([]AgentMessage, len(prompts))
-		copy(newMessages, prompts)
+		var newMessages []AgentMessage
+		defer recoverLoop(stream, config.Model, &newMessages)
+		// Moderation and intent analysis below are charged to the run.
+		ctx := withMeter(ctx, &config)
+
+		stream.Push(AgentEvent{Type: AgentEventStart})
+		stream.Push(AgentEvent{Type: TurnEventStart})
+
+		if config.ImageModeration != nil {
+			config.moderationLog = &moderationLog{}
+			var err error
+			if prompts, err = moderatePrompts(ctx, &config, prompts, stream); err != nil {
+				// The prompt is rejected and never enters the conversation.
+				am := NewAgentMessageFromMessage(ai.Message{Assistant: makeErrorAssistantMessage(config.Model, err.Error())})
+				stream.Push(AgentEvent{Type: TurnEventEnd, Message: &am})
+				stream.Push(AgentEvent{Type: AgentEventEnd})
+				stream.End(nil)
+				return
+			}
+		}
+		if config.TurnAnalyzer != nil {
+			prompts = tagIntents(ctx, config.TurnAnalyzer, prompts)
+		}
+		newMessages = append(newMessages, prompts...)
 
 		currentCtx := AgentContext{
 			SystemPrompt: agentCtx.SystemPrompt,
@@ -29,9 +54,6 @@ func AgentLoop(
 			Tools:        agentCtx.Tools,
 		}
 
-		stream.Push(AgentEvent{Type: AgentEventStart})
-		stream.Push(AgentEvent{Type: TurnEventStart})
-
 		for _, p := range prompts {
 			pm := p
 			stream.Push(AgentEvent{Type: MessageEventStart, Message: &pm})
@@ -55,7 +77,13 @@ func AgentLoopContinue(
 	if len(agentCtx.Messages) == 0 {
 		return nil, fmt.Errorf("cannot continue: no messages in context")
 	}
-	last := agentCtx.Messages[len(agentCtx.Messages)-1]
+	// A compaction summary is not a turn of its own; continue from the
+	// message before it.
+	i := len(agentCtx.Messages) - 1
+	for i > 0 && isCompactionSummary(agentCtx.Messages[i]) {
+		i--
+	}
+	last := agentCtx.Messages[i]
 	if last.Role() == ai.RoleAssistant {
 		return nil, fmt.Errorf("cannot continue from message role: assistant")
 	}
@@ -64,6 +92,7 @@ func AgentLoopContinue(
 
 	go func() {
 		newMessages := []AgentMessage{}
+		defer recoverLoop(stream, config.Model, &newMessages)
 		currentCtx := AgentContext{
 			SystemPrompt: agentCtx.SystemPrompt,
 			Messages:     append([]AgentMessage{}, agentCtx.Messages...),
@@ -79,6 +108,26 @@ func AgentLoopContinue(
 	return stream, nil
 }
 
+// recoverLoop ends the run with an error turn if the loop goroutine panics,
+// so that consumers always see AgentEventEnd. It must be deferred directly.
+func recoverLoop(stream *AgentEventStream, model *ai.Model, newMessages *[]AgentMessage) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	select {
+	case <-stream.Done():
+		return
+	default:
+	}
+	errMsg := makeErrorAssistantMessage(model, ai.RecoverPanic(v).Error())
+	am := NewAgentMessageFromMessage(ai.Message{Assistant: errMsg})
+	*newMessages = append(*newMessages, am)
+	stream.Push(AgentEvent{Type: TurnEventEnd, Message: &am})
+	stream.Push(AgentEvent{Type: AgentEventEnd, Messages: *newMessages})
+	stream.End(*newMessages)
+}
+
 // runLoop is the shared main loop for AgentLoop and AgentLoopContinue.
 func runLoop(
 	ctx context.Context,
@@ -89,12 +138,20 @@ func runLoop(
 	streamFn StreamFn,
 ) {
 	firstTurn := true
+	nudges, turns := 0, 0
+	if config.ImageModeration != nil && config.moderationLog == nil {
+		config.moderationLog = &moderationLog{}
+	}
+	ctx = withMeter(ctx, &config)
+	ctx = context.WithValue(ctx, streamKey{}, stream)
+	ctx = withRemainingCost(ctx, &config, newMessages)
+	runner := newToolRunner(&config, stream)
 
 	// Check for steering messages at start.
 	var pendingMessages []AgentMessage
 	if config.GetSteeringMessages != nil {
 		if msgs, err := config.GetSteeringMessages(); err == nil {
-			pendingMessages = msgs
+			pendingMessages = acceptQueued(ctx, &config, msgs, stream)
 		}
 	}
 
@@ -123,6 +180,20 @@ func runLoop(
 				pendingMessages = nil
 			}
 
+			if config.MaxTurns > 0 && turns >= config.MaxTurns {
+				e := &RunLimitError{Limit: LimitTurns, Max: config.MaxTurns}
+				endAtLimit(&config, e.text(&config), true, stream, newMessages)
+				return
+			}
+			if overBudget(&config, stream, newMessages) {
+				return
+			}
+			turns++
+
+			if config.Compaction.maybeCompact(ctx, currentCtx, &config, stream, streamFn, newMessages) && overBudget(&config, stream, newMessages) {
+				return
+			}
+
 			// Stream assistant response.
 			message, err := streamAssistantResponse(ctx, currentCtx, config, stream, streamFn)
 			if err != nil {
@@ -156,10 +227,13 @@ func runLoop(
 			hasMoreToolCalls = len(toolCalls) > 0
 
 			var toolResults []ai.ToolResultMessage
+			var retryErr error
 			if hasMoreToolCalls {
-				results, steering := executeToolCalls(ctx, currentCtx.Tools, message, stream, config.GetSteeringMessages)
+				results, steering, err := runner.executeToolCalls(ctx, currentCtx.Tools, message)
 				toolResults = results
 				steeringAfterTools = steering
+				retryErr = err
+				currentCtx.Tools = runner.strictTools(currentCtx.Tools)
 
 				for _, r := range toolResults {
 					trMsg := NewAgentMessageFromMessage(ai.Message{ToolResult: &r})
@@ -169,26 +243,57 @@ func runLoop(
 			}
 
 			stream.Push(AgentEvent{Type: TurnEventEnd, Message: &am, ToolResults: toolResults})
+			if usage, ok := measureContext(&config, currentCtx, message, toolResults); ok {
+				config.ContextMonitor.observe(&usage)
+				stream.Push(AgentEvent{Type: ContextUsageEvent, ContextUsage: &usage})
+			}
+			var limitErr *RunLimitError
+			if errors.As(retryErr, &limitErr) {
+				endAtLimit(&config, limitErr.text(&config), false, stream, newMessages)
+				return
+			}
+			if retryErr != nil {
+				errAm := NewAgentMessageFromMessage(ai.Message{Assistant: makeErrorAssistantMessage(config.Model, retryErr.Error())})
+				stream.Push(AgentEvent{Type: MessageEventStart, Message: &errAm})
+				stream.Push(AgentEvent{Type: MessageEventEnd, Message: &errAm})
+				*newMessages = append(*newMessages, errAm)
+				stream.Push(AgentEvent{Type: AgentEventEnd, Messages: *newMessages})
+				stream.End(*newMessages)
+				return
+			}
 
 			// Get steering messages after turn completes.
 			if len(steeringAfterTools) > 0 {
-				pendingMessages = steeringAfterTools
+				pendingMessages = acceptQueued(ctx, &config, steeringAfterTools, stream)
 				steeringAfterTools = nil
 			} else if config.GetSteeringMessages != nil {
 				if msgs, err := config.GetSteeringMessages(); err == nil {
-					pendingMessages = msgs
+					pendingMessages = acceptQueued(ctx, &config, msgs, stream)
 				}
 			}
+			pendingMessages = append(pendingMessages, runner.provisional.take()...)
 		}
 
-		// Agent would stop here. Check for follow-up messages.
+		// Agent would stop here. Wait for tools that returned provisional
+		// results, then check for follow-up messages.
+		if final := runner.provisional.wait(ctx); len(final) > 0 {
+			pendingMessages = final
+			continue
+		}
 		if config.GetFollowUpMessages != nil {
 			if followUp, err := config.GetFollowUpMessages(); err == nil && len(followUp) > 0 {
-				pendingMessages = followUp
-				continue
+				if pendingMessages = acceptQueued(ctx, &config, followUp, stream); len(pendingMessages) > 0 {
+					continue
+				}
 			}
 		}
 
+		// Nudge the model if the task is not done yet.
+		if nudge := config.Continuation.next(ctx, &config, currentCtx.Messages, &nudges); nudge != nil {
+			pendingMessages = []AgentMessage{*nudge}
+			continue
+		}
+
 		break
 	}
 
@@ -205,9 +310,13 @@ func streamAssistantResponse(
 	stream *AgentEventStream,
 	streamFn StreamFn,
 ) (*ai.AssistantMessage, error) {
-	messages := agentCtx.Messages
+	messages := CompactedView(agentCtx.Messages)
+	if d, ok := queueWait(messages, time.Now()); ok {
+		pushStage(stream, TurnStageQueueWait, d)
+	}
 
 	// Apply context transform if configured.
+	started := time.Now()
 	if config.TransformContext != nil {
 		var err error
 		messages, err = config.TransformContext(ctx, messages)
@@ -215,31 +324,66 @@ func streamAssistantResponse(
 			return nil, fmt.Errorf("transformContext: %w", err)
 		}
 	}
+	if config.Pipeline != nil {
+		var err error
+		messages, err = config.Pipeline.run(ctx, messages, func(t StageTiming) {
+			pushStage(stream, TurnStagePipeline+TurnStage(t.Name), t.Duration)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("transform pipeline: %w", err)
+		}
+	}
+	if config.TransformContext != nil || config.Pipeline != nil {
+		pushStage(stream, TurnStageTransform, time.Since(started))
+	}
 
 	// Convert to LLM messages.
+	started = time.Now()
 	llmMessages, err := config.ConvertToLLM(messages)
 	if err != nil {
 		return nil, fmt.Errorf("convertToLLM: %w", err)
 	}
-
-	// Build LLM context.
-	llmCtx := ai.Context{
-		SystemPrompt: agentCtx.SystemPrompt,
-		Messages:     llmMessages,
+	if config.ImageModeration != nil {
+		llmMessages, err = moderateContext(ctx, &config, llmMessages, stream)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	// Convert AgentTools to ai.Tools.
-	if len(agentCtx.Tools) > 0 {
-		tools := make([]ai.Tool, len(agentCtx.Tools))
-		for i, t := range agentCtx.Tools {
-			tools[i] = t.Tool
+	// The LLM context depends on the model: a fallback may differ from
+	// config.Model in image and tool support.
+	contexts := map[*ai.Model]ai.Context{}
+	contextFor := func(m *ai.Model) ai.Context {
+		if c, ok := contexts[m]; ok {
+			return c
+		}
+		msgs := llmMessages
+		if config.ImageCaptioner != nil && !m.CanUseImages() {
+			// Images that could not be captioned are dropped by limitImages.
+			msgs, _ = config.ImageCaptioner.DescribeImages(ctx, msgs)
+		}
+		msgs = limitImages(msgs, m, config.text(MsgImageOmitted))
+		c := ai.Context{
+			SystemPrompt: agentCtx.SystemPrompt,
+			Messages:     ai.PrepareToolResultImages(m, msgs),
+		}
+		// Tools the network policy forbids are left out: the model cannot
+		// call what it is not offered.
+		if m.CanUseTools() {
+			for _, t := range agentCtx.Tools {
+				if tool := t.LLMTool(); ai.CheckTool(tool.Name) == nil {
+					c.Tools = append(c.Tools, tool)
+				}
+			}
 		}
-		llmCtx.Tools = tools
+		contexts[m] = c
+		return c
 	}
+	llmCtx := contextFor(config.Model)
+	pushStage(stream, TurnStageConvert, time.Since(started))
 
-	sf := streamFn
-	if sf == nil {
-		return nil, fmt.Errorf("no stream function provided")
+	sf, err := loopStreamFn(&config, streamFn)
+	if err != nil {
+		return nil, err
 	}
 
 	// Resolve API key.
@@ -251,12 +395,29 @@ func streamAssistantResponse(
 		}
 	}
 
-	response := sf(config.Model, llmCtx, &opts)
+	var attempt *streamAttempt
+	var trace *TurnTrace
+	if config.OnTurnTrace != nil {
+		trace = newTurnTrace(messages, llmCtx, opts, config.Model)
+		defer func() {
+			trace.Model, trace.Context, _ = attempt.current()
+			trace.Duration = time.Since(trace.StartedAt)
+			config.OnTurnTrace(trace)
+		}()
+	}
+
+	var firstDelta time.Time
+	var response *ai.AssistantMessageEventStream
+	response, attempt = startStream(ctx, ai.RegistryOrDefault(config.Registry), config.Model, contextFor, &opts, sf)
 
 	var partialMessage *ai.AssistantMessage
 	addedPartial := false
+	invalidCalls := map[int]bool{}
 
 	for event := range response.Events() {
+		if trace != nil {
+			trace.Events = append(trace.Events, event)
+		}
 		switch event.Type {
 		case ai.EventStart:
 			partialMessage = event.Partial
@@ -268,6 +429,11 @@ func streamAssistantResponse(
 		case ai.EventTextStart, ai.EventTextDelta, ai.EventTextEnd,
 			ai.EventThinkingStart, ai.EventThinkingDelta, ai.EventThinkingEnd,
 			ai.EventToolCallStart, ai.EventToolCallDelta, ai.EventToolCallEnd:
+			if firstDelta.IsZero() && (event.Type == ai.EventTextDelta || event.Type == ai.EventThinkingDelta || event.Type == ai.EventToolCallDelta) {
+				firstDelta = time.Now()
+				_, _, requested := attempt.current()
+				pushStage(stream, TurnStageTTFT, firstDelta.Sub(requested))
+			}
 			if partialMessage != nil {
 				partialMessage = event.Partial
 				if addedPartial {
@@ -275,10 +441,25 @@ func streamAssistantResponse(
 				}
 				am := NewAgentMessageFromMessage(ai.Message{Assistant: cloneAssistant(partialMessage)})
 				stream.Push(AgentEvent{Type: MessageEventUpdate, AssistantMessageEvent: &event, Message: &am})
+				checkStreamingToolCall(event, agentCtx.Tools, config.CoerceArguments, invalidCalls, stream)
 			}
 
 		case ai.EventDone, ai.EventError:
+			if !firstDelta.IsZero() {
+				pushStage(stream, TurnStageGeneration, time.Since(firstDelta))
+			}
 			finalMessage := response.Result()
+			if usedModel, _, _ := attempt.current(); usedModel != config.Model && finalMessage != nil {
+				finalMessage.FallbackFrom = config.Model.ID
+			}
+			if finalMessage != nil {
+				for _, pp := range config.PostProcessors {
+					pp(finalMessage)
+				}
+				if config.ImageModeration != nil {
+					moderateAssistant(ctx, &config, finalMessage, stream)
+				}
+			}
 			if addedPartial {
 				agentCtx.Messages[len(agentCtx.Messages)-1] = NewAgentMessageFromMessage(ai.Message{Assistant: finalMessage})
 			} else {
@@ -290,21 +471,254 @@ func streamAssistantResponse(
 			}
 			fam := NewAgentMessageFromMessage(ai.Message{Assistant: finalMessage})
 			stream.Push(AgentEvent{Type: MessageEventEnd, Message: &fam})
+			if trace != nil {
+				trace.Response = finalMessage
+			}
 			return finalMessage, nil
 		}
 	}
 
-	return response.Result(), nil
+	final := response.Result()
+	if trace != nil {
+		trace.Response = final
+	}
+	return final, nil
+}
+
+// checkStreamingToolCall emits ToolCallInvalidEvent for a tool call that is
+// still streaming but already names an unknown tool or violates its schema,
+// at most once per content block (tracked in reported). Arguments are
+// coerced first, as runToolCall will, so that repairable values are not
+// reported.
+func checkStreamingToolCall(event ai.AssistantMessageEvent, tools []AgentTool, coerce *ai.CoerceOptions, reported map[int]bool, stream *AgentEventStream) {
+	switch event.Type {
+	case ai.EventToolCallStart, ai.EventToolCallDelta:
+	default:
+		return
+	}
+	if reported[event.ContentIndex] || event.Partial == nil || event.ContentIndex >= len(event.Partial.Content) {
+		return
+	}
+	tc := event.Partial.Content[event.ContentIndex].ToolCall
+	if tc == nil || tc.Name == "" {
+		return
+	}
+	var err error
+	if tool := findTool(tools, tc.Name); tool == nil {
+		err = fmt.Errorf("tool %q not found", tc.Name)
+	} else {
+		args := tc.Arguments
+		if opts := coerceOptions(tool, coerce); opts != nil && !tool.Strict {
+			args, _ = ai.CoerceArguments(tool.Parameters, args, *opts)
+		}
+		err = ai.ValidatePartialToolArguments(&tool.Tool, args)
+	}
+	if err == nil {
+		return
+	}
+	reported[event.ContentIndex] = true
+	stream.Push(AgentEvent{
+		Type:            ToolCallInvalidEvent,
+		ToolCallID:      tc.ID,
+		ToolName:        tc.Name,
+		Args:            tc.Arguments,
+		ValidationError: err.Error(),
+	})
+}
+
+// fallbackCooldown is how long a model that failed is skipped in favour of
+// its fallbacks.
+const fallbackCooldown = time.Minute
+
+// streamAttempt records which model of a fallback chain startStream is
+// calling, with the context it was sent and when.
+type streamAttempt struct {
+	mu      sync.Mutex
+	model   *ai.Model
+	llmCtx  ai.Context
+	started time.Time
+}
+
+func (a *streamAttempt) set(m *ai.Model, llmCtx ai.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.model, a.llmCtx, a.started = m, llmCtx, time.Now()
+}
+
+// current returns the model of the latest attempt, its context and when it
+// was requested. Once the stream has ended, that is the model that answered.
+func (a *streamAttempt) current() (*ai.Model, ai.Context, time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.model, a.llmCtx, a.started
+}
+
+// startStream calls sf on model, walking the model's fallback chain when a
+// call fails before producing any content. Each model is sent the context
+// contextFor builds for it. A model that failed because it was unavailable
+// (see ai.ModelUnavailable) is marked unhealthy in registry; unhealthy
+// models and models past their sunset date are skipped unless they are the
+// last in the chain. Deprecated models are still used; the agent warns
+// about them instead. The first start event is passed on at once; failed
+// attempts are otherwise invisible to the consumer.
+func startStream(ctx context.Context, registry *ai.Registry, model *ai.Model, contextFor func(*ai.Model) ai.Context, opts *ai.SimpleStreamOptions, sf StreamCtxFn) (*ai.AssistantMessageEventStream, *streamAttempt) {
+	attempt := &streamAttempt{}
+	// MaxTokens is fitted per model: fallbacks may have smaller windows.
+	call := func(m *ai.Model) *ai.AssistantMessageEventStream {
+		llmCtx := contextFor(m)
+		attempt.set(m, llmCtx)
+		return sf(ctx, m, llmCtx, ai.FitMaxTokens(m, llmCtx, opts))
+	}
+	chain := registry.ModelChain(model)
+	if len(chain) <= 1 {
+		return call(model), attempt
+	}
+
+	out := ai.NewAssistantMessageEventStream()
+	go func() {
+		started := false
+		push := func(src *ai.AssistantMessageEventStream, e ai.AssistantMessageEvent) {
+			if e.Type == ai.EventStart {
+				if started {
+					return
+				}
+				started = true
+			}
+			if e.Type == ai.EventError {
+				out.SetErr(src.Err())
+			}
+			out.Push(e)
+		}
+		for i, m := range chain {
+			last := i == len(chain)-1
+			if !last && (!registry.IsModelHealthy(m.Provider, m.ID) || ai.IsSunset(m, time.Now())) {
+				continue
+			}
+			src := call(m)
+			if !last && failedBeforeContent(ctx, registry, m, src, push) {
+				continue
+			}
+			for e := range src.Events() {
+				push(src, e)
+			}
+			out.SetErr(src.Err())
+			out.End(src.Result())
+			return
+		}
+	}()
+	return out, attempt
+}
+
+// failedBeforeContent passes on src's events through push until the first
+// one after the start event, and reports whether that was an error worth
+// falling back from. The failed attempt's usage is charged and, if the
+// model was unavailable, it is marked unhealthy.
+func failedBeforeContent(ctx context.Context, registry *ai.Registry, m *ai.Model, src *ai.AssistantMessageEventStream, push func(*ai.AssistantMessageEventStream, ai.AssistantMessageEvent)) bool {
+	for event := range src.Events() {
+		if event.Type == ai.EventStart {
+			push(src, event)
+			continue
+		}
+		if event.Type != ai.EventError || event.Reason == ai.StopReasonAborted {
+			push(src, event)
+			return false
+		}
+		var message string
+		if e := event.Error; e != nil {
+			message = e.ErrorMessage
+			ChargeUsage(ctx, e.Usage) // not part of the conversation
+		}
+		if ai.ModelUnavailable(src.Err(), message) {
+			registry.MarkModelUnhealthy(m.Provider, m.ID, fallbackCooldown)
+		}
+		return true
+	}
+	return false
+}
+
+// loopStreamFn returns the stream function a run calls: StreamCtxFn,
+// then streamFn, then the registry's providers.
+func loopStreamFn(config *AgentLoopConfig, streamFn StreamFn) (StreamCtxFn, error) {
+	sf := config.StreamCtxFn
+	switch {
+	case sf != nil:
+	case streamFn != nil:
+		sf = abortableStreamFn(streamFn)
+	case config.Registry != nil:
+		sf = registryStreamFn(config.Registry, config.ResumeDisconnects)
+	default:
+		return nil, fmt.Errorf("no stream function provided")
+	}
+	return safeStreamFn(sf), nil
+}
+
+// registryStreamFn adapts a registry's StreamSimpleCtx to a StreamCtxFn,
+// turning lookup failures into an error event. resumes > 0 streams with
+// StreamResumable instead.
+func registryStreamFn(registry *ai.Registry, resumes int) StreamCtxFn {
+	return func(ctx context.Context, model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		var s *ai.AssistantMessageEventStream
+		var err error
+		if resumes > 0 {
+			s, err = registry.StreamResumable(ctx, model, llmCtx, opts, resumes)
+		} else {
+			s, err = registry.StreamSimpleCtx(ctx, model, llmCtx, opts)
+		}
+		if err == nil {
+			return s
+		}
+		return errorStream(model, err)
+	}
+}
+
+// errorStream returns a stream that fails with err.
+func errorStream(model *ai.Model, err error) *ai.AssistantMessageEventStream {
+	s := ai.NewAssistantMessageEventStream()
+	s.SetErr(err)
+	s.Push(ai.AssistantMessageEvent{Type: ai.EventError, Reason: ai.StopReasonError, Error: makeErrorAssistantMessage(model, err.Error())})
+	return s
+}
+
+// safeStreamFn applies the network policy to the model, converts a panic
+// while starting a stream into an error stream and backfills Usage and
+// Timing for stream functions that do not. Custom stream functions are
+// checked here as the registry is bypassed.
+func safeStreamFn(sf StreamCtxFn) StreamCtxFn {
+	return func(ctx context.Context, model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		if err := ai.CheckModel(model); err != nil {
+			return errorStream(model, err)
+		}
+		start := time.Now()
+		s := ai.SafeStream(model, func() *ai.AssistantMessageEventStream { return sf(ctx, model, llmCtx, opts) })
+		return ai.WithTiming(ai.BackfillUsage(s, model, llmCtx), start)
+	}
 }
 
-// executeToolCalls runs tool calls sequentially, checking for steering after each.
-func executeToolCalls(
+// abortableStreamFn adapts a context-unaware StreamFn: the request itself
+// keeps running, but the stream ends with an aborted error as soon as ctx
+// is cancelled or the idle timeout fires.
+func abortableStreamFn(sf StreamFn) StreamCtxFn {
+	return func(ctx context.Context, model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		s := ai.AbortOnCancel(ctx, model, sf(model, llmCtx, opts))
+		if opts == nil {
+			return s
+		}
+		return ai.WithIdleTimeout(s, model, opts.IdleTimeout(), nil)
+	}
+}
+
+// executeToolCalls runs the assistant's tool calls in order, checking for
+// steering after each batch as SteeringToolPolicy says. With concurrency
+// > 1, consecutive calls to Parallelizable tools run together in batches of
+// up to concurrency; results always keep the order of the calls. The error
+// is a *RunLimitError when calls were refused under MaxToolCalls, or a
+// *ToolRetriesExhaustedError once MaxToolRetries is exceeded.
+func (r *toolRunner) executeToolCalls(
 	ctx context.Context,
 	tools []AgentTool,
 	assistantMsg *ai.AssistantMessage,
-	stream *AgentEventStream,
-	getSteeringMessages func() ([]AgentMessage, error),
-) ([]ai.ToolResultMessage, []AgentMessage) {
+) ([]ai.ToolResultMessage, []AgentMessage, error) {
+	stream := r.stream
 	var toolCalls []ai.ToolCall
 	for _, c := range assistantMsg.Content {
 		if c.ToolCall != nil {
@@ -314,112 +728,239 @@ func executeToolCalls(
 
 	var results []ai.ToolResultMessage
 	var steeringMessages []AgentMessage
+	var all []toolOutcome
+	policy := r.config.SteeringToolPolicy
+	allowed := len(toolCalls)
+	if limit := r.config.MaxToolCalls; limit > 0 {
+		allowed = max(0, min(allowed, limit-r.toolCalls))
+	}
 
-	for i, tc := range toolCalls {
-		tool := findTool(tools, tc.Name)
+	for i := 0; i < allowed; {
+		batch := toolCalls[i : i+toolBatchSize(tools, toolCalls[i:allowed], r.config.ToolConcurrency)]
+		outcomes := make([]toolOutcome, len(batch))
+		batchCtx, stopWatch := ctx, func() []AgentMessage { return nil }
+		if policy == SteeringAbortCurrent && r.config.GetSteeringMessages != nil {
+			batchCtx, stopWatch = watchSteering(ctx, r.config.GetSteeringMessages)
+		}
+		if len(batch) == 1 {
+			outcomes[0] = r.runToolCall(batchCtx, tools, batch[0])
+		} else {
+			var wg sync.WaitGroup
+			for j, tc := range batch {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					outcomes[j] = r.runToolCall(batchCtx, tools, tc)
+				}()
+			}
+			wg.Wait()
+		}
+		steeringMessages = stopWatch()
+		i += len(batch)
+		all = append(all, outcomes...)
+
+		for j, tc := range batch {
+			trMsg := ai.ToolResultMessage{
+				Role:       ai.RoleToolResult,
+				ToolCallID: tc.ID,
+				ToolName:   tc.Name,
+				Content:    outcomes[j].result.Content,
+				Details:    outcomes[j].result.Details,
+				IsError:    outcomes[j].isError,
+				Timestamp:  time.Now().UnixMilli(),
+			}
+			results = append(results, trMsg)
 
-		stream.Push(AgentEvent{
-			Type:       ToolExecutionEventStart,
-			ToolCallID: tc.ID,
-			ToolName:   tc.Name,
-			Args:       tc.Arguments,
-		})
+			am := NewAgentMessageFromMessage(ai.Message{ToolResult: &trMsg})
+			stream.Push(AgentEvent{Type: MessageEventStart, Message: &am})
+			stream.Push(AgentEvent{Type: MessageEventEnd, Message: &am})
+		}
+
+		// Check for steering messages — skip remaining tools if user interrupted.
+		if steeringMessages == nil && policy != SteeringFinishTools && r.config.GetSteeringMessages != nil {
+			if steering, err := r.config.GetSteeringMessages(); err == nil && len(steering) > 0 {
+				steeringMessages = steering
+			}
+		}
+		if steeringMessages != nil {
+			for _, skipped := range toolCalls[i:] {
+				results = append(results, skipToolCall(skipped, r.config.text(MsgToolSkipped), stream))
+			}
+			break
+		}
+	}
+	r.toolCalls += len(all)
+
+	if steeringMessages == nil && allowed < len(toolCalls) {
+		for _, refused := range toolCalls[allowed:] {
+			results = append(results, skipToolCall(refused, r.config.text(MsgToolOverLimit, r.config.MaxToolCalls), stream))
+		}
+		r.countRetries(all)
+		return results, nil, &RunLimitError{Limit: LimitToolCalls, Max: r.config.MaxToolCalls}
+	}
+	return results, steeringMessages, r.countRetries(all)
+}
+
+// toolRunner executes tool calls for one run of the loop.
+type toolRunner struct {
+	config      *AgentLoopConfig
+	stream      *AgentEventStream
+	approvals   *toolApprovals
+	provisional *provisionalCalls
+	locks       *ResourceLocks
+
+	mu              sync.Mutex
+	toolRetries     int             // consecutive turns with only recoverable tool errors
+	toolCalls       int             // tool calls executed this run, for MaxToolCalls
+	lastRecoverable string          // text of the last recoverable error
+	invalid         map[string]int  // consecutive validation failures by tool
+	strict          map[string]bool // tools switched to strict schema mode
+}
+
+func newToolRunner(config *AgentLoopConfig, stream *AgentEventStream) *toolRunner {
+	approvals := config.toolApprovals
+	if approvals == nil {
+		approvals = &toolApprovals{}
+	}
+	locks := config.Locks
+	if locks == nil {
+		locks = NewResourceLocks()
+	}
+	return &toolRunner{config: config, stream: stream, approvals: approvals, provisional: newProvisionalCalls(), locks: locks}
+}
+
+// toolOutcome is the result of one tool call.
+type toolOutcome struct {
+	result      AgentToolResult
+	isError     bool
+	recoverable bool // the model may fix the error by retrying the call
+}
+
+// toolBatchSize returns how many of calls, from the first, may run
+// concurrently: the leading run of Parallelizable tools, capped at
+// concurrency, and always at least one.
+func toolBatchSize(tools []AgentTool, calls []ai.ToolCall, concurrency int) int {
+	n := 0
+	for n < len(calls) && n < concurrency {
+		tool := findTool(tools, calls[n].Name)
+		if tool == nil || !tool.Parallelizable {
+			break
+		}
+		n++
+	}
+	return max(n, 1)
+}
+
+// runToolCall validates and executes one tool call, emitting its execution
+// events.
+func (r *toolRunner) runToolCall(ctx context.Context, tools []AgentTool, tc ai.ToolCall) toolOutcome {
+	stream := r.stream
+	tool := findTool(tools, tc.Name)
 
-		var result AgentToolResult
-		var isError bool
+	stream.Push(ToolExecutionStart{ToolCallID: tc.ID, ToolName: tc.Name, Args: tc.Arguments}.Event())
 
-		if tool == nil {
+	var result AgentToolResult
+	var isError, recoverable bool
+
+	if tool == nil {
+		result = AgentToolResult{
+			Content: []ai.Content{ai.NewTextContent(r.config.text(MsgToolNotFound, tc.Name))},
+		}
+		isError, recoverable = true, true
+	} else if err := ai.CheckTool(tc.Name); err != nil {
+		result = AgentToolResult{
+			Content: []ai.Content{ai.NewTextContent(err.Error())},
+		}
+		isError = true
+	} else {
+		// Repair and validate arguments.
+		if opts := coerceOptions(tool, r.config.CoerceArguments); opts != nil && !tool.Strict {
+			var changes []string
+			tc.Arguments, changes = ai.CoerceArguments(tool.Parameters, tc.Arguments, *opts)
+			if len(changes) > 0 {
+				stream.Push(AgentEvent{Type: WarningEvent, ToolCallID: tc.ID, ToolName: tc.Name,
+					Warning: fmt.Sprintf("coerced arguments of tool %s: %s", tc.Name, strings.Join(changes, "; "))})
+			}
+		}
+		args, err := validateArguments(tool, tc)
+		args, err = r.validated(ctx, tool, tc, args, err)
+		if err != nil {
+			result = AgentToolResult{
+				Content: []ai.Content{ai.NewTextContent(err.Error())},
+			}
+			isError, recoverable = true, true
+		} else if err := r.approvals.approve(ctx, r.config, tc, stream); err != nil {
 			result = AgentToolResult{
-				Content: []ai.Content{ai.NewTextContent(fmt.Sprintf("Tool %s not found", tc.Name))},
+				Content: []ai.Content{ai.NewTextContent(err.Error())},
 			}
 			isError = true
 		} else {
-			// Validate arguments.
-			args, err := ai.ValidateToolArguments(&tool.Tool, tc)
+			onUpdate := func(partial AgentToolResult) {
+				stream.Push(ToolExecutionUpdate{
+					ToolCallID:    tc.ID,
+					ToolName:      tc.Name,
+					Args:          tc.Arguments,
+					PartialResult: partial,
+				}.Event())
+			}
+
+			tool = r.cachingTool(tool, tc)
+			if len(r.config.ToolInterceptors) > 0 {
+				wrapped := WrapTool(*tool, r.config.ToolInterceptors...)
+				tool = &wrapped
+			}
+			started := time.Now()
+			execResult, err := r.executeProvisional(WithResourceLocks(ctx, r.locks, tc.ID), tool, tc, args, onUpdate)
+			stream.Push(AgentEvent{
+				Type:            TurnStageTimingEvent,
+				ToolCallID:      tc.ID,
+				ToolName:        tc.Name,
+				TurnStageTiming: &TurnStageTiming{Stage: TurnStageToolExecution, DurationMs: ms(time.Since(started))},
+			})
 			if err != nil {
+				text := err.Error()
+				var timeout *ToolTimeoutError
+				if errors.As(err, &timeout) {
+					text = r.config.text(MsgToolTimedOut, timeout.ToolName, timeout.Timeout)
+				} else if steeringInterrupted(ctx) {
+					text = r.config.text(MsgToolInterrupted)
+				}
 				result = AgentToolResult{
-					Content: []ai.Content{ai.NewTextContent(err.Error())},
+					Content: []ai.Content{ai.NewTextContent(text)},
 				}
 				isError = true
+				recoverable = errors.Is(err, ErrRecoverable)
 			} else {
-				onUpdate := func(partial AgentToolResult) {
-					stream.Push(AgentEvent{
-						Type:          ToolExecutionEventUpdate,
-						ToolCallID:    tc.ID,
-						ToolName:      tc.Name,
-						Args:          tc.Arguments,
-						PartialResult: partial,
-					})
-				}
-
-				execResult, err := tool.Execute(ctx, tc.ID, args, onUpdate)
-				if err != nil {
-					result = AgentToolResult{
-						Content: []ai.Content{ai.NewTextContent(err.Error())},
-					}
-					isError = true
-				} else {
-					result = execResult
-				}
+				result = execResult
 			}
 		}
+	}
+	if r.config.ImageModeration != nil && !isError {
+		result, isError = moderateToolResult(ctx, r.config, tc, result, stream)
+	}
+	if recoverable {
+		r.retryFeedback(&result)
+	}
 
-		stream.Push(AgentEvent{
-			Type:       ToolExecutionEventEnd,
-			ToolCallID: tc.ID,
-			ToolName:   tc.Name,
-			Result:     result,
-			IsError:    isError,
-		})
-
-		trMsg := ai.ToolResultMessage{
-			Role:       ai.RoleToolResult,
-			ToolCallID: tc.ID,
-			ToolName:   tc.Name,
-			Content:    result.Content,
-			Details:    result.Details,
-			IsError:    isError,
-			Timestamp:  time.Now().UnixMilli(),
-		}
-		results = append(results, trMsg)
-
-		am := NewAgentMessageFromMessage(ai.Message{ToolResult: &trMsg})
-		stream.Push(AgentEvent{Type: MessageEventStart, Message: &am})
-		stream.Push(AgentEvent{Type: MessageEventEnd, Message: &am})
+	stream.Push(ToolExecutionEnd{ToolCallID: tc.ID, ToolName: tc.Name, Result: result, IsError: isError}.Event())
+	return toolOutcome{result: result, isError: isError, recoverable: recoverable}
+}
 
-		// Check for steering messages — skip remaining tools if user interrupted.
-		if getSteeringMessages != nil {
-			if steering, err := getSteeringMessages(); err == nil && len(steering) > 0 {
-				steeringMessages = steering
-				for _, skipped := range toolCalls[i+1:] {
-					results = append(results, skipToolCall(skipped, stream))
-				}
-				break
-			}
-		}
+// coerceOptions returns the tool's own coercion options, or def.
+func coerceOptions(tool *AgentTool, def *ai.CoerceOptions) *ai.CoerceOptions {
+	if tool.Coerce != nil {
+		return tool.Coerce
 	}
-
-	return results, steeringMessages
+	return def
 }
 
-func skipToolCall(tc ai.ToolCall, stream *AgentEventStream) ai.ToolResultMessage {
+func skipToolCall(tc ai.ToolCall, text string, stream *AgentEventStream) ai.ToolResultMessage {
 	result := AgentToolResult{
-		Content: []ai.Content{ai.NewTextContent("Skipped due to queued user message.")},
+		Content: []ai.Content{ai.NewTextContent(text)},
 	}
 
-	stream.Push(AgentEvent{
-		Type:       ToolExecutionEventStart,
-		ToolCallID: tc.ID,
-		ToolName:   tc.Name,
-		Args:       tc.Arguments,
-	})
-	stream.Push(AgentEvent{
-		Type:       ToolExecutionEventEnd,
-		ToolCallID: tc.ID,
-		ToolName:   tc.Name,
-		Result:     result,
-		IsError:    true,
-	})
+	stream.Push(ToolExecutionStart{ToolCallID: tc.ID, ToolName: tc.Name, Args: tc.Arguments}.Event())
+	stream.Push(ToolExecutionEnd{ToolCallID: tc.ID, ToolName: tc.Name, Result: result, IsError: true}.Event())
 
 	trMsg := ai.ToolResultMessage{
 		Role:       ai.RoleToolResult,
@@ -437,6 +978,55 @@ func skipToolCall(tc ai.ToolCall, stream *AgentEventStream) ai.ToolResultMessage
 	return trMsg
 }
 
+// limitImages replaces image content the model cannot accept with a text
+// placeholder: all images if the model has no image input, otherwise all but
+// the most recent MaxImagesPerRequest. The input slice is not modified.
+func limitImages(messages []ai.Message, model *ai.Model, placeholder string) []ai.Message {
+	keep := -1
+	if !model.CanUseImages() {
+		keep = 0
+	} else if model.MaxImagesPerRequest > 0 {
+		keep = model.MaxImagesPerRequest
+	}
+	if keep < 0 {
+		return messages
+	}
+
+	out := make([]ai.Message, len(messages))
+	copy(out, messages)
+	for i := len(out) - 1; i >= 0; i-- {
+		m := out[i]
+		switch {
+		case m.User != nil:
+			u := *m.User
+			u.Content = dropImages(u.Content, &keep, placeholder)
+			out[i] = ai.Message{User: &u}
+		case m.ToolResult != nil:
+			tr := *m.ToolResult
+			tr.Content = dropImages(tr.Content, &keep, placeholder)
+			out[i] = ai.Message{ToolResult: &tr}
+		}
+	}
+	return out
+}
+
+// dropImages keeps up to *keep images (from the end) and decrements it.
+func dropImages(content []ai.Content, keep *int, placeholder string) []ai.Content {
+	out := make([]ai.Content, len(content))
+	for i := len(content) - 1; i >= 0; i-- {
+		c := content[i]
+		if c.Image != nil {
+			if *keep > 0 {
+				*keep--
+			} else {
+				c = ai.NewTextContent(placeholder)
+			}
+		}
+		out[i] = c
+	}
+	return out
+}
+
 func findTool(tools []AgentTool, name string) *AgentTool {
 	for i := range tools {
 		if tools[i].Name == name {
@@ -471,3 +1061,13 @@ func cloneAssistant(m *ai.AssistantMessage) *ai.AssistantMessage {
 	copy(clone.Content, m.Content)
 	return &clone
 }
+
+// executeTool runs a tool, converting a panic into an error result.
+func executeTool(ctx context.Context, tool *AgentTool, id string, args map[string]any, onUpdate AgentToolUpdateCallback) (result AgentToolResult, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = ai.RecoverPanic(v)
+		}
+	}()
+	return tool.Execute(ctx, id, args, onUpdate)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// moderationLog remembers which images were reported in a run, so that a
+// flagged image staying in the context is reported once, not every turn.
+type moderationLog struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// push emits an ImageModerationEvent for r unless its image was already
+// reported.
+func (l *moderationLog) push(stream *AgentEventStream, r ai.ImageModeration, tc *ai.ToolCall) {
+	l.mu.Lock()
+	seen := l.seen[r.Hash]
+	if l.seen == nil {
+		l.seen = map[string]bool{}
+	}
+	l.seen[r.Hash] = true
+	l.mu.Unlock()
+	if seen {
+		return
+	}
+	e := AgentEvent{Type: ImageModerationEvent, ImageModeration: &r}
+	if tc != nil {
+		e.ToolCallID, e.ToolName = tc.ID, tc.Name
+	}
+	stream.Push(e)
+}
+
+// moderateToolResult applies the image policy to a tool's result before it
+// is recorded, so that blurred images never reach the conversation. A
+// blocked image turns the result into an error.
+func moderateToolResult(ctx context.Context, config *AgentLoopConfig, tc ai.ToolCall, result AgentToolResult, stream *AgentEventStream) (AgentToolResult, bool) {
+	content, reports, err := config.ImageModeration.ModerateContent(ctx, ai.RoleToolResult, result.Content)
+	for _, r := range reports {
+		config.moderationLog.push(stream, r, &tc)
+	}
+	if err != nil {
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent(config.text(MsgToolWithheld, tc.Name, err))}}, true
+	}
+	result.Content = content
+	return result, false
+}
+
+// moderatePrompts applies the image policy to user messages as they are
+// accepted into the conversation, so that the history keeps blurred images
+// only. Messages with a blocked image are left out; the error reports the
+// first of them.
+func moderatePrompts(ctx context.Context, config *AgentLoopConfig, msgs []AgentMessage, stream *AgentEventStream) ([]AgentMessage, error) {
+	var accepted []AgentMessage
+	var blocked error
+	for _, m := range msgs {
+		if m.User == nil {
+			accepted = append(accepted, m)
+			continue
+		}
+		content, reports, err := config.ImageModeration.ModerateContent(ctx, ai.RoleUser, m.User.Content)
+		for _, r := range reports {
+			config.moderationLog.push(stream, r, nil)
+		}
+		if err != nil {
+			if blocked == nil {
+				blocked = err
+			}
+			continue
+		}
+		u := *m.User
+		u.Content = content
+		m.User = &u
+		accepted = append(accepted, m)
+	}
+	return accepted, blocked
+}
+
+// acceptQueued moderates and tags steering and follow-up messages as they
+// are taken from the queues. A message with a blocked image is dropped;
+// its image_moderation event tells the sender.
+func acceptQueued(ctx context.Context, config *AgentLoopConfig, msgs []AgentMessage, stream *AgentEventStream) []AgentMessage {
+	if len(msgs) == 0 {
+		return msgs
+	}
+	if config.ImageModeration != nil {
+		msgs, _ = moderatePrompts(ctx, config, msgs, stream)
+	}
+	if config.TurnAnalyzer != nil {
+		msgs = tagIntents(ctx, config.TurnAnalyzer, msgs)
+	}
+	return msgs
+}
+
+// moderateAssistant applies the image policy to the images a model
+// generated before the message ends. Assistant output cannot be rejected,
+// so blocked images are withheld like blurred ones.
+func moderateAssistant(ctx context.Context, config *AgentLoopConfig, msg *ai.AssistantMessage, stream *AgentEventStream) {
+	content, reports, err := config.ImageModeration.ModerateContent(ctx, ai.RoleAssistant, msg.Content)
+	for _, r := range reports {
+		config.moderationLog.push(stream, r, nil)
+	}
+	if errors.Is(err, ai.ErrImageBlocked) {
+		content = append([]ai.Content{}, content...)
+		for _, r := range reports {
+			if r.Action == ai.ModerationBlock {
+				content[r.Content] = ai.NewTextContent(config.text(MsgImageBlocked, strings.Join(r.Verdict.Categories, ", ")))
+			}
+		}
+	}
+	msg.Content = content
+}
+
+// moderateContext applies the image policy to every image about to be sent
+// to the model: user attachments, tool results and earlier assistant
+// output. A blocked image fails the call.
+func moderateContext(ctx context.Context, config *AgentLoopConfig, msgs []ai.Message, stream *AgentEventStream) ([]ai.Message, error) {
+	msgs, reports, err := config.ImageModeration.ModerateImages(ctx, msgs)
+	for _, r := range reports {
+		config.moderationLog.push(stream, r, nil)
+	}
+	return msgs, err
+}
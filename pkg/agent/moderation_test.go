@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// unsafeImages flags every image whose data is "unsafe".
+var unsafeImages = ai.ImageClassifierFunc(func(ctx context.Context, img *ai.ImageContent) (ai.ImageVerdict, error) {
+	if img.Data == "unsafe" {
+		return ai.ImageVerdict{Flagged: true, Categories: []string{"violence"}}, nil
+	}
+	return ai.ImageVerdict{}, nil
+})
+
+// replyStream answers every call with content.
+func replyStream(content ...ai.Content) StreamFn {
+	return func(model *ai.Model, _ ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonStop, Content: content}
+		out := ai.NewAssistantMessageEventStream()
+		partial := *msg
+		go func() {
+			out.Push(ai.AssistantMessageEvent{Type: ai.EventStart, Partial: &partial})
+			out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+		}()
+		return out
+	}
+}
+
+func TestBlockedPromptIsRejected(t *testing.T) {
+	calls := 0
+	reply := replyStream(ai.NewTextContent("ok"))
+	a := NewAgent(AgentOptions{
+		StreamFn: func(model *ai.Model, c ai.Context, o *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			calls++
+			return reply(model, c, o)
+		},
+		ImageModeration: &ai.ImageModerator{Classifier: unsafeImages, Action: ai.ModerationBlock},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	var reports []ai.ImageModeration
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == ImageModerationEvent {
+			reports = append(reports, *e.ImageModeration)
+		}
+	})
+
+	if err := a.Prompt("look", ai.ImageContent{Data: "unsafe", MimeType: "image/png"}); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	st := a.State()
+	if len(st.Messages) != 0 || calls != 0 {
+		t.Fatalf("blocked prompt was recorded (%d messages) or sent (%d calls)", len(st.Messages), calls)
+	}
+	if !strings.Contains(st.Error, ai.ErrImageBlocked.Error()) {
+		t.Errorf("error = %q, want the block", st.Error)
+	}
+	if len(reports) != 1 || reports[0].Role != ai.RoleUser || reports[0].Action != ai.ModerationBlock {
+		t.Errorf("reports = %+v", reports)
+	}
+
+	// The agent is not stuck: the next prompt goes through.
+	if err := a.Prompt("hello"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if st := a.State(); st.Error != "" || len(st.Messages) != 2 {
+		t.Errorf("next prompt: error %q, %d messages", st.Error, len(st.Messages))
+	}
+}
+
+func TestGeneratedImageIsModeratedAtMessageEnd(t *testing.T) {
+	a := NewAgent(AgentOptions{
+		StreamFn:        replyStream(ai.NewTextContent("here"), ai.Content{Image: &ai.ImageContent{Data: "unsafe", MimeType: "image/png"}}),
+		ImageModeration: &ai.ImageModerator{Classifier: unsafeImages, Action: ai.ModerationBlock},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	var ended *ai.AssistantMessage
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == MessageEventEnd && e.Message.Assistant != nil {
+			ended = e.Message.Assistant
+		}
+	})
+
+	if err := a.Prompt("draw"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if ended == nil {
+		t.Fatal("no assistant message_end")
+	}
+	if img := ended.Content[1]; img.Image != nil || img.Text == nil || !strings.Contains(img.Text.Text, "violence") {
+		t.Errorf("message_end carries %+v, want the image withheld", img)
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// OutputSchemaError is returned for a tool result that does not match the
+// tool's OutputSchema.
+type OutputSchemaError struct {
+	ToolName string
+	Err      error
+}
+
+func (e *OutputSchemaError) Error() string {
+	return fmt.Sprintf("tool %s returned output not matching its output schema: %v", e.ToolName, e.Err)
+}
+
+func (e *OutputSchemaError) Unwrap() error { return e.Err }
+
+// LLMTool returns the tool definition sent to the model: the embedded
+// ai.Tool, with the OutputSchema appended to the description when set.
+func (t AgentTool) LLMTool() ai.Tool {
+	if t.OutputSchema == nil {
+		return t.Tool
+	}
+	schema, err := json.Marshal(t.OutputSchema)
+	if err != nil {
+		return t.Tool
+	}
+	tool := t.Tool
+	tool.Description = strings.TrimRight(tool.Description, "\n") + "\n\nReturns JSON matching this schema:\n" + string(schema)
+	return tool
+}
+
+// ToolOutput returns the structured output of a result for validation
+// against an OutputSchema: Details when set, otherwise the result's text
+// decoded as JSON.
+func ToolOutput(result AgentToolResult) (any, error) {
+	var data []byte
+	if result.Details != nil {
+		var err error
+		if data, err = json.Marshal(result.Details); err != nil {
+			return nil, err
+		}
+	} else {
+		var sb strings.Builder
+		for _, c := range result.Content {
+			if c.Text != nil {
+				sb.WriteString(c.Text.Text)
+			}
+		}
+		data = []byte(sb.String())
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("output is not JSON: %w", err)
+	}
+	return out, nil
+}
+
+// checkOutput validates a successful result against the tool's
+// OutputSchema.
+func checkOutput(tool *AgentTool, result AgentToolResult, err error) (AgentToolResult, error) {
+	if err != nil || tool.OutputSchema == nil {
+		return result, err
+	}
+	out, err := ToolOutput(result)
+	if err == nil {
+		err = ai.ValidateValue(tool.OutputSchema, out)
+	}
+	if err != nil {
+		return result, &OutputSchemaError{ToolName: tool.Name, Err: err}
+	}
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package agent
+
+import "encoding/json"
+
+// Typed payloads for tool execution events. AgentEvent keeps its untyped
+// Args / Result fields for wire compatibility; producers
+// build events from these structs and consumers read them back with the
+// accessor methods instead of type assertions.
+
+// ToolExecutionStart is the payload of a tool_execution_start event.
+type ToolExecutionStart struct {
+	ToolCallID string
+	ToolName   string
+	Args       map[string]any
+}
+
+// ToolExecutionUpdate is the payload of a tool_execution_update event.
+type ToolExecutionUpdate struct {
+	ToolCallID    string
+	ToolName      string
+	Args          map[string]any
+	PartialResult AgentToolResult
+}
+
+// ToolExecutionEnd is the payload of a tool_execution_end event.
+type ToolExecutionEnd struct {
+	ToolCallID string
+	ToolName   string
+	Result     AgentToolResult
+	IsError    bool
+}
+
+// Event wraps the payload in an AgentEvent.
+func (p ToolExecutionStart) Event() AgentEvent {
+	return AgentEvent{Type: ToolExecutionEventStart, ToolCallID: p.ToolCallID, ToolName: p.ToolName, Args: p.Args}
+}
+
+// Event wraps the payload in an AgentEvent.
+func (p ToolExecutionUpdate) Event() AgentEvent {
+	return AgentEvent{
+		Type:          ToolExecutionEventUpdate,
+		ToolCallID:    p.ToolCallID,
+		ToolName:      p.ToolName,
+		Args:          p.Args,
+		PartialResult: &p.PartialResult,
+	}
+}
+
+// Event wraps the payload in an AgentEvent.
+func (p ToolExecutionEnd) Event() AgentEvent {
+	return AgentEvent{
+		Type:       ToolExecutionEventEnd,
+		ToolCallID: p.ToolCallID,
+		ToolName:   p.ToolName,
+		Result:     p.Result,
+		IsError:    p.IsError,
+	}
+}
+
+// ToolExecutionStart returns the payload of a tool_execution_start event;
+// ok is false for other event types.
+func (e AgentEvent) ToolExecutionStart() (p ToolExecutionStart, ok bool) {
+	if e.Type != ToolExecutionEventStart {
+		return p, false
+	}
+	return ToolExecutionStart{ToolCallID: e.ToolCallID, ToolName: e.ToolName, Args: argsOf(e.Args)}, true
+}
+
+// ToolExecutionUpdate returns the payload of a tool_execution_update
+// event; ok is false for other event types.
+func (e AgentEvent) ToolExecutionUpdate() (p ToolExecutionUpdate, ok bool) {
+	if e.Type != ToolExecutionEventUpdate {
+		return p, false
+	}
+	return ToolExecutionUpdate{
+		ToolCallID:    e.ToolCallID,
+		ToolName:      e.ToolName,
+		Args:          argsOf(e.Args),
+		PartialResult: toolResultOf(e.PartialResult),
+	}, true
+}
+
+// ToolExecutionEnd returns the payload of a tool_execution_end event; ok is
+// false for other event types.
+func (e AgentEvent) ToolExecutionEnd() (p ToolExecutionEnd, ok bool) {
+	if e.Type != ToolExecutionEventEnd {
+		return p, false
+	}
+	return ToolExecutionEnd{
+		ToolCallID: e.ToolCallID,
+		ToolName:   e.ToolName,
+		Result:     toolResultOf(e.Result),
+		IsError:    e.IsError,
+	}, true
+}
+
+// argsOf converts an Args field to a map. Events decoded from JSON already
+// hold a map; anything else is round-tripped through JSON.
+func argsOf(v any) map[string]any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		return v
+	}
+	var out map[string]any
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &out)
+	}
+	return out
+}
+
+// toolResultOf converts a Result or PartialResult field, which holds an
+// AgentToolResult in-process or a decoded JSON object after a round trip.
+func toolResultOf(v any) AgentToolResult {
+	switch v := v.(type) {
+	case AgentToolResult:
+		return v
+	case *AgentToolResult:
+		if v != nil {
+			return *v
+		}
+		return AgentToolResult{}
+	case nil:
+		return AgentToolResult{}
+	}
+	var out AgentToolResult
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &out)
+	}
+	return out
+}
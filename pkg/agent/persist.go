@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// SessionFormatVersion is the envelope version written by SaveSession.
+const SessionFormatVersion = 1
+
+// SavedSession is the envelope written by SaveSession and read by
+// LoadSession. Tools are not serializable; the restoring host supplies
+// them.
+type SavedSession struct {
+	Version       int              `json:"version"`
+	SavedAt       int64            `json:"savedAt"` // Unix ms
+	SystemPrompt  string           `json:"systemPrompt"`
+	Model         *ai.Model        `json:"model,omitempty"` // resolved against the registry on Restore
+	ModelAlias    string           `json:"modelAlias,omitempty"`
+	ThinkingLevel ai.ThinkingLevel `json:"thinkingLevel,omitempty"`
+	Language      string           `json:"language,omitempty"`
+	Messages      []AgentMessage   `json:"messages"`
+	Usage         ai.Usage         `json:"usage"` // summed over assistant messages
+}
+
+// SaveSession writes the agent's conversation state to w as JSON. Messages
+// keep their IDs, metadata and Custom payloads; register Custom types with
+// RegisterCustomType to get them back typed.
+func (a *Agent) SaveSession(w io.Writer) error {
+	st := a.State()
+	s := SavedSession{
+		Version:       SessionFormatVersion,
+		SavedAt:       time.Now().UnixMilli(),
+		SystemPrompt:  st.SystemPrompt,
+		Model:         st.Model,
+		ModelAlias:    st.ModelAlias,
+		ThinkingLevel: st.ThinkingLevel,
+		Language:      st.Language,
+		Messages:      st.Messages,
+		Usage:         TotalUsage(st.Messages),
+	}
+	if s.Messages == nil {
+		s.Messages = []AgentMessage{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession reads a session written by SaveSession.
+func LoadSession(r io.Reader) (*SavedSession, error) {
+	var s SavedSession
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("load session: %w", err)
+	}
+	if s.Version < 1 || s.Version > SessionFormatVersion {
+		return nil, fmt.Errorf("load session: unsupported version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// Restore replaces the agent's conversation state with a loaded session.
+// The model is looked up in the agent's registry by provider and ID, so
+// updated pricing and limits apply. A model the registry does not know is
+// an error: the saved definition carries a base URL and headers, and a
+// session file from elsewhere could send requests, and API keys, to any
+// host. AgentOptions.TrustSavedModels uses the saved definition instead.
+// Restore fails while a run is in progress.
+func (a *Agent) Restore(s *SavedSession) error {
+	model := s.Model
+	if model != nil {
+		if m := ai.RegistryOrDefault(a.registry).GetModel(model.Provider, model.ID); m != nil {
+			model = m
+		} else if !a.trustSavedModels {
+			return fmt.Errorf("restore session: model %s/%s is not in the registry", model.Provider, model.ID)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state.IsStreaming {
+		return fmt.Errorf("cannot restore a session while the agent is running")
+	}
+	a.state.SystemPrompt = s.SystemPrompt
+	a.state.Model = model
+	a.state.ModelAlias = s.ModelAlias
+	a.state.ThinkingLevel = s.ThinkingLevel
+	a.state.Language = s.Language
+	a.state.Messages = append([]AgentMessage{}, s.Messages...)
+	a.state.Error = ""
+	a.sessionUsage = s.Usage
+	return nil
+}
+
+// TotalUsage sums the usage of the assistant messages in msgs.
+func TotalUsage(msgs []AgentMessage) ai.Usage {
+	var u ai.Usage
+	for _, m := range msgs {
+		if m.Assistant != nil {
+			u = ai.AddUsage(u, m.Assistant.Usage)
+		}
+	}
+	return u
+}
+
+// ---------------------------------------------------------------------------
+// AgentMessage JSON
+// ---------------------------------------------------------------------------
+
+var customTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{byName: map[string]reflect.Type{}, byType: map[reflect.Type]string{}}
+
+// RegisterCustomType registers the Go type of sample (a value or pointer)
+// under name, so that Custom payloads of that type decode back into it
+// instead of generic JSON values.
+func RegisterCustomType(name string, sample any) {
+	t := reflect.TypeOf(sample)
+	customTypes.Lock()
+	defer customTypes.Unlock()
+	customTypes.byName[name] = t
+	customTypes.byType[t] = name
+}
+
+// wireAgentMessage holds the fields AgentMessage adds to ai.Message.
+type wireAgentMessage struct {
+	ID         string          `json:"id,omitempty"`
+	CustomType string          `json:"customType,omitempty"`
+	Custom     json.RawMessage `json:"custom,omitempty"`
+	Metadata   map[string]any  `json:"metadata,omitempty"`
+}
+
+// MarshalJSON writes the LLM message's fields followed by id, customType,
+// custom and metadata.
+func (m AgentMessage) MarshalJSON() ([]byte, error) {
+	w := wireAgentMessage{ID: m.ID, Metadata: m.Metadata}
+	if m.Custom != nil {
+		data, err := json.Marshal(m.Custom)
+		if err != nil {
+			return nil, fmt.Errorf("custom message payload: %w", err)
+		}
+		w.Custom = data
+		customTypes.RLock()
+		w.CustomType = customTypes.byType[reflect.TypeOf(m.Custom)]
+		customTypes.RUnlock()
+	}
+	extra, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	if m.Role() == "" {
+		return extra, nil
+	}
+	base, err := json.Marshal(m.Message)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(extra, []byte("{}")) {
+		return base, nil
+	}
+	// Splice the two objects: {base..., extra...}.
+	out := append(base[:len(base)-1:len(base)-1], ',')
+	return append(out, extra[1:]...), nil
+}
+
+// metadataTypes gives the Go types of the built-in metadata keys, so that
+// they decode to the type that was stored rather than to []any or float64.
+var metadataTypes = map[string]reflect.Type{
+	MetadataIntents:    reflect.TypeFor[[]TurnIntent](),
+	MetadataFeedback:   reflect.TypeFor[[]Feedback](),
+	MetadataCompaction: reflect.TypeFor[int](),
+}
+
+// decodeMetadata decodes message metadata, giving the built-in keys their
+// types.
+func decodeMetadata(raw map[string]json.RawMessage) (map[string]any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	md := make(map[string]any, len(raw))
+	for k, data := range raw {
+		t := metadataTypes[k]
+		if t == nil {
+			var v any
+			if err := json.Unmarshal(data, &v); err != nil {
+				return nil, err
+			}
+			md[k] = v
+			continue
+		}
+		v := reflect.New(t)
+		if err := json.Unmarshal(data, v.Interface()); err != nil {
+			return nil, fmt.Errorf("metadata %s: %w", k, err)
+		}
+		md[k] = v.Elem().Interface()
+	}
+	return md, nil
+}
+
+// UnmarshalJSON reads what MarshalJSON writes.
+func (m *AgentMessage) UnmarshalJSON(data []byte) error {
+	var msg ai.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	var w struct {
+		wireAgentMessage
+		Metadata map[string]json.RawMessage `json:"metadata,omitempty"`
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	md, err := decodeMetadata(w.Metadata)
+	if err != nil {
+		return err
+	}
+	*m = AgentMessage{Message: msg, ID: w.ID, Metadata: md}
+	if len(w.Custom) == 0 {
+		return nil
+	}
+	customTypes.RLock()
+	t := customTypes.byName[w.CustomType]
+	customTypes.RUnlock()
+	if t == nil {
+		return json.Unmarshal(w.Custom, &m.Custom)
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(w.Custom, v.Interface()); err != nil {
+		return fmt.Errorf("custom message payload %s: %w", w.CustomType, err)
+	}
+	m.Custom = v.Elem().Interface()
+	return nil
+}
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestRestoreRequiresARegisteredModel(t *testing.T) {
+	reg := ai.NewRegistry()
+	reg.RegisterModel(&ai.Model{ID: "known", Provider: ai.ProviderOpenAI, BaseURL: "https://api.openai.com/v1"}, "test")
+
+	a := NewAgent(AgentOptions{Registry: reg})
+	saved := &SavedSession{Version: SessionFormatVersion, Model: &ai.Model{ID: "known", Provider: ai.ProviderOpenAI, BaseURL: "https://evil.example"}}
+	if err := a.Restore(saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.State().Model.BaseURL; got != "https://api.openai.com/v1" {
+		t.Errorf("restored base URL %q; the registry's definition should win", got)
+	}
+
+	saved.Model = &ai.Model{ID: "unknown", Provider: ai.ProviderOpenAI, BaseURL: "https://evil.example"}
+	if err := a.Restore(saved); err == nil {
+		t.Error("restored a model the registry does not know")
+	}
+
+	trusting := NewAgent(AgentOptions{Registry: reg, TrustSavedModels: true})
+	if err := trusting.Restore(saved); err != nil {
+		t.Fatal(err)
+	}
+	if trusting.State().Model.ID != "unknown" {
+		t.Error("TrustSavedModels did not use the saved definition")
+	}
+}
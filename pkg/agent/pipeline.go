@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TransformStage is one named step of a TransformPipeline.
+type TransformStage struct {
+	Name      string
+	Transform func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
+	Disabled  bool
+}
+
+// StageTiming reports how long a stage took in one pipeline run.
+type StageTiming struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// TransformPipeline is an ordered, inspectable list of context transforms
+// (e.g. trim, inject memories, compress, sanitize). It is safe to modify
+// between runs from any goroutine.
+type TransformPipeline struct {
+	mu     sync.RWMutex
+	stages []TransformStage
+
+	// OnStage, if set, is called after each stage with its timing.
+	OnStage func(StageTiming)
+}
+
+// NewTransformPipeline creates a pipeline running stages in order.
+func NewTransformPipeline(stages ...TransformStage) *TransformPipeline {
+	return &TransformPipeline{stages: append([]TransformStage{}, stages...)}
+}
+
+// Stages returns a copy of the stages in order.
+func (p *TransformPipeline) Stages() []TransformStage {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]TransformStage{}, p.stages...)
+}
+
+func (p *TransformPipeline) indexLocked(name string) int {
+	for i, s := range p.stages {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Append adds a stage at the end.
+func (p *TransformPipeline) Append(stage TransformStage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages = append(p.stages, stage)
+}
+
+// Insert adds stage before the stage named before ("" appends).
+func (p *TransformPipeline) Insert(before string, stage TransformStage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if before == "" {
+		p.stages = append(p.stages, stage)
+		return nil
+	}
+	i := p.indexLocked(before)
+	if i < 0 {
+		return fmt.Errorf("no stage named %q", before)
+	}
+	p.stages = append(p.stages[:i], append([]TransformStage{stage}, p.stages[i:]...)...)
+	return nil
+}
+
+// Remove deletes the named stage.
+func (p *TransformPipeline) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i := p.indexLocked(name); i >= 0 {
+		p.stages = append(p.stages[:i], p.stages[i+1:]...)
+	}
+}
+
+// Reorder sets the stage order. names must list every stage exactly once.
+func (p *TransformPipeline) Reorder(names ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(names) != len(p.stages) {
+		return fmt.Errorf("reorder needs all %d stages, got %d", len(p.stages), len(names))
+	}
+	out := make([]TransformStage, 0, len(names))
+	seen := map[string]bool{}
+	for _, n := range names {
+		i := p.indexLocked(n)
+		if i < 0 || seen[n] {
+			return fmt.Errorf("unknown or repeated stage %q", n)
+		}
+		seen[n] = true
+		out = append(out, p.stages[i])
+	}
+	p.stages = out
+	return nil
+}
+
+// SetEnabled enables or disables the named stage.
+func (p *TransformPipeline) SetEnabled(name string, enabled bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.indexLocked(name)
+	if i < 0 {
+		return fmt.Errorf("no stage named %q", name)
+	}
+	p.stages[i].Disabled = !enabled
+	return nil
+}
+
+type stageOverridesKey struct{}
+
+// WithStageOverrides returns a context that enables (true) or disables
+// (false) stages for runs using it, overriding their Disabled flag. Pass it
+// to Agent.PromptContext to change the stages of one run.
+func WithStageOverrides(ctx context.Context, overrides map[string]bool) context.Context {
+	return context.WithValue(ctx, stageOverridesKey{}, overrides)
+}
+
+// Run applies the enabled stages in order. Errors are wrapped with the
+// stage name.
+func (p *TransformPipeline) Run(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error) {
+	return p.run(ctx, messages, nil)
+}
+
+// run is Run that also reports each stage's timing to onStage; the loop
+// uses it to emit stage_timing events.
+func (p *TransformPipeline) run(ctx context.Context, messages []AgentMessage, onStage func(StageTiming)) ([]AgentMessage, error) {
+	overrides, _ := ctx.Value(stageOverridesKey{}).(map[string]bool)
+	for _, stage := range p.Stages() {
+		enabled := !stage.Disabled
+		if v, ok := overrides[stage.Name]; ok {
+			enabled = v
+		}
+		if !enabled || stage.Transform == nil {
+			continue
+		}
+		start := time.Now()
+		out, err := stage.Transform(ctx, messages)
+		timing := StageTiming{Name: stage.Name, Duration: time.Since(start), Err: err}
+		if p.OnStage != nil {
+			p.OnStage(timing)
+		}
+		if onStage != nil {
+			onStage(timing)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		messages = out
+	}
+	return messages, nil
+}
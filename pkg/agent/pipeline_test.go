@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestPipelineStagesPerRunAndTimed(t *testing.T) {
+	var ran []string
+	stage := func(name string, disabled bool) TransformStage {
+		return TransformStage{Name: name, Disabled: disabled, Transform: func(ctx context.Context, msgs []AgentMessage) ([]AgentMessage, error) {
+			ran = append(ran, name)
+			return msgs, nil
+		}}
+	}
+	a := NewAgent(AgentOptions{
+		StreamFn: replyStream(ai.NewTextContent("ok")),
+		Pipeline: NewTransformPipeline(stage("trim", false), stage("memories", true)),
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	var timed []TurnStage
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == TurnStageTimingEvent {
+			timed = append(timed, e.TurnStageTiming.Stage)
+		}
+	})
+
+	if err := a.Prompt("one"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	ctx := WithStageOverrides(context.Background(), map[string]bool{"trim": false, "memories": true})
+	if err := a.PromptContext(ctx, "two"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+
+	if !slices.Equal(ran, []string{"trim", "memories"}) {
+		t.Errorf("ran %v, want trim in the first run and memories in the second", ran)
+	}
+	for _, want := range []TurnStage{"transform:trim", "transform:memories"} {
+		if !slices.Contains(timed, want) {
+			t.Errorf("no stage_timing for %s in %v", want, timed)
+		}
+	}
+}
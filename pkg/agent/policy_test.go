@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestForbiddenToolsAreNotOffered(t *testing.T) {
+	ai.SetNetworkPolicy(&ai.NetworkPolicy{AllowedTools: []string{"read"}})
+	defer ai.SetNetworkPolicy(nil)
+
+	var offered []string
+	reply := replyStream(ai.NewTextContent("ok"))
+	a := NewAgent(AgentOptions{
+		StreamFn: func(model *ai.Model, c ai.Context, o *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			for _, tool := range c.Tools {
+				offered = append(offered, tool.Name)
+			}
+			return reply(model, c, o)
+		},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	a.SetTools([]AgentTool{{Tool: ai.Tool{Name: "read"}}, {Tool: ai.Tool{Name: "fetch"}}})
+
+	if err := a.Prompt("hi"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if len(offered) != 1 || offered[0] != "read" {
+		t.Errorf("offered tools %v, want only read", offered)
+	}
+}
+
+func TestCustomStreamFnHonoursProviderPolicy(t *testing.T) {
+	ai.SetNetworkPolicy(&ai.NetworkPolicy{AllowedProviders: []ai.Provider{"local"}})
+	defer ai.SetNetworkPolicy(nil)
+
+	calls := 0
+	reply := replyStream(ai.NewTextContent("ok"))
+	a := NewAgent(AgentOptions{
+		StreamFn: func(model *ai.Model, c ai.Context, o *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			calls++
+			return reply(model, c, o)
+		},
+	})
+	a.SetModel(&ai.Model{ID: "test", Provider: "openai"})
+
+	if err := a.Prompt("hi"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if calls != 0 {
+		t.Errorf("stream function called %d times for a forbidden provider", calls)
+	}
+	msgs := a.State().Messages
+	if last := msgs[len(msgs)-1].Assistant; last == nil || !strings.Contains(last.ErrorMessage, "network policy") {
+		t.Errorf("last message = %+v, want a policy error", msgs[len(msgs)-1])
+	}
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// provisionalCalls tracks tool calls that returned a provisional result
+// and are still running, and collects their final outputs.
+type provisionalCalls struct {
+	mu      sync.Mutex
+	running int
+	done    []AgentMessage
+	wake    chan struct{}
+}
+
+func newProvisionalCalls() *provisionalCalls {
+	return &provisionalCalls{wake: make(chan struct{}, 1)}
+}
+
+func (p *provisionalCalls) start() {
+	p.mu.Lock()
+	p.running++
+	p.mu.Unlock()
+}
+
+func (p *provisionalCalls) finish(msg AgentMessage) {
+	p.mu.Lock()
+	p.running--
+	p.done = append(p.done, msg)
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the final outputs collected so far.
+func (p *provisionalCalls) take() []AgentMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := p.done
+	p.done = nil
+	return out
+}
+
+// wait blocks until at least one running call has finished and returns the
+// collected outputs; it returns nil at once when nothing is running.
+func (p *provisionalCalls) wait(ctx context.Context) []AgentMessage {
+	for {
+		p.mu.Lock()
+		out, running := p.done, p.running
+		p.done = nil
+		p.mu.Unlock()
+		if len(out) > 0 || running == 0 {
+			return out
+		}
+		select {
+		case <-p.wake:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// executeProvisional runs tool like executeToolWithTimeout, but when the
+// call outlasts tool.ProvisionalAfter it returns the latest partial result
+// marked as provisional and leaves the call running; its final output is
+// handed to r.provisional when it finishes.
+func (r *toolRunner) executeProvisional(ctx context.Context, tool *AgentTool, tc ai.ToolCall, args map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+	timeout := toolTimeout(tool, r.config.DefaultToolTimeout)
+	if tool.ProvisionalAfter <= 0 {
+		result, err := executeToolWithTimeout(ctx, tool, timeout, tc.ID, args, onUpdate)
+		result, err = checkOutput(tool, result, err)
+		return result, err
+	}
+
+	var mu sync.Mutex
+	var latest AgentToolResult
+	provisional := false
+	// Updates stop once the provisional result has been returned: the
+	// call's tool_execution_end has been emitted by then.
+	track := func(partial AgentToolResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if provisional {
+			return
+		}
+		latest = partial
+		onUpdate(partial)
+	}
+
+	type outcome struct {
+		result AgentToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := executeToolWithTimeout(ctx, tool, timeout, tc.ID, args, track)
+		result, err = checkOutput(tool, result, err)
+		mu.Lock()
+		late := provisional
+		mu.Unlock()
+		if late {
+			r.provisional.finish(finalToolOutput(r.config, tc, result, err))
+			return
+		}
+		done <- outcome{result, err}
+	}()
+
+	timer := time.NewTimer(tool.ProvisionalAfter)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-timer.C:
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	default:
+	}
+	provisional = true
+	r.provisional.start()
+	note := r.config.text(MsgToolProvisional, tc.Name, tool.ProvisionalAfter)
+	content := append(append([]ai.Content{}, latest.Content...), ai.NewTextContent(note))
+	return AgentToolResult{Content: content, Details: latest.Details}, nil
+}
+
+// finalToolOutput is the user message that delivers the final output of a
+// call that returned a provisional result.
+func finalToolOutput(config *AgentLoopConfig, tc ai.ToolCall, result AgentToolResult, err error) AgentMessage {
+	var content []ai.Content
+	if err != nil {
+		content = []ai.Content{ai.NewTextContent(config.text(MsgToolFinalFailed, tc.Name, tc.ID, err))}
+	} else {
+		content = append([]ai.Content{ai.NewTextContent(config.text(MsgToolFinalOutput, tc.Name, tc.ID))}, result.Content...)
+	}
+	return NewAgentMessageFromMessage(ai.NewUserMessageWithContent(content))
+}
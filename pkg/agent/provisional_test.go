@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestProvisionalCallStopsUpdatesAfterItsEnd(t *testing.T) {
+	tool := AgentTool{
+		Tool: ai.Tool{Name: "read", Parameters: map[string]any{"type": "object"}},
+		Execute: func(ctx context.Context, _ string, _ map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+			for range 50 {
+				onUpdate(AgentToolResult{Content: []ai.Content{ai.NewTextContent("working")}})
+				time.Sleep(time.Millisecond)
+			}
+			return AgentToolResult{Content: []ai.Content{ai.NewTextContent("page")}}, nil
+		},
+		ProvisionalAfter: 10 * time.Millisecond,
+	}
+	s := &toolTurnsStream{turns: 1}
+	a := NewAgent(AgentOptions{StreamFn: s.stream})
+	a.SetModel(&ai.Model{ID: "test"})
+	a.SetTools([]AgentTool{tool})
+	var mu sync.Mutex
+	ended, updates, late := false, 0, 0
+	a.Subscribe(func(e AgentEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e.Type {
+		case ToolExecutionEventUpdate:
+			updates++
+			if ended {
+				late++
+			}
+		case ToolExecutionEventEnd:
+			ended = true
+		}
+	})
+
+	if err := a.Prompt("read"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	time.Sleep(60 * time.Millisecond) // let the call finish in the background
+	mu.Lock()
+	defer mu.Unlock()
+	if !ended || updates == 0 {
+		t.Fatalf("ended %v after %d updates", ended, updates)
+	}
+	if late != 0 {
+		t.Errorf("%d updates arrived after tool_execution_end", late)
+	}
+}
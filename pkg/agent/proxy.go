@@ -1,15 +1,18 @@
 package agent
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/ai/sse"
 )
 
 // ProxyStreamOptions configures a proxy stream call.
@@ -35,19 +38,27 @@ type ProxyAssistantMessageEvent struct {
 
 // StreamProxy is a StreamFn that routes LLM calls through a proxy server.
 func StreamProxy(model *ai.Model, ctx ai.Context, opts *ProxyStreamOptions) *ai.AssistantMessageEventStream {
-	stream := ai.NewAssistantMessageEventStream()
-
-	go func() {
-		partial := &ai.AssistantMessage{
-			Role:       ai.RoleAssistant,
-			StopReason: ai.StopReasonStop,
-			Content:    []ai.Content{},
-			Api:        model.Api,
-			Provider:   model.Provider,
-			Model:      model.ID,
-			Usage:      ai.Usage{},
-			Timestamp:  time.Now().UnixMilli(),
-		}
+	return StreamProxyCtx(context.Background(), model, ctx, opts)
+}
+
+// StreamProxyCtx is StreamProxy with a request context; cancelling reqCtx
+// aborts the HTTP request and ends the stream with StopReasonAborted. With
+// opts.IdleTimeoutMs set, a connection that stops delivering events is
+// closed and the stream ends with ai.ErrIdleTimeout.
+func StreamProxyCtx(reqCtx context.Context, model *ai.Model, ctx ai.Context, opts *ProxyStreamOptions) *ai.AssistantMessageEventStream {
+	if idle := opts.IdleTimeout(); idle > 0 {
+		reqCtx, cancel := context.WithCancel(reqCtx)
+		return ai.WithIdleTimeout(streamProxy(reqCtx, model, ctx, opts), model, idle, cancel)
+	}
+	return streamProxy(reqCtx, model, ctx, opts)
+}
+
+func streamProxy(reqCtx context.Context, model *ai.Model, ctx ai.Context, opts *ProxyStreamOptions) *ai.AssistantMessageEventStream {
+	stream := ai.NewAssistantMessageEventStreamFor(&opts.StreamOptions)
+
+	ai.GoSafe(stream, model, func() {
+		acc := ai.NewMessageAccumulator(model)
+		partial := acc.Message()
 
 		body := map[string]any{
 			"model":   model,
@@ -60,21 +71,25 @@ func StreamProxy(model *ai.Model, ctx ai.Context, opts *ProxyStreamOptions) *ai.
 		}
 		bodyJSON, err := json.Marshal(body)
 		if err != nil {
-			emitProxyError(stream, partial, fmt.Sprintf("marshal error: %v", err))
+			emitProxyError(stream, partial, fmt.Errorf("marshal error: %w", err))
 			return
 		}
 
-		req, err := http.NewRequest("POST", opts.ProxyURL+"/api/stream", strings.NewReader(string(bodyJSON)))
+		req, err := http.NewRequestWithContext(reqCtx, "POST", opts.ProxyURL+"/api/stream", strings.NewReader(string(bodyJSON)))
 		if err != nil {
-			emitProxyError(stream, partial, fmt.Sprintf("request error: %v", err))
+			emitProxyError(stream, partial, fmt.Errorf("request error: %w", err))
 			return
 		}
 		req.Header.Set("Authorization", "Bearer "+opts.AuthToken)
 		req.Header.Set("Content-Type", "application/json")
 
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := ai.GetTransport(opts.Transport).Do(reqCtx, req)
 		if err != nil {
-			emitProxyError(stream, partial, fmt.Sprintf("request failed: %v", err))
+			if reqCtx.Err() != nil {
+				emitProxyAborted(stream, partial, reqCtx.Err())
+				return
+			}
+			emitProxyError(stream, partial, fmt.Errorf("request failed: %w", err))
 			return
 		}
 		defer resp.Body.Close()
@@ -88,138 +103,104 @@ func StreamProxy(model *ai.Model, ctx ai.Context, opts *ProxyStreamOptions) *ai.
 			if json.Unmarshal(bodyBytes, &errData) == nil && errData.Error != "" {
 				errMsg = fmt.Sprintf("Proxy error: %s", errData.Error)
 			}
-			emitProxyError(stream, partial, errMsg)
+			emitProxyError(stream, partial, &ai.APIError{
+				StatusCode: resp.StatusCode,
+				Message:    errMsg,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			})
 			return
 		}
 
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-			data := strings.TrimSpace(line[6:])
-			if data == "" {
-				continue
-			}
-
-			var proxyEvent ProxyAssistantMessageEvent
-			if err := json.Unmarshal([]byte(data), &proxyEvent); err != nil {
-				continue
+		dec := sse.NewDecoder(resp.Body)
+		for {
+			msg, err := dec.Next()
+			if err != nil {
+				break
 			}
-
-			event := processProxyEvent(&proxyEvent, partial)
-			if event != nil {
-				stream.Push(*event)
+			for _, data := range proxyEventData(msg.Data) {
+				var proxyEvent ProxyAssistantMessageEvent
+				if err := json.Unmarshal([]byte(data), &proxyEvent); err != nil {
+					continue
+				}
+				if event, ok := processProxyEvent(&proxyEvent, acc); ok {
+					stream.Push(event)
+				}
 			}
 		}
 
+		if reqCtx.Err() != nil {
+			emitProxyAborted(stream, partial, reqCtx.Err())
+			return
+		}
 		stream.End(partial)
-	}()
+	})
 
 	return stream
 }
 
-func processProxyEvent(pe *ProxyAssistantMessageEvent, partial *ai.AssistantMessage) *ai.AssistantMessageEvent {
-	ensureContentIndex(partial, pe.ContentIndex)
-
-	switch pe.Type {
-	case "start":
-		return &ai.AssistantMessageEvent{Type: ai.EventStart, Partial: partial}
-
-	case "text_start":
-		partial.Content[pe.ContentIndex] = ai.NewTextContent("")
-		return &ai.AssistantMessageEvent{Type: ai.EventTextStart, ContentIndex: pe.ContentIndex, Partial: partial}
-
-	case "text_delta":
-		c := partial.Content[pe.ContentIndex]
-		if c.Text != nil {
-			c.Text.Text += pe.Delta
-			partial.Content[pe.ContentIndex] = c
-			return &ai.AssistantMessageEvent{Type: ai.EventTextDelta, ContentIndex: pe.ContentIndex, Delta: pe.Delta, Partial: partial}
-		}
-		return nil
-
-	case "text_end":
-		c := partial.Content[pe.ContentIndex]
-		if c.Text != nil {
-			c.Text.TextSignature = pe.ContentSignature
-			partial.Content[pe.ContentIndex] = c
-			return &ai.AssistantMessageEvent{Type: ai.EventTextEnd, ContentIndex: pe.ContentIndex, Content: c.Text.Text, Partial: partial}
-		}
-		return nil
-
-	case "thinking_start":
-		partial.Content[pe.ContentIndex] = ai.NewThinkingContent("")
-		return &ai.AssistantMessageEvent{Type: ai.EventThinkingStart, ContentIndex: pe.ContentIndex, Partial: partial}
-
-	case "thinking_delta":
-		c := partial.Content[pe.ContentIndex]
-		if c.Thinking != nil {
-			c.Thinking.Thinking += pe.Delta
-			partial.Content[pe.ContentIndex] = c
-			return &ai.AssistantMessageEvent{Type: ai.EventThinkingDelta, ContentIndex: pe.ContentIndex, Delta: pe.Delta, Partial: partial}
-		}
-		return nil
-
-	case "thinking_end":
-		c := partial.Content[pe.ContentIndex]
-		if c.Thinking != nil {
-			c.Thinking.ThinkingSignature = pe.ContentSignature
-			partial.Content[pe.ContentIndex] = c
-			return &ai.AssistantMessageEvent{Type: ai.EventThinkingEnd, ContentIndex: pe.ContentIndex, Content: c.Thinking.Thinking, Partial: partial}
-		}
+// proxyEventData returns the JSON events in the data of one SSE event.
+// Proxies that write one "data:" line per event without blank lines in
+// between produce a single SSE event whose lines are separate JSON
+// documents; those are split again.
+func proxyEventData(data string) []string {
+	if strings.TrimSpace(data) == "" {
 		return nil
-
-	case "toolcall_start":
-		partial.Content[pe.ContentIndex] = ai.NewToolCallContent(pe.ID, pe.ToolName, map[string]any{})
-		return &ai.AssistantMessageEvent{Type: ai.EventToolCallStart, ContentIndex: pe.ContentIndex, Partial: partial}
-
-	case "toolcall_delta":
-		c := partial.Content[pe.ContentIndex]
-		if c.ToolCall != nil {
-			// Parse partial JSON for arguments.
-			c.ToolCall.Arguments = ai.ParseStreamingJSON(pe.Delta)
-			partial.Content[pe.ContentIndex] = c
-			return &ai.AssistantMessageEvent{Type: ai.EventToolCallDelta, ContentIndex: pe.ContentIndex, Delta: pe.Delta, Partial: partial}
-		}
-		return nil
-
-	case "toolcall_end":
-		c := partial.Content[pe.ContentIndex]
-		if c.ToolCall != nil {
-			return &ai.AssistantMessageEvent{Type: ai.EventToolCallEnd, ContentIndex: pe.ContentIndex, ToolCallData: c.ToolCall, Partial: partial}
+	}
+	if json.Valid([]byte(data)) {
+		return []string{data}
+	}
+	var out []string
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
 		}
-		return nil
+	}
+	return out
+}
 
-	case "done":
-		partial.StopReason = ai.StopReason(pe.Reason)
+func processProxyEvent(pe *ProxyAssistantMessageEvent, acc *ai.MessageAccumulator) (ai.AssistantMessageEvent, bool) {
+	e := ai.AssistantMessageEvent{
+		Type:         ai.AssistantMessageEventType(pe.Type),
+		ContentIndex: pe.ContentIndex,
+		Delta:        pe.Delta,
+		Reason:       ai.StopReason(pe.Reason),
+	}
+	partial := acc.Message()
+	switch e.Type {
+	case ai.EventToolCallStart:
+		e.ToolCallData = &ai.ToolCall{ID: pe.ID, Name: pe.ToolName}
+	case ai.EventTextEnd, ai.EventThinkingEnd:
+		acc.SetSignature(pe.ContentIndex, pe.ContentSignature)
+	case ai.EventDone, ai.EventError:
 		if pe.Usage != nil {
 			partial.Usage = *pe.Usage
 		}
-		return &ai.AssistantMessageEvent{Type: ai.EventDone, Reason: ai.StopReason(pe.Reason), Message: partial}
-
-	case "error":
-		partial.StopReason = ai.StopReason(pe.Reason)
-		partial.ErrorMessage = pe.ErrorMessage
-		if pe.Usage != nil {
-			partial.Usage = *pe.Usage
+		if e.Type == ai.EventError {
+			partial.ErrorMessage = pe.ErrorMessage
 		}
-		return &ai.AssistantMessageEvent{Type: ai.EventError, Reason: ai.StopReason(pe.Reason), Error: partial}
 	}
-
-	return nil
+	return acc.Apply(e)
 }
 
-func ensureContentIndex(msg *ai.AssistantMessage, idx int) {
-	for len(msg.Content) <= idx {
-		msg.Content = append(msg.Content, ai.Content{})
-	}
+func emitProxyAborted(stream *ai.AssistantMessageEventStream, partial *ai.AssistantMessage, err error) {
+	partial.StopReason = ai.StopReasonAborted
+	partial.ErrorMessage = "Request was aborted"
+	stream.SetErr(err)
+	stream.Push(ai.AssistantMessageEvent{
+		Type:   ai.EventError,
+		Reason: ai.StopReasonAborted,
+		Error:  partial,
+	})
 }
 
-func emitProxyError(stream *ai.AssistantMessageEventStream, partial *ai.AssistantMessage, errMsg string) {
+func emitProxyError(stream *ai.AssistantMessageEventStream, partial *ai.AssistantMessage, err error) {
 	partial.StopReason = ai.StopReasonError
-	partial.ErrorMessage = errMsg
+	partial.ErrorMessage = err.Error()
+	var apiErr *ai.APIError
+	if errors.As(err, &apiErr) {
+		partial.ErrorMessage = apiErr.Message
+	}
+	stream.SetErr(err)
 	stream.Push(ai.AssistantMessageEvent{
 		Type:   ai.EventError,
 		Reason: ai.StopReasonError,
@@ -227,3 +208,12 @@ func emitProxyError(stream *ai.AssistantMessageEventStream, partial *ai.Assistan
 	})
 	stream.End(partial)
 }
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestStreamProxyReadsUnseparatedDataLines(t *testing.T) {
+	// One data line per event, no blank lines, no trailing newline.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `data: {"type":"start"}`+"\n"+
+			`data: {"type":"text_start","contentIndex":0}`+"\n"+
+			`data: {"type":"text_delta","contentIndex":0,"delta":"hel"}`+"\n"+
+			`data: {"type":"text_delta","contentIndex":0,"delta":"lo"}`+"\n"+
+			`data: {"type":"text_end","contentIndex":0}`+"\n"+
+			`data: {"type":"done","reason":"stop"}`)
+	}))
+	defer srv.Close()
+
+	s := StreamProxy(&ai.Model{ID: "test"}, ai.Context{}, &ProxyStreamOptions{ProxyURL: srv.URL})
+	for range s.Events() {
+	}
+	msg := s.Result()
+	if msg == nil || msg.StopReason != ai.StopReasonStop || len(msg.Content) != 1 || msg.Content[0].Text == nil || msg.Content[0].Text.Text != "hello" {
+		t.Fatalf("result = %+v", msg)
+	}
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ReplayOverrides are the parameters changed when replaying a turn; nil
+// fields keep the recorded values.
+type ReplayOverrides struct {
+	Model        *ai.Model
+	Temperature  *float64
+	SystemPrompt *string
+}
+
+// TurnReplay is the alternative outcome of a replayed turn.
+type TurnReplay struct {
+	Original *TurnTrace
+	Response *ai.AssistantMessage
+
+	// ToolResults answer the response's tool calls from results recorded
+	// in the conversation; calls without a recorded match get an error
+	// result saying the tool was not run.
+	ToolResults []ai.ToolResultMessage
+
+	// Branch is the turn's context (Original.Transformed) followed by the
+	// response and tool results, ready to be written to a session branch
+	// (see session.Store.Fork) and compared with the original.
+	Branch []AgentMessage
+}
+
+// ReplayTurn re-runs the LLM call of turn index (see ExplainTurn) on its
+// recorded context with some parameters changed. Only the LLM call is
+// repeated: tools are not executed, and the agent's own state is left
+// untouched, except that the call is charged to SessionUsage; it fails
+// once SessionBudget is used up. Cancelling ctx aborts the call. Requires
+// AgentOptions.TraceTurns.
+func (a *Agent) ReplayTurn(ctx context.Context, index int, o ReplayOverrides) (*TurnReplay, error) {
+	trace, err := a.ExplainTurn(index)
+	if err != nil {
+		return nil, err
+	}
+
+	llmCtx := trace.Context
+	if o.SystemPrompt != nil {
+		llmCtx.SystemPrompt = *o.SystemPrompt
+	}
+	model := trace.Model
+	if o.Model != nil {
+		model = o.Model
+	}
+	opts := trace.Options
+	opts.ApiKey = ""
+	if o.Temperature != nil {
+		opts.Temperature = o.Temperature
+	}
+
+	a.mu.Lock()
+	sf := a.StreamCtxFn
+	switch {
+	case sf != nil:
+	case a.StreamFn != nil:
+		sf = abortableStreamFn(a.StreamFn)
+	case a.registry != nil:
+		sf = registryStreamFn(a.registry, a.resumeDisconnects)
+	}
+	getApiKey := a.GetApiKey
+	catalog, locale := a.catalog, a.localeLocked()
+	recorded := append([]AgentMessage{}, a.state.Messages...)
+	exhausted := a.sessionBudget.exhausted(a.sessionUsage)
+	a.mu.Unlock()
+	if exhausted {
+		return nil, fmt.Errorf("replay of turn %d: session budget is used up", trace.Index)
+	}
+	if sf == nil {
+		return nil, fmt.Errorf("no stream function provided")
+	}
+	if getApiKey != nil {
+		if key, err := getApiKey(model.Provider); err == nil {
+			opts.ApiKey = key
+		}
+	}
+
+	stream := safeStreamFn(sf)(ctx, model, llmCtx, ai.FitMaxTokens(model, llmCtx, &opts))
+	for range stream.Events() {
+	}
+	response := stream.Result()
+	if response != nil {
+		a.chargeSession(response.Usage)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("replay of turn %d: %w", trace.Index, err)
+	}
+	if response == nil {
+		return nil, fmt.Errorf("replay of turn %d produced no response", trace.Index)
+	}
+
+	r := &TurnReplay{
+		Original: trace,
+		Response: response,
+		Branch:   append(append([]AgentMessage{}, trace.Transformed...), NewAgentMessageFromMessage(ai.Message{Assistant: response})),
+	}
+	for _, c := range response.Content {
+		if c.ToolCall == nil {
+			continue
+		}
+		tr := recordedToolResult(recorded, *c.ToolCall, catalog.Text(locale, MsgToolNotReplayed, c.ToolCall.Name))
+		r.ToolResults = append(r.ToolResults, tr)
+		r.Branch = append(r.Branch, NewAgentMessageFromMessage(ai.Message{ToolResult: &tr}))
+	}
+	return r, nil
+}
+
+// recordedToolResult answers tc with the result of an earlier call of the
+// same tool with equal arguments found in msgs, or with an error result
+// saying notRun.
+func recordedToolResult(msgs []AgentMessage, tc ai.ToolCall, notRun string) ai.ToolResultMessage {
+	ids := map[string]bool{}
+	for _, m := range msgs {
+		if m.Assistant == nil {
+			continue
+		}
+		for _, c := range m.Assistant.Content {
+			if c.ToolCall != nil && c.ToolCall.Name == tc.Name && reflect.DeepEqual(c.ToolCall.Arguments, tc.Arguments) {
+				ids[c.ToolCall.ID] = true
+			}
+		}
+	}
+	for _, m := range msgs {
+		if m.ToolResult != nil && ids[m.ToolResult.ToolCallID] {
+			tr := *m.ToolResult
+			tr.ToolCallID = tc.ID
+			tr.Timestamp = time.Now().UnixMilli()
+			return tr
+		}
+	}
+	return ai.ToolResultMessage{
+		Role:       ai.RoleToolResult,
+		ToolCallID: tc.ID,
+		ToolName:   tc.Name,
+		Content:    []ai.Content{ai.NewTextContent(notRun)},
+		IsError:    true,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+}
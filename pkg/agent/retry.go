@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// ToolRetryPolicy makes the loop retry a failing tool with exponential
+// backoff before the error is reported to the model.
+type ToolRetryPolicy struct {
+	MaxAttempts  int                  // total attempts including the first; <= 1 disables retries
+	InitialDelay time.Duration        // delay before the first retry (default 500ms)
+	MaxDelay     time.Duration        // backoff cap (default 10s)
+	Multiplier   float64              // backoff growth factor (default 2)
+	RetryOn      func(err error) bool // errors worth retrying; default IsRetryableToolError
+}
+
+// ErrRecoverable marks a tool error the model can fix by calling the tool
+// differently, e.g. fmt.Errorf("%w: unknown column %q", agent.ErrRecoverable, c).
+// It counts towards AgentLoopConfig.MaxToolRetries.
+var ErrRecoverable = errors.New("recoverable tool error")
+
+// ErrPermanent marks a tool error that must not be retried. Wrap it, e.g.
+// fmt.Errorf("%w: file not found", agent.ErrPermanent).
+var ErrPermanent = errors.New("permanent tool error")
+
+// IsRetryableToolError is the default RetryOn: every error is retried except
+// ErrPermanent, ErrRecoverable (which goes back to the model), context
+// cancellation, and recovered panics.
+func IsRetryableToolError(err error) bool {
+	var pe *ai.PanicError
+	return !errors.Is(err, ErrPermanent) &&
+		!errors.Is(err, ErrRecoverable) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.As(err, &pe)
+}
+
+// delay returns the backoff before retry n (1-based).
+func (p *ToolRetryPolicy) delay(n int) time.Duration {
+	d, limit, mult := p.InitialDelay, p.MaxDelay, p.Multiplier
+	if d <= 0 {
+		d = 500 * time.Millisecond
+	}
+	if limit <= 0 {
+		limit = 10 * time.Second
+	}
+	if mult < 1 {
+		mult = 2
+	}
+	for i := 1; i < n && d < limit; i++ {
+		d = time.Duration(float64(d) * mult)
+	}
+	return min(d, limit)
+}
+
+func (p *ToolRetryPolicy) retryable(err error) bool {
+	if p.RetryOn != nil {
+		return p.RetryOn(err)
+	}
+	return IsRetryableToolError(err)
+}
+
+// ToolRetriesExhaustedError ends a run after MaxToolRetries consecutive
+// turns failed with recoverable tool errors.
+type ToolRetriesExhaustedError struct {
+	Retries int
+	Last    string // the last error fed back to the model
+}
+
+func (e *ToolRetriesExhaustedError) Error() string {
+	return fmt.Sprintf("tool calls still failing after %d retries: %s", e.Retries, e.Last)
+}
+
+// retryFeedback appends a request to correct the call to a recoverable
+// tool error while retries remain.
+func (r *toolRunner) retryFeedback(result *AgentToolResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastRecoverable = resultText(*result)
+	if max := r.config.MaxToolRetries; max > 0 && r.toolRetries < max {
+		result.Content = append(result.Content, ai.NewTextContent(r.config.text(MsgToolRetry, r.toolRetries+1, max)))
+	}
+}
+
+// countRetries updates the consecutive retry count after a turn's tool
+// calls and returns an error once MaxToolRetries is exceeded.
+func (r *toolRunner) countRetries(outcomes []toolOutcome) error {
+	max := r.config.MaxToolRetries
+	if max <= 0 || len(outcomes) == 0 {
+		return nil
+	}
+	for _, o := range outcomes {
+		if !o.recoverable {
+			r.toolRetries = 0
+			return nil
+		}
+	}
+	r.toolRetries++
+	if r.toolRetries > max {
+		return &ToolRetriesExhaustedError{Retries: max, Last: r.lastRecoverable}
+	}
+	return nil
+}
+
+// resultText joins the text content of a tool result.
+func resultText(result AgentToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		if c.Text != nil {
+			parts = append(parts, c.Text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// executeToolWithRetry runs the tool, retrying per its Retry policy.
+func executeToolWithRetry(ctx context.Context, tool *AgentTool, id string, args map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+	policy := tool.Retry
+	for attempt := 1; ; attempt++ {
+		result, err := executeTool(ctx, tool, id, args, onUpdate)
+		if err == nil || policy == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return result, err
+		}
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestIsRetryableToolError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), true},
+		{fmt.Errorf("%w: file not found", ErrPermanent), false},
+		{fmt.Errorf("%w: unknown column", ErrRecoverable), false},
+		{context.Canceled, false},
+		{&ai.PanicError{}, false},
+	} {
+		if got := IsRetryableToolError(tc.err); got != tc.want {
+			t.Errorf("IsRetryableToolError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// spectatorPrompt introduces the session a spectator answers questions about.
+const spectatorPrompt = `You are reviewing a past agent session for an audit. The session's system prompt and transcript are given below. Answer the reviewer's questions about what happened in it. You cannot run tools and nothing you say is added to the session.`
+
+// NewSpectator creates a read-only agent for interrogating a past session,
+// e.g. NewSpectator(a.State(), opts). The session's system prompt and
+// messages are rendered into the spectator's system prompt, so the
+// reviewer's questions and the answers form a separate conversation that
+// is never written back to the session. Tools are always disabled. The
+// spectator uses the session's model and thinking level unless
+// opts.InitialState sets a model.
+func NewSpectator(session AgentState, opts AgentOptions) *Agent {
+	var model *ai.Model
+	if opts.InitialState != nil {
+		model = opts.InitialState.Model
+	}
+	if model == nil {
+		model = session.Model
+	}
+	opts.InitialState = nil
+	opts.OnToolApproval = nil
+
+	a := NewAgent(opts)
+	a.spectator = true
+	a.state.Model = model
+	a.state.ThinkingLevel = session.ThinkingLevel
+	a.state.SystemPrompt = spectatorSystemPrompt(session)
+	return a
+}
+
+// IsSpectator reports whether the agent was created by NewSpectator.
+func (a *Agent) IsSpectator() bool {
+	return a.spectator
+}
+
+func spectatorSystemPrompt(session AgentState) string {
+	var sb strings.Builder
+	sb.WriteString(spectatorPrompt)
+	sb.WriteString("\n\n<system_prompt>\n")
+	sb.WriteString(session.SystemPrompt)
+	sb.WriteString("\n</system_prompt>\n\n<transcript>\n")
+	sb.WriteString(RenderTranscript(session.Messages))
+	sb.WriteString("</transcript>")
+	return sb.String()
+}
+
+// RenderTranscript renders messages as plain text, one block per message,
+// with tool calls and results spelled out and images as placeholders.
+func RenderTranscript(msgs []AgentMessage) string {
+	var sb strings.Builder
+	for i, m := range msgs {
+		switch {
+		case m.User != nil:
+			fmt.Fprintf(&sb, "[%d] user:\n", i)
+			renderContent(&sb, m.User.Content)
+		case m.Assistant != nil:
+			fmt.Fprintf(&sb, "[%d] assistant (%s):\n", i, m.Assistant.Model)
+			renderContent(&sb, m.Assistant.Content)
+			if m.Assistant.ErrorMessage != "" {
+				fmt.Fprintf(&sb, "(error: %s)\n", m.Assistant.ErrorMessage)
+			}
+		case m.ToolResult != nil:
+			status := "result"
+			if m.ToolResult.IsError {
+				status = "error"
+			}
+			fmt.Fprintf(&sb, "[%d] tool %s %s (call %s):\n", i, m.ToolResult.ToolName, status, m.ToolResult.ToolCallID)
+			renderContent(&sb, m.ToolResult.Content)
+		case m.Custom != nil:
+			data, _ := json.Marshal(m.Custom)
+			fmt.Fprintf(&sb, "[%d] custom message:\n%s\n", i, data)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func renderContent(sb *strings.Builder, content []ai.Content) {
+	for _, c := range content {
+		switch {
+		case c.Text != nil:
+			sb.WriteString(c.Text.Text)
+			sb.WriteByte('\n')
+		case c.Thinking != nil:
+			fmt.Fprintf(sb, "(thinking) %s\n", c.Thinking.Thinking)
+		case c.Image != nil:
+			fmt.Fprintf(sb, "[image %s]\n", c.Image.MimeType)
+		case c.ToolCall != nil:
+			args, _ := json.Marshal(c.ToolCall.Arguments)
+			fmt.Fprintf(sb, "(tool call %s %s) %s\n", c.ToolCall.ID, c.ToolCall.Name, args)
+		}
+	}
+}
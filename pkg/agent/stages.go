@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TurnStage names a phase of a turn reported by TurnStageTimingEvent.
+type TurnStage string
+
+const (
+	TurnStageQueueWait     TurnStage = "queue_wait"     // oldest new user message waiting for its turn to start
+	TurnStageTransform     TurnStage = "transform"      // TransformContext and Pipeline
+	TurnStageConvert       TurnStage = "convert"        // ConvertToLLM and image handling
+	TurnStageTTFT          TurnStage = "ttft"           // request start to the first content delta
+	TurnStageGeneration    TurnStage = "generation"     // first content delta to the end of the response
+	TurnStageToolExecution TurnStage = "tool_execution" // one tool call, including retries
+
+	// TurnStagePipeline prefixes the name of a TransformPipeline stage,
+	// e.g. "transform:trim"; those stages are also part of "transform".
+	TurnStagePipeline TurnStage = "transform:"
+)
+
+// stageOrder is the order stages occur in a turn.
+var stageOrder = []TurnStage{TurnStageQueueWait, TurnStageTransform, TurnStageConvert, TurnStageTTFT, TurnStageGeneration, TurnStageToolExecution}
+
+// TurnStageTiming is the payload of a stage_timing event.
+type TurnStageTiming struct {
+	Stage      TurnStage `json:"stage"`
+	DurationMs float64   `json:"durationMs"`
+}
+
+func pushStage(stream *AgentEventStream, stage TurnStage, d time.Duration) {
+	stream.Push(AgentEvent{Type: TurnStageTimingEvent, TurnStageTiming: &TurnStageTiming{Stage: stage, DurationMs: ms(d)}})
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// queueWait returns how long the oldest user message after the last
+// assistant message has waited, and false when there is none.
+func queueWait(messages []AgentMessage, now time.Time) (time.Duration, bool) {
+	oldest := int64(0)
+	for i := len(messages) - 1; i >= 0 && messages[i].Assistant == nil; i-- {
+		if u := messages[i].User; u != nil && u.Timestamp > 0 && (oldest == 0 || u.Timestamp < oldest) {
+			oldest = u.Timestamp
+		}
+	}
+	if oldest == 0 {
+		return 0, false
+	}
+	return max(now.Sub(time.UnixMilli(oldest)), 0), true
+}
+
+// StageLatency summarizes the recorded durations of one stage, in
+// milliseconds.
+type StageLatency struct {
+	Stage TurnStage `json:"stage"`
+	Count int       `json:"count"` // all observations, not only the window
+	Mean  float64   `json:"meanMs"`
+	P50   float64   `json:"p50Ms"`
+	P90   float64   `json:"p90Ms"`
+	P99   float64   `json:"p99Ms"`
+	Max   float64   `json:"maxMs"`
+}
+
+// LatencyRecorder aggregates stage_timing events into latency percentiles.
+// Pass its Observe method to Agent.Subscribe, or call it from any other
+// event hook; one recorder may observe many agents.
+type LatencyRecorder struct {
+	// Window is the number of recent samples kept per stage (default 1000).
+	Window int
+
+	mu      sync.Mutex
+	samples map[TurnStage][]float64
+	counts  map[TurnStage]int
+}
+
+// Observe records e if it is a stage_timing event.
+func (r *LatencyRecorder) Observe(e AgentEvent) {
+	if e.Type != TurnStageTimingEvent || e.TurnStageTiming == nil {
+		return
+	}
+	window := r.Window
+	if window <= 0 {
+		window = 1000
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.samples == nil {
+		r.samples, r.counts = map[TurnStage][]float64{}, map[TurnStage]int{}
+	}
+	st := e.TurnStageTiming
+	s := append(r.samples[st.Stage], st.DurationMs)
+	if len(s) > window {
+		s = s[len(s)-window:]
+	}
+	r.samples[st.Stage] = s
+	r.counts[st.Stage]++
+}
+
+// Stats returns the statistics of every observed stage, known stages in
+// turn order first.
+func (r *LatencyRecorder) Stats() []StageLatency {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stages := make([]TurnStage, 0, len(r.samples))
+	for s := range r.samples {
+		stages = append(stages, s)
+	}
+	slices.SortFunc(stages, func(a, b TurnStage) int {
+		ia, ib := slices.Index(stageOrder, a), slices.Index(stageOrder, b)
+		if ia < 0 {
+			ia = len(stageOrder)
+		}
+		if ib < 0 {
+			ib = len(stageOrder)
+		}
+		if ia != ib {
+			return ia - ib
+		}
+		return strings.Compare(string(a), string(b))
+	})
+	out := make([]StageLatency, 0, len(stages))
+	for _, s := range stages {
+		sorted := slices.Sorted(slices.Values(r.samples[s]))
+		n := len(sorted)
+		sum := 0.0
+		for _, d := range sorted {
+			sum += d
+		}
+		pct := func(p int) float64 { return sorted[max((n*p+99)/100-1, 0)] }
+		out = append(out, StageLatency{
+			Stage: s, Count: r.counts[s], Mean: sum / float64(n),
+			P50: pct(50), P90: pct(90), P99: pct(99), Max: sorted[n-1],
+		})
+	}
+	return out
+}
+
+// Reset discards all recorded samples.
+func (r *LatencyRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples, r.counts = nil, nil
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SteeringToolPolicy decides what happens to an assistant message's tool
+// calls when a steering message arrives while they run.
+type SteeringToolPolicy string
+
+const (
+	// SteeringSkipRest finishes the running calls and skips the remaining
+	// ones with an error result (the default).
+	SteeringSkipRest SteeringToolPolicy = "skip-rest"
+	// SteeringFinishTools runs every call; the steering message is
+	// delivered after the last result.
+	SteeringFinishTools SteeringToolPolicy = "finish-all-tools"
+	// SteeringAbortCurrent also cancels the running calls, whose context
+	// is cancelled as soon as steering arrives, and skips the rest.
+	SteeringAbortCurrent SteeringToolPolicy = "abort-current-too"
+)
+
+// steeringPollInterval is how often SteeringAbortCurrent polls for
+// steering while tool calls run.
+const steeringPollInterval = 50 * time.Millisecond
+
+// errSteeringInterrupt is the cancellation cause of tool calls interrupted
+// under SteeringAbortCurrent.
+var errSteeringInterrupt = errors.New("interrupted by a queued user message")
+
+// watchSteering polls get while a batch of tool calls runs and cancels the
+// returned context, with errSteeringInterrupt as cause, once it yields
+// messages. stop ends the watch and returns those messages, if any.
+func watchSteering(ctx context.Context, get func() ([]AgentMessage, error)) (context.Context, func() []AgentMessage) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	quit := make(chan struct{})
+	found := make(chan []AgentMessage, 1)
+	go func() {
+		t := time.NewTicker(steeringPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-quit:
+				found <- nil
+				return
+			case <-ctx.Done():
+				found <- nil
+				return
+			case <-t.C:
+				if msgs, err := get(); err == nil && len(msgs) > 0 {
+					cancel(errSteeringInterrupt)
+					found <- msgs
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() []AgentMessage {
+		close(quit)
+		msgs := <-found
+		cancel(nil)
+		return msgs
+	}
+}
+
+// steeringInterrupted reports whether ctx was cancelled by watchSteering.
+func steeringInterrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errSteeringInterrupt)
+}
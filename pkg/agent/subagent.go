@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// SubAgentOptions configures NewSubAgentTool.
+type SubAgentOptions struct {
+	Name        string // tool name (default "task")
+	Description string // tool description; has a default
+	Label       string // nesting label on forwarded events (default Name)
+
+	// SystemPrompt is the child's system prompt; "" gives a short
+	// default asking for a self-contained final answer.
+	SystemPrompt string
+
+	// Model runs the child; nil uses the parent's current model.
+	Model *ai.Model
+
+	// Tools names the parent tools the child may use; nil allows all of
+	// them. The task tool itself is never passed on.
+	Tools []string
+
+	// MaxTurns caps the child's LLM calls per task; 0 is unlimited.
+	MaxTurns int
+
+	// MaxCost caps the child's spend per task, in USD as computed from
+	// the model's pricing; 0 is unlimited. The child never spends more
+	// than the parent's Budget and SessionBudget have left.
+	MaxCost float64
+}
+
+// SubAgentArgs are the arguments of the task tool.
+type SubAgentArgs struct {
+	Task string `json:"task" jsonschema:"description=The task to delegate. Include all context the sub-agent needs; it does not see this conversation."`
+}
+
+// SubAgentResult is the Details of a task tool result.
+type SubAgentResult struct {
+	Turns    int            `json:"turns"`
+	Usage    ai.Usage       `json:"usage"`
+	Stopped  RunLimit       `json:"stopped,omitempty"` // the budget limit that stopped the child, if any
+	Messages []AgentMessage `json:"messages"`          // the child's conversation
+}
+
+// NestedEvent is an event of a sub-agent, forwarded to the parent's
+// subscribers as a SubAgentEvent. Events of deeper sub-agents arrive
+// wrapped once per level.
+type NestedEvent struct {
+	Label string     `json:"label"`
+	Event AgentEvent `json:"event"`
+}
+
+const defaultSubAgentPrompt = `You are a sub-agent working on one task delegated by another agent. Complete the task using the tools available, then reply with a self-contained final answer: it is all the delegating agent will see.`
+
+// NewSubAgentTool returns a tool that delegates a task to a child Agent.
+// Each call starts a fresh child with its own system prompt, a subset of
+// the parent's tools and a turn and cost budget; it inherits the parent's
+// stream function, registry, API keys, tool approval, interceptors,
+// timeouts and locale. The child's final answer becomes the tool result,
+// and its events are forwarded to the parent's subscribers as
+// SubAgentEvent with the tool call ID and a nesting label. The child's
+// spend is charged to the parent's run (see ChargeUsage).
+func NewSubAgentTool(parent *Agent, opts SubAgentOptions) AgentTool {
+	if opts.Name == "" {
+		opts.Name = "task"
+	}
+	if opts.Label == "" {
+		opts.Label = opts.Name
+	}
+	if opts.Description == "" {
+		opts.Description = "Delegate a self-contained task to a sub-agent, which works on it with its own context and returns its final answer."
+	}
+	if opts.SystemPrompt == "" {
+		opts.SystemPrompt = defaultSubAgentPrompt
+	}
+	return AgentTool{
+		Tool: ai.Tool{
+			Name:        opts.Name,
+			Description: opts.Description,
+			Parameters:  ai.SchemaFor[SubAgentArgs](),
+		},
+		Label: opts.Label,
+		Execute: func(ctx context.Context, toolCallID string, params map[string]any, _ AgentToolUpdateCallback) (AgentToolResult, error) {
+			task, _ := params["task"].(string)
+			if strings.TrimSpace(task) == "" {
+				return AgentToolResult{}, fmt.Errorf("%w: task must not be empty", ErrRecoverable)
+			}
+			return runSubAgent(ctx, parent, opts, toolCallID, task)
+		},
+	}
+}
+
+// subAgentBudget stops a child before an LLM call that would exceed its
+// turn or cost limit. It sees the child's conversation in every call, so
+// no accounting across goroutines is needed.
+type subAgentBudget struct {
+	maxTurns int
+	maxCost  float64 // +Inf is unlimited
+	stopped  RunLimit // set by the child's loop goroutine, read after the run
+}
+
+func (b *subAgentBudget) wrap(sf StreamCtxFn) StreamCtxFn {
+	return func(ctx context.Context, model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		turns := 0
+		var usage ai.Usage
+		for _, m := range llmCtx.Messages {
+			if m.Assistant != nil {
+				turns++
+				usage = ai.AddUsage(usage, m.Assistant.Usage)
+			}
+		}
+		switch {
+		case b.maxTurns > 0 && turns >= b.maxTurns:
+			b.stopped = LimitTurns
+		case usage.Cost.Total >= b.maxCost:
+			b.stopped = LimitCost
+		default:
+			return sf(ctx, model, llmCtx, opts)
+		}
+		s := ai.NewAssistantMessageEventStream()
+		msg := makeErrorAssistantMessage(model, "sub-agent stopped at "+string(b.stopped))
+		msg.StopReason = ai.StopReasonAborted
+		s.Push(ai.AssistantMessageEvent{Type: ai.EventError, Reason: ai.StopReasonAborted, Error: msg})
+		return s
+	}
+}
+
+// newSubAgent builds the child for one task.
+func newSubAgent(parent *Agent, opts SubAgentOptions, budget *subAgentBudget) (*Agent, error) {
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+	model := opts.Model
+	if model == nil {
+		model = parent.state.Model
+	}
+	if model == nil {
+		return nil, fmt.Errorf("sub-agent has no model")
+	}
+	sf := parent.StreamCtxFn
+	switch {
+	case sf != nil:
+	case parent.StreamFn != nil:
+		sf = abortableStreamFn(parent.StreamFn)
+	default:
+		sf = registryStreamFn(ai.RegistryOrDefault(parent.registry), parent.resumeDisconnects)
+	}
+	var tools []AgentTool
+	for _, t := range parent.runToolsLocked() {
+		if t.Name != opts.Name && (opts.Tools == nil || slices.Contains(opts.Tools, t.Name)) {
+			tools = append(tools, t)
+		}
+	}
+	return NewAgent(AgentOptions{
+		InitialState: &AgentState{
+			SystemPrompt:  opts.SystemPrompt,
+			Model:         model,
+			ThinkingLevel: parent.state.ThinkingLevel,
+			Tools:         tools,
+		},
+		StreamCtxFn:        budget.wrap(sf),
+		GetApiKey:          parent.GetApiKey,
+		Registry:           parent.registry,
+		ThinkingBudgets:    parent.thinkingBudgets,
+		MaxRetryDelayMs:    parent.maxRetryDelayMs,
+		OnToolApproval:     parent.onToolApproval,
+		DefaultToolTimeout: parent.defaultToolTimeout,
+		ToolInterceptors:   parent.toolInterceptors,
+		ImageModeration:    parent.imageModeration,
+		Locale:             parent.localeLocked(),
+		Catalog:            parent.catalog,
+		Priority:           parent.priority,
+	}), nil
+}
+
+// runSubAgent runs one task to completion.
+func runSubAgent(ctx context.Context, parent *Agent, opts SubAgentOptions, toolCallID, task string) (AgentToolResult, error) {
+	// The child's spend is charged to the parent's run, so it may use no
+	// more than the run's budgets have left.
+	budget := &subAgentBudget{maxTurns: opts.MaxTurns, maxCost: remainingCost(ctx)}
+	if opts.MaxCost > 0 {
+		budget.maxCost = min(budget.maxCost, opts.MaxCost)
+	}
+	child, err := newSubAgent(parent, opts, budget)
+	if err != nil {
+		return AgentToolResult{}, err
+	}
+	defer child.Close()
+	// Forward through the parent's run, after its tool_execution_start for
+	// this call; outside a run, straight to the parent's listeners.
+	forward := func(e AgentEvent) { parent.publish(e, false) }
+	if stream, ok := ctx.Value(streamKey{}).(*AgentEventStream); ok {
+		forward = stream.Push
+	}
+	child.Subscribe(func(e AgentEvent) {
+		forward(AgentEvent{Type: SubAgentEvent, ToolCallID: toolCallID, ToolName: opts.Name, Nested: &NestedEvent{Label: opts.Label, Event: e}})
+	})
+	parent.mu.Lock()
+	catalog, locale := parent.catalog, parent.localeLocked()
+	parent.mu.Unlock()
+
+	if err := child.Prompt(task); err != nil {
+		return AgentToolResult{}, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			child.Abort()
+		case <-done:
+		}
+	}()
+	child.WaitForIdle()
+	close(done)
+
+	st := child.State()
+	details := SubAgentResult{Usage: child.SessionUsage(), Stopped: budget.stopped, Messages: st.Messages}
+	ChargeUsage(ctx, details.Usage)
+	for _, m := range st.Messages {
+		if m.Assistant != nil && m.Assistant.StopReason != ai.StopReasonAborted {
+			details.Turns++
+		}
+	}
+	answer := lastAssistantText(st.Messages)
+	switch {
+	case ctx.Err() != nil:
+		return AgentToolResult{}, ctx.Err()
+	case budget.stopped != "":
+		text := catalog.Text(locale, MsgSubAgentTurns, opts.MaxTurns)
+		if budget.stopped == LimitCost {
+			text = catalog.Text(locale, MsgSubAgentCost, max(budget.maxCost, 0))
+		}
+		if answer != "" {
+			text += " " + catalog.Text(locale, MsgSubAgentLast) + "\n\n" + answer
+		}
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent(text)}, Details: details}, nil
+	case st.Error != "":
+		return AgentToolResult{}, fmt.Errorf("sub-agent failed: %s", st.Error)
+	}
+	return AgentToolResult{Content: []ai.Content{ai.NewTextContent(answer)}, Details: details}, nil
+}
+
+// streamKey carries a run's event stream in the ctx of its tool calls.
+type streamKey struct{}
+
+// lastAssistantText returns the text of the last assistant message that
+// has any.
+func lastAssistantText(msgs []AgentMessage) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		m := msgs[i].Assistant
+		if m == nil {
+			continue
+		}
+		var sb strings.Builder
+		for _, c := range m.Content {
+			if c.Text != nil {
+				sb.WriteString(c.Text.Text)
+			}
+		}
+		if sb.Len() > 0 {
+			return sb.String()
+		}
+	}
+	return ""
+}
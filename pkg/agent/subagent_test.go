@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestSubAgentIsCappedByParentBudget(t *testing.T) {
+	var mu sync.Mutex
+	var childTools [][]string
+	parentTurns := 0
+	stream := func(model *ai.Model, c ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+		mu.Lock()
+		defer mu.Unlock()
+		msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonToolUse}
+		if c.SystemPrompt == defaultSubAgentPrompt {
+			var names []string
+			for _, tool := range c.Tools {
+				names = append(names, tool.Name)
+			}
+			childTools = append(childTools, names)
+			// The child never finishes on its own; each turn costs 0.25.
+			msg.Usage.Cost.Total = 0.25
+			msg.Content = []ai.Content{ai.NewToolCallContent(fmt.Sprintf("noop_%d", len(childTools)), "noop", map[string]any{})}
+		} else if parentTurns++; parentTurns == 1 {
+			msg.Usage.Cost.Total = 0.4
+			msg.Content = []ai.Content{ai.NewToolCallContent("task_1", "task", map[string]any{"task": "loop"})}
+		} else {
+			msg.StopReason = ai.StopReasonStop
+			msg.Content = []ai.Content{ai.NewTextContent("done")}
+		}
+		out := ai.NewAssistantMessageEventStream()
+		go out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+		return out
+	}
+
+	type noArgs struct{}
+	noop := NewTool("noop", "Does nothing.", func(context.Context, noArgs) (AgentToolResult, error) {
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent("ok")}}, nil
+	})
+	a := NewAgent(AgentOptions{StreamFn: stream, Budget: &Budget{MaxCost: 1}})
+	a.SetModel(&ai.Model{ID: "test"})
+	// Listing the task tool itself must not hand it to the child.
+	a.SetTools([]AgentTool{noop, NewSubAgentTool(a, SubAgentOptions{Tools: []string{"task", "noop"}, MaxTurns: 10})})
+	if err := a.Prompt("go"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+
+	var details SubAgentResult
+	for _, m := range a.State().Messages {
+		if m.ToolResult != nil && m.ToolResult.ToolName == "task" {
+			details, _ = m.ToolResult.Details.(SubAgentResult)
+		}
+	}
+	// 0.4 spent by the parent leaves 0.6: the child stops after its third
+	// turn instead of running all ten.
+	if details.Stopped != LimitCost || details.Turns != 3 {
+		t.Errorf("child stopped at %q after %d turns, want max_cost after 3", details.Stopped, details.Turns)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, names := range childTools {
+		if slices.Contains(names, "task") {
+			t.Fatalf("child was offered tools %v, including the task tool", names)
+		}
+	}
+}
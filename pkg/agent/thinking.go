@@ -0,0 +1,15 @@
+package agent
+
+import "github.com/badlogic/pi-go/pkg/ai"
+
+// ThinkingSummaries returns a MessagePostProcessor that adds display
+// summaries to thinking blocks of at least minChars. A nil summarize uses
+// ai.ExtractiveSummarizer(0).
+func ThinkingSummaries(summarize ai.ThinkingSummarizer, minChars int) MessagePostProcessor {
+	if summarize == nil {
+		summarize = ai.ExtractiveSummarizer(0)
+	}
+	return func(msg *ai.AssistantMessage) {
+		ai.SummarizeThinking(msg, summarize, minChars)
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ToolTimeoutError is returned when a tool call exceeds its timeout.
+type ToolTimeoutError struct {
+	ToolName string
+	Timeout  time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s and was cancelled", e.ToolName, e.Timeout)
+}
+
+// toolTimeout returns the timeout for tool: its own Timeout if set,
+// otherwise def. Zero or negative means no timeout.
+func toolTimeout(tool *AgentTool, def time.Duration) time.Duration {
+	if tool.Timeout != 0 {
+		return tool.Timeout
+	}
+	return def
+}
+
+// executeToolWithTimeout runs the tool (with retries) under timeout. When
+// it expires the tool's ctx is cancelled and a *ToolTimeoutError returned
+// at once, even if the tool ignores cancellation; its late result and
+// updates are discarded.
+func executeToolWithTimeout(ctx context.Context, tool *AgentTool, timeout time.Duration, id string, args map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+	if timeout <= 0 {
+		return executeToolWithRetry(ctx, tool, id, args, onUpdate)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	expired := false
+	guarded := func(partial AgentToolResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !expired {
+			onUpdate(partial)
+		}
+	}
+
+	type outcome struct {
+		result AgentToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := executeToolWithRetry(ctx, tool, id, args, guarded)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		if ctx.Err() == context.DeadlineExceeded {
+			return AgentToolResult{}, &ToolTimeoutError{ToolName: tool.Name, Timeout: timeout}
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		mu.Lock()
+		expired = true
+		mu.Unlock()
+		if ctx.Err() == context.DeadlineExceeded {
+			return AgentToolResult{}, &ToolTimeoutError{ToolName: tool.Name, Timeout: timeout}
+		}
+		return AgentToolResult{}, ctx.Err()
+	}
+}
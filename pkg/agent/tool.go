@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// NewTool builds an AgentTool from a typed handler. The parameters schema
+// is generated from TArgs, which must be a struct, by ai.SchemaFor.
+// Validated arguments are decoded into a TArgs before fn is called.
+func NewTool[TArgs any](name, description string, fn func(ctx context.Context, args TArgs) (AgentToolResult, error)) AgentTool {
+	return NewStreamingTool(name, description, func(ctx context.Context, args TArgs, _ AgentToolUpdateCallback) (AgentToolResult, error) {
+		return fn(ctx, args)
+	})
+}
+
+// NewStructuredTool creates a tool whose handler returns a typed output.
+// The output is sent to the model as JSON text and kept in Details, and its
+// JSON schema becomes the tool's OutputSchema.
+func NewStructuredTool[TArgs, TOut any](name, description string, fn func(ctx context.Context, args TArgs) (TOut, error)) AgentTool {
+	tool := NewTool(name, description, func(ctx context.Context, args TArgs) (AgentToolResult, error) {
+		out, err := fn(ctx, args)
+		if err != nil {
+			return AgentToolResult{}, err
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return AgentToolResult{}, fmt.Errorf("encode output of tool %q: %w", name, err)
+		}
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent(string(data))}, Details: out}, nil
+	})
+	tool.OutputSchema = ai.SchemaFor[TOut]()
+	return tool
+}
+
+// NewStreamingTool is NewTool for handlers that report progress through
+// onUpdate.
+func NewStreamingTool[TArgs any](name, description string, fn func(ctx context.Context, args TArgs, onUpdate AgentToolUpdateCallback) (AgentToolResult, error)) AgentTool {
+	return AgentTool{
+		Tool: ai.Tool{
+			Name:        name,
+			Description: description,
+			Parameters:  ai.SchemaFor[TArgs](),
+		},
+		Label: name,
+		Execute: func(ctx context.Context, _ string, params map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+			var args TArgs
+			data, err := json.Marshal(params)
+			if err == nil {
+				err = json.Unmarshal(data, &args)
+			}
+			if err != nil {
+				return AgentToolResult{}, fmt.Errorf("invalid arguments for tool %q: %w", name, err)
+			}
+			if onUpdate == nil {
+				onUpdate = func(AgentToolResult) {}
+			}
+			return fn(ctx, args, onUpdate)
+		},
+	}
+}
+
+// ToolExecuteFunc is the signature of AgentTool.Execute.
+type ToolExecuteFunc func(ctx context.Context, toolCallID string, params map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error)
+
+// ToolInterceptor wraps a tool's Execute, like HTTP middleware: it may
+// inspect or rewrite the arguments, call next (or not), and inspect or
+// replace the result. tool describes the tool being wrapped. params is
+// shared with the assistant message's tool call, so rewrite a copy.
+type ToolInterceptor func(tool AgentTool, next ToolExecuteFunc) ToolExecuteFunc
+
+// WrapTool returns a copy of tool whose Execute runs through interceptors.
+// The first interceptor is the outermost.
+func WrapTool(tool AgentTool, interceptors ...ToolInterceptor) AgentTool {
+	if tool.Execute == nil {
+		return tool
+	}
+	next := ToolExecuteFunc(tool.Execute)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next = interceptors[i](tool, next)
+	}
+	tool.Execute = next
+	return tool
+}
@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Defaults for ToolCache.
+const (
+	DefaultToolCacheTTL  = 5 * time.Minute
+	DefaultToolCacheSize = 256
+)
+
+// ToolCache remembers successful results of Cacheable tools, keyed by tool
+// name and canonicalized arguments, so repeated calls within a session
+// return the earlier result instead of running the tool again. A hit is
+// announced with ToolCacheHitEvent and still runs through the loop's
+// ToolInterceptors. Results are copied in and out, so callers may modify
+// what they get. It is safe for concurrent use.
+type ToolCache struct {
+	// TTL is how long a result stays valid. Default DefaultToolCacheTTL.
+	TTL time.Duration
+	// MaxEntries bounds the cache; when full, expired entries and then the
+	// oldest are evicted. Default DefaultToolCacheSize.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]toolCacheEntry
+}
+
+type toolCacheEntry struct {
+	result  AgentToolResult
+	stored  time.Time
+	expires time.Time
+}
+
+// NewToolCache creates a cache whose entries expire after ttl.
+func NewToolCache(ttl time.Duration) *ToolCache {
+	return &ToolCache{TTL: ttl}
+}
+
+// toolCacheKey returns the cache key of a call. encoding/json sorts map
+// keys, so equal arguments always encode identically.
+func toolCacheKey(name string, args map[string]any) (string, bool) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return name + "\x00" + string(data), true
+}
+
+// Get returns the cached result of calling name with args.
+func (c *ToolCache) Get(name string, args map[string]any) (AgentToolResult, bool) {
+	key, ok := toolCacheKey(name, args)
+	if !ok {
+		return AgentToolResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return AgentToolResult{}, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return AgentToolResult{}, false
+	}
+	return cloneToolResult(e.result), true
+}
+
+// Put stores the result of calling name with args.
+func (c *ToolCache) Put(name string, args map[string]any, result AgentToolResult) {
+	key, ok := toolCacheKey(name, args)
+	if !ok {
+		return
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultToolCacheTTL
+	}
+	now := time.Now()
+	e := toolCacheEntry{result: cloneToolResult(result), stored: now, expires: now.Add(ttl)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]toolCacheEntry{}
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.makeRoomLocked(now)
+	}
+	c.entries[key] = e
+}
+
+// makeRoomLocked evicts entries until one more fits: expired entries
+// first, then the oldest.
+func (c *ToolCache) makeRoomLocked(now time.Time) {
+	limit := c.MaxEntries
+	if limit <= 0 {
+		limit = DefaultToolCacheSize
+	}
+	if len(c.entries) < limit {
+		return
+	}
+	for key, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+	for len(c.entries) >= limit {
+		var oldest string
+		var at time.Time
+		for key, e := range c.entries {
+			if oldest == "" || e.stored.Before(at) {
+				oldest, at = key, e.stored
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// Invalidate drops all cached results of one tool, e.g. after a write
+// that makes them stale.
+func (c *ToolCache) Invalidate(name string) {
+	prefix := name + "\x00"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Clear drops every cached result.
+func (c *ToolCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// cachingTool returns tool with an Execute that serves and stores results
+// through the loop's cache, if tool is Cacheable and the loop has one. It
+// is the innermost layer, so ToolInterceptors see cache hits like any
+// other execution.
+func (r *toolRunner) cachingTool(tool *AgentTool, tc ai.ToolCall) *AgentTool {
+	cache := r.config.ToolCache
+	if cache == nil || !tool.Cacheable || tool.Execute == nil {
+		return tool
+	}
+	execute := tool.Execute
+	wrapped := *tool
+	wrapped.Execute = func(ctx context.Context, id string, params map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+		if cached, ok := cache.Get(tool.Name, params); ok {
+			r.stream.Push(AgentEvent{Type: ToolCacheHitEvent, ToolCallID: tc.ID, ToolName: tc.Name, Args: tc.Arguments})
+			return cached, nil
+		}
+		result, err := execute(ctx, id, params, onUpdate)
+		if err == nil {
+			cache.Put(tool.Name, params, result)
+		}
+		return result, err
+	}
+	return &wrapped
+}
+
+// cloneToolResult deep-copies result so that the cache and its callers
+// never share content blocks or details.
+func cloneToolResult(result AgentToolResult) AgentToolResult {
+	if result.Content != nil {
+		content := make([]ai.Content, len(result.Content))
+		for i, c := range result.Content {
+			if c.Text != nil {
+				t := *c.Text
+				c.Text = &t
+			}
+			if c.Thinking != nil {
+				t := *c.Thinking
+				c.Thinking = &t
+			}
+			if c.Image != nil {
+				img := *c.Image
+				c.Image = &img
+			}
+			if c.ToolCall != nil {
+				call := *c.ToolCall
+				call.Arguments = deepCopy(reflect.ValueOf(call.Arguments)).Interface().(map[string]any)
+				c.ToolCall = &call
+			}
+			content[i] = c
+		}
+		result.Content = content
+	}
+	if result.Details != nil {
+		result.Details = deepCopy(reflect.ValueOf(result.Details)).Interface()
+	}
+	return result
+}
+
+// deepCopy copies maps, slices, pointers and the exported fields of
+// structs reachable from v. Other values, including unexported fields,
+// are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(deepCopy(v.Elem()))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			out.SetMapIndex(it.Key(), deepCopy(it.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			out.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := range v.NumField() {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestToolCacheHitsRunThroughInterceptors(t *testing.T) {
+	type noArgs struct{}
+	executed, intercepted := 0, 0
+	tool := NewTool("read", "Read a page.", func(ctx context.Context, _ noArgs) (AgentToolResult, error) {
+		executed++
+		return AgentToolResult{Content: []ai.Content{ai.NewTextContent("page")}}, nil
+	})
+	tool.Cacheable = true
+	s := &toolTurnsStream{turns: 3}
+	a := NewAgent(AgentOptions{
+		StreamFn:  s.stream,
+		ToolCache: NewToolCache(time.Minute),
+		ToolInterceptors: []ToolInterceptor{func(tool AgentTool, next ToolExecuteFunc) ToolExecuteFunc {
+			return func(ctx context.Context, id string, params map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error) {
+				intercepted++
+				return next(ctx, id, params, onUpdate)
+			}
+		}},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	a.SetTools([]AgentTool{tool})
+	hits := 0
+	a.Subscribe(func(e AgentEvent) {
+		if e.Type == ToolCacheHitEvent {
+			hits++
+		}
+	})
+
+	if err := a.Prompt("read"); err != nil {
+		t.Fatal(err)
+	}
+	a.WaitForIdle()
+	if executed != 1 || intercepted != 3 || hits != 2 {
+		t.Errorf("executed %d, intercepted %d, hits %d; want 1, 3, 2", executed, intercepted, hits)
+	}
+}
+
+func TestToolCacheCopiesAndEvicts(t *testing.T) {
+	c := &ToolCache{MaxEntries: 2}
+	details := map[string]any{"n": 1}
+	c.Put("a", nil, AgentToolResult{Content: []ai.Content{ai.NewTextContent("one")}, Details: details})
+	details["n"] = 2
+
+	got, ok := c.Get("a", nil)
+	if !ok {
+		t.Fatal("miss")
+	}
+	got.Content[0].Text.Text = "changed"
+	got.Details.(map[string]any)["n"] = 3
+	again, _ := c.Get("a", nil)
+	if again.Content[0].Text.Text != "one" || again.Details.(map[string]any)["n"] != 1 {
+		t.Errorf("cached result was modified through a copy: %+v", again)
+	}
+
+	c.Put("b", nil, AgentToolResult{})
+	c.Put("c", nil, AgentToolResult{})
+	if _, ok := c.Get("a", nil); ok {
+		t.Error("oldest entry was not evicted")
+	}
+	if _, ok := c.Get("c", nil); !ok {
+		t.Error("newest entry is missing")
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import "fmt"
+
+// Toolset groups related tools (an MCP server, built-ins, app tools) so
+// they can be added, removed, and toggled together.
+type Toolset struct {
+	Name     string
+	Prefix   string // prepended to every tool name, e.g. "git_"
+	Tools    []AgentTool
+	Disabled bool
+}
+
+// AgentTools returns the toolset's tools with Prefix applied.
+func (ts *Toolset) AgentTools() []AgentTool {
+	out := make([]AgentTool, len(ts.Tools))
+	for i, t := range ts.Tools {
+		t.Name = ts.Prefix + t.Name
+		out[i] = t
+	}
+	return out
+}
+
+// AddToolset adds ts to the agent. It fails if a toolset with the same name
+// exists or if a prefixed tool name collides with another tool.
+func (a *Agent) AddToolset(ts Toolset) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if ts.Name == "" {
+		return fmt.Errorf("toolset needs a name")
+	}
+	names := map[string]string{}
+	for _, t := range a.state.Tools {
+		names[t.Name] = "agent tools"
+	}
+	for _, other := range a.toolsets {
+		if other.Name == ts.Name {
+			return fmt.Errorf("toolset %q already added", ts.Name)
+		}
+		for _, t := range other.AgentTools() {
+			names[t.Name] = "toolset " + other.Name
+		}
+	}
+	for _, t := range ts.AgentTools() {
+		if owner, ok := names[t.Name]; ok {
+			return fmt.Errorf("toolset %q: tool %q collides with %s", ts.Name, t.Name, owner)
+		}
+		names[t.Name] = ts.Name
+	}
+	ts.Tools = append([]AgentTool{}, ts.Tools...)
+	a.toolsets = append(a.toolsets, &ts)
+	return nil
+}
+
+// RemoveToolset removes the named toolset, reporting whether it existed.
+func (a *Agent) RemoveToolset(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, ts := range a.toolsets {
+		if ts.Name == name {
+			a.toolsets = append(a.toolsets[:i], a.toolsets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// EnableToolset enables or disables the named toolset from the next run on.
+func (a *Agent) EnableToolset(name string, enabled bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ts := range a.toolsets {
+		if ts.Name == name {
+			ts.Disabled = !enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("toolset %q not found", name)
+}
+
+// Toolsets returns copies of the agent's toolsets in the order added.
+func (a *Agent) Toolsets() []Toolset {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Toolset, len(a.toolsets))
+	for i, ts := range a.toolsets {
+		out[i] = *ts
+		out[i].Tools = append([]AgentTool{}, ts.Tools...)
+	}
+	return out
+}
+
+// runToolsLocked returns the tools offered in a run: the agent's own tools
+// followed by those of every enabled toolset. On a name collision (possible
+// after SetTools) the earlier tool wins. Spectators are offered none.
+func (a *Agent) runToolsLocked() []AgentTool {
+	if a.spectator {
+		return nil
+	}
+	if len(a.toolsets) == 0 {
+		return a.state.Tools
+	}
+	tools := append([]AgentTool{}, a.state.Tools...)
+	seen := map[string]bool{}
+	for _, t := range tools {
+		seen[t.Name] = true
+	}
+	for _, ts := range a.toolsets {
+		if ts.Disabled {
+			continue
+		}
+		for _, t := range ts.AgentTools() {
+			if !seen[t.Name] {
+				seen[t.Name] = true
+				tools = append(tools, t)
+			}
+		}
+	}
+	return tools
+}
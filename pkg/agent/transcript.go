@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ObjectStore writes immutable objects. Package pkg/objstore implements it
+// for S3 and S3-compatible stores, including GCS; chunked objects avoid
+// the need for append support.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, data []byte) error
+}
+
+// TranscriptSinkOptions configures a TranscriptSink.
+type TranscriptSinkOptions struct {
+	Store          ObjectStore
+	Prefix         string        // object key prefix, e.g. "transcripts/<session>/"
+	ChunkBytes     int           // flush once this many bytes are buffered (default 64 KiB)
+	MaxBufferBytes int           // drop events beyond this many unflushed bytes (default 64 chunks)
+	FlushInterval  time.Duration // flush buffered events at least this often (default 2s)
+	IncludeUpdates bool          // also record message_update / tool_execution_update events
+	OnError        func(error)
+}
+
+// transcriptEntry is one JSONL line of a transcript chunk.
+type transcriptEntry struct {
+	Timestamp int64      `json:"timestamp"` // Unix ms
+	Event     AgentEvent `json:"event"`
+}
+
+// TranscriptSink appends agent events to object storage as a sequence of
+// numbered JSONL chunks (<prefix>000001.jsonl, ...) in near-real-time.
+// Register it with agent.Subscribe(sink.Handle).
+type TranscriptSink struct {
+	opts TranscriptSinkOptions
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	next    int
+	dropped int
+
+	flushMu  sync.Mutex
+	kick     chan struct{} // asks the flusher for an early flush
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTranscriptSink creates a sink and starts its periodic flusher.
+func NewTranscriptSink(opts TranscriptSinkOptions) *TranscriptSink {
+	if opts.ChunkBytes <= 0 {
+		opts.ChunkBytes = 64 * 1024
+	}
+	if opts.MaxBufferBytes <= 0 {
+		opts.MaxBufferBytes = 64 * opts.ChunkBytes
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+	s := &TranscriptSink{
+		opts: opts,
+		next: 1,
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// Handle records an agent event. While the store keeps failing, events
+// that would grow the buffer past MaxBufferBytes are dropped; OnError hears
+// when dropping starts and, once a flush succeeds again, how many events
+// were lost.
+func (s *TranscriptSink) Handle(e AgentEvent) {
+	if !s.opts.IncludeUpdates && (e.Type == MessageEventUpdate || e.Type == ToolExecutionEventUpdate) {
+		return
+	}
+	line, err := json.Marshal(transcriptEntry{Timestamp: time.Now().UnixMilli(), Event: e})
+	if err != nil {
+		s.reportError(fmt.Errorf("transcript: marshal event: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	if s.buf.Len()+len(line)+1 > s.opts.MaxBufferBytes {
+		s.dropped++
+		first := s.dropped == 1
+		s.mu.Unlock()
+		if first {
+			s.reportError(fmt.Errorf("transcript: buffer full (%d bytes), dropping events until a flush succeeds", s.opts.MaxBufferBytes))
+		}
+		return
+	}
+	s.buf.Write(line)
+	s.buf.WriteByte('\n')
+	full := s.buf.Len() >= s.opts.ChunkBytes
+	s.mu.Unlock()
+
+	if full || e.Type == AgentEventEnd {
+		select {
+		case s.kick <- struct{}{}:
+		default: // a flush is already pending
+		}
+	}
+}
+
+
+// Flush writes any buffered events as a new chunk object. Events stay
+// buffered until the put succeeds, so a failed flush is retried with the
+// same chunk number by the next one.
+func (s *TranscriptSink) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	if s.buf.Len() == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	data := append([]byte(nil), s.buf.Bytes()...)
+	key := fmt.Sprintf("%s%06d.jsonl", s.opts.Prefix, s.next)
+	s.mu.Unlock()
+
+	if err := s.opts.Store.PutObject(ctx, key, data); err != nil {
+		err = fmt.Errorf("transcript: put %s: %w", key, err)
+		s.reportError(err)
+		return err
+	}
+	s.mu.Lock()
+	s.buf.Next(len(data)) // events that arrived during the put stay
+	s.next++
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+	if dropped > 0 {
+		s.reportError(fmt.Errorf("transcript: %d events were dropped before %s", dropped, key))
+	}
+	return nil
+}
+
+// Close stops the periodic flusher and writes remaining events. Calling
+// it again only retries the final flush.
+func (s *TranscriptSink) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.Flush(context.Background())
+}
+
+func (s *TranscriptSink) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_ = s.Flush(context.Background())
+		case <-s.kick:
+			_ = s.Flush(context.Background())
+		}
+	}
+}
+
+func (s *TranscriptSink) reportError(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryObjects is an ObjectStore that can be made to fail.
+type memoryObjects struct {
+	mu      sync.Mutex
+	objects map[string]string
+	failing bool
+}
+
+func (m *memoryObjects) PutObject(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return errors.New("unavailable")
+	}
+	if m.objects == nil {
+		m.objects = map[string]string{}
+	}
+	m.objects[key] = string(data)
+	return nil
+}
+
+func (m *memoryObjects) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.objects)
+}
+
+func TestTranscriptSinkFlushesOnAgentEnd(t *testing.T) {
+	store := &memoryObjects{}
+	sink := NewTranscriptSink(TranscriptSinkOptions{Store: store, Prefix: "t/", FlushInterval: time.Hour})
+	defer sink.Close()
+
+	sink.Handle(AgentEvent{Type: AgentEventStart})
+	sink.Handle(AgentEvent{Type: AgentEventEnd})
+	deadline := time.Now().Add(5 * time.Second)
+	for store.count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("agent_end did not trigger a flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := store.objects["t/000001.jsonl"]; strings.Count(got, "\n") != 2 {
+		t.Errorf("chunk = %q, want both events", got)
+	}
+}
+
+func TestTranscriptSinkCapsBufferWhileStoreFails(t *testing.T) {
+	store := &memoryObjects{failing: true}
+	var mu sync.Mutex
+	var reports []string
+	sink := NewTranscriptSink(TranscriptSinkOptions{
+		Store:          store,
+		MaxBufferBytes: 512,
+		FlushInterval:  time.Hour,
+		OnError: func(err error) {
+			mu.Lock()
+			reports = append(reports, err.Error())
+			mu.Unlock()
+		},
+	})
+	defer sink.Close()
+
+	for range 50 {
+		sink.Handle(AgentEvent{Type: TurnEventStart})
+	}
+	sink.mu.Lock()
+	buffered := sink.buf.Len()
+	sink.mu.Unlock()
+	if buffered > 512 {
+		t.Errorf("buffered %d bytes, want at most 512", buffered)
+	}
+
+	store.mu.Lock()
+	store.failing = false
+	store.mu.Unlock()
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var full, lost bool
+	for _, r := range reports {
+		full = full || strings.Contains(r, "buffer full")
+		lost = lost || strings.Contains(r, "events were dropped")
+	}
+	if !full || !lost {
+		t.Errorf("reports = %q, want the drop start and the loss count", reports)
+	}
+}
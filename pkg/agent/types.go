@@ -2,6 +2,9 @@ package agent
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
 
 	"github.com/badlogic/pi-go/pkg/ai"
 )
@@ -10,18 +13,32 @@ import (
 // Mirrors the TypeScript StreamFn type.
 type StreamFn func(model *ai.Model, ctx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream
 
+// StreamCtxFn is a StreamFn that also receives the run's context, so that
+// aborting the agent cancels the in-flight request.
+type StreamCtxFn func(ctx context.Context, model *ai.Model, llmCtx ai.Context, opts *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream
+
 // AgentLoopConfig configures a single run of the agent loop.
 type AgentLoopConfig struct {
 	ai.SimpleStreamOptions
 
 	Model *ai.Model
 
+	// Registry supplies models and providers; nil uses the default registry.
+	// When no StreamFn is given, calls are streamed through the registry.
+	Registry *ai.Registry
+
+	// StreamCtxFn, when set, is used instead of the StreamFn argument.
+	StreamCtxFn StreamCtxFn
+
 	// ConvertToLLM transforms AgentMessages to LLM-compatible Messages before each call.
 	ConvertToLLM func(messages []AgentMessage) ([]ai.Message, error)
 
 	// TransformContext optionally transforms the agent-level context before ConvertToLLM.
 	TransformContext func(ctx context.Context, messages []AgentMessage) ([]AgentMessage, error)
 
+	// Pipeline runs named transform stages after TransformContext.
+	Pipeline *TransformPipeline
+
 	// GetApiKey dynamically resolves an API key for expiring tokens.
 	GetApiKey func(provider string) (string, error)
 
@@ -30,13 +47,149 @@ type AgentLoopConfig struct {
 
 	// GetFollowUpMessages returns follow-up messages after the agent would stop.
 	GetFollowUpMessages func() ([]AgentMessage, error)
+
+	// SteeringToolPolicy decides what happens to the remaining and running
+	// tool calls when steering arrives; "" is SteeringSkipRest.
+	SteeringToolPolicy SteeringToolPolicy
+
+	// ImageCaptioner, when set, replaces images with text descriptions for
+	// models that do not accept image input.
+	ImageCaptioner *ai.ImageCaptioner
+
+	// OnTurnTrace, when set, receives a TurnTrace after every LLM call.
+	OnTurnTrace func(*TurnTrace)
+
+	// PostProcessors rewrite each final assistant message, in order, before
+	// MessageEventEnd is emitted (e.g. Glossary.PostProcessor).
+	PostProcessors []MessagePostProcessor
+
+	// ToolConcurrency is the maximum number of Parallelizable tool calls
+	// from one assistant message that run at once; <= 1 runs every call
+	// sequentially.
+	ToolConcurrency int
+
+	// Continuation, when set, nudges the model to keep going if it stops
+	// before the task is complete.
+	Continuation *ContinuationPolicy
+
+	// OnToolApproval, when set, is asked before each tool call executes
+	// (after argument validation). Denied calls and callback errors produce
+	// an IsError tool result; ApprovalAlwaysAllow skips the callback for
+	// later calls of the same tool in this run.
+	OnToolApproval ToolApprovalFunc
+
+	// ContextMonitor, when set, is told the context usage after every turn
+	// and fires its callback when a fill threshold is crossed.
+	ContextMonitor *ContextMonitor
+
+	// DefaultToolTimeout bounds each tool call whose AgentTool.Timeout is
+	// zero. On expiry the tool's ctx is cancelled and the model receives an
+	// IsError result saying the call timed out. Zero means no timeout.
+	DefaultToolTimeout time.Duration
+
+	// ToolInterceptors wrap every tool's Execute (see WrapTool), the first
+	// outermost. They run for each attempt, inside retries and timeouts.
+	ToolInterceptors []ToolInterceptor
+
+	// MaxToolRetries, when > 0, bounds how many consecutive turns may fail
+	// with only recoverable tool errors (unknown tools, invalid arguments,
+	// errors wrapping ErrRecoverable). Each failure is fed back to the
+	// model with a request to correct the call; once the retries are used
+	// up the run ends with an error. 0 feeds errors back without limit.
+	MaxToolRetries int
+
+	// MaxTurns, when > 0, bounds the LLM calls of a run, and MaxToolCalls
+	// the tool calls it executes. A run that would exceed either ends with
+	// a synthetic assistant message with ai.StopReasonLimit explaining the
+	// cutoff; tool calls over the limit get an error result.
+	MaxTurns     int
+	MaxToolCalls int
+
+	// ResumeDisconnects, when > 0, resumes responses cut off by a dropped
+	// connection up to that many times, stitching the continuation onto
+	// the partial answer (see ai.Registry.StreamResumable). It applies
+	// when the loop streams through Registry rather than a StreamFn.
+	ResumeDisconnects int
+
+	// Budget, when set, caps the spend of a run: once the usage of its
+	// assistant messages reaches MaxCost or MaxTokens, no further LLM call
+	// starts; a BudgetExceededEvent is emitted and the run ends like at
+	// MaxTurns. Compaction summaries and usage charged with ChargeUsage
+	// (sub-agents) count as well. SessionBudget does the same for
+	// SessionUsage, the spend before this run, plus this run's.
+	Budget        *Budget
+	SessionBudget *Budget
+	SessionUsage  ai.Usage
+
+	// CoerceArguments repairs slightly mistyped tool arguments ("42" for
+	// 42, ...) before validation, for tools without their own Coerce.
+	CoerceArguments *ai.CoerceOptions
+
+	// ValidationPolicy, when set, intervenes once calls to one tool fail
+	// argument validation several times in a row.
+	ValidationPolicy *ValidationPolicy
+
+	// Locks is the keyed mutex tools reach through LockResources; nil
+	// gives each run its own set.
+	Locks *ResourceLocks
+
+	// ToolCache, when set, serves repeated calls of Cacheable tools.
+	ToolCache *ToolCache
+
+	// Compaction, when set, summarizes older turns before a call that
+	// would come close to the model's context window.
+	Compaction *CompactionPolicy
+
+	// ImageModeration, when set, checks images as they enter the
+	// conversation and every image in the context before each LLM call,
+	// applying its block, blur and flag actions (see ai.ImageModerator).
+	// A prompt with a blocked image is rejected: the run ends with an
+	// error before the prompt is recorded. Steering and follow-up messages
+	// with one are dropped, tool results are withheld and blocked images
+	// the model generates are withheld at message_end.
+	ImageModeration *ai.ImageModerator
+
+	// TurnAnalyzer, when set, tags prompts, steering and follow-up
+	// messages with intents (MetadataIntents) as they enter the
+	// conversation.
+	TurnAnalyzer TurnAnalyzer
+
+	// Locale selects the language of the strings the loop writes into
+	// the conversation, such as tool errors ("de", "pt-BR"); "" is English.
+	Locale string
+
+	// Catalog overrides or extends DefaultCatalog for Locale.
+	Catalog Catalog
+
+	// moderationLog is set by AgentLoop or runLoop when ImageModeration is.
+	moderationLog *moderationLog
+
+	// meter collects usage outside the conversation's assistant messages;
+	// set by Agent, or by runLoop.
+	meter *usageMeter
+
+	// toolApprovals, when set by Agent, keeps ApprovalAlwaysAllow grants
+	// across runs.
+	toolApprovals *toolApprovals
 }
 
 // AgentMessage is a union: it can be a standard LLM Message or a custom app message.
 // The Custom field can hold arbitrary application-specific data.
 type AgentMessage struct {
 	ai.Message
-	Custom any `json:"custom,omitempty"`
+	ID     string `json:"id,omitempty"` // assigned by Agent when the message is committed
+	Custom any    `json:"custom,omitempty"`
+
+	// Metadata holds application annotations (intents, feedback, ...) that
+	// are never sent to the model.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// NewMessageID returns a random message identifier.
+func NewMessageID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
 }
 
 // NewAgentMessageFromMessage wraps a standard Message.
@@ -44,6 +197,14 @@ func NewAgentMessageFromMessage(m ai.Message) AgentMessage {
 	return AgentMessage{Message: m}
 }
 
+// SetMetadata sets a metadata key, allocating the map if needed.
+func (m *AgentMessage) SetMetadata(key string, value any) {
+	if m.Metadata == nil {
+		m.Metadata = map[string]any{}
+	}
+	m.Metadata[key] = value
+}
+
 // IsLLMMessage returns true if this is a standard LLM message (not custom).
 func (m AgentMessage) IsLLMMessage() bool {
 	return m.Custom == nil && m.Role() != ""
@@ -60,6 +221,13 @@ type AgentState struct {
 	StreamMessage   *AgentMessage
 	PendingToolCalls map[string]struct{}
 	Error           string
+	Language        string // detected or configured user language, if any
+
+	// ModelAlias is the alias Model was selected by (see SetModelAlias); it
+	// is re-resolved before each run so re-pointing takes effect, unless
+	// pinned in AliasPins (alias → concrete model ID).
+	ModelAlias string
+	AliasPins  map[string]string
 }
 
 // AgentToolResult is the result of executing a tool.
@@ -76,6 +244,39 @@ type AgentTool struct {
 	ai.Tool
 	Label   string `json:"label"`
 	Execute func(ctx context.Context, toolCallID string, params map[string]any, onUpdate AgentToolUpdateCallback) (AgentToolResult, error)
+	Retry   *ToolRetryPolicy `json:"-"` // retries failed executions; nil means none
+
+	// Parallelizable marks the tool as safe to run concurrently with other
+	// parallelizable calls from the same turn (see ToolConcurrency).
+	Parallelizable bool `json:"-"`
+
+	// Timeout bounds one call, including retries, overriding the loop's
+	// DefaultToolTimeout; negative disables it for this tool.
+	Timeout time.Duration `json:"-"`
+
+	// ProvisionalAfter opts a long-running tool into provisional results:
+	// if a call is still running after this long, its latest partial
+	// output (see AgentToolUpdateCallback) is returned to the model so the
+	// run can continue, and the final output follows as a user message
+	// once the call finishes. Zero disables it.
+	ProvisionalAfter time.Duration `json:"-"`
+
+	// Coerce repairs slightly mistyped arguments before validation (see
+	// ai.CoerceArguments), overriding the loop's CoerceArguments.
+	Coerce *ai.CoerceOptions `json:"-"`
+
+	// OutputSchema, when set, is the JSON schema of the tool's output. It
+	// is appended to the description the model sees, and results that do
+	// not match it fail with an *OutputSchemaError (see ToolOutput).
+	OutputSchema ai.ToolSchema `json:"outputSchema,omitempty"`
+
+	// Examples are valid argument sets, shown to the model by
+	// ValidationExample when its calls keep failing validation.
+	Examples []map[string]any `json:"-"`
+
+	// Cacheable marks the tool as idempotent: with a ToolCache configured,
+	// a repeated call with the same arguments returns the cached result.
+	Cacheable bool `json:"-"`
 }
 
 // AgentContext bundles the system prompt, messages, and tools for the agent loop.
@@ -93,16 +294,27 @@ type AgentContext struct {
 type AgentEventType string
 
 const (
-	AgentEventStart          AgentEventType = "agent_start"
-	AgentEventEnd            AgentEventType = "agent_end"
-	TurnEventStart           AgentEventType = "turn_start"
-	TurnEventEnd             AgentEventType = "turn_end"
-	MessageEventStart        AgentEventType = "message_start"
-	MessageEventUpdate       AgentEventType = "message_update"
-	MessageEventEnd          AgentEventType = "message_end"
-	ToolExecutionEventStart  AgentEventType = "tool_execution_start"
-	ToolExecutionEventUpdate AgentEventType = "tool_execution_update"
-	ToolExecutionEventEnd    AgentEventType = "tool_execution_end"
+	AgentEventStart            AgentEventType = "agent_start"
+	AgentEventEnd              AgentEventType = "agent_end"
+	TurnEventStart             AgentEventType = "turn_start"
+	TurnEventEnd               AgentEventType = "turn_end"
+	MessageEventStart          AgentEventType = "message_start"
+	MessageEventUpdate         AgentEventType = "message_update"
+	MessageEventEnd            AgentEventType = "message_end"
+	ToolExecutionEventStart    AgentEventType = "tool_execution_start"
+	ToolExecutionEventUpdate   AgentEventType = "tool_execution_update"
+	ToolExecutionEventEnd      AgentEventType = "tool_execution_end"
+	FeedbackEventRecorded      AgentEventType = "feedback"
+	WarningEvent               AgentEventType = "warning"
+	ToolCallInvalidEvent       AgentEventType = "tool_call_invalid"
+	ToolApprovalRequestedEvent AgentEventType = "tool_approval_requested"
+	ContextUsageEvent          AgentEventType = "context_usage"
+	TurnStageTimingEvent       AgentEventType = "stage_timing"
+	ToolCacheHitEvent          AgentEventType = "tool_cache_hit"
+	CompactionEvent            AgentEventType = "compaction"
+	ImageModerationEvent       AgentEventType = "image_moderation"
+	SubAgentEvent              AgentEventType = "subagent"
+	BudgetExceededEvent        AgentEventType = "budget_exceeded"
 )
 
 // AgentEvent is emitted during the agent loop for lifecycle observability.
@@ -121,13 +333,48 @@ type AgentEvent struct {
 	// turn_end
 	ToolResults []ai.ToolResultMessage
 
-	// tool_execution_*
+	// tool_execution_* (see the typed accessors ToolExecutionStart etc.);
+	// tool_approval_requested and tool_cache_hit carry ToolCallID,
+	// ToolName and Args
 	ToolCallID    string
 	ToolName      string
 	Args          any
-	PartialResult any
+	PartialResult *AgentToolResult // tool_execution_update
 	Result        any
 	IsError       bool
+
+	// feedback
+	Feedback *Feedback
+
+	// warning
+	Warning string
+
+	// tool_call_invalid (with ToolCallID, ToolName and Args): emitted while
+	// a tool call is still streaming once its arguments violate the schema
+	ValidationError string
+
+	// context_usage: emitted after each turn when the model's context
+	// window is known
+	ContextUsage *ContextUsage
+
+	// stage_timing: the duration of one stage of a turn; tool_execution
+	// stages also carry ToolCallID and ToolName
+	TurnStageTiming *TurnStageTiming
+
+	// compaction: older turns were replaced by a summary message
+	Compaction *Compaction
+
+	// image_moderation: an image was flagged, once per image and run;
+	// images in tool results also carry ToolCallID and ToolName
+	ImageModeration *ai.ImageModeration
+
+	// subagent: an event of a sub-agent started by the tool call
+	// ToolCallID of ToolName (see NewSubAgentTool)
+	Nested *NestedEvent
+
+	// budget_exceeded: the run or session budget that stopped the run,
+	// emitted before its final message
+	BudgetExceeded *BudgetExceeded
 }
 
 // AgentEventStream is an EventStream for agent events with a final result
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// EventSchemaVersion is the version of the AgentEvent JSON wire format
+// written by MarshalJSON. Transports (SSE, WebSocket, transcripts) carry it
+// in every event as "v" so clients in other languages can detect changes.
+//
+// Version 1 encodes an event as one object with camelCase fields; fields
+// that do not apply to the event type are omitted:
+//
+//	v                      int     always 1
+//	type                   string  an AgentEventType
+//	messages               array   agent_end: all new messages
+//	message                object  message_*, turn_end: an AgentMessage
+//	assistantMessageEvent  object  message_update: an ai.AssistantMessageEvent
+//	toolResults            array   turn_end: ai.ToolResultMessage values
+//	toolCallId, toolName   string  tool_execution_*, tool_call_invalid,
+//	                               tool_approval_requested, tool_cache_hit,
+//	                               image_moderation, subagent
+//	args                   object  tool call arguments
+//	partialResult, result  object  AgentToolResult {content, details}
+//	isError                bool    tool_execution_end
+//	feedback               object  feedback
+//	warning                string  warning
+//	validationError        string  tool_call_invalid
+//	contextUsage           object  context_usage: ContextUsage
+//	stageTiming            object  stage_timing: TurnStageTiming
+//	compaction             object  compaction: Compaction
+//	imageModeration        object  image_moderation: ai.ImageModeration
+//	nested                 object  subagent: NestedEvent {label, event}
+//	budgetExceeded         object  budget_exceeded: BudgetExceeded
+//
+// Version 0 is the legacy encoding with Go field names ("Type",
+// "ToolCallID", ...) and no "v". Its fields are frozen at Type through
+// IsError, always present, and messages are bare LLM messages without
+// "id", "metadata" or custom payloads; the payloads of later event types
+// are not carried. UnmarshalJSON reads both versions; EncodeAgentEvent can
+// still write version 0 for older clients.
+const EventSchemaVersion = 1
+
+// wireAgentEvent is the version 1 encoding of AgentEvent.
+type wireAgentEvent struct {
+	V                     int                       `json:"v"`
+	Type                  AgentEventType            `json:"type"`
+	Messages              []AgentMessage            `json:"messages,omitempty"`
+	Message               *AgentMessage             `json:"message,omitempty"`
+	AssistantMessageEvent *ai.AssistantMessageEvent `json:"assistantMessageEvent,omitempty"`
+	ToolResults           []ai.ToolResultMessage    `json:"toolResults,omitempty"`
+	ToolCallID            string                    `json:"toolCallId,omitempty"`
+	ToolName              string                    `json:"toolName,omitempty"`
+	Args                  any                       `json:"args,omitempty"`
+	PartialResult         *AgentToolResult          `json:"partialResult,omitempty"`
+	Result                any                       `json:"result,omitempty"`
+	IsError               bool                      `json:"isError,omitempty"`
+	Feedback              *Feedback                 `json:"feedback,omitempty"`
+	Warning               string                    `json:"warning,omitempty"`
+	ValidationError       string                    `json:"validationError,omitempty"`
+	ContextUsage          *ContextUsage             `json:"contextUsage,omitempty"`
+	TurnStageTiming       *TurnStageTiming          `json:"stageTiming,omitempty"`
+	Compaction            *Compaction               `json:"compaction,omitempty"`
+	ImageModeration       *ai.ImageModeration       `json:"imageModeration,omitempty"`
+	Nested                *NestedEvent              `json:"nested,omitempty"`
+	BudgetExceeded        *BudgetExceeded           `json:"budgetExceeded,omitempty"`
+}
+
+// legacyAgentEvent is the version 0 encoding of AgentEvent. Fields added
+// to AgentEvent since must not be added here.
+type legacyAgentEvent struct {
+	Type                  AgentEventType
+	Messages              []ai.Message
+	Message               *ai.Message
+	AssistantMessageEvent *ai.AssistantMessageEvent
+	ToolResults           []ai.ToolResultMessage
+	ToolCallID            string
+	ToolName              string
+	Args                  any
+	PartialResult         *AgentToolResult
+	Result                any
+	IsError               bool
+}
+
+// MarshalJSON writes the event in the current wire format.
+func (e AgentEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireAgentEvent{
+		V:                     EventSchemaVersion,
+		Type:                  e.Type,
+		Messages:              e.Messages,
+		Message:               e.Message,
+		AssistantMessageEvent: e.AssistantMessageEvent,
+		ToolResults:           e.ToolResults,
+		ToolCallID:            e.ToolCallID,
+		ToolName:              e.ToolName,
+		Args:                  e.Args,
+		PartialResult:         e.PartialResult,
+		Result:                e.Result,
+		IsError:               e.IsError,
+		Feedback:              e.Feedback,
+		Warning:               e.Warning,
+		ValidationError:       e.ValidationError,
+		ContextUsage:          e.ContextUsage,
+		TurnStageTiming:       e.TurnStageTiming,
+		Compaction:            e.Compaction,
+		ImageModeration:       e.ImageModeration,
+		Nested:                e.Nested,
+		BudgetExceeded:        e.BudgetExceeded,
+	})
+}
+
+// UnmarshalJSON reads any supported wire version. Field names match
+// case-insensitively, which also covers the version 0 Go field names.
+func (e *AgentEvent) UnmarshalJSON(data []byte) error {
+	var w wireAgentEvent
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.V > EventSchemaVersion {
+		return fmt.Errorf("agent event schema version %d is newer than supported version %d", w.V, EventSchemaVersion)
+	}
+	*e = AgentEvent{
+		Type:                  w.Type,
+		Messages:              w.Messages,
+		Message:               w.Message,
+		AssistantMessageEvent: w.AssistantMessageEvent,
+		ToolResults:           w.ToolResults,
+		ToolCallID:            w.ToolCallID,
+		ToolName:              w.ToolName,
+		Args:                  w.Args,
+		PartialResult:         w.PartialResult,
+		Result:                w.Result,
+		IsError:               w.IsError,
+		Feedback:              w.Feedback,
+		Warning:               w.Warning,
+		ValidationError:       w.ValidationError,
+		ContextUsage:          w.ContextUsage,
+		TurnStageTiming:       w.TurnStageTiming,
+		Compaction:            w.Compaction,
+		ImageModeration:       w.ImageModeration,
+		Nested:                w.Nested,
+		BudgetExceeded:        w.BudgetExceeded,
+	}
+	return nil
+}
+
+// EncodeAgentEvent marshals e in the given wire version, for transports
+// that must keep serving clients written against an older version.
+func EncodeAgentEvent(e AgentEvent, version int) ([]byte, error) {
+	switch version {
+	case 0:
+		w := legacyAgentEvent{
+			Type:                  e.Type,
+			AssistantMessageEvent: e.AssistantMessageEvent,
+			ToolResults:           e.ToolResults,
+			ToolCallID:            e.ToolCallID,
+			ToolName:              e.ToolName,
+			Args:                  e.Args,
+			PartialResult:         e.PartialResult,
+			Result:                e.Result,
+			IsError:               e.IsError,
+		}
+		for _, m := range e.Messages {
+			w.Messages = append(w.Messages, m.Message)
+		}
+		if e.Message != nil {
+			w.Message = &e.Message.Message
+		}
+		return json.Marshal(w)
+	case EventSchemaVersion:
+		return json.Marshal(e)
+	}
+	return nil, fmt.Errorf("unsupported agent event schema version %d", version)
+}
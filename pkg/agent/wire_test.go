@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// wireEvents covers every kind of payload an AgentEvent carries.
+func wireEvents() []AgentEvent {
+	msg := NewAgentMessageFromMessage(ai.NewUserMessage("hi"))
+	args := map[string]any{"city": "Berlin"}
+	result := AgentToolResult{Content: []ai.Content{ai.NewTextContent("sunny")}}
+	return []AgentEvent{
+		{Type: AgentEventEnd, Messages: []AgentMessage{msg}},
+		{Type: MessageEventUpdate, Message: &msg, AssistantMessageEvent: &ai.AssistantMessageEvent{Type: ai.EventTextDelta, Delta: "h"}},
+		ToolExecutionUpdate{ToolCallID: "call_1", ToolName: "weather", Args: args, PartialResult: result}.Event(),
+		{Type: WarningEvent, Warning: "careful"},
+		{Type: ToolCallInvalidEvent, ToolCallID: "call_2", ToolName: "weather", Args: args, ValidationError: "city: expected string"},
+		{Type: FeedbackEventRecorded, Message: &msg, Feedback: &Feedback{MessageID: msg.ID, Rating: FeedbackPositive, Timestamp: 1}},
+	}
+}
+
+func TestAgentEventWireRoundTrip(t *testing.T) {
+	for _, e := range wireEvents() {
+		data, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"v":1,"type":"`+string(e.Type)+`"`) {
+			t.Errorf("%s: %s lacks the version header", e.Type, data)
+		}
+		if strings.Contains(string(data), `"Type"`) || strings.Contains(string(data), `null`) {
+			t.Errorf("%s: %s has Go field names or empty fields", e.Type, data)
+		}
+		var got AgentEvent
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: %v", e.Type, err)
+		}
+		again, _ := json.Marshal(got)
+		if string(again) != string(data) {
+			t.Errorf("%s: round trip changed the event:\n%s\n%s", e.Type, data, again)
+		}
+	}
+}
+
+func TestAgentEventLegacyEncoding(t *testing.T) {
+	for _, e := range wireEvents() {
+		switch e.Type {
+		case WarningEvent, ToolCallInvalidEvent, FeedbackEventRecorded:
+			continue // not in version 0
+		}
+		v0, err := EncodeAgentEvent(e, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(v0), `{"Type":`) || strings.Contains(string(v0), `"v":`) {
+			t.Errorf("%s: %s is not version 0", e.Type, v0)
+		}
+		var got AgentEvent
+		if err := json.Unmarshal(v0, &got); err != nil {
+			t.Fatalf("%s: %v", e.Type, err)
+		}
+		want, _ := json.Marshal(e)
+		if again, _ := json.Marshal(got); string(again) != string(want) {
+			t.Errorf("%s: version 0 decodes to\n%s\nwant\n%s", e.Type, again, want)
+		}
+	}
+}
+
+func TestAgentEventLegacyEncodingIsFrozen(t *testing.T) {
+	msg := NewAgentMessageFromMessage(ai.NewUserMessage("hi"))
+	msg.ID = "m1"
+	msg.Metadata = map[string]any{"intent": "greeting"}
+	v0, err := EncodeAgentEvent(AgentEvent{Type: MessageEventEnd, Message: &msg, Warning: "w"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(v0, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Feedback", "Warning", "ValidationError"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("version 0 has field %s: %s", name, v0)
+		}
+	}
+	if strings.Contains(string(fields["Message"]), `"id"`) || strings.Contains(string(fields["Message"]), `"metadata"`) {
+		t.Errorf("version 0 message carries agent fields: %s", fields["Message"])
+	}
+}
+
+func TestAgentEventRejectsNewerVersions(t *testing.T) {
+	var e AgentEvent
+	if err := json.Unmarshal([]byte(`{"v":2,"type":"warning"}`), &e); err == nil {
+		t.Error("accepted a newer schema version")
+	}
+	if _, err := EncodeAgentEvent(AgentEvent{}, 7); err == nil {
+		t.Error("encoded an unknown schema version")
+	}
+	v1, _ := EncodeAgentEvent(AgentEvent{Type: WarningEvent, Warning: "w"}, EventSchemaVersion)
+	if want, _ := json.Marshal(AgentEvent{Type: WarningEvent, Warning: "w"}); !reflect.DeepEqual(v1, want) {
+		t.Errorf("EncodeAgentEvent(v1) = %s, want %s", v1, want)
+	}
+}
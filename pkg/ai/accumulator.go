@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"strings"
+	"time"
+)
+
+// MessageAccumulator builds an AssistantMessage from a sequence of events
+// that carry only deltas, such as events decoded from a wire format or
+// produced by a custom StreamFn. Apply returns each event completed with the
+// accumulated message, ready to push to an AssistantMessageEventStream.
+type MessageAccumulator struct {
+	msg      *AssistantMessage
+	toolJSON map[int]*strings.Builder // raw argument JSON by content index
+}
+
+// NewMessageAccumulator starts an empty assistant message for model.
+func NewMessageAccumulator(model *Model) *MessageAccumulator {
+	msg := &AssistantMessage{
+		Role:       RoleAssistant,
+		StopReason: StopReasonStop,
+		Content:    []Content{},
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	if model != nil {
+		msg.Api, msg.Provider, msg.Model = model.Api, model.Provider, model.ID
+	}
+	return &MessageAccumulator{msg: msg, toolJSON: map[int]*strings.Builder{}}
+}
+
+// Message returns the message accumulated so far. It is updated in place
+// by Apply; callers may set fields such as Usage directly.
+func (a *MessageAccumulator) Message() *AssistantMessage {
+	return a.msg
+}
+
+// SetSignature records a provider signature on the text or thinking block
+// at index.
+func (a *MessageAccumulator) SetSignature(index int, sig string) {
+	a.ensure(index)
+	c := a.msg.Content[index]
+	switch {
+	case c.Text != nil:
+		c.Text.TextSignature = sig
+	case c.Thinking != nil:
+		c.Thinking.ThinkingSignature = sig
+	}
+}
+
+// Apply folds e into the message and returns it with Partial (or Message /
+// Error for terminal events), Content and ToolCallData filled in. Start
+// events create the block at ContentIndex; a toolcall_start takes the call's
+// ID and name from e.ToolCallData. ok is false for deltas that do not match
+// the block at their index; such events should be dropped.
+func (a *MessageAccumulator) Apply(e AssistantMessageEvent) (out AssistantMessageEvent, ok bool) {
+	i := e.ContentIndex
+	if i < 0 {
+		return e, false
+	}
+	switch e.Type {
+	case EventStart:
+	case EventTextStart:
+		a.set(i, NewTextContent(""))
+	case EventThinkingStart:
+		a.set(i, NewThinkingContent(""))
+	case EventToolCallStart:
+		var id, name string
+		if e.ToolCallData != nil {
+			id, name = e.ToolCallData.ID, e.ToolCallData.Name
+		}
+		a.set(i, NewToolCallContent(id, name, map[string]any{}))
+		a.toolJSON[i] = &strings.Builder{}
+	case EventTextDelta, EventTextEnd:
+		c := a.block(i)
+		if c.Text == nil {
+			return e, false
+		}
+		if e.Type == EventTextDelta {
+			c.Text.Text += e.Delta
+		} else {
+			e.Content = c.Text.Text
+		}
+	case EventThinkingDelta, EventThinkingEnd:
+		c := a.block(i)
+		if c.Thinking == nil {
+			return e, false
+		}
+		if e.Type == EventThinkingDelta {
+			c.Thinking.Thinking += e.Delta
+		} else {
+			e.Content = c.Thinking.Thinking
+		}
+	case EventToolCallDelta, EventToolCallEnd:
+		c := a.block(i)
+		if c.ToolCall == nil {
+			return e, false
+		}
+		raw := a.toolJSON[i]
+		if raw == nil {
+			raw = &strings.Builder{}
+			a.toolJSON[i] = raw
+		}
+		raw.WriteString(e.Delta)
+		c.ToolCall.Arguments = ParseStreamingJSON(raw.String())
+		if e.Type == EventToolCallEnd {
+			e.ToolCallData = c.ToolCall
+		}
+	case EventDone:
+		if e.Reason != "" {
+			a.msg.StopReason = e.Reason
+		}
+		e.Message = a.msg
+		return e, true
+	case EventError:
+		if e.Reason == "" {
+			e.Reason = StopReasonError
+		}
+		a.msg.StopReason = e.Reason
+		if e.Error != nil && e.Error != a.msg && e.Error.ErrorMessage != "" {
+			a.msg.ErrorMessage = e.Error.ErrorMessage
+		}
+		e.Error = a.msg
+		return e, true
+	default:
+		return e, false
+	}
+	e.Partial = a.msg
+	return e, true
+}
+
+func (a *MessageAccumulator) ensure(index int) {
+	for len(a.msg.Content) <= index {
+		a.msg.Content = append(a.msg.Content, Content{})
+	}
+}
+
+func (a *MessageAccumulator) set(index int, c Content) {
+	a.ensure(index)
+	a.msg.Content[index] = c
+}
+
+func (a *MessageAccumulator) block(index int) Content {
+	a.ensure(index)
+	return a.msg.Content[index]
+}
@@ -0,0 +1,92 @@
+package ai
+
+import "sync"
+
+// Broadcast fans one EventStream out to any number of subscribers. Every
+// subscriber receives the complete event sequence, including events that
+// were pushed before it subscribed, followed by the source's result and
+// error. Subscriber streams are unbounded, so a slow consumer never stalls
+// the source or the other subscribers.
+type Broadcast[T any, R any] struct {
+	src *EventStream[T, R]
+
+	mu      sync.Mutex
+	history []T
+	subs    []*EventStream[T, R]
+	done    bool
+	result  R
+	err     error
+}
+
+// NewBroadcast starts consuming src. src must not be read elsewhere.
+func NewBroadcast[T any, R any](src *EventStream[T, R]) *Broadcast[T, R] {
+	b := &Broadcast[T, R]{src: src}
+	go b.run()
+	return b
+}
+
+// Subscribe returns a new stream replaying src from its first event.
+func (b *Broadcast[T, R]) Subscribe() *EventStream[T, R] {
+	out := b.src.derive(BufferOptions{Policy: BufferUnbounded})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		out.SetErr(b.err)
+	}
+	for _, e := range b.history {
+		if b.src.isComplete(e) {
+			out.SetErr(b.err)
+		}
+		out.Push(e)
+	}
+	if b.done {
+		out.End(b.result)
+		return out
+	}
+	b.subs = append(b.subs, out)
+	return out
+}
+
+func (b *Broadcast[T, R]) run() {
+	for e := range b.src.Events() {
+		var err error
+		if b.src.isComplete(e) {
+			err = b.src.Err()
+		}
+		b.mu.Lock()
+		b.history = append(b.history, e)
+		for _, s := range b.subs {
+			if err != nil {
+				s.SetErr(err)
+			}
+			s.Push(e)
+		}
+		b.mu.Unlock()
+	}
+	err := b.src.Err()
+	result := b.src.Result()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done, b.result, b.err = true, result, err
+	for _, s := range b.subs {
+		s.SetErr(err)
+		s.End(result)
+	}
+	b.subs = nil
+}
+
+// Tee splits s into n independent streams that each receive every event,
+// the result and the error. s must not be read after calling Tee.
+func (s *EventStream[T, R]) Tee(n int) []*EventStream[T, R] {
+	b := NewBroadcast(s)
+	out := make([]*EventStream[T, R], n)
+	for i := range out {
+		out[i] = b.Subscribe()
+	}
+	return out
+}
+
+// derive creates an empty stream with the same terminal semantics as s.
+func (s *EventStream[T, R]) derive(buf BufferOptions) *EventStream[T, R] {
+	return NewBufferedEventStream(s.isComplete, s.extractResult, buf, s.merge)
+}
@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StreamFunctionCtx is a StreamFunction that honours cancellation of ctx,
+// aborting the underlying request.
+type StreamFunctionCtx func(ctx context.Context, model *Model, llmCtx Context, opts *StreamOptions) *AssistantMessageEventStream
+
+// StreamSimpleFunctionCtx is the context-aware variant of StreamSimpleFunction.
+type StreamSimpleFunctionCtx func(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions) *AssistantMessageEventStream
+
+// AbortOnCancel forwards events from src until ctx is cancelled. On
+// cancellation it emits an EventError with StopReasonAborted (carrying the
+// content received so far) and drains src in the background so the
+// producer never blocks. If ctx can never be cancelled src is returned.
+func AbortOnCancel(ctx context.Context, model *Model, src *AssistantMessageEventStream) *AssistantMessageEventStream {
+	if ctx.Done() == nil {
+		return src
+	}
+	return watchStream(ctx, model, src, 0, nil)
+}
+
+// WithIdleTimeout ends the stream with an error if src produces no event
+// for idle, calling cancel (if non-nil) to abort the underlying request.
+// Unlike an overall deadline, a steadily streaming response never times out.
+func WithIdleTimeout(src *AssistantMessageEventStream, model *Model, idle time.Duration, cancel context.CancelFunc) *AssistantMessageEventStream {
+	if idle <= 0 {
+		return src
+	}
+	return watchStream(context.Background(), model, src, idle, cancel)
+}
+
+// ErrIdleTimeout is the stream error when WithIdleTimeout fires.
+var ErrIdleTimeout = errors.New("stream idle timeout")
+
+// watchStream forwards src, ending the output early when ctx is cancelled
+// or (if idle > 0) when no event arrives for idle. cancel, if non-nil, is
+// called once the output has ended.
+func watchStream(ctx context.Context, model *Model, src *AssistantMessageEventStream, idle time.Duration, cancel context.CancelFunc) *AssistantMessageEventStream {
+	out := NewAssistantMessageEventStream()
+	go func() {
+		if cancel != nil {
+			defer cancel()
+		}
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if idle > 0 {
+			timer = time.NewTimer(idle)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		var partial *AssistantMessage
+		events := src.Events()
+		stop := func(reason StopReason, err error, errMsg string) {
+			go func() {
+				for range events {
+				}
+			}()
+			msg := abortedMessage(model, partial)
+			msg.StopReason = reason
+			msg.ErrorMessage = errMsg
+			out.SetErr(err)
+			out.Push(AssistantMessageEvent{Type: EventError, Reason: reason, Error: msg})
+		}
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					out.SetErr(src.Err())
+					out.End(src.Result())
+					return
+				}
+				if timer != nil {
+					timer.Reset(idle)
+				}
+				if e.Partial != nil {
+					partial = e.Partial
+				}
+				if e.Type == EventError {
+					out.SetErr(src.Err())
+				}
+				out.Push(e)
+			case <-ctx.Done():
+				stop(StopReasonAborted, ctx.Err(), "Request was aborted")
+				return
+			case <-timeout:
+				stop(StopReasonError, ErrIdleTimeout, fmt.Sprintf("No response from provider for %s", idle))
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// abortedMessage builds the terminal message for a stream cut short; the
+// caller sets StopReason and ErrorMessage.
+func abortedMessage(model *Model, partial *AssistantMessage) *AssistantMessage {
+	msg := &AssistantMessage{
+		Role:      RoleAssistant,
+		Content:   []Content{},
+		Api:       model.Api,
+		Provider:  model.Provider,
+		Model:     model.ID,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if partial != nil {
+		clone := *partial
+		clone.Content = append([]Content{}, partial.Content...)
+		msg = &clone
+	}
+	return msg
+}
+
+// StreamCtx starts a streaming call that is aborted when ctx is cancelled.
+// Providers with a StreamCtx function receive ctx directly; others are
+// wrapped with AbortOnCancel.
+func (r *Registry) StreamCtx(ctx context.Context, model *Model, llmCtx Context, opts *StreamOptions) (*AssistantMessageEventStream, error) {
+	if err := CheckModel(model); err != nil {
+		return nil, err
+	}
+	p := r.GetApiProvider(model.Api)
+	if p == nil {
+		return nil, errNoProvider(model.Api)
+	}
+	llmCtx.Messages = PrepareToolResultImages(model, llmCtx.Messages)
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	s := SafeStream(model, func() *AssistantMessageEventStream {
+		if p.StreamCtx != nil {
+			return p.StreamCtx(ctx, model, llmCtx, opts)
+		}
+		return AbortOnCancel(ctx, model, p.Stream(model, llmCtx, opts))
+	})
+	return instrument(watchIdle(s, model, opts, cancel), model, llmCtx, start), nil
+}
+
+// StreamSimpleCtx is the context-aware variant of StreamSimple.
+func (r *Registry) StreamSimpleCtx(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	if err := CheckModel(model); err != nil {
+		return nil, err
+	}
+	p := r.GetApiProvider(model.Api)
+	if p == nil {
+		return nil, errNoProvider(model.Api)
+	}
+	llmCtx.Messages = PrepareToolResultImages(model, llmCtx.Messages)
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	s := SafeStream(model, func() *AssistantMessageEventStream {
+		if p.StreamSimpleCtx != nil {
+			return p.StreamSimpleCtx(ctx, model, llmCtx, opts)
+		}
+		return AbortOnCancel(ctx, model, p.StreamSimple(model, llmCtx, opts))
+	})
+	var so *StreamOptions
+	if opts != nil {
+		so = &opts.StreamOptions
+	}
+	return instrument(watchIdle(s, model, so, cancel), model, llmCtx, start), nil
+}
+
+// watchIdle applies opts.IdleTimeoutMs to s. cancel is always released
+// once the stream ends.
+func watchIdle(s *AssistantMessageEventStream, model *Model, opts *StreamOptions, cancel context.CancelFunc) *AssistantMessageEventStream {
+	if idle := opts.IdleTimeout(); idle > 0 {
+		return WithIdleTimeout(s, model, idle, cancel)
+	}
+	go func() {
+		<-s.Done()
+		cancel()
+	}()
+	return s
+}
+
+// StreamCtx starts a cancellable streaming call using the default registry.
+func StreamCtx(ctx context.Context, model *Model, llmCtx Context, opts *StreamOptions) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.StreamCtx(ctx, model, llmCtx, opts)
+}
+
+// StreamSimpleCtx starts a cancellable streaming call with reasoning options
+// using the default registry.
+func StreamSimpleCtx(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.StreamSimpleCtx(ctx, model, llmCtx, opts)
+}
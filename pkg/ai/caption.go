@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultCaptionPrompt asks the vision model for a self-contained description.
+const defaultCaptionPrompt = "Describe this image in detail for someone who cannot see it. " +
+	"Transcribe any visible text verbatim. Reply with the description only."
+
+// ImageCaptioner replaces images with text descriptions produced by a
+// vision-capable model, so that conversations containing images can still
+// be sent to text-only models. Captions are cached by image content.
+type ImageCaptioner struct {
+	Model    *Model         // vision-capable model used for captioning
+	Registry *Registry      // nil uses the default registry
+	Prompt   string         // instruction sent with each image; has a default
+	Options  *StreamOptions // options for captioning calls (API key, max tokens, ...)
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// Caption returns a description of img.
+func (c *ImageCaptioner) Caption(ctx context.Context, img *ImageContent) (string, error) {
+	if c.Model == nil || !c.Model.CanUseImages() {
+		return "", fmt.Errorf("caption model does not accept images")
+	}
+	sum := sha256.Sum256([]byte(img.MimeType + ":" + img.Data))
+	key := hex.EncodeToString(sum[:])
+	c.mu.Lock()
+	if text, ok := c.cache[key]; ok {
+		c.mu.Unlock()
+		return text, nil
+	}
+	c.mu.Unlock()
+
+	prompt := c.Prompt
+	if prompt == "" {
+		prompt = defaultCaptionPrompt
+	}
+	llmCtx := Context{Messages: []Message{NewUserMessageWithContent([]Content{
+		NewTextContent(prompt),
+		{Image: img},
+	})}}
+	s, err := RegistryOrDefault(c.Registry).StreamCtx(ctx, c.Model, llmCtx, c.Options)
+	if err != nil {
+		return "", err
+	}
+	msg := s.Result()
+	if msg == nil {
+		return "", fmt.Errorf("caption call returned no message")
+	}
+	ReportUsage(ctx, msg.Usage)
+	if msg.StopReason == StopReasonError || msg.StopReason == StopReasonAborted {
+		return "", fmt.Errorf("caption call failed: %s", msg.ErrorMessage)
+	}
+	var sb strings.Builder
+	for _, part := range msg.Content {
+		if part.Text != nil {
+			sb.WriteString(part.Text.Text)
+		}
+	}
+	text := strings.TrimSpace(sb.String())
+
+	c.mu.Lock()
+	if c.cache == nil {
+		c.cache = map[string]string{}
+	}
+	c.cache[key] = text
+	c.mu.Unlock()
+	return text, nil
+}
+
+// DescribeImages returns messages with every image in user and tool-result
+// content replaced by a text description. Images that fail to caption are
+// left in place and the first error is returned alongside the result.
+func (c *ImageCaptioner) DescribeImages(ctx context.Context, messages []Message) ([]Message, error) {
+	var firstErr error
+	describe := func(content []Content) []Content {
+		out := make([]Content, len(content))
+		for i, part := range content {
+			out[i] = part
+			if part.Image == nil {
+				continue
+			}
+			text, err := c.Caption(ctx, part.Image)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			out[i] = NewTextContent("[Image: " + text + "]")
+		}
+		return out
+	}
+
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = m
+		switch {
+		case m.User != nil && hasImage(m.User.Content):
+			u := *m.User
+			u.Content = describe(u.Content)
+			out[i] = Message{User: &u}
+		case m.ToolResult != nil && hasImage(m.ToolResult.Content):
+			tr := *m.ToolResult
+			tr.Content = describe(tr.Content)
+			out[i] = Message{ToolResult: &tr}
+		}
+	}
+	return out, firstErr
+}
+
+func hasImage(content []Content) bool {
+	for _, c := range content {
+		if c.Image != nil {
+			return true
+		}
+	}
+	return false
+}
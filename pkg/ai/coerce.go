@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CoerceOptions selects the repairs CoerceArguments applies to arguments
+// that have almost the right shape, as smaller models often produce.
+type CoerceOptions struct {
+	Numbers      bool // "42" → 42 where the schema expects a number or integer
+	Booleans     bool // "true" / "false" → true / false where it expects a boolean
+	Arrays       bool // a single value → a one-element array where it expects an array
+	JSONStrings  bool // a string holding JSON → the decoded array or object where one is expected
+	StripUnknown bool // drop properties missing from "properties" when additionalProperties is false
+}
+
+// AllCoercions enables every repair.
+var AllCoercions = CoerceOptions{Numbers: true, Booleans: true, Arrays: true, JSONStrings: true, StripUnknown: true}
+
+// CoerceArguments returns a copy of args with values converted to the
+// types the schema expects where opts allows it, and a description of each
+// change (e.g. `$.count: "42" → 42`). Values that cannot be converted are
+// left for validation to report.
+func CoerceArguments(schema ToolSchema, args map[string]any, opts CoerceOptions) (map[string]any, []string) {
+	var changes []string
+	out, _ := coerceValue(schema, args, "$", opts, &changes).(map[string]any)
+	if out == nil {
+		out = args
+	}
+	return out, changes
+}
+
+func coerceValue(schema map[string]any, v any, path string, opts CoerceOptions, changes *[]string) any {
+	if schema == nil {
+		return v
+	}
+	if want := schemaTypes(schema["type"]); len(want) > 0 && !matchesType(v, want) {
+		if c, ok := coerceScalar(v, want, opts); ok {
+			*changes = append(*changes, fmt.Sprintf("%s: %s → %s", path, describe(v), describe(c)))
+			v = c
+		}
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		out := make(map[string]any, len(val))
+		for _, name := range slices.Sorted(maps.Keys(val)) {
+			x := val[name]
+			prop, ok := props[name].(map[string]any)
+			if !ok && opts.StripUnknown && props != nil && schema["additionalProperties"] == false {
+				*changes = append(*changes, fmt.Sprintf("%s.%s: removed unknown property", path, name))
+				continue
+			}
+			out[name] = coerceValue(prop, x, path+"."+name, opts, changes)
+		}
+		return out
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		out := make([]any, len(val))
+		for i, x := range val {
+			out[i] = coerceValue(items, x, fmt.Sprintf("%s[%d]", path, i), opts, changes)
+		}
+		return out
+	}
+	return v
+}
+
+// coerceScalar converts v to one of the wanted types.
+func coerceScalar(v any, want []string, opts CoerceOptions) (any, bool) {
+	s, isString := v.(string)
+	s = strings.TrimSpace(s)
+	for _, w := range want {
+		switch {
+		case isString && opts.Numbers && (w == "number" || w == "integer"):
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil || math.IsInf(f, 0) || math.IsNaN(f) || (w == "integer" && f != math.Trunc(f)) {
+				continue
+			}
+			return f, true
+		case isString && opts.Booleans && w == "boolean":
+			switch strings.ToLower(s) {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		case isString && opts.JSONStrings && (w == "array" || w == "object"):
+			var decoded any
+			if json.Unmarshal([]byte(s), &decoded) == nil && matchesType(decoded, []string{w}) {
+				return decoded, true
+			}
+		}
+	}
+	if opts.Arrays && slices.Contains(want, "array") && v != nil {
+		if _, isArray := v.([]any); !isArray {
+			if opts.JSONStrings && isString && strings.HasPrefix(s, "[") {
+				return nil, false // malformed JSON array; wrapping would hide it
+			}
+			return []any{v}, true
+		}
+	}
+	return nil, false
+}
+
+func describe(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package ai
+
+// TransformStream returns a stream built by calling fn for every event of
+// src; fn emits zero or more events in its place, so it can drop, rewrite or
+// inject events. The source's result and error are carried over. Once a
+// terminal event has been emitted the remaining source events are drained
+// without calling fn.
+func TransformStream[T any, R any](src *EventStream[T, R], fn func(e T, emit func(T))) *EventStream[T, R] {
+	out := src.derive(src.buf)
+	go func() {
+		ended := false
+		emit := func(e T) {
+			if ended {
+				return
+			}
+			ended = out.isComplete(e)
+			out.Push(e)
+		}
+		for e := range src.Events() {
+			if ended {
+				continue
+			}
+			if src.isComplete(e) {
+				out.SetErr(src.Err())
+			}
+			fn(e, emit)
+		}
+		if !ended {
+			out.SetErr(src.Err())
+			out.End(src.Result())
+		}
+	}()
+	return out
+}
+
+// MapStream returns a stream of fn applied to each event of src.
+func MapStream[T any, R any](src *EventStream[T, R], fn func(T) T) *EventStream[T, R] {
+	return TransformStream(src, func(e T, emit func(T)) { emit(fn(e)) })
+}
+
+// FilterStream returns a stream of the events of src for which keep
+// returns true. Terminal events are always kept.
+func FilterStream[T any, R any](src *EventStream[T, R], keep func(T) bool) *EventStream[T, R] {
+	return TransformStream(src, func(e T, emit func(T)) {
+		if src.isComplete(e) || keep(e) {
+			emit(e)
+		}
+	})
+}
+
+// StreamMiddleware wraps a stream, e.g. a MapStream or FilterStream call.
+type StreamMiddleware[T any, R any] func(*EventStream[T, R]) *EventStream[T, R]
+
+// Pipe applies middlewares to src in order.
+func Pipe[T any, R any](src *EventStream[T, R], middlewares ...StreamMiddleware[T, R]) *EventStream[T, R] {
+	for _, mw := range middlewares {
+		src = mw(src)
+	}
+	return src
+}
+
+// StripThinking is a FilterStream middleware that removes thinking events.
+func StripThinking(src *AssistantMessageEventStream) *AssistantMessageEventStream {
+	return FilterStream(src, func(e AssistantMessageEvent) bool {
+		switch e.Type {
+		case EventThinkingStart, EventThinkingDelta, EventThinkingEnd:
+			return false
+		}
+		return true
+	})
+}
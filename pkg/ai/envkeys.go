@@ -1,7 +1,5 @@
 package ai
 
-import "os"
-
 // providerEnvKeys maps provider names to environment variable names.
 var providerEnvKeys = map[Provider][]string{
 	ProviderOpenAI:            {"OPENAI_API_KEY"},
@@ -30,7 +28,7 @@ func GetEnvApiKey(provider Provider) string {
 		return ""
 	}
 	for _, k := range keys {
-		if v := os.Getenv(k); v != "" {
+		if v := lookupEnv(k); v != "" {
 			return v
 		}
 	}
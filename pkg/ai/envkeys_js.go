@@ -0,0 +1,24 @@
+//go:build js
+
+package ai
+
+import "syscall/js"
+
+// lookupEnv reads a variable from globalThis.process.env when the host
+// runtime provides one (Node, Deno, edge workers). Browsers have no
+// environment, so keys must be passed explicitly via StreamOptions.ApiKey.
+func lookupEnv(key string) string {
+	process := js.Global().Get("process")
+	if process.IsUndefined() || process.IsNull() {
+		return ""
+	}
+	env := process.Get("env")
+	if env.IsUndefined() || env.IsNull() {
+		return ""
+	}
+	v := env.Get(key)
+	if v.Type() != js.TypeString {
+		return ""
+	}
+	return v.String()
+}
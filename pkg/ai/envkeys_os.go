@@ -0,0 +1,10 @@
+//go:build !js
+
+package ai
+
+import "os"
+
+// lookupEnv reads a variable from the process environment.
+func lookupEnv(key string) string {
+	return os.Getenv(key)
+}
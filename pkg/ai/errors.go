@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// APIError is returned by EventStream.Err when a provider answered with a
+// non-success HTTP status, so callers can tell e.g. 401 from 429.
+type APIError struct {
+	Provider   Provider
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *APIError) Error() string {
+	if e.Provider != "" {
+		return fmt.Sprintf("%s: HTTP %d: %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// StatusCode returns the HTTP status of an *APIError in err's chain, or 0.
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
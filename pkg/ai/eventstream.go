@@ -1,16 +1,65 @@
 package ai
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStreamTimeout is returned by EventStream.WaitTimeout.
+var ErrStreamTimeout = errors.New("timed out waiting for stream result")
+
+// BufferPolicy controls what Push does when consumers fall behind.
+type BufferPolicy int
+
+const (
+	// BufferBlock makes Push block once the buffer is full (the default).
+	BufferBlock BufferPolicy = iota
+	// BufferUnbounded queues every event without limit; Push never blocks.
+	BufferUnbounded
+	// BufferDropOldestPartial keeps at most Size queued events by folding
+	// the oldest partial event into the one after it, e.g. two consecutive
+	// text deltas into one carrying both. No content is lost, so consumers
+	// rebuilding text from deltas still see all of it, only in fewer
+	// events. When nothing can be folded the queue grows; Push never blocks.
+	BufferDropOldestPartial
+)
+
+// BufferOptions configures an EventStream's buffering.
+type BufferOptions struct {
+	Size   int // channel capacity or queue bound; default 64
+	Policy BufferPolicy
+}
 
 // EventStream is a push-based, channel-backed async event stream.
 // Consumers range over Events(); producers call Push/End.
 // R is the final result type extracted from the terminal event.
 type EventStream[T any, R any] struct {
 	ch            chan T
-	once          sync.Once
-	resultCh      chan R
 	isComplete    func(T) bool
 	extractResult func(T) R
+
+	// mu serializes Push and End. closing is set once the stream has
+	// ended; later Pushes are dropped.
+	mu       sync.Mutex
+	closing  bool
+	stop     chan struct{} // BufferBlock: closed by End to release a blocked Push
+	stopOnce sync.Once
+
+	// Queued (non-blocking) policies: Push appends to queue and a pump
+	// goroutine feeds ch.
+	buf   BufferOptions
+	merge func(older, newer T) (T, bool)
+	queue []T
+	wake  chan struct{}
+
+	errMu      sync.Mutex
+	err        error
+	result     R
+	errCh      chan error
+	done       chan struct{}
+	finishOnce sync.Once
 }
 
 // NewEventStream creates an event stream.
@@ -20,33 +69,181 @@ func NewEventStream[T any, R any](
 	isComplete func(T) bool,
 	extractResult func(T) R,
 ) *EventStream[T, R] {
-	return &EventStream[T, R]{
-		ch:            make(chan T, 64),
-		resultCh:      make(chan R, 1),
+	return NewBufferedEventStream(isComplete, extractResult, BufferOptions{}, nil)
+}
+
+// NewBufferedEventStream creates an event stream with the given buffering.
+// merge folds two consecutive events into one for BufferDropOldestPartial,
+// reporting false for events that cannot be folded; nil folds none.
+func NewBufferedEventStream[T any, R any](
+	isComplete func(T) bool,
+	extractResult func(T) R,
+	buf BufferOptions,
+	merge func(older, newer T) (T, bool),
+) *EventStream[T, R] {
+	if buf.Size <= 0 {
+		buf.Size = 64
+	}
+	s := &EventStream[T, R]{
 		isComplete:    isComplete,
 		extractResult: extractResult,
+		buf:           buf,
+		merge:         merge,
+		errCh:         make(chan error, 1),
+		done:          make(chan struct{}),
 	}
+	if buf.Policy == BufferBlock {
+		s.ch = make(chan T, buf.Size)
+		s.stop = make(chan struct{})
+		return s
+	}
+	s.ch = make(chan T)
+	s.wake = make(chan struct{}, 1)
+	go s.pump()
+	return s
 }
 
 // Push sends an event to consumers. If the event is terminal the result is
-// resolved and the channel is closed.
+// resolved and the channel is closed. Pushing after the stream has ended
+// (a terminal event or End) is a no-op, so a misbehaving provider cannot
+// crash the consumer; concurrent Pushes are serialized, so none lands
+// after the terminal event.
 func (s *EventStream[T, R]) Push(event T) {
-	if s.isComplete(event) {
-		s.resultCh <- s.extractResult(event)
+	if s.wake != nil {
+		s.enqueue(event)
+		return
 	}
-	s.ch <- event
-	if s.isComplete(event) {
-		s.once.Do(func() { close(s.ch) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return
+	}
+	complete := s.isComplete(event)
+	if complete {
+		s.closing = true
+		s.finish(s.extractResult(event))
+	}
+	select {
+	case s.ch <- event:
+	case <-s.stop: // End was called while the buffer was full
+	}
+	if complete {
+		close(s.ch)
 	}
 }
 
 // End closes the stream with an explicit result (used when no terminal event).
+// Calling End on an ended stream is a no-op.
 func (s *EventStream[T, R]) End(result R) {
+	s.finish(result)
+	if s.wake != nil {
+		s.mu.Lock()
+		s.closing = true
+		s.mu.Unlock()
+		s.signal()
+		return
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closing {
+		s.closing = true
+		close(s.ch)
+	}
+}
+
+func (s *EventStream[T, R]) enqueue(event T) {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return
+	}
+	if s.isComplete(event) {
+		s.finish(s.extractResult(event))
+		s.closing = true
+	}
+	s.queue = append(s.queue, event)
+	if s.buf.Policy == BufferDropOldestPartial && len(s.queue) > s.buf.Size && s.merge != nil {
+		for i := 0; i+1 < len(s.queue); i++ {
+			if e, ok := s.merge(s.queue[i], s.queue[i+1]); ok {
+				s.queue[i] = e
+				s.queue = append(s.queue[:i+1], s.queue[i+2:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *EventStream[T, R]) signal() {
 	select {
-	case s.resultCh <- result:
+	case s.wake <- struct{}{}:
 	default:
 	}
-	s.once.Do(func() { close(s.ch) })
+}
+
+// pump delivers queued events to ch in order and closes it once the stream
+// has ended and the queue is drained.
+func (s *EventStream[T, R]) pump() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			closing := s.closing
+			s.mu.Unlock()
+			if closing {
+				close(s.ch)
+				return
+			}
+			<-s.wake
+			continue
+		}
+		e := s.queue[0]
+		var zero T
+		s.queue[0] = zero
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		s.ch <- e
+	}
+}
+
+// SetErr attaches the Go error behind a failed stream (e.g. an *APIError or
+// a transport error). Producers call it before pushing the terminal event
+// or calling End.
+func (s *EventStream[T, R]) SetErr(err error) {
+	s.errMu.Lock()
+	s.err = err
+	s.errMu.Unlock()
+}
+
+// Err blocks until the stream has ended and returns the error attached with
+// SetErr, or nil if it completed normally.
+func (s *EventStream[T, R]) Err() error {
+	<-s.done
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+// ErrChan returns a channel that receives the stream's error, if any, and
+// is closed once the stream has ended.
+func (s *EventStream[T, R]) ErrChan() <-chan error {
+	return s.errCh
+}
+
+// finish marks the stream as ended and publishes its result and error.
+// Only the first call has an effect.
+func (s *EventStream[T, R]) finish(result R) {
+	s.finishOnce.Do(func() {
+		s.errMu.Lock()
+		s.result = result
+		if s.err != nil {
+			s.errCh <- s.err
+		}
+		s.errMu.Unlock()
+		close(s.errCh)
+		close(s.done)
+	})
 }
 
 // Events returns a channel that yields events until the stream ends.
@@ -54,9 +251,45 @@ func (s *EventStream[T, R]) Events() <-chan T {
 	return s.ch
 }
 
-// Result blocks until the final result is available.
+// Result blocks until the final result is available. It may be called any
+// number of times and returns the result of the first terminal event or End
+// (which may be nil).
 func (s *EventStream[T, R]) Result() R {
-	return <-s.resultCh
+	<-s.done
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.result
+}
+
+// ResultCtx is Result bounded by ctx, for producers that may die without
+// ending the stream. It returns ctx.Err() if ctx is done first.
+func (s *EventStream[T, R]) ResultCtx(ctx context.Context) (R, error) {
+	select {
+	case <-s.done:
+		return s.Result(), nil
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// WaitTimeout is Result bounded by d. It returns ErrStreamTimeout if the
+// stream has not ended in time.
+func (s *EventStream[T, R]) WaitTimeout(d time.Duration) (R, error) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-s.done:
+		return s.Result(), nil
+	case <-t.C:
+		var zero R
+		return zero, ErrStreamTimeout
+	}
+}
+
+// Done returns a channel that is closed once the stream has ended.
+func (s *EventStream[T, R]) Done() <-chan struct{} {
+	return s.done
 }
 
 // ---------------------------------------------------------------------------
@@ -69,7 +302,23 @@ type AssistantMessageEventStream = EventStream[AssistantMessageEvent, *Assistant
 
 // NewAssistantMessageEventStream creates a stream for assistant message events.
 func NewAssistantMessageEventStream() *AssistantMessageEventStream {
-	return NewEventStream[AssistantMessageEvent, *AssistantMessage](
+	return NewBufferedAssistantMessageEventStream(BufferOptions{})
+}
+
+// NewAssistantMessageEventStreamFor creates a stream using the buffering
+// requested in opts (nil or unset uses the default).
+func NewAssistantMessageEventStreamFor(opts *StreamOptions) *AssistantMessageEventStream {
+	if opts == nil || opts.Buffer == nil {
+		return NewAssistantMessageEventStream()
+	}
+	return NewBufferedAssistantMessageEventStream(*opts.Buffer)
+}
+
+// NewBufferedAssistantMessageEventStream creates an assistant stream with the
+// given buffering. BufferDropOldestPartial folds consecutive text, thinking
+// and tool-call deltas of the same content block.
+func NewBufferedAssistantMessageEventStream(buf BufferOptions) *AssistantMessageEventStream {
+	return NewBufferedEventStream[AssistantMessageEvent, *AssistantMessage](
 		func(e AssistantMessageEvent) bool {
 			return e.Type == EventDone || e.Type == EventError
 		},
@@ -82,5 +331,22 @@ func NewAssistantMessageEventStream() *AssistantMessageEventStream {
 			}
 			return nil
 		},
+		buf,
+		mergeDeltas,
 	)
 }
+
+// mergeDeltas folds newer into older when both are deltas of the same
+// content block: the result carries both deltas and newer's Partial.
+func mergeDeltas(older, newer AssistantMessageEvent) (AssistantMessageEvent, bool) {
+	switch older.Type {
+	case EventTextDelta, EventThinkingDelta, EventToolCallDelta:
+	default:
+		return older, false
+	}
+	if newer.Type != older.Type || newer.ContentIndex != older.ContentIndex {
+		return older, false
+	}
+	newer.Delta = older.Delta + newer.Delta
+	return newer, true
+}
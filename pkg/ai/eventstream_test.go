@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDropOldestPartialKeepsDeltaText(t *testing.T) {
+	s := NewBufferedAssistantMessageEventStream(BufferOptions{Size: 4, Policy: BufferDropOldestPartial})
+	var want strings.Builder
+	go func() {
+		s.Push(AssistantMessageEvent{Type: EventStart, Partial: &AssistantMessage{}})
+		s.Push(AssistantMessageEvent{Type: EventTextStart})
+		for i := range 500 {
+			d := fmt.Sprintf("w%d ", i)
+			want.WriteString(d)
+			s.Push(AssistantMessageEvent{Type: EventTextDelta, Delta: d})
+		}
+		s.Push(AssistantMessageEvent{Type: EventTextEnd})
+		s.Push(AssistantMessageEvent{Type: EventDone, Message: &AssistantMessage{}})
+	}()
+
+	var got strings.Builder
+	events, types := 0, map[AssistantMessageEventType]int{}
+	for e := range s.Events() {
+		events++
+		types[e.Type]++
+		got.WriteString(e.Delta)
+		if events < 10 {
+			time.Sleep(5 * time.Millisecond) // lag so that deltas pile up
+		}
+	}
+	if got.String() != want.String() {
+		t.Errorf("rebuilt text differs: got %d bytes, want %d", got.Len(), want.Len())
+	}
+	if types[EventStart] != 1 || types[EventTextStart] != 1 || types[EventTextEnd] != 1 || types[EventDone] != 1 {
+		t.Errorf("lost a non-delta event: %v", types)
+	}
+	if events >= 504 {
+		t.Errorf("got %d events; no deltas were folded", events)
+	}
+}
+
+func TestPushAfterEndIsNoop(t *testing.T) {
+	for _, policy := range []BufferPolicy{BufferBlock, BufferUnbounded, BufferDropOldestPartial} {
+		s := NewBufferedAssistantMessageEventStream(BufferOptions{Policy: policy})
+		done := &AssistantMessage{StopReason: StopReasonStop}
+		s.Push(AssistantMessageEvent{Type: EventStart})
+		s.Push(AssistantMessageEvent{Type: EventDone, Message: done})
+		s.Push(AssistantMessageEvent{Type: EventTextDelta, Delta: "late"})
+		s.End(&AssistantMessage{})
+		s.Push(AssistantMessageEvent{Type: EventTextDelta, Delta: "later"})
+
+		var types []AssistantMessageEventType
+		for e := range s.Events() {
+			types = append(types, e.Type)
+		}
+		if len(types) != 2 || types[1] != EventDone {
+			t.Errorf("policy %d: got events %v", policy, types)
+		}
+		if s.Result() != done {
+			t.Errorf("policy %d: result is not the terminal event's", policy)
+		}
+	}
+}
+
+func TestConcurrentPushNeverFollowsTerminalEvent(t *testing.T) {
+	for range 50 {
+		s := NewAssistantMessageEventStream()
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 20 {
+					s.Push(AssistantMessageEvent{Type: EventTextDelta, Delta: "x"})
+				}
+			}()
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.Push(AssistantMessageEvent{Type: EventDone, Message: &AssistantMessage{}})
+		}()
+		go func() {
+			defer wg.Done()
+			s.End(&AssistantMessage{})
+		}()
+
+		sawEnd := false
+		for e := range s.Events() {
+			if sawEnd {
+				t.Fatalf("%s arrived after the terminal event", e.Type)
+			}
+			sawEnd = e.Type == EventDone
+		}
+		wg.Wait()
+	}
+}
+
+func TestEndReleasesBlockedPush(t *testing.T) {
+	s := NewBufferedAssistantMessageEventStream(BufferOptions{Size: 1})
+	s.Push(AssistantMessageEvent{Type: EventStart})
+	pushed := make(chan struct{})
+	go func() {
+		s.Push(AssistantMessageEvent{Type: EventTextDelta}) // buffer full, nobody reading
+		close(pushed)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.End(&AssistantMessage{})
+	select {
+	case <-pushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("End did not release a blocked Push")
+	}
+}
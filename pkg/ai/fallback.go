@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+func healthKey(provider Provider, modelID string) string {
+	return provider + "\x00" + modelID
+}
+
+// MarkModelUnhealthy excludes a model from fallback resolution and model
+// suggestions for d.
+func (r *Registry) MarkModelUnhealthy(provider Provider, modelID string, d time.Duration) {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+	r.health[healthKey(provider, modelID)] = time.Now().Add(d)
+}
+
+// MarkModelHealthy clears any unhealthy mark on a model.
+func (r *Registry) MarkModelHealthy(provider Provider, modelID string) {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+	delete(r.health, healthKey(provider, modelID))
+}
+
+// IsModelHealthy returns false while a model is marked unhealthy.
+func (r *Registry) IsModelHealthy(provider Provider, modelID string) bool {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+	until, ok := r.health[healthKey(provider, modelID)]
+	if !ok {
+		return true
+	}
+	if time.Now().After(until) {
+		delete(r.health, healthKey(provider, modelID))
+		return true
+	}
+	return false
+}
+
+// ModelUnavailable reports whether a failed call means the model itself
+// could not serve it: a 5xx status, a timeout or an overload. err is the
+// stream's Err and message its ErrorMessage. Bad requests, auth failures
+// and rate limits say nothing about the model and return false.
+func ModelUnavailable(err error, message string) bool {
+	if code := StatusCode(err); code != 0 {
+		return code >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return strings.Contains(strings.ToLower(message), "overloaded")
+}
+
+// ModelChain returns m followed by its registered fallback models, in
+// order. Fallbacks of fallbacks are followed; duplicates and unregistered
+// IDs are skipped.
+func (r *Registry) ModelChain(m *Model) []*Model {
+	if m == nil {
+		return nil
+	}
+	chain := []*Model{m}
+	seen := map[string]bool{m.ID: true}
+	for i := 0; i < len(chain); i++ {
+		for _, id := range chain[i].FallbackIDs {
+			fb := r.GetModel(chain[i].Provider, id)
+			if fb == nil || seen[fb.ID] {
+				continue
+			}
+			seen[fb.ID] = true
+			chain = append(chain, fb)
+		}
+	}
+	return chain
+}
+
+// ResolveWithFallback returns the first registered, healthy model in the
+// fallback chain starting at provider/modelID that is not past its sunset
+// date, or nil if none qualifies. Deprecated models are still served by
+// their provider and are not skipped.
+func (r *Registry) ResolveWithFallback(provider Provider, modelID string) *Model {
+	now := time.Now()
+	for _, m := range r.ModelChain(r.GetModel(provider, modelID)) {
+		if r.IsModelHealthy(m.Provider, m.ID) && !IsSunset(m, now) {
+			return m
+		}
+	}
+	return nil
+}
+
+// ModelChain returns the fallback chain of m in the default registry.
+func ModelChain(m *Model) []*Model { return defaultRegistry.ModelChain(m) }
+
+// ResolveWithFallback resolves a fallback chain in the default registry.
+func ResolveWithFallback(provider Provider, modelID string) *Model {
+	return defaultRegistry.ResolveWithFallback(provider, modelID)
+}
+
+// MarkModelUnhealthy marks a model unhealthy in the default registry.
+func MarkModelUnhealthy(provider Provider, modelID string, d time.Duration) {
+	defaultRegistry.MarkModelUnhealthy(provider, modelID, d)
+}
+
+// MarkModelHealthy clears an unhealthy mark in the default registry.
+func MarkModelHealthy(provider Provider, modelID string) {
+	defaultRegistry.MarkModelHealthy(provider, modelID)
+}
+
+// IsModelHealthy reports a model's health in the default registry.
+func IsModelHealthy(provider Provider, modelID string) bool {
+	return defaultRegistry.IsModelHealthy(provider, modelID)
+}
@@ -0,0 +1,17 @@
+package ai
+
+import "testing"
+
+func TestResolveWithFallbackSkipsOnlySunsetModels(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterModel(&Model{ID: "old", Provider: "p", Deprecated: true, FallbackIDs: []string{"new"}}, "test")
+	r.RegisterModel(&Model{ID: "gone", Provider: "p", SunsetDate: "2020-01-01", FallbackIDs: []string{"new"}}, "test")
+	r.RegisterModel(&Model{ID: "new", Provider: "p"}, "test")
+
+	if m := r.ResolveWithFallback("p", "old"); m == nil || m.ID != "old" {
+		t.Errorf("deprecated model resolved to %v, want itself", m)
+	}
+	if m := r.ResolveWithFallback("p", "gone"); m == nil || m.ID != "new" {
+		t.Errorf("sunset model resolved to %v, want its fallback", m)
+	}
+}
@@ -0,0 +1,313 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FineTuneStatus is the normalized state of a fine-tuning job. Statuses
+// the provider reports that have no normalized form are passed through
+// as is and count as not done.
+type FineTuneStatus string
+
+const (
+	FineTuneQueued    FineTuneStatus = "queued"
+	FineTuneRunning   FineTuneStatus = "running"
+	FineTuneSucceeded FineTuneStatus = "succeeded"
+	FineTuneFailed    FineTuneStatus = "failed"
+	FineTuneCancelled FineTuneStatus = "cancelled"
+)
+
+// Done reports whether the status is terminal.
+func (s FineTuneStatus) Done() bool {
+	return s == FineTuneSucceeded || s == FineTuneFailed || s == FineTuneCancelled
+}
+
+// FineTuneJob describes a provider fine-tuning job.
+type FineTuneJob struct {
+	ID             string         `json:"id"`
+	BaseModel      string         `json:"baseModel"`
+	Status         FineTuneStatus `json:"status"`
+	FineTunedModel string         `json:"fineTunedModel,omitempty"` // set once succeeded
+	TrainedTokens  int            `json:"trainedTokens,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	CreatedAt      int64          `json:"createdAt"` // Unix ms
+}
+
+// FineTuneRequest configures a new job.
+type FineTuneRequest struct {
+	BaseModel      string
+	TrainingFile   string // file ID from UploadFile
+	ValidationFile string // optional file ID
+	Suffix         string // appended to the tuned model's name, if supported
+	Epochs         int    // 0 lets the provider choose
+}
+
+// FineTuner wraps a provider's fine-tuning API.
+type FineTuner interface {
+	// UploadFile uploads JSONL training data and returns its file ID.
+	UploadFile(ctx context.Context, name string, data io.Reader) (string, error)
+	CreateJob(ctx context.Context, req FineTuneRequest) (*FineTuneJob, error)
+	GetJob(ctx context.Context, id string) (*FineTuneJob, error)
+	CancelJob(ctx context.Context, id string) (*FineTuneJob, error)
+}
+
+// OpenAIFineTuner implements FineTuner for the OpenAI fine-tuning API and
+// compatible services.
+type OpenAIFineTuner struct {
+	APIKey    string    // default: the OpenAI key from the environment
+	BaseURL   string    // default "https://api.openai.com/v1"
+	Provider  Provider  // reported in errors (default ProviderOpenAI)
+	Transport Transport // nil uses the default transport
+}
+
+// openAIJob is the wire form of a fine-tuning job.
+type openAIJob struct {
+	ID             string `json:"id"`
+	Model          string `json:"model"`
+	Status         string `json:"status"`
+	FineTunedModel string `json:"fine_tuned_model"`
+	TrainedTokens  int    `json:"trained_tokens"`
+	CreatedAt      int64  `json:"created_at"` // Unix seconds
+	Error          *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (j *openAIJob) job() *FineTuneJob {
+	out := &FineTuneJob{
+		ID:             j.ID,
+		BaseModel:      j.Model,
+		FineTunedModel: j.FineTunedModel,
+		TrainedTokens:  j.TrainedTokens,
+		CreatedAt:      j.CreatedAt * 1000,
+	}
+	switch j.Status {
+	case "validating_files", "queued":
+		out.Status = FineTuneQueued
+	case "running":
+		out.Status = FineTuneRunning
+	case "succeeded":
+		out.Status = FineTuneSucceeded
+	case "failed":
+		out.Status = FineTuneFailed
+	case "cancelled":
+		out.Status = FineTuneCancelled
+	default:
+		out.Status = FineTuneStatus(j.Status)
+	}
+	if j.Error != nil {
+		out.Error = j.Error.Message
+	}
+	return out
+}
+
+// UploadFile implements FineTuner.
+func (f *OpenAIFineTuner) UploadFile(ctx context.Context, name string, data io.Reader) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("purpose", "fine-tune"); err != nil {
+		return "", err
+	}
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, data); err != nil {
+		return "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := f.do(ctx, http.MethodPost, "/files", mw.FormDataContentType(), &body, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// CreateJob implements FineTuner.
+func (f *OpenAIFineTuner) CreateJob(ctx context.Context, req FineTuneRequest) (*FineTuneJob, error) {
+	payload := map[string]any{"model": req.BaseModel, "training_file": req.TrainingFile}
+	if req.ValidationFile != "" {
+		payload["validation_file"] = req.ValidationFile
+	}
+	if req.Suffix != "" {
+		payload["suffix"] = req.Suffix
+	}
+	if req.Epochs > 0 {
+		payload["hyperparameters"] = map[string]any{"n_epochs": req.Epochs}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var j openAIJob
+	if err := f.do(ctx, http.MethodPost, "/fine_tuning/jobs", "application/json", bytes.NewReader(data), &j); err != nil {
+		return nil, err
+	}
+	return j.job(), nil
+}
+
+// GetJob implements FineTuner.
+func (f *OpenAIFineTuner) GetJob(ctx context.Context, id string) (*FineTuneJob, error) {
+	var j openAIJob
+	if err := f.do(ctx, http.MethodGet, "/fine_tuning/jobs/"+url.PathEscape(id), "", nil, &j); err != nil {
+		return nil, err
+	}
+	return j.job(), nil
+}
+
+// CancelJob implements FineTuner.
+func (f *OpenAIFineTuner) CancelJob(ctx context.Context, id string) (*FineTuneJob, error) {
+	var j openAIJob
+	if err := f.do(ctx, http.MethodPost, "/fine_tuning/jobs/"+url.PathEscape(id)+"/cancel", "", nil, &j); err != nil {
+		return nil, err
+	}
+	return j.job(), nil
+}
+
+func (f *OpenAIFineTuner) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	provider := f.Provider
+	if provider == "" {
+		provider = ProviderOpenAI
+	}
+	key := f.APIKey
+	if key == "" {
+		key = GetEnvApiKey(provider)
+	}
+	base := f.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := GetTransport(f.Transport).Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// FineTuneOptions configures Registry.FineTune and WaitForFineTune.
+type FineTuneOptions struct {
+	FileName      string        // uploaded file name (default "train.jsonl")
+	Validation    io.Reader     // optional validation JSONL
+	Suffix        string        // see FineTuneRequest
+	Epochs        int           // see FineTuneRequest
+	PollInterval  time.Duration // default 30s
+	OnStatus      func(*FineTuneJob)
+	SourceID      string     // registry source of the tuned model (default "fine-tune")
+	CancelOnAbort bool       // cancel the job when ctx is cancelled while waiting
+	Cost          *ModelCost // pricing of the tuned model; nil leaves it unknown (zero)
+}
+
+// FineTune runs the whole lifecycle: upload training (and validation)
+// data, create a job on base, wait for it, and register the tuned model.
+// The returned model copies base's metadata, except its pricing, under the
+// tuned model's ID.
+func (r *Registry) FineTune(ctx context.Context, ft FineTuner, base *Model, training io.Reader, opts FineTuneOptions) (*Model, error) {
+	name := opts.FileName
+	if name == "" {
+		name = "train.jsonl"
+	}
+	trainID, err := ft.UploadFile(ctx, name, training)
+	if err != nil {
+		return nil, fmt.Errorf("upload training file: %w", err)
+	}
+	req := FineTuneRequest{BaseModel: base.ID, TrainingFile: trainID, Suffix: opts.Suffix, Epochs: opts.Epochs}
+	if opts.Validation != nil {
+		if req.ValidationFile, err = ft.UploadFile(ctx, "validation-"+name, opts.Validation); err != nil {
+			return nil, fmt.Errorf("upload validation file: %w", err)
+		}
+	}
+	job, err := ft.CreateJob(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("create fine-tuning job: %w", err)
+	}
+	return r.WaitForFineTune(ctx, ft, job.ID, base, opts)
+}
+
+// WaitForFineTune polls job id until it finishes. On success the tuned
+// model is registered (a copy of base with the tuned ID and opts.Cost, since
+// tuned models are priced differently) and returned.
+func (r *Registry) WaitForFineTune(ctx context.Context, ft FineTuner, id string, base *Model, opts FineTuneOptions) (*Model, error) {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for {
+		job, err := ft.GetJob(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("poll fine-tuning job %s: %w", id, err)
+		}
+		if opts.OnStatus != nil {
+			opts.OnStatus(job)
+		}
+		switch job.Status {
+		case FineTuneSucceeded:
+			if job.FineTunedModel == "" {
+				return nil, fmt.Errorf("fine-tuning job %s succeeded without a model", id)
+			}
+			m := *base
+			m.ID = job.FineTunedModel
+			m.Name = base.Name + " (fine-tuned)"
+			m.Deprecated, m.DeprecationDate, m.SunsetDate, m.Successor = false, "", "", ""
+			m.Cost = ModelCost{}
+			if opts.Cost != nil {
+				m.Cost = *opts.Cost
+			}
+			source := opts.SourceID
+			if source == "" {
+				source = "fine-tune"
+			}
+			r.RegisterModel(&m, source)
+			return &m, nil
+		case FineTuneFailed, FineTuneCancelled:
+			return nil, fmt.Errorf("fine-tuning job %s %s: %s", id, job.Status, job.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			if opts.CancelOnAbort {
+				_, _ = ft.CancelJob(context.WithoutCancel(ctx), id)
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// FineTune runs a fine-tuning lifecycle against the default registry.
+func FineTune(ctx context.Context, ft FineTuner, base *Model, training io.Reader, opts FineTuneOptions) (*Model, error) {
+	return defaultRegistry.FineTune(ctx, ft, base, training, opts)
+}
+
+// WaitForFineTune waits for a job and registers the tuned model in the
+// default registry.
+func WaitForFineTune(ctx context.Context, ft FineTuner, id string, base *Model, opts FineTuneOptions) (*Model, error) {
+	return defaultRegistry.WaitForFineTune(ctx, ft, id, base, opts)
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWaitForFineTunePollsUnknownStatuses(t *testing.T) {
+	statuses := []string{"validating_files", "pending_review", "succeeded"}
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/fine_tuning/jobs/ft%2Fjob%201" {
+			http.Error(w, "bad path "+r.URL.EscapedPath(), http.StatusNotFound)
+			return
+		}
+		status := statuses[min(polls, len(statuses)-1)]
+		polls++
+		fmt.Fprintf(w, `{"id":"ft/job 1","model":"base","status":%q,"fine_tuned_model":"ft:base:x"}`, status)
+	}))
+	defer srv.Close()
+
+	r := NewRegistry()
+	base := &Model{ID: "base", Name: "Base", Provider: ProviderOpenAI, Cost: ModelCost{Input: 1, Output: 2}}
+	var seen []FineTuneStatus
+	m, err := r.WaitForFineTune(context.Background(), &OpenAIFineTuner{BaseURL: srv.URL, APIKey: "k"}, "ft/job 1", base, FineTuneOptions{
+		PollInterval: 1,
+		OnStatus:     func(j *FineTuneJob) { seen = append(seen, j.Status) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 3 || seen[1] != "pending_review" || seen[1].Done() {
+		t.Errorf("statuses = %v", seen)
+	}
+	if m.ID != "ft:base:x" || m.Cost != (ModelCost{}) {
+		t.Errorf("tuned model = %s with cost %+v, want no inherited cost", m.ID, m.Cost)
+	}
+	if r.GetModel(ProviderOpenAI, "ft:base:x") == nil {
+		t.Error("tuned model not registered")
+	}
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// HashMessage returns the hex SHA-256 of a message's canonical JSON
+// encoding. Struct fields encode in declaration order and map keys sorted,
+// so equal messages always hash equally.
+func HashMessage(m Message) (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("hash message: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainHash links a message hash to the previous chain hash:
+// sha256(prev || ":" || HashMessage(m)). Use "" as prev for the first message.
+func ChainHash(prev string, m Message) (string, error) {
+	h, err := HashMessage(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(prev + ":" + h))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashTranscript returns the chain hash after each message. The last
+// element is the head hash identifying the whole transcript.
+func HashTranscript(messages []Message) ([]string, error) {
+	out := make([]string, len(messages))
+	prev := ""
+	for i, m := range messages {
+		h, err := ChainHash(prev, m)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		out[i] = h
+		prev = h
+	}
+	return out, nil
+}
+
+// VerifyTranscript recomputes the chain over messages and compares it with
+// hashes. It returns an error naming the first message that does not match.
+func VerifyTranscript(messages []Message, hashes []string) error {
+	if len(messages) != len(hashes) {
+		return fmt.Errorf("transcript has %d messages but %d hashes", len(messages), len(hashes))
+	}
+	got, err := HashTranscript(messages)
+	if err != nil {
+		return err
+	}
+	for i := range got {
+		if got[i] != hashes[i] {
+			return fmt.Errorf("transcript tampered at message %d", i)
+		}
+	}
+	return nil
+}
+
+// SignTranscript signs a transcript head hash.
+func SignTranscript(key ed25519.PrivateKey, head string) []byte {
+	return ed25519.Sign(key, []byte(head))
+}
+
+// VerifyTranscriptSignature checks a signature produced by SignTranscript.
+func VerifyTranscriptSignature(key ed25519.PublicKey, head string, sig []byte) bool {
+	return ed25519.Verify(key, []byte(head), sig)
+}
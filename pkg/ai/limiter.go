@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Priority classifies a request for a Limiter. Lower values are served
+// first; the zero value is PriorityInteractive.
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota // user-facing prompts
+	PriorityBackground                  // batch and background runs
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+type priorityKey struct{}
+
+// WithPriority tags ctx with a request priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority tagged on ctx, or PriorityInteractive.
+func PriorityFrom(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// Limiter shares a provider's rate-limit budget and connection slots
+// between requests by priority: waiting interactive requests are always
+// admitted before background ones. A request that has waited MaxWait is
+// treated as interactive, so background work is never starved.
+type Limiter struct {
+	MaxConcurrent     int           // concurrent requests; 0 means unlimited
+	RequestsPerMinute int           // admission rate; 0 means unlimited
+	MaxWait           time.Duration // starvation bound for lower priorities (default 30s)
+
+	mu      sync.Mutex
+	active  int
+	tokens  float64
+	refill  time.Time
+	waiters []*limiterWaiter
+	timer   *time.Timer
+}
+
+type limiterWaiter struct {
+	priority Priority
+	since    time.Time
+	ready    chan struct{}
+}
+
+// Acquire waits for a slot for a request with ctx's priority. The
+// returned release must be called once the request is finished.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	w := &limiterWaiter{priority: PriorityFrom(ctx), since: time.Now(), ready: make(chan struct{})}
+	l.mu.Lock()
+	l.waiters = append(l.waiters, w)
+	l.dispatchLocked()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.releaser(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Admitted concurrently with cancellation; give the slot back.
+			l.active--
+			l.dispatchLocked()
+		default:
+			l.removeLocked(w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting returns the number of queued requests per priority.
+func (l *Limiter) Waiting() map[Priority]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := map[Priority]int{}
+	for _, w := range l.waiters {
+		out[w.priority]++
+	}
+	return out
+}
+
+func (l *Limiter) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.active--
+			l.dispatchLocked()
+			l.mu.Unlock()
+		})
+	}
+}
+
+func (l *Limiter) removeLocked(w *limiterWaiter) {
+	for i, x := range l.waiters {
+		if x == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchLocked admits waiters while slots and rate budget allow.
+func (l *Limiter) dispatchLocked() {
+	now := time.Now()
+	if l.RequestsPerMinute > 0 {
+		perSec := float64(l.RequestsPerMinute) / 60
+		if l.refill.IsZero() {
+			l.tokens = 1
+		} else {
+			l.tokens += now.Sub(l.refill).Seconds() * perSec
+		}
+		l.tokens = min(l.tokens, float64(l.RequestsPerMinute))
+		l.refill = now
+	}
+	for len(l.waiters) > 0 {
+		if l.MaxConcurrent > 0 && l.active >= l.MaxConcurrent {
+			return
+		}
+		if l.RequestsPerMinute > 0 && l.tokens < 1 {
+			if l.timer == nil {
+				wait := time.Duration((1 - l.tokens) / (float64(l.RequestsPerMinute) / 60) * float64(time.Second))
+				l.timer = time.AfterFunc(wait, func() {
+					l.mu.Lock()
+					l.timer = nil
+					l.dispatchLocked()
+					l.mu.Unlock()
+				})
+			}
+			return
+		}
+		w := l.nextLocked(now)
+		l.removeLocked(w)
+		l.active++
+		if l.RequestsPerMinute > 0 {
+			l.tokens--
+		}
+		close(w.ready)
+	}
+}
+
+// nextLocked picks the waiter with the best effective priority, oldest
+// first within a class.
+func (l *Limiter) nextLocked(now time.Time) *limiterWaiter {
+	maxWait := l.MaxWait
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+	var best *limiterWaiter
+	bestPriority := Priority(0)
+	for _, w := range l.waiters {
+		p := w.priority
+		if now.Sub(w.since) >= maxWait {
+			p = PriorityInteractive
+		}
+		if best == nil || p < bestPriority {
+			best, bestPriority = w, p
+		}
+	}
+	return best
+}
+
+// Transport wraps next so that every request first acquires a slot from
+// l. The slot is held until the response body is closed, so streaming
+// responses count against MaxConcurrent for their whole duration. A nil
+// next uses HTTPTransport, so the result can be installed with
+// SetDefaultTransport.
+func (l *Limiter) Transport(next Transport) Transport {
+	if next == nil {
+		next = HTTPTransport{}
+	}
+	return TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.Do(ctx, req)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,75 @@
+package ai
+
+// defaultThinkingBudgets are the token budgets used for levels that
+// ThinkingBudgets leaves unset.
+var defaultThinkingBudgets = map[ThinkingLevel]int{
+	ThinkingMinimal: 1024,
+	ThinkingLow:     2048,
+	ThinkingMedium:  8192,
+	ThinkingHigh:    16384,
+	ThinkingXHigh:   32768,
+}
+
+// outputReserveMin is the smallest output budget FitMaxTokens will set; a
+// request with less room is left unchanged and surfaces as an overflow.
+const outputReserveMin = 256
+
+// Budget returns the thinking token budget for level, falling back to the
+// defaults for unset levels. Off (or "") is 0. b may be nil.
+func (b *ThinkingBudgets) Budget(level ThinkingLevel) int {
+	var v *int
+	if b != nil {
+		switch level {
+		case ThinkingMinimal:
+			v = b.Minimal
+		case ThinkingLow:
+			v = b.Low
+		case ThinkingMedium:
+			v = b.Medium
+		case ThinkingHigh, ThinkingXHigh:
+			v = b.High
+		}
+	}
+	if v != nil {
+		return *v
+	}
+	return defaultThinkingBudgets[level]
+}
+
+// OutputBudget returns the largest MaxTokens that fits the call:
+// min(model.MaxTokens, ContextWindow - estimated input - thinking budget),
+// further capped by opts.MaxTokens when set. It returns 0 when the model
+// does not declare a context window.
+func OutputBudget(model *Model, llmCtx Context, opts *SimpleStreamOptions) int {
+	if model == nil || model.ContextWindow <= 0 {
+		return 0
+	}
+	room := model.ContextWindow - CountTokens(model, llmCtx)
+	if opts != nil && model.Reasoning {
+		room -= opts.ThinkingBudgets.Budget(opts.Reasoning)
+	}
+	if model.MaxTokens > 0 {
+		room = min(room, model.MaxTokens)
+	}
+	if opts != nil && opts.MaxTokens != nil {
+		room = min(room, *opts.MaxTokens)
+	}
+	return room
+}
+
+// FitMaxTokens returns a copy of opts whose MaxTokens is OutputBudget, so
+// that long histories do not push input plus output past the context
+// window. opts is returned unchanged when the budget is unknown or below a
+// useful minimum.
+func FitMaxTokens(model *Model, llmCtx Context, opts *SimpleStreamOptions) *SimpleStreamOptions {
+	budget := OutputBudget(model, llmCtx, opts)
+	if budget < outputReserveMin {
+		return opts
+	}
+	var out SimpleStreamOptions
+	if opts != nil {
+		out = *opts
+	}
+	out.MaxTokens = &budget
+	return &out
+}
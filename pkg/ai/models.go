@@ -1,48 +1,159 @@
 package ai
 
-import "sync"
-
-var (
-	modelRegistry   = map[Provider]map[string]*Model{}
-	modelRegistryMu sync.RWMutex
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
+// maxAliasDepth bounds alias-to-alias resolution to guard against cycles.
+const maxAliasDepth = 8
+
 // RegisterModel adds a model to the registry.
-func RegisterModel(m *Model) {
-	modelRegistryMu.Lock()
-	defer modelRegistryMu.Unlock()
-	if modelRegistry[m.Provider] == nil {
-		modelRegistry[m.Provider] = map[string]*Model{}
+// An optional sourceID can be supplied so that a batch of models can be
+// unregistered together via UnregisterModels.
+func (r *Registry) RegisterModel(m *Model, sourceID string) {
+	r.modelsMu.Lock()
+	if r.models[m.Provider] == nil {
+		r.models[m.Provider] = map[string]*Model{}
+		r.sources[m.Provider] = map[string]string{}
+	}
+	r.models[m.Provider][m.ID] = m
+	r.sources[m.Provider][m.ID] = sourceID
+	r.modelsMu.Unlock()
+	r.emit(RegistryEvent{Type: RegistryModelRegistered, Model: m, SourceID: sourceID})
+}
+
+// UnregisterModel removes a single model.
+func (r *Registry) UnregisterModel(provider Provider, modelID string) {
+	r.modelsMu.Lock()
+	e, ok := r.removeModelLocked(provider, modelID)
+	r.modelsMu.Unlock()
+	if ok {
+		r.emit(e)
+	}
+}
+
+// UnregisterModels removes all models registered with the given sourceID.
+func (r *Registry) UnregisterModels(sourceID string) {
+	r.modelsMu.Lock()
+	var events []RegistryEvent
+	for provider, ids := range r.sources {
+		for id, src := range ids {
+			if src == sourceID {
+				if e, ok := r.removeModelLocked(provider, id); ok {
+					events = append(events, e)
+				}
+			}
+		}
+	}
+	r.modelsMu.Unlock()
+	r.emit(events...)
+}
+
+func (r *Registry) removeModelLocked(provider Provider, modelID string) (RegistryEvent, bool) {
+	m, ok := r.models[provider][modelID]
+	if !ok {
+		return RegistryEvent{}, false
+	}
+	e := RegistryEvent{Type: RegistryModelUnregistered, Model: m, SourceID: r.sources[provider][modelID]}
+	delete(r.models[provider], modelID)
+	delete(r.sources[provider], modelID)
+	if len(r.models[provider]) == 0 {
+		delete(r.models, provider)
+		delete(r.sources, provider)
 	}
-	modelRegistry[m.Provider][m.ID] = m
+	return e, true
 }
 
 // GetModel returns a model by provider and id, or nil.
-func GetModel(provider Provider, modelID string) *Model {
-	modelRegistryMu.RLock()
-	defer modelRegistryMu.RUnlock()
-	if pm := modelRegistry[provider]; pm != nil {
-		return pm[modelID]
+// If modelID is a registered alias it is resolved first.
+func (r *Registry) GetModel(provider Provider, modelID string) *Model {
+	r.modelsMu.RLock()
+	defer r.modelsMu.RUnlock()
+	pm := r.models[provider]
+	if pm == nil {
+		return nil
 	}
-	return nil
+	if m := pm[modelID]; m != nil {
+		return m
+	}
+	return pm[r.resolveAliasLocked(provider, modelID)]
+}
+
+// RegisterAlias points alias at a concrete model ID (or another alias) for a
+// provider, e.g. "sonnet-latest" → "claude-sonnet-4-5-20250929". Registering
+// an existing alias again re-points it; subscribers receive an
+// alias_changed event either way.
+func (r *Registry) RegisterAlias(provider Provider, alias, modelID string) {
+	r.modelsMu.Lock()
+	if r.aliases[provider] == nil {
+		r.aliases[provider] = map[string]string{}
+	}
+	r.aliases[provider][alias] = modelID
+	r.modelsMu.Unlock()
+	r.emit(RegistryEvent{Type: RegistryAliasChanged, Provider: provider, Alias: alias, Target: modelID})
+}
+
+// UnregisterAlias removes an alias.
+func (r *Registry) UnregisterAlias(provider Provider, alias string) {
+	r.modelsMu.Lock()
+	_, ok := r.aliases[provider][alias]
+	delete(r.aliases[provider], alias)
+	r.modelsMu.Unlock()
+	if ok {
+		r.emit(RegistryEvent{Type: RegistryAliasChanged, Provider: provider, Alias: alias})
+	}
+}
+
+// Aliases returns a copy of a provider's aliases (alias → target).
+func (r *Registry) Aliases(provider Provider) map[string]string {
+	r.modelsMu.RLock()
+	defer r.modelsMu.RUnlock()
+	out := make(map[string]string, len(r.aliases[provider]))
+	for alias, target := range r.aliases[provider] {
+		out[alias] = target
+	}
+	return out
+}
+
+// ResolveAlias returns the concrete model ID an alias points to, or modelID
+// unchanged if it is not an alias.
+func (r *Registry) ResolveAlias(provider Provider, modelID string) string {
+	r.modelsMu.RLock()
+	defer r.modelsMu.RUnlock()
+	return r.resolveAliasLocked(provider, modelID)
+}
+
+func (r *Registry) resolveAliasLocked(provider Provider, modelID string) string {
+	aliases := r.aliases[provider]
+	for i := 0; i < maxAliasDepth; i++ {
+		target, ok := aliases[modelID]
+		if !ok {
+			break
+		}
+		modelID = target
+	}
+	return modelID
 }
 
 // GetProviders returns all registered provider names.
-func GetProviders() []Provider {
-	modelRegistryMu.RLock()
-	defer modelRegistryMu.RUnlock()
-	out := make([]Provider, 0, len(modelRegistry))
-	for p := range modelRegistry {
+func (r *Registry) GetProviders() []Provider {
+	r.modelsMu.RLock()
+	defer r.modelsMu.RUnlock()
+	out := make([]Provider, 0, len(r.models))
+	for p := range r.models {
 		out = append(out, p)
 	}
 	return out
 }
 
 // GetModels returns all models for a provider.
-func GetModels(provider Provider) []*Model {
-	modelRegistryMu.RLock()
-	defer modelRegistryMu.RUnlock()
-	pm := modelRegistry[provider]
+func (r *Registry) GetModels(provider Provider) []*Model {
+	r.modelsMu.RLock()
+	defer r.modelsMu.RUnlock()
+	pm := r.models[provider]
 	if pm == nil {
 		return nil
 	}
@@ -53,6 +164,128 @@ func GetModels(provider Provider) []*Model {
 	return out
 }
 
+// FindModel returns models from all providers whose ID or name matches
+// query. Matching is case-insensitive and treats '.', '_', '-', '/' and
+// spaces as equivalent, so "sonnet-4.5" finds "claude-sonnet-4-5-20250929".
+// Results are ordered exact match first, then prefix, then substring.
+func (r *Registry) FindModel(query string) []*Model {
+	q := normalizeModelQuery(query)
+	if q == "" {
+		return nil
+	}
+
+	type match struct {
+		model *Model
+		rank  int
+	}
+	var matches []match
+
+	r.modelsMu.RLock()
+	for _, pm := range r.models {
+		for _, m := range pm {
+			rank := -1
+			for _, field := range []string{m.ID, m.Name} {
+				f := normalizeModelQuery(field)
+				switch {
+				case f == q:
+					rank = 0
+				case strings.HasPrefix(f, q) && (rank < 0 || rank > 1):
+					rank = 1
+				case strings.Contains(f, q) && rank < 0:
+					rank = 2
+				}
+			}
+			if rank >= 0 {
+				matches = append(matches, match{model: m, rank: rank})
+			}
+		}
+	}
+	r.modelsMu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.model.Provider != b.model.Provider {
+			return a.model.Provider < b.model.Provider
+		}
+		return a.model.ID < b.model.ID
+	})
+	out := make([]*Model, len(matches))
+	for i, m := range matches {
+		out[i] = m.model
+	}
+	return out
+}
+
+func normalizeModelQuery(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '_', '/', ' ':
+			return '-'
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// Package-level model functions operate on the default registry.
+
+// RegisterModel adds a model to the default registry.
+func RegisterModel(m *Model, sourceID string) { defaultRegistry.RegisterModel(m, sourceID) }
+
+// UnregisterModel removes a model from the default registry.
+func UnregisterModel(provider Provider, modelID string) {
+	defaultRegistry.UnregisterModel(provider, modelID)
+}
+
+// UnregisterModels removes models by sourceID from the default registry.
+func UnregisterModels(sourceID string) { defaultRegistry.UnregisterModels(sourceID) }
+
+// GetModel returns a model from the default registry, or nil.
+func GetModel(provider Provider, modelID string) *Model {
+	return defaultRegistry.GetModel(provider, modelID)
+}
+
+// RegisterAlias registers a model alias in the default registry.
+func RegisterAlias(provider Provider, alias, modelID string) {
+	defaultRegistry.RegisterAlias(provider, alias, modelID)
+}
+
+// UnregisterAlias removes an alias from the default registry.
+func UnregisterAlias(provider Provider, alias string) {
+	defaultRegistry.UnregisterAlias(provider, alias)
+}
+
+// ResolveAlias resolves an alias in the default registry.
+func ResolveAlias(provider Provider, modelID string) string {
+	return defaultRegistry.ResolveAlias(provider, modelID)
+}
+
+// Successor returns model's successor from the default registry.
+func Successor(model *Model) *Model { return defaultRegistry.Successor(model) }
+
+// AliasSuccessors aliases deprecated models to their successors in the
+// default registry.
+func AliasSuccessors() { defaultRegistry.AliasSuccessors() }
+
+// DeprecationWarning checks model against the default registry.
+func DeprecationWarning(model *Model, now time.Time) string {
+	return defaultRegistry.DeprecationWarning(model, now)
+}
+
+// Aliases returns a provider's aliases in the default registry.
+func Aliases(provider Provider) map[string]string { return defaultRegistry.Aliases(provider) }
+
+// GetProviders returns all provider names in the default registry.
+func GetProviders() []Provider { return defaultRegistry.GetProviders() }
+
+// GetModels returns all models for a provider in the default registry.
+func GetModels(provider Provider) []*Model { return defaultRegistry.GetModels(provider) }
+
+// FindModel searches the default registry; see Registry.FindModel.
+func FindModel(query string) []*Model { return defaultRegistry.FindModel(query) }
+
 // CalculateCost computes costs on a Usage given a Model's pricing.
 func CalculateCost(model *Model, usage *Usage) Cost {
 	usage.Cost.Input = (model.Cost.Input / 1_000_000) * float64(usage.Input)
@@ -74,6 +307,91 @@ func SupportsXHigh(model *Model) bool {
 	return false
 }
 
+// modelDateLayout is the format of Model date fields.
+const modelDateLayout = "2006-01-02"
+
+// ParseModelDate parses a Model date field. ok is false if it is empty or
+// malformed.
+func ParseModelDate(s string) (t time.Time, ok bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(modelDateLayout, s)
+	return t, err == nil
+}
+
+// IsDeprecated reports whether the model is flagged Deprecated or its
+// DeprecationDate or SunsetDate has been reached at now.
+func IsDeprecated(model *Model, now time.Time) bool {
+	if model.Deprecated || IsSunset(model, now) {
+		return true
+	}
+	d, ok := ParseModelDate(model.DeprecationDate)
+	return ok && !now.Before(d)
+}
+
+// IsSunset reports whether the model's SunsetDate has been reached at now,
+// after which the provider no longer serves it.
+func IsSunset(model *Model, now time.Time) bool {
+	d, ok := ParseModelDate(model.SunsetDate)
+	return ok && !now.Before(d)
+}
+
+// Successor follows model's Successor chain and returns the first
+// registered model that is not deprecated, or nil.
+func (r *Registry) Successor(model *Model) *Model {
+	now := time.Now()
+	m := model
+	for i := 0; i < maxAliasDepth && m.Successor != ""; i++ {
+		next := r.GetModel(m.Provider, m.Successor)
+		if next == nil {
+			return nil
+		}
+		if !IsDeprecated(next, now) {
+			return next
+		}
+		m = next
+	}
+	return nil
+}
+
+// AliasSuccessors registers every deprecated model's ID as an alias of its
+// successor, so references keep working once the catalog drops the old
+// model. Aliases only apply to IDs that are not registered models.
+func (r *Registry) AliasSuccessors() {
+	now := time.Now()
+	for _, p := range r.GetProviders() {
+		for _, m := range r.GetModels(p) {
+			if !IsDeprecated(m, now) {
+				continue
+			}
+			if next := r.Successor(m); next != nil {
+				r.RegisterAlias(p, m.ID, next.ID)
+			}
+		}
+	}
+}
+
+// DeprecationWarning returns a human-readable warning if model is
+// deprecated at now, naming the successor when one is registered, or "".
+func (r *Registry) DeprecationWarning(model *Model, now time.Time) string {
+	if !IsDeprecated(model, now) {
+		return ""
+	}
+	msg := fmt.Sprintf("model %s/%s is deprecated", model.Provider, model.ID)
+	if model.SunsetDate != "" {
+		if IsSunset(model, now) {
+			msg += fmt.Sprintf(" and was sunset on %s", model.SunsetDate)
+		} else {
+			msg += fmt.Sprintf(" and will be sunset on %s", model.SunsetDate)
+		}
+	}
+	if next := r.Successor(model); next != nil {
+		msg += fmt.Sprintf("; migrate to %s", next.ID)
+	}
+	return msg
+}
+
 // ModelsAreEqual compares two models by ID and Provider.
 func ModelsAreEqual(a, b *Model) bool {
 	if a == nil || b == nil {
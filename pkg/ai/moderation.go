@@ -0,0 +1,273 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ImageVerdict is a classifier's judgement of one image.
+type ImageVerdict struct {
+	Flagged    bool               `json:"flagged"`
+	Categories []string           `json:"categories,omitempty"` // e.g. "sexual", "violence", "self-harm"
+	Scores     map[string]float64 `json:"scores,omitempty"`     // per category, if the classifier reports them
+	Reason     string             `json:"reason,omitempty"`
+}
+
+// ImageClassifier detects unsafe images. Implement it over a provider's
+// moderation API or a local model; VisionClassifier uses a vision-capable
+// chat model.
+type ImageClassifier interface {
+	Classify(ctx context.Context, img *ImageContent) (ImageVerdict, error)
+}
+
+// ImageClassifierFunc adapts a function to ImageClassifier.
+type ImageClassifierFunc func(ctx context.Context, img *ImageContent) (ImageVerdict, error)
+
+// Classify calls f.
+func (f ImageClassifierFunc) Classify(ctx context.Context, img *ImageContent) (ImageVerdict, error) {
+	return f(ctx, img)
+}
+
+// defaultModerationPrompt asks the vision model for a JSON verdict.
+const defaultModerationPrompt = `Check this image for unsafe content: sexual content, sexual content involving minors, graphic violence, self-harm, hate symbols. ` +
+	`Reply with JSON only: {"flagged": true|false, "categories": ["..."], "reason": "..."}.`
+
+// VisionClassifier classifies images by asking a vision-capable model.
+type VisionClassifier struct {
+	Model    *Model         // vision-capable model
+	Registry *Registry      // nil uses the default registry
+	Prompt   string         // must ask for the JSON verdict; has a default
+	Options  *StreamOptions // options for classification calls
+}
+
+// Classify asks the model for a verdict on img.
+func (c *VisionClassifier) Classify(ctx context.Context, img *ImageContent) (ImageVerdict, error) {
+	if c.Model == nil || !c.Model.CanUseImages() {
+		return ImageVerdict{}, fmt.Errorf("moderation model does not accept images")
+	}
+	prompt := c.Prompt
+	if prompt == "" {
+		prompt = defaultModerationPrompt
+	}
+	llmCtx := Context{Messages: []Message{NewUserMessageWithContent([]Content{
+		NewTextContent(prompt),
+		{Image: img},
+	})}}
+	s, err := RegistryOrDefault(c.Registry).StreamCtx(ctx, c.Model, llmCtx, c.Options)
+	if err != nil {
+		return ImageVerdict{}, err
+	}
+	msg := s.Result()
+	if msg == nil {
+		return ImageVerdict{}, fmt.Errorf("moderation call returned no message")
+	}
+	ReportUsage(ctx, msg.Usage)
+	if msg.StopReason == StopReasonError || msg.StopReason == StopReasonAborted {
+		return ImageVerdict{}, fmt.Errorf("moderation call failed: %s", msg.ErrorMessage)
+	}
+	var sb strings.Builder
+	for _, part := range msg.Content {
+		if part.Text != nil {
+			sb.WriteString(part.Text.Text)
+		}
+	}
+	text := sb.String()
+	if i, j := strings.Index(text, "{"), strings.LastIndex(text, "}"); i >= 0 && j > i {
+		text = text[i : j+1]
+	}
+	var v ImageVerdict
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return ImageVerdict{}, fmt.Errorf("moderation verdict is not JSON: %w", err)
+	}
+	return v, nil
+}
+
+// ModerationAction is what happens to a flagged image.
+type ModerationAction string
+
+const (
+	// ModerationBlock rejects the content holding the image.
+	ModerationBlock ModerationAction = "block"
+	// ModerationBlur replaces the image with a text reference naming the
+	// flagged categories, so the conversation can continue without it.
+	ModerationBlur ModerationAction = "blur"
+	// ModerationFlag keeps the image and only reports it.
+	ModerationFlag ModerationAction = "flag"
+)
+
+// ErrImageBlocked is matched (via errors.Is) by the error returned when a
+// ModerationBlock action applies.
+var ErrImageBlocked = errors.New("image blocked by moderation")
+
+// ImageModeration reports one flagged image.
+type ImageModeration struct {
+	Message  int              `json:"message"` // index of the message holding the image
+	Content  int              `json:"content"` // index of the image in the message content
+	Role     MessageRole      `json:"role"`
+	MimeType string           `json:"mimeType"`
+	Hash     string           `json:"hash"` // SHA-256 of the image, for audit logs
+	Verdict  ImageVerdict     `json:"verdict"`
+	Action   ModerationAction `json:"action"`
+}
+
+// ImageModerator checks the images of a conversation with a classifier and
+// applies policy actions to flagged ones. Verdicts are cached by image
+// content. It is safe for concurrent use.
+type ImageModerator struct {
+	Classifier ImageClassifier
+
+	// Action applies to flagged images (default ModerationBlur); Actions
+	// overrides it per category, the strictest matching action winning.
+	Action  ModerationAction
+	Actions map[string]ModerationAction
+
+	// FailOpen keeps images whose classification fails; by default they
+	// are treated as flagged with category "unknown".
+	FailOpen bool
+
+	mu    sync.Mutex
+	cache map[string]ImageVerdict
+}
+
+// ImageHash returns the SHA-256 of img's MIME type and data, hex encoded.
+func ImageHash(img *ImageContent) string {
+	sum := sha256.Sum256([]byte(img.MimeType + ":" + img.Data))
+	return hex.EncodeToString(sum[:])
+}
+
+// Check classifies img.
+func (m *ImageModerator) Check(ctx context.Context, img *ImageContent) (ImageVerdict, error) {
+	key := ImageHash(img)
+	m.mu.Lock()
+	if v, ok := m.cache[key]; ok {
+		m.mu.Unlock()
+		return v, nil
+	}
+	m.mu.Unlock()
+
+	v, err := m.Classifier.Classify(ctx, img)
+	if err != nil {
+		return ImageVerdict{}, err
+	}
+	m.mu.Lock()
+	if m.cache == nil {
+		m.cache = map[string]ImageVerdict{}
+	}
+	m.cache[key] = v
+	m.mu.Unlock()
+	return v, nil
+}
+
+// action returns the action for a flagged verdict.
+func (m *ImageModerator) action(v ImageVerdict) ModerationAction {
+	rank := map[ModerationAction]int{ModerationFlag: 1, ModerationBlur: 2, ModerationBlock: 3}
+	best := ModerationAction("")
+	for _, c := range v.Categories {
+		if a, ok := m.Actions[c]; ok && rank[a] > rank[best] {
+			best = a
+		}
+	}
+	if best != "" {
+		return best
+	}
+	if m.Action != "" {
+		return m.Action
+	}
+	return ModerationBlur
+}
+
+// ModerateContent checks the images in content. It returns the content
+// with blurred images replaced and a report per flagged image (Message is
+// left 0). If a flagged image is to be blocked, the error wraps
+// ErrImageBlocked.
+func (m *ImageModerator) ModerateContent(ctx context.Context, role MessageRole, content []Content) ([]Content, []ImageModeration, error) {
+	var out []Content
+	var reports []ImageModeration
+	var blocked error
+	for i, c := range content {
+		if c.Image == nil {
+			continue
+		}
+		v, err := m.Check(ctx, c.Image)
+		if err != nil {
+			if m.FailOpen {
+				continue
+			}
+			v = ImageVerdict{Flagged: true, Categories: []string{"unknown"}, Reason: err.Error()}
+		}
+		if !v.Flagged {
+			continue
+		}
+		action := m.action(v)
+		reports = append(reports, ImageModeration{Content: i, Role: role, MimeType: c.Image.MimeType, Hash: ImageHash(c.Image), Verdict: v, Action: action})
+		switch action {
+		case ModerationBlock:
+			if blocked == nil {
+				blocked = fmt.Errorf("%w: %s", ErrImageBlocked, strings.Join(v.Categories, ", "))
+			}
+		case ModerationBlur:
+			if out == nil {
+				out = append([]Content{}, content...)
+			}
+			out[i] = NewTextContent(fmt.Sprintf("[Image withheld by moderation: %s]", strings.Join(v.Categories, ", ")))
+		}
+	}
+	if out == nil {
+		out = content
+	}
+	return out, reports, blocked
+}
+
+// ModerateImages checks every image in messages. It returns the messages
+// with blurred images replaced (the input is not modified) and a report
+// per flagged image. If any image is to be blocked, the error wraps
+// ErrImageBlocked.
+func (m *ImageModerator) ModerateImages(ctx context.Context, messages []Message) ([]Message, []ImageModeration, error) {
+	out := make([]Message, len(messages))
+	var reports []ImageModeration
+	var blocked error
+	for i, msg := range messages {
+		out[i] = msg
+		var content []Content
+		switch {
+		case msg.User != nil:
+			content = msg.User.Content
+		case msg.Assistant != nil:
+			content = msg.Assistant.Content
+		case msg.ToolResult != nil:
+			content = msg.ToolResult.Content
+		}
+		if !hasImage(content) {
+			continue
+		}
+		moderated, rs, err := m.ModerateContent(ctx, msg.Role(), content)
+		if err != nil && blocked == nil {
+			blocked = err
+		}
+		for _, r := range rs {
+			r.Message = i
+			reports = append(reports, r)
+		}
+		switch {
+		case msg.User != nil:
+			u := *msg.User
+			u.Content = moderated
+			out[i] = Message{User: &u}
+		case msg.Assistant != nil:
+			a := *msg.Assistant
+			a.Content = moderated
+			out[i] = Message{Assistant: &a}
+		case msg.ToolResult != nil:
+			tr := *msg.ToolResult
+			tr.Content = moderated
+			out[i] = Message{ToolResult: &tr}
+		}
+	}
+	return out, reports, blocked
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrPolicyViolation is matched (via errors.Is) by every error produced when
+// the network policy blocks a provider, host, or tool.
+var ErrPolicyViolation = errors.New("blocked by network policy")
+
+// PolicyError describes what the network policy blocked.
+type PolicyError struct {
+	Kind  string // "host", "provider", or "tool"
+	Value string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s %q is not allowed by network policy", e.Kind, e.Value)
+}
+
+// Is reports whether target is ErrPolicyViolation.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// NetworkPolicy restricts what an offline or air-gapped deployment may reach.
+// A nil list leaves that kind unrestricted; an empty, non-nil list allows
+// nothing of that kind.
+type NetworkPolicy struct {
+	// AllowedHosts lists hostnames (optionally with port) that outbound
+	// requests may target. A leading "*." matches any subdomain.
+	AllowedHosts []string
+	// AllowedProviders lists providers whose models may be streamed.
+	AllowedProviders []Provider
+	// AllowedTools lists agent tool names that may be executed.
+	AllowedTools []string
+}
+
+var (
+	networkPolicy   *NetworkPolicy
+	networkPolicyMu sync.RWMutex
+)
+
+// SetNetworkPolicy installs a process-wide policy. nil removes it.
+func SetNetworkPolicy(p *NetworkPolicy) {
+	networkPolicyMu.Lock()
+	defer networkPolicyMu.Unlock()
+	networkPolicy = p
+}
+
+// GetNetworkPolicy returns the active policy, or nil.
+func GetNetworkPolicy() *NetworkPolicy {
+	networkPolicyMu.RLock()
+	defer networkPolicyMu.RUnlock()
+	return networkPolicy
+}
+
+// CheckHost returns a PolicyError if host (with optional port) is not allowed.
+func CheckHost(host string) error {
+	p := GetNetworkPolicy()
+	if p == nil || p.AllowedHosts == nil {
+		return nil
+	}
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	name = strings.ToLower(name)
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		switch {
+		case allowed == strings.ToLower(host), allowed == name:
+			return nil
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(name, allowed[1:]):
+			return nil
+		}
+	}
+	return &PolicyError{Kind: "host", Value: host}
+}
+
+// CheckURL returns a PolicyError if the URL's host is not allowed.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	return CheckHost(u.Host)
+}
+
+// CheckProvider returns a PolicyError if the provider is not allowed.
+func CheckProvider(provider Provider) error {
+	p := GetNetworkPolicy()
+	if p == nil || p.AllowedProviders == nil {
+		return nil
+	}
+	for _, allowed := range p.AllowedProviders {
+		if allowed == provider {
+			return nil
+		}
+	}
+	return &PolicyError{Kind: "provider", Value: provider}
+}
+
+// CheckTool returns a PolicyError if the tool is not allowed.
+func CheckTool(name string) error {
+	p := GetNetworkPolicy()
+	if p == nil || p.AllowedTools == nil {
+		return nil
+	}
+	for _, allowed := range p.AllowedTools {
+		if allowed == name {
+			return nil
+		}
+	}
+	return &PolicyError{Kind: "tool", Value: name}
+}
+
+// CheckModel returns a PolicyError if the model's provider or base URL is
+// not allowed.
+func CheckModel(model *Model) error {
+	if err := CheckProvider(model.Provider); err != nil {
+		return err
+	}
+	if model.BaseURL != "" {
+		return CheckURL(model.BaseURL)
+	}
+	return nil
+}
+
+// policyTransport rejects requests to hosts the policy does not allow.
+type policyTransport struct {
+	next Transport
+}
+
+func (t policyTransport) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := CheckHost(req.URL.Host); err != nil {
+		return nil, err
+	}
+	return t.next.Do(ctx, req)
+}
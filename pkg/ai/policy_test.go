@@ -0,0 +1,29 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNetworkPolicyEmptyListDeniesAll(t *testing.T) {
+	defer SetNetworkPolicy(nil)
+
+	SetNetworkPolicy(&NetworkPolicy{AllowedTools: []string{}})
+	if err := CheckTool("read"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("empty tool allowlist: CheckTool = %v, want a violation", err)
+	}
+	if err := CheckHost("example.com"); err != nil {
+		t.Errorf("unset host allowlist: CheckHost = %v, want nil", err)
+	}
+
+	SetNetworkPolicy(&NetworkPolicy{AllowedHosts: []string{"*.example.com"}, AllowedProviders: []Provider{}})
+	if err := CheckHost("api.example.com:443"); err != nil {
+		t.Errorf("CheckHost = %v, want allowed", err)
+	}
+	if err := CheckHost("example.org"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("CheckHost(example.org) = %v, want a violation", err)
+	}
+	if err := CheckProvider("openai"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("empty provider allowlist: CheckProvider = %v, want a violation", err)
+	}
+}
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ApiPool and ProviderPool identify virtual models created by RegisterPool.
+const (
+	ApiPool      Api      = "pool"
+	ProviderPool Provider = "pool"
+)
+
+// PoolStrategy selects which member of a pool serves a call.
+type PoolStrategy string
+
+const (
+	PoolRoundRobin    PoolStrategy = "round-robin"
+	PoolLeastCost     PoolStrategy = "least-cost"
+	PoolLowestLatency PoolStrategy = "lowest-latency"
+)
+
+// latencyWeight is the EWMA weight of the newest time-to-first-event sample.
+const latencyWeight = 0.3
+
+// Pool routes calls for a virtual model to one of several real models.
+type Pool struct {
+	Name     string
+	Strategy PoolStrategy
+	Members  []*Model
+
+	mu      sync.Mutex
+	next    int
+	latency map[*Model]time.Duration
+}
+
+// pick chooses the member for the next call.
+func (p *Pool) pick() *Model {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.Strategy {
+	case PoolLeastCost:
+		best := p.Members[0]
+		for _, m := range p.Members[1:] {
+			if m.Cost.Input+m.Cost.Output < best.Cost.Input+best.Cost.Output {
+				best = m
+			}
+		}
+		return best
+	case PoolLowestLatency:
+		var best *Model
+		for _, m := range p.Members {
+			l, ok := p.latency[m]
+			if !ok {
+				return m // measure unmeasured members first
+			}
+			if best == nil || l < p.latency[best] {
+				best = m
+			}
+		}
+		return best
+	default:
+		m := p.Members[p.next%len(p.Members)]
+		p.next++
+		return m
+	}
+}
+
+func (p *Pool) observe(m *Model, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prev, ok := p.latency[m]; ok {
+		d = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(prev))
+	}
+	p.latency[m] = d
+}
+
+// RegisterPool registers a virtual model named name whose calls are routed
+// to members according to strategy. The virtual model's limits are the
+// most restrictive of its members. The final AssistantMessage names the
+// member that answered and records the pool in RoutedVia.
+func (r *Registry) RegisterPool(name string, strategy PoolStrategy, members ...*Model) (*Model, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("pool %q has no members", name)
+	}
+	pool := &Pool{Name: name, Strategy: strategy, Members: members, latency: map[*Model]time.Duration{}}
+
+	virtual := &Model{
+		ID:            name,
+		Name:          name,
+		Api:           ApiPool,
+		Provider:      ProviderPool,
+		Reasoning:     members[0].Reasoning,
+		Input:         members[0].Input,
+		ContextWindow: members[0].ContextWindow,
+		MaxTokens:     members[0].MaxTokens,
+	}
+	for _, m := range members[1:] {
+		virtual.Reasoning = virtual.Reasoning && m.Reasoning
+		virtual.Input = intersectInputs(virtual.Input, m.Input)
+		virtual.ContextWindow = min(virtual.ContextWindow, m.ContextWindow)
+		virtual.MaxTokens = min(virtual.MaxTokens, m.MaxTokens)
+	}
+
+	r.poolsMu.Lock()
+	r.pools[name] = pool
+	r.poolsMu.Unlock()
+
+	if r.GetApiProvider(ApiPool) == nil {
+		r.RegisterApiProvider(&ApiProvider{
+			Api: ApiPool,
+			Stream: func(model *Model, ctx Context, opts *StreamOptions) *AssistantMessageEventStream {
+				return r.streamPool(model, func(m *Model) (*AssistantMessageEventStream, error) {
+					return r.Stream(m, ctx, opts)
+				})
+			},
+			StreamSimple: func(model *Model, ctx Context, opts *SimpleStreamOptions) *AssistantMessageEventStream {
+				return r.streamPool(model, func(m *Model) (*AssistantMessageEventStream, error) {
+					return r.StreamSimple(m, ctx, opts)
+				})
+			},
+			StreamCtx: func(ctx context.Context, model *Model, llmCtx Context, opts *StreamOptions) *AssistantMessageEventStream {
+				return r.streamPool(model, func(m *Model) (*AssistantMessageEventStream, error) {
+					return r.StreamCtx(ctx, m, llmCtx, opts)
+				})
+			},
+			StreamSimpleCtx: func(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions) *AssistantMessageEventStream {
+				return r.streamPool(model, func(m *Model) (*AssistantMessageEventStream, error) {
+					return r.StreamSimpleCtx(ctx, m, llmCtx, opts)
+				})
+			},
+		}, "pool")
+	}
+	r.RegisterModel(virtual, "pool")
+	return virtual, nil
+}
+
+// GetPool returns a registered pool, or nil.
+func (r *Registry) GetPool(name string) *Pool {
+	r.poolsMu.RLock()
+	defer r.poolsMu.RUnlock()
+	return r.pools[name]
+}
+
+// streamPool routes one call for a virtual model.
+func (r *Registry) streamPool(virtual *Model, start func(*Model) (*AssistantMessageEventStream, error)) *AssistantMessageEventStream {
+	out := NewAssistantMessageEventStream()
+	pool := r.GetPool(virtual.ID)
+	if pool == nil {
+		pushStreamError(out, virtual, fmt.Errorf("pool %q is not registered", virtual.ID))
+		return out
+	}
+	member := pool.pick()
+	began := time.Now()
+	src, err := start(member)
+	if err != nil {
+		pushStreamError(out, member, err)
+		return out
+	}
+
+	measured := false
+	return MapStream(src, func(e AssistantMessageEvent) AssistantMessageEvent {
+		if !measured && e.Type != EventStart {
+			measured = true
+			if e.Type != EventError {
+				pool.observe(member, time.Since(began))
+			}
+		}
+		for _, m := range []*AssistantMessage{e.Partial, e.Message, e.Error} {
+			if m != nil {
+				m.RoutedVia = virtual.ID
+			}
+		}
+		return e
+	})
+}
+
+// intersectInputs returns the input types both a and b accept. A nil list
+// is unknown and accepts anything the other does.
+func intersectInputs(a, b []string) []string {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := []string{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				out = append(out, x)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// pushStreamError terminates s with err, attributed to model.
+func pushStreamError(s *AssistantMessageEventStream, model *Model, err error) {
+	msg := &AssistantMessage{
+		Role:         RoleAssistant,
+		Content:      []Content{},
+		Api:          model.Api,
+		Provider:     model.Provider,
+		Model:        model.ID,
+		StopReason:   StopReasonError,
+		ErrorMessage: err.Error(),
+		Timestamp:    time.Now().UnixMilli(),
+	}
+	s.SetErr(err)
+	s.Push(AssistantMessageEvent{Type: EventError, Reason: StopReasonError, Error: msg})
+}
+
+// RegisterPool registers a routing pool in the default registry.
+func RegisterPool(name string, strategy PoolStrategy, members ...*Model) (*Model, error) {
+	return defaultRegistry.RegisterPool(name, strategy, members...)
+}
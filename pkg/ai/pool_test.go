@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+type poolTestKey struct{}
+
+func TestPoolPassesContextToMember(t *testing.T) {
+	r := NewRegistry()
+	var got any
+	r.RegisterApiProvider(&ApiProvider{
+		Api: ApiOpenAICompletions,
+		Stream: func(model *Model, ctx Context, _ *StreamOptions) *AssistantMessageEventStream {
+			t.Error("member was called without the caller's context")
+			s := NewAssistantMessageEventStream()
+			s.End(&AssistantMessage{})
+			return s
+		},
+		StreamCtx: func(ctx context.Context, model *Model, _ Context, _ *StreamOptions) *AssistantMessageEventStream {
+			got = ctx.Value(poolTestKey{})
+			s := NewAssistantMessageEventStream()
+			s.Push(AssistantMessageEvent{Type: EventDone, Reason: StopReasonStop, Message: &AssistantMessage{Model: model.ID}})
+			return s
+		},
+	}, "test")
+	member := &Model{ID: "m", Api: ApiOpenAICompletions, Provider: "test", Input: []string{"text"}}
+	r.RegisterModel(member, "test")
+	virtual, err := r.RegisterPool("p", PoolRoundRobin, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), poolTestKey{}, "caller")
+	s, err := r.StreamCtx(ctx, virtual, Context{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := s.Result()
+	if got != "caller" {
+		t.Errorf("member saw context value %v, want the caller's", got)
+	}
+	if msg.RoutedVia != "p" {
+		t.Errorf("RoutedVia = %q, want p", msg.RoutedVia)
+	}
+}
+
+func TestPoolInputs(t *testing.T) {
+	r := NewRegistry()
+	unknown := &Model{ID: "u", Api: ApiOpenAICompletions, Provider: "test"}
+	vision := &Model{ID: "v", Api: ApiOpenAICompletions, Provider: "test", Input: []string{"text", "image"}}
+	text := &Model{ID: "t", Api: ApiOpenAICompletions, Provider: "test", Input: []string{"text"}}
+
+	if !unknown.CanUseImages() {
+		t.Error("a model with unknown inputs refuses images")
+	}
+	if p, _ := r.RegisterPool("a", PoolRoundRobin, unknown, vision); !p.CanUseImages() {
+		t.Errorf("pool of unknown and vision members has inputs %v, want images", p.Input)
+	}
+	if p, _ := r.RegisterPool("b", PoolRoundRobin, vision, text); p.CanUseImages() {
+		t.Errorf("pool with a text-only member has inputs %v, want no images", p.Input)
+	}
+}
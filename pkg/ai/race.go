@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// StreamRace starts the same call on every model and streams the first one
+// to produce output (any event after EventStart), cancelling the rest. This
+// hedges latency across providers. Models that fail before producing are
+// dropped; if all of them fail the stream ends with the last failure.
+// Cancelling ctx aborts the winner as well.
+func (r *Registry) StreamRace(ctx context.Context, models []*Model, llmCtx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to race")
+	}
+	type candidate struct {
+		src    *AssistantMessageEventStream
+		cancel context.CancelFunc
+	}
+	var candidates []candidate
+	var startErr error
+	for _, m := range models {
+		cctx, cancel := context.WithCancel(ctx)
+		src, err := r.StreamSimpleCtx(cctx, m, llmCtx, opts)
+		if err != nil {
+			cancel()
+			startErr = err
+			continue
+		}
+		candidates = append(candidates, candidate{src, cancel})
+	}
+	if len(candidates) == 0 {
+		return nil, startErr
+	}
+
+	out := NewAssistantMessageEventStreamFor(raceBuffer(opts))
+	var mu sync.Mutex
+	winner := -1
+	remaining := len(candidates)
+	// claim makes i the winner if none has been chosen yet.
+	claim := func(i int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if winner == -1 {
+			winner = i
+			for j, c := range candidates {
+				if j != i {
+					c.cancel()
+				}
+			}
+		}
+		return winner == i
+	}
+	// fail records that i ended without winning and reports whether it was
+	// the last candidate standing.
+	fail := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		remaining--
+		return remaining == 0 && winner == -1
+	}
+
+	for i, c := range candidates {
+		go func() {
+			defer c.cancel()
+			var pending []AssistantMessageEvent
+			won := false
+			for e := range c.src.Events() {
+				if won {
+					if e.Type == EventError {
+						out.SetErr(c.src.Err())
+					}
+					out.Push(e)
+					continue
+				}
+				if e.Type == EventStart {
+					pending = append(pending, e)
+					continue
+				}
+				if e.Type == EventError {
+					if fail() {
+						out.SetErr(c.src.Err())
+						out.Push(e)
+					}
+					go drain(c.src)
+					return
+				}
+				if !claim(i) {
+					go drain(c.src)
+					return
+				}
+				won = true
+				for _, p := range pending {
+					out.Push(p)
+				}
+				out.Push(e)
+			}
+			if won || fail() {
+				out.SetErr(c.src.Err())
+				out.End(c.src.Result())
+			}
+		}()
+	}
+	return out, nil
+}
+
+func raceBuffer(opts *SimpleStreamOptions) *StreamOptions {
+	if opts == nil {
+		return nil
+	}
+	return &opts.StreamOptions
+}
+
+func drain(s *AssistantMessageEventStream) {
+	for range s.Events() {
+	}
+}
+
+// LabeledEvent is an event from one of the streams merged by StreamAll.
+type LabeledEvent struct {
+	Index int // position of Model in the models passed to StreamAll
+	Model *Model
+	Event AssistantMessageEvent
+}
+
+// MultiStream carries the merged events of StreamAll. Its result holds each
+// model's final message (done or error), indexed like the models.
+type MultiStream = EventStream[LabeledEvent, []*AssistantMessage]
+
+// StreamAll runs the same call on every model concurrently and merges the
+// events, labeled by model, into one stream, e.g. for side-by-side model
+// comparison. Events of one model stay in order; models that cannot start
+// contribute a single EventError. The stream ends once every model has.
+func (r *Registry) StreamAll(ctx context.Context, models []*Model, llmCtx Context, opts *SimpleStreamOptions) *MultiStream {
+	out := NewEventStream[LabeledEvent, []*AssistantMessage](
+		func(LabeledEvent) bool { return false },
+		func(LabeledEvent) []*AssistantMessage { return nil },
+	)
+	results := make([]*AssistantMessage, len(models))
+	var wg sync.WaitGroup
+	for i, m := range models {
+		src, err := r.StreamSimpleCtx(ctx, m, llmCtx, opts)
+		if err != nil {
+			src = NewAssistantMessageEventStream()
+			pushStreamError(src, m, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range src.Events() {
+				out.Push(LabeledEvent{Index: i, Model: m, Event: e})
+			}
+			results[i] = src.Result()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		out.End(results)
+	}()
+	return out
+}
+
+// StreamRace races models using the default registry.
+func StreamRace(ctx context.Context, models []*Model, llmCtx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.StreamRace(ctx, models, llmCtx, opts)
+}
+
+// StreamAll streams from all models using the default registry.
+func StreamAll(ctx context.Context, models []*Model, llmCtx Context, opts *SimpleStreamOptions) *MultiStream {
+	return defaultRegistry.StreamAll(ctx, models, llmCtx, opts)
+}
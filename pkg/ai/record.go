@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// recordedEvent is one JSONL line written by RecordStream.
+type recordedEvent struct {
+	OffsetMs int64                 `json:"t"` // since the first event
+	Event    AssistantMessageEvent `json:"event"`
+	Err      string                `json:"err,omitempty"` // stream error, on the terminal event
+}
+
+// RecordStream forwards src unchanged while writing every event to w as a
+// JSONL line with its time offset, for later ReplayStream. Recording stops
+// silently at the first write error; the stream itself is unaffected.
+func RecordStream(src *AssistantMessageEventStream, w io.Writer) *AssistantMessageEventStream {
+	enc := json.NewEncoder(w)
+	var start time.Time
+	failed := false
+	return MapStream(src, func(e AssistantMessageEvent) AssistantMessageEvent {
+		if failed {
+			return e
+		}
+		now := time.Now()
+		if start.IsZero() {
+			start = now
+		}
+		rec := recordedEvent{OffsetMs: now.Sub(start).Milliseconds(), Event: e}
+		if e.Type == EventError {
+			if err := src.Err(); err != nil {
+				rec.Err = err.Error()
+			}
+		}
+		failed = enc.Encode(rec) != nil
+		return e
+	})
+}
+
+// ReplayStream re-emits a recording made by RecordStream. speed scales the
+// original timing: 1 replays in real time, 2 twice as fast, and 0 (or less)
+// without delays. A malformed recording ends the stream with an error.
+func ReplayStream(r io.Reader, speed float64) *AssistantMessageEventStream {
+	out := NewAssistantMessageEventStream()
+	go func() {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		var prev int64
+		model := &Model{}
+		for sc.Scan() {
+			if len(sc.Bytes()) == 0 {
+				continue
+			}
+			var rec recordedEvent
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				pushStreamError(out, model, fmt.Errorf("replay: %w", err))
+				return
+			}
+			if speed > 0 && rec.OffsetMs > prev {
+				time.Sleep(time.Duration(float64(rec.OffsetMs-prev)/speed) * time.Millisecond)
+			}
+			prev = rec.OffsetMs
+			if p := rec.Event.Partial; p != nil {
+				model = &Model{ID: p.Model, Api: p.Api, Provider: p.Provider}
+			}
+			if rec.Err != "" {
+				out.SetErr(errors.New(rec.Err))
+			}
+			out.Push(rec.Event)
+			if rec.Event.Type == EventDone || rec.Event.Type == EventError {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			pushStreamError(out, model, fmt.Errorf("replay: %w", err))
+			return
+		}
+		pushStreamError(out, model, fmt.Errorf("replay: recording ended without a terminal event"))
+	}()
+	return out
+}
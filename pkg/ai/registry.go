@@ -1,6 +1,9 @@
 package ai
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 // StreamFunction is the signature for a provider's streaming function.
 type StreamFunction func(model *Model, ctx Context, opts *StreamOptions) *AssistantMessageEventStream
@@ -9,10 +12,14 @@ type StreamFunction func(model *Model, ctx Context, opts *StreamOptions) *Assist
 type StreamSimpleFunction func(model *Model, ctx Context, opts *SimpleStreamOptions) *AssistantMessageEventStream
 
 // ApiProvider bundles a provider's stream functions for a specific API.
+// The optional Ctx variants let cancellation abort in-flight requests; when
+// absent, StreamCtx/StreamSimpleCtx fall back to Stream/StreamSimple.
 type ApiProvider struct {
-	Api          Api
-	Stream       StreamFunction
-	StreamSimple StreamSimpleFunction
+	Api             Api
+	Stream          StreamFunction
+	StreamSimple    StreamSimpleFunction
+	StreamCtx       StreamFunctionCtx
+	StreamSimpleCtx StreamSimpleFunctionCtx
 }
 
 type registeredProvider struct {
@@ -20,55 +27,132 @@ type registeredProvider struct {
 	sourceID string
 }
 
-var (
-	providerRegistry   = map[Api]*registeredProvider{}
-	providerRegistryMu sync.RWMutex
-)
+// Registry holds a set of models and API providers. The package-level
+// functions operate on a process-wide default instance; create separate
+// registries with NewRegistry to run isolated configurations (e.g. one per
+// tenant) in the same process.
+type Registry struct {
+	modelsMu sync.RWMutex
+	models   map[Provider]map[string]*Model
+	sources  map[Provider]map[string]string // model sourceIDs
+	aliases  map[Provider]map[string]string
+
+	providersMu sync.RWMutex
+	providers   map[Api]*registeredProvider
+	resumers    map[Api]ResumeStrategy
+
+	poolsMu sync.RWMutex
+	pools   map[string]*Pool
+
+	listenersMu    sync.Mutex
+	listeners      map[int]func(RegistryEvent)
+	nextListenerID int
+
+	healthMu sync.Mutex
+	health   map[string]time.Time // provider/id → unhealthy until
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		models:    map[Provider]map[string]*Model{},
+		sources:   map[Provider]map[string]string{},
+		aliases:   map[Provider]map[string]string{},
+		providers: map[Api]*registeredProvider{},
+		resumers:  map[Api]ResumeStrategy{},
+		pools:     map[string]*Pool{},
+		listeners: map[int]func(RegistryEvent){},
+		health:    map[string]time.Time{},
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry used by the package-level functions.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// RegistryOrDefault returns r, or the default registry if r is nil.
+func RegistryOrDefault(r *Registry) *Registry {
+	if r != nil {
+		return r
+	}
+	return defaultRegistry
+}
 
 // RegisterApiProvider registers a provider for the given API.
 // An optional sourceID can be supplied so that a batch of providers can be
 // unregistered together via UnregisterApiProviders.
-func RegisterApiProvider(p *ApiProvider, sourceID string) {
-	providerRegistryMu.Lock()
-	defer providerRegistryMu.Unlock()
-	providerRegistry[p.Api] = &registeredProvider{provider: p, sourceID: sourceID}
+func (r *Registry) RegisterApiProvider(p *ApiProvider, sourceID string) {
+	r.providersMu.Lock()
+	r.providers[p.Api] = &registeredProvider{provider: p, sourceID: sourceID}
+	r.providersMu.Unlock()
+	r.emit(RegistryEvent{Type: RegistryProviderRegistered, Api: p.Api, SourceID: sourceID})
 }
 
 // GetApiProvider returns the registered provider for an API, or nil.
-func GetApiProvider(api Api) *ApiProvider {
-	providerRegistryMu.RLock()
-	defer providerRegistryMu.RUnlock()
-	if r := providerRegistry[api]; r != nil {
-		return r.provider
+func (r *Registry) GetApiProvider(api Api) *ApiProvider {
+	r.providersMu.RLock()
+	defer r.providersMu.RUnlock()
+	if rp := r.providers[api]; rp != nil {
+		return rp.provider
 	}
 	return nil
 }
 
 // GetApiProviders returns all registered providers.
-func GetApiProviders() []*ApiProvider {
-	providerRegistryMu.RLock()
-	defer providerRegistryMu.RUnlock()
-	out := make([]*ApiProvider, 0, len(providerRegistry))
-	for _, r := range providerRegistry {
-		out = append(out, r.provider)
+func (r *Registry) GetApiProviders() []*ApiProvider {
+	r.providersMu.RLock()
+	defer r.providersMu.RUnlock()
+	out := make([]*ApiProvider, 0, len(r.providers))
+	for _, rp := range r.providers {
+		out = append(out, rp.provider)
 	}
 	return out
 }
 
 // UnregisterApiProviders removes all providers with the given sourceID.
-func UnregisterApiProviders(sourceID string) {
-	providerRegistryMu.Lock()
-	defer providerRegistryMu.Unlock()
-	for api, r := range providerRegistry {
-		if r.sourceID == sourceID {
-			delete(providerRegistry, api)
+func (r *Registry) UnregisterApiProviders(sourceID string) {
+	r.providersMu.Lock()
+	var events []RegistryEvent
+	for api, rp := range r.providers {
+		if rp.sourceID == sourceID {
+			delete(r.providers, api)
+			events = append(events, RegistryEvent{Type: RegistryProviderUnregistered, Api: api, SourceID: sourceID})
 		}
 	}
+	r.providersMu.Unlock()
+	r.emit(events...)
 }
 
 // ClearApiProviders removes all registered providers.
-func ClearApiProviders() {
-	providerRegistryMu.Lock()
-	defer providerRegistryMu.Unlock()
-	providerRegistry = map[Api]*registeredProvider{}
+func (r *Registry) ClearApiProviders() {
+	r.providersMu.Lock()
+	events := make([]RegistryEvent, 0, len(r.providers))
+	for api, rp := range r.providers {
+		events = append(events, RegistryEvent{Type: RegistryProviderUnregistered, Api: api, SourceID: rp.sourceID})
+	}
+	r.providers = map[Api]*registeredProvider{}
+	r.providersMu.Unlock()
+	r.emit(events...)
 }
+
+// Package-level provider functions operate on the default registry.
+
+// RegisterApiProvider registers a provider in the default registry.
+func RegisterApiProvider(p *ApiProvider, sourceID string) {
+	defaultRegistry.RegisterApiProvider(p, sourceID)
+}
+
+// GetApiProvider returns a provider from the default registry, or nil.
+func GetApiProvider(api Api) *ApiProvider { return defaultRegistry.GetApiProvider(api) }
+
+// GetApiProviders returns all providers in the default registry.
+func GetApiProviders() []*ApiProvider { return defaultRegistry.GetApiProviders() }
+
+// UnregisterApiProviders removes providers by sourceID from the default registry.
+func UnregisterApiProviders(sourceID string) { defaultRegistry.UnregisterApiProviders(sourceID) }
+
+// ClearApiProviders removes all providers from the default registry.
+func ClearApiProviders() { defaultRegistry.ClearApiProviders() }
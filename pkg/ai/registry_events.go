@@ -0,0 +1,64 @@
+package ai
+
+// RegistryEventType discriminates registry change events.
+type RegistryEventType string
+
+const (
+	RegistryModelRegistered      RegistryEventType = "model_registered"
+	RegistryModelUnregistered    RegistryEventType = "model_unregistered"
+	RegistryProviderRegistered   RegistryEventType = "provider_registered"
+	RegistryProviderUnregistered RegistryEventType = "provider_unregistered"
+	RegistryAliasChanged         RegistryEventType = "alias_changed"
+)
+
+// RegistryEvent describes a change to a Registry's catalog.
+type RegistryEvent struct {
+	Type     RegistryEventType
+	Model    *Model // model_* events
+	Api      Api    // provider_* events
+	SourceID string
+
+	// alias_changed events. Target is "" when the alias was removed.
+	Provider Provider
+	Alias    string
+	Target   string
+}
+
+// Subscribe registers fn to be called after every catalog change. fn runs
+// synchronously on the goroutine that made the change and must not block.
+// Returns an unsubscribe function.
+func (r *Registry) Subscribe(fn func(RegistryEvent)) func() {
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+	id := r.nextListenerID
+	r.nextListenerID++
+	r.listeners[id] = fn
+	return func() {
+		r.listenersMu.Lock()
+		defer r.listenersMu.Unlock()
+		delete(r.listeners, id)
+	}
+}
+
+// emit delivers events to subscribers. Callers must not hold registry locks.
+func (r *Registry) emit(events ...RegistryEvent) {
+	if len(events) == 0 {
+		return
+	}
+	r.listenersMu.Lock()
+	fns := make([]func(RegistryEvent), 0, len(r.listeners))
+	for _, fn := range r.listeners {
+		fns = append(fns, fn)
+	}
+	r.listenersMu.Unlock()
+	for _, e := range events {
+		for _, fn := range fns {
+			fn(e)
+		}
+	}
+}
+
+// SubscribeRegistry subscribes to changes of the default registry.
+func SubscribeRegistry(fn func(RegistryEvent)) func() {
+	return defaultRegistry.Subscribe(fn)
+}
@@ -0,0 +1,418 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// ResumeStrategy continues a response whose connection dropped. It starts
+// a stream that produces only the remainder of partial, which holds the
+// content received so far (without incomplete tool calls).
+type ResumeStrategy interface {
+	Resume(ctx context.Context, r *Registry, model *Model, llmCtx Context, opts *SimpleStreamOptions, partial *AssistantMessage) (*AssistantMessageEventStream, error)
+}
+
+// ResumeFunc adapts a function to a ResumeStrategy.
+type ResumeFunc func(ctx context.Context, r *Registry, model *Model, llmCtx Context, opts *SimpleStreamOptions, partial *AssistantMessage) (*AssistantMessageEventStream, error)
+
+// Resume calls f.
+func (f ResumeFunc) Resume(ctx context.Context, r *Registry, model *Model, llmCtx Context, opts *SimpleStreamOptions, partial *AssistantMessage) (*AssistantMessageEventStream, error) {
+	return f(ctx, r, model, llmCtx, opts, partial)
+}
+
+// PrefillResume re-requests with the partial answer as a trailing
+// assistant message, which providers such as Anthropic treat as a prefill
+// to continue from.
+var PrefillResume ResumeStrategy = ResumeFunc(func(ctx context.Context, r *Registry, model *Model, llmCtx Context, opts *SimpleStreamOptions, partial *AssistantMessage) (*AssistantMessageEventStream, error) {
+	llmCtx.Messages = append(append([]Message{}, llmCtx.Messages...), Message{Assistant: prefillMessage(partial)})
+	return r.StreamSimpleCtx(ctx, model, llmCtx, opts)
+})
+
+// continuePrompt asks the model to pick up where the dropped answer ended.
+const continuePrompt = "Your previous response was cut off by a network error. " +
+	"Continue exactly where it ends, without repeating any of it or commenting on the interruption."
+
+// ContinueResume re-requests with the partial answer followed by a user
+// message asking the model to continue, for providers without prefill.
+var ContinueResume ResumeStrategy = ResumeFunc(func(ctx context.Context, r *Registry, model *Model, llmCtx Context, opts *SimpleStreamOptions, partial *AssistantMessage) (*AssistantMessageEventStream, error) {
+	llmCtx.Messages = append(append([]Message{}, llmCtx.Messages...),
+		Message{Assistant: prefillMessage(partial)}, NewUserMessage(continuePrompt))
+	return r.StreamSimpleCtx(ctx, model, llmCtx, opts)
+})
+
+// prefillMessage returns the text of partial as a complete assistant
+// message. Trailing whitespace is trimmed, as some providers reject it.
+func prefillMessage(partial *AssistantMessage) *AssistantMessage {
+	msg := *partial
+	msg.Content = nil
+	for _, c := range partial.Content {
+		if c.Text != nil && c.Text.Text != "" {
+			msg.Content = append(msg.Content, NewTextContent(c.Text.Text))
+		}
+	}
+	if n := len(msg.Content); n > 0 {
+		msg.Content[n-1].Text.Text = strings.TrimRightFunc(msg.Content[n-1].Text.Text, isSpace)
+	}
+	msg.StopReason, msg.ErrorMessage = StopReasonStop, ""
+	return &msg
+}
+
+func isSpace(r rune) bool { return r == ' ' || r == '\n' || r == '\t' || r == '\r' }
+
+// SetResumeStrategy sets the strategy StreamResumable uses for an API,
+// e.g. a provider-native resume. nil restores the default: PrefillResume
+// for Anthropic and Bedrock, ContinueResume otherwise.
+func (r *Registry) SetResumeStrategy(api Api, s ResumeStrategy) {
+	r.providersMu.Lock()
+	defer r.providersMu.Unlock()
+	if s == nil {
+		delete(r.resumers, api)
+		return
+	}
+	r.resumers[api] = s
+}
+
+func (r *Registry) resumeStrategy(api Api) ResumeStrategy {
+	r.providersMu.RLock()
+	s := r.resumers[api]
+	r.providersMu.RUnlock()
+	switch {
+	case s != nil:
+		return s
+	case api == ApiAnthropicMessages || api == ApiBedrockConverseStream:
+		return PrefillResume
+	}
+	return ContinueResume
+}
+
+// disconnectPatterns match error messages of dropped connections.
+var disconnectPatterns = []string{
+	"goaway", "connection reset", "broken pipe", "unexpected eof",
+	"stream error", "internal_error", "connection closed", "use of closed network connection",
+}
+
+// IsDisconnect reports whether err (or message, the error text of a
+// failed response) indicates a dropped connection rather than an API or
+// request error.
+func IsDisconnect(err error, message string) bool {
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var netErr net.Error
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.EPIPE) || errors.As(err, &netErr) {
+			return true
+		}
+		if message == "" {
+			message = err.Error()
+		}
+	}
+	message = strings.ToLower(message)
+	for _, p := range disconnectPatterns {
+		if strings.Contains(message, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamResumable is StreamSimpleCtx that survives dropped connections:
+// when a response fails mid-stream with a disconnect (see IsDisconnect),
+// up to maxResumes continuations are requested with the API's
+// ResumeStrategy and stitched onto the partial message, so consumers see
+// one uninterrupted stream. The final message has Recovered set and its
+// Usage summed over all requests. A connection that failed before any
+// text arrived (e.g. a failed dial) has nothing to continue from, and the
+// request is simply retried. Responses cut off inside a tool call are not
+// resumed. maxResumes <= 0 means 2.
+func (r *Registry) StreamResumable(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions, maxResumes int) (*AssistantMessageEventStream, error) {
+	if maxResumes <= 0 {
+		maxResumes = 2
+	}
+	src, err := r.StreamSimpleCtx(ctx, model, llmCtx, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := NewAssistantMessageEventStreamFor(raceBuffer(opts))
+	go func() {
+		st := &stitcher{open: map[int]bool{}}
+		for attempt := 0; ; attempt++ {
+			failed, partial := st.forward(src, out)
+			if failed == nil {
+				return
+			}
+			if attempt >= maxResumes || ctx.Err() != nil || !st.resumable() ||
+				!IsDisconnect(src.Err(), failed.Error.ErrorMessage) {
+				out.SetErr(src.Err())
+				out.Push(*failed)
+				out.End(failed.Error)
+				return
+			}
+			var next *AssistantMessageEventStream
+			fresh := st.acc == nil && len(prefillMessage(partial).Content) == 0
+			if fresh {
+				next, err = r.StreamSimpleCtx(ctx, model, llmCtx, opts)
+			} else {
+				next, err = r.resumeStrategy(model.Api).Resume(ctx, r, model, llmCtx, opts, partial)
+			}
+			if err != nil {
+				out.SetErr(err)
+				out.Push(*failed)
+				out.End(failed.Error)
+				return
+			}
+			if fresh {
+				st.restart(partial, out)
+			} else {
+				st.resume(model, partial, out)
+			}
+			src = next
+		}
+	}()
+	return out, nil
+}
+
+// StreamResumable starts a disconnect-resilient call using the default
+// registry.
+func StreamResumable(ctx context.Context, model *Model, llmCtx Context, opts *SimpleStreamOptions, maxResumes int) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.StreamResumable(ctx, model, llmCtx, opts, maxResumes)
+}
+
+// stitcher forwards the events of successive attempts as one stream.
+type stitcher struct {
+	acc     *MessageAccumulator // nil for the first attempt
+	offset  int                 // content index of the continuation's first block
+	merge   int                 // open text block the continuation extends, or -1
+	trim    bool                // drop leading whitespace of the merged text
+	open    map[int]bool        // block index → started but not ended
+	usage   Usage
+	toolIdx map[int]bool // open blocks that are tool calls
+	started bool         // the start event was forwarded
+}
+
+// forward copies src to out until it ends. On failure the error event is
+// withheld and returned together with the partial message.
+func (st *stitcher) forward(src, out *AssistantMessageEventStream) (*AssistantMessageEvent, *AssistantMessage) {
+	var last *AssistantMessage
+	mergedStarted := false
+	for e := range src.Events() {
+		if st.acc == nil {
+			st.track(e)
+			if e.Partial != nil {
+				last = e.Partial
+			}
+			if e.Type == EventError {
+				if e.Error != nil && len(e.Error.Content) > 0 {
+					last = e.Error
+				}
+				return &e, st.partialOf(last, e.Error)
+			}
+			if e.Type == EventStart {
+				// A retried request starts again.
+				if st.started {
+					continue
+				}
+				st.started = true
+			}
+			out.Push(e)
+			continue
+		}
+
+		// Continuation: shift indices onto the stitched message.
+		if e.Type == EventStart {
+			continue
+		}
+		orig := e.ContentIndex
+		isText := e.Type == EventTextStart || e.Type == EventTextDelta || e.Type == EventTextEnd
+		if st.merge >= 0 && !mergedStarted && (orig != 0 || !isText) {
+			// The continuation does not open with text: the cut-off
+			// block is finished as it is.
+			st.end(out, st.merge)
+			st.merge = -1
+		}
+		idx := orig + st.offset
+		if st.merge >= 0 && orig == 0 && isText {
+			idx = st.merge
+			if e.Type == EventTextStart {
+				mergedStarted = true
+				continue
+			}
+			if e.Type == EventTextDelta && st.trim {
+				e.Delta = strings.TrimLeftFunc(e.Delta, isSpace)
+				if e.Delta == "" {
+					continue
+				}
+				st.trim = false
+			}
+		} else if st.merge >= 0 && mergedStarted {
+			idx = orig + st.offset - 1
+		}
+		e.ContentIndex = idx
+		switch e.Type {
+		case EventDone, EventError:
+			var m *AssistantMessage
+			if e.Type == EventDone {
+				m = e.Message
+			} else {
+				m = e.Error
+			}
+			ev, _ := st.acc.Apply(e)
+			final := st.acc.Message()
+			if m != nil {
+				final.Usage = AddUsage(st.usage, m.Usage)
+				final.Timing = m.Timing
+				if e.Type == EventError {
+					final.ErrorMessage = m.ErrorMessage
+				}
+			}
+			if e.Type == EventError {
+				st.usage = final.Usage
+				return &ev, st.partialOf(final, final)
+			}
+			final.Recovered = true
+			out.Push(ev)
+			out.End(final)
+			return nil, nil
+		}
+		st.track(e)
+		if ev, ok := st.acc.Apply(e); ok {
+			out.Push(ev)
+		}
+	}
+	// Source closed without a terminal event: end with what we have.
+	if st.acc != nil {
+		out.End(st.acc.Message())
+	} else {
+		out.End(src.Result())
+	}
+	return nil, nil
+}
+
+// track records which blocks are open.
+func (st *stitcher) track(e AssistantMessageEvent) {
+	if st.toolIdx == nil {
+		st.toolIdx = map[int]bool{}
+	}
+	switch e.Type {
+	case EventTextStart, EventThinkingStart:
+		st.open[e.ContentIndex] = true
+	case EventToolCallStart:
+		st.open[e.ContentIndex] = true
+		st.toolIdx[e.ContentIndex] = true
+	case EventTextEnd, EventThinkingEnd, EventToolCallEnd:
+		delete(st.open, e.ContentIndex)
+		delete(st.toolIdx, e.ContentIndex)
+	}
+}
+
+// resumable reports whether no tool call was cut off.
+func (st *stitcher) resumable() bool {
+	return len(st.toolIdx) == 0
+}
+
+// partialOf returns the message to resume from, preferring errMsg when it
+// carries content.
+func (st *stitcher) partialOf(last, errMsg *AssistantMessage) *AssistantMessage {
+	if last == nil {
+		last = errMsg
+	}
+	if last == nil {
+		return &AssistantMessage{Role: RoleAssistant}
+	}
+	return last
+}
+
+// restart prepares the stitcher for a retry from scratch after partial,
+// which holds no text. Its open blocks are ended, as the retry sends the
+// whole response again from index 0.
+func (st *stitcher) restart(partial *AssistantMessage, out *AssistantMessageEventStream) {
+	for _, i := range slices.Sorted(maps.Keys(st.open)) {
+		if i >= len(partial.Content) {
+			continue
+		}
+		e := AssistantMessageEvent{ContentIndex: i, Partial: partial}
+		switch c := partial.Content[i]; {
+		case c.Thinking != nil:
+			e.Type, e.Content = EventThinkingEnd, c.Thinking.Thinking
+		case c.Text != nil:
+			e.Type, e.Content = EventTextEnd, c.Text.Text
+		default:
+			continue
+		}
+		out.Push(e)
+	}
+	st.open = map[int]bool{}
+	st.toolIdx = nil
+}
+
+// resume prepares the stitcher for a continuation of partial. Open blocks
+// other than a text block the continuation extends are ended.
+func (st *stitcher) resume(model *Model, partial *AssistantMessage, out *AssistantMessageEventStream) {
+	if st.acc == nil {
+		st.usage = partial.Usage
+	}
+	acc := NewMessageAccumulator(model)
+	msg := acc.Message()
+	msg.Content = make([]Content, len(partial.Content))
+	for i, c := range partial.Content {
+		msg.Content[i] = cloneContent(c)
+	}
+	msg.Timestamp = partial.Timestamp
+	st.acc = acc
+	st.offset = len(msg.Content)
+	st.merge = -1
+	st.trim = false
+	// Continue an open text block in place.
+	for i := range msg.Content {
+		if st.open[i] && msg.Content[i].Text != nil && i == len(msg.Content)-1 {
+			st.merge = i
+			text := msg.Content[i].Text.Text
+			st.trim = text != strings.TrimRightFunc(text, isSpace)
+		}
+	}
+	for _, i := range slices.Sorted(maps.Keys(st.open)) {
+		if i != st.merge {
+			st.end(out, i)
+		}
+	}
+}
+
+// end ends the open text or thinking block i of the stitched message.
+func (st *stitcher) end(out *AssistantMessageEventStream, i int) {
+	delete(st.open, i)
+	e := AssistantMessageEvent{ContentIndex: i}
+	switch c := st.acc.Message().Content[i]; {
+	case c.Text != nil:
+		e.Type = EventTextEnd
+	case c.Thinking != nil:
+		e.Type = EventThinkingEnd
+	default:
+		return
+	}
+	if ev, ok := st.acc.Apply(e); ok {
+		out.Push(ev)
+	}
+}
+
+func cloneContent(c Content) Content {
+	switch {
+	case c.Text != nil:
+		t := *c.Text
+		c.Text = &t
+	case c.Thinking != nil:
+		t := *c.Thinking
+		c.Thinking = &t
+	case c.ToolCall != nil:
+		t := *c.ToolCall
+		c.ToolCall = &t
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func tStart(i int) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventTextStart, ContentIndex: i}
+}
+func tDelta(i int, d string) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventTextDelta, ContentIndex: i, Delta: d}
+}
+func tEnd(i int) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventTextEnd, ContentIndex: i}
+}
+func kStart(i int) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventThinkingStart, ContentIndex: i}
+}
+func kDelta(i int, d string) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventThinkingDelta, ContentIndex: i, Delta: d}
+}
+func kEnd(i int) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventThinkingEnd, ContentIndex: i}
+}
+func callStart(i int, name string) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventToolCallStart, ContentIndex: i, ToolCallData: &ToolCall{ID: "c1", Name: name}}
+}
+func callDelta(i int, d string) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventToolCallDelta, ContentIndex: i, Delta: d}
+}
+func callEnd(i int) AssistantMessageEvent {
+	return AssistantMessageEvent{Type: EventToolCallEnd, ContentIndex: i}
+}
+
+// scriptedAttempts serves one scripted response per request. A script
+// without a trailing EventDone ends with a dropped connection.
+func scriptedAttempts(model *Model, scripts ...[]AssistantMessageEvent) func(*Model, Context, *SimpleStreamOptions) *AssistantMessageEventStream {
+	n := 0
+	return func(*Model, Context, *SimpleStreamOptions) *AssistantMessageEventStream {
+		events := scripts[n]
+		n++
+		out := NewAssistantMessageEventStream()
+		go func() {
+			acc := NewMessageAccumulator(model)
+			start, _ := acc.Apply(AssistantMessageEvent{Type: EventStart})
+			start.Partial = acc.Message()
+			out.Push(start)
+			for _, e := range events {
+				if e, ok := acc.Apply(e); ok {
+					out.Push(e)
+				}
+			}
+			if len(events) == 0 || events[len(events)-1].Type != EventDone {
+				e, _ := acc.Apply(AssistantMessageEvent{Type: EventError, Error: &AssistantMessage{ErrorMessage: "read: connection reset by peer"}})
+				out.Push(e)
+			}
+		}()
+		return out
+	}
+}
+
+func contentSummary(content []Content) []string {
+	var out []string
+	for _, c := range content {
+		switch {
+		case c.Text != nil:
+			out = append(out, "text:"+c.Text.Text)
+		case c.Thinking != nil:
+			out = append(out, "thinking:"+c.Thinking.Thinking)
+		case c.ToolCall != nil:
+			out = append(out, fmt.Sprintf("call:%s%v", c.ToolCall.Name, c.ToolCall.Arguments))
+		}
+	}
+	return out
+}
+
+func TestStreamResumableStitchesAttempts(t *testing.T) {
+	done := AssistantMessageEvent{Type: EventDone, Reason: StopReasonStop}
+	for _, tc := range []struct {
+		name     string
+		attempts [][]AssistantMessageEvent
+		want     []string
+	}{{
+		name: "drop mid-text",
+		attempts: [][]AssistantMessageEvent{
+			{tStart(0), tDelta(0, "Hello wor")},
+			{tStart(0), tDelta(0, "ld!"), tEnd(0), done},
+		},
+		want: []string{"text:Hello world!"},
+	}, {
+		name: "drop before any text",
+		attempts: [][]AssistantMessageEvent{
+			{kStart(0), kDelta(0, "hmm")},
+			{kStart(0), kDelta(0, "hmm"), kEnd(0), tStart(1), tDelta(1, "Hi"), tEnd(1), done},
+		},
+		want: []string{"thinking:hmm", "text:Hi"},
+	}, {
+		name: "drop after a finished tool call",
+		attempts: [][]AssistantMessageEvent{
+			{tStart(0), tDelta(0, "Reading."), tEnd(0), callStart(1, "read"), callDelta(1, `{"path":"a"}`), callEnd(1)},
+			{tStart(0), tDelta(0, "Done."), tEnd(0), done},
+		},
+		want: []string{"text:Reading.", "call:readmap[path:a]", "text:Done."},
+	}, {
+		name: "continuation opens with thinking",
+		attempts: [][]AssistantMessageEvent{
+			{tStart(0), tDelta(0, "Hello")},
+			{kStart(0), kDelta(0, "x"), kEnd(0), tStart(1), tDelta(1, " world"), tEnd(1), done},
+		},
+		want: []string{"text:Hello", "thinking:x", "text: world"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRegistry()
+			model := &Model{ID: "m", Api: ApiOpenAICompletions, Provider: "test"}
+			r.RegisterApiProvider(&ApiProvider{Api: ApiOpenAICompletions, StreamSimple: scriptedAttempts(model, tc.attempts...)}, "test")
+
+			s, err := r.StreamResumable(context.Background(), model, Context{}, nil, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			open := map[int]bool{}
+			for e := range s.Events() {
+				switch e.Type {
+				case EventTextStart, EventThinkingStart, EventToolCallStart:
+					if open[e.ContentIndex] {
+						t.Errorf("block %d started twice", e.ContentIndex)
+					}
+					open[e.ContentIndex] = true
+				case EventTextEnd, EventThinkingEnd, EventToolCallEnd:
+					if !open[e.ContentIndex] {
+						t.Errorf("block %d ended but not open", e.ContentIndex)
+					}
+					delete(open, e.ContentIndex)
+				}
+			}
+			if len(open) > 0 {
+				t.Errorf("blocks left open: %v", open)
+			}
+			msg := s.Result()
+			if got := contentSummary(msg.Content); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("content = %q, want %q", got, tc.want)
+			}
+			if msg.StopReason != StopReasonStop {
+				t.Errorf("stop reason = %s (%s)", msg.StopReason, msg.ErrorMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+var debugPanics atomic.Bool
+
+// SetDebugPanics controls whether recovered panics include the goroutine's
+// stack trace in the resulting ErrorMessage. Off by default.
+func SetDebugPanics(on bool) { debugPanics.Store(on) }
+
+// PanicError is the error attached to a stream whose producer panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if debugPanics.Load() {
+		return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+	}
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RecoverPanic converts a recovered panic value into a *PanicError.
+// Call as: if v := recover(); v != nil { err := RecoverPanic(v) }.
+func RecoverPanic(v any) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// SafeStream calls start and returns its stream. If start panics, the panic
+// is recovered and an already-terminated error stream is returned instead.
+func SafeStream(model *Model, start func() *AssistantMessageEventStream) (s *AssistantMessageEventStream) {
+	defer func() {
+		if v := recover(); v != nil {
+			s = NewAssistantMessageEventStream()
+			pushStreamError(s, model, RecoverPanic(v))
+		}
+	}()
+	return start()
+}
+
+// GoSafe runs fn on a new goroutine. If fn panics before s has ended, s is
+// terminated with an EventError carrying a *PanicError instead of crashing
+// the process. Providers should start their streaming goroutine with it.
+func GoSafe(s *AssistantMessageEventStream, model *Model, fn func()) {
+	go func() {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			select {
+			case <-s.Done():
+			default:
+				pushStreamError(s, model, RecoverPanic(v))
+			}
+		}()
+		fn()
+	}()
+}
@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaFor returns the JSON Schema for T, typically a struct describing
+// tool arguments or structured output.
+//
+// Struct fields are named by their json tags (fields tagged "-" and
+// unexported fields are skipped, embedded structs are flattened) and are
+// required unless they are pointers or tagged omitempty. A `jsonschema` tag
+// adds comma-separated options:
+//
+//	description=...  field description (escape commas as \,)
+//	enum=...         allowed value; repeat for each value
+//	required         force the field to be required
+//	optional         force the field to be optional
+//
+// A plain `description` tag is also honoured.
+func SchemaFor[T any]() ToolSchema {
+	return SchemaOf(reflect.TypeFor[T]())
+}
+
+// SchemaOf returns the JSON Schema for t. See SchemaFor.
+func SchemaOf(t reflect.Type) ToolSchema {
+	return schemaOf(t, map[reflect.Type]bool{})
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) ToolSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return ToolSchema{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return ToolSchema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return ToolSchema{"type": "string"}
+	case reflect.Bool:
+		return ToolSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ToolSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return ToolSchema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ToolSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return ToolSchema{"type": "array", "items": schemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		return ToolSchema{"type": "object", "additionalProperties": schemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// Recursive types are left open rather than expanded forever.
+			return ToolSchema{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		props := map[string]any{}
+		var required []any
+		addStructFields(t, props, &required, visiting)
+		s := ToolSchema{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return ToolSchema{}
+}
+
+// addStructFields adds the properties of struct type t, flattening
+// untagged embedded structs the way encoding/json does.
+func addStructFields(t reflect.Type, props map[string]any, required *[]any, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, jsonOpts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && jsonOpts == "" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			addStructFields(ft, props, required, visiting)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := schemaOf(f.Type, visiting)
+		isRequired := f.Type.Kind() != reflect.Pointer && !strings.Contains(jsonOpts, "omitempty")
+		if d := f.Tag.Get("description"); d != "" {
+			prop["description"] = d
+		}
+		var enum []any
+		for _, opt := range splitTagOptions(f.Tag.Get("jsonschema")) {
+			key, value, _ := strings.Cut(opt, "=")
+			switch strings.TrimSpace(key) {
+			case "description":
+				prop["description"] = value
+			case "enum":
+				enum = append(enum, enumValue(prop, value))
+			case "required":
+				isRequired = true
+			case "optional":
+				isRequired = false
+			}
+		}
+		if len(enum) > 0 {
+			if items, ok := prop["items"].(ToolSchema); ok && prop["type"] == "array" {
+				items["enum"] = enum
+			} else {
+				prop["enum"] = enum
+			}
+		}
+		props[name] = prop
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// splitTagOptions splits a jsonschema tag at commas, honouring \, escapes.
+func splitTagOptions(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	var out []string
+	var sb strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			out = append(out, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(tag[i])
+		}
+	}
+	return append(out, sb.String())
+}
+
+// enumValue converts an enum tag value to the JSON type of the property
+// (or of its items, for arrays).
+func enumValue(prop ToolSchema, value string) any {
+	typ := prop["type"]
+	if items, ok := prop["items"].(ToolSchema); ok && typ == "array" {
+		typ = items["type"]
+	}
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// ExampleValue builds a placeholder value that satisfies schema, for
+// showing a model the expected shape of tool arguments: enums use their
+// first value, defaults and examples are preferred, strings are
+// "<name>" placeholders and every declared property is included.
+func ExampleValue(schema ToolSchema) any {
+	return exampleValue(schema, "value", 0)
+}
+
+func exampleValue(schema map[string]any, name string, depth int) any {
+	if schema == nil || depth > 8 {
+		return nil
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if ex, ok := schema["examples"].([]any); ok && len(ex) > 0 {
+		return ex[0]
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	types := schemaTypes(schema["type"])
+	typ := ""
+	if len(types) > 0 {
+		typ = types[0]
+	} else if _, ok := schema["properties"]; ok {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		out := map[string]any{}
+		props, _ := schema["properties"].(map[string]any)
+		for key, p := range props {
+			prop, _ := p.(map[string]any)
+			out[key] = exampleValue(prop, key, depth+1)
+		}
+		return out
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		return []any{exampleValue(items, name, depth+1)}
+	case "integer", "number":
+		if lo, ok := schema["minimum"].(float64); ok {
+			return lo
+		}
+		return 1
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	}
+	return "<" + name + ">"
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SentenceChunker buffers streamed text and splits it into speakable
+// segments at sentence boundaries, for feeding TTS engines with low latency.
+type SentenceChunker struct {
+	// MinChars is the shortest segment emitted; shorter sentences are merged
+	// with the next one. Default 20.
+	MinChars int
+	// MaxChars forces a cut (at the last comma or space) when no sentence
+	// boundary appears. Default 250.
+	MaxChars int
+
+	buf strings.Builder
+}
+
+// abbreviations are words whose trailing period does not end a sentence.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true,
+	"jr": true, "st": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"inc": true, "ltd": true, "no": true, "approx": true,
+}
+
+// Write appends a text delta and returns any segments that are complete.
+func (c *SentenceChunker) Write(delta string) []string {
+	c.buf.WriteString(delta)
+	var out []string
+	for {
+		text := c.buf.String()
+		cut := c.findCut(text)
+		if cut <= 0 {
+			break
+		}
+		seg := cleanSpeech(text[:cut])
+		rest := text[cut:]
+		c.buf.Reset()
+		c.buf.WriteString(rest)
+		if seg != "" {
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+// Flush returns whatever text remains buffered.
+func (c *SentenceChunker) Flush() string {
+	seg := cleanSpeech(c.buf.String())
+	c.buf.Reset()
+	return seg
+}
+
+func (c *SentenceChunker) limits() (int, int) {
+	minChars, maxChars := c.MinChars, c.MaxChars
+	if minChars <= 0 {
+		minChars = 20
+	}
+	if maxChars <= 0 {
+		maxChars = 250
+	}
+	return minChars, maxChars
+}
+
+// findCut returns the byte offset after which text forms a complete segment,
+// or 0 if more input is needed.
+func (c *SentenceChunker) findCut(text string) int {
+	minChars, maxChars := c.limits()
+
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		if end < minChars && r != '\n' {
+			continue
+		}
+		switch r {
+		case '\n':
+			if strings.TrimSpace(text[:end]) != "" && end >= minChars/2 {
+				return end
+			}
+		case '。', '！', '？':
+			return end
+		case '.', '!', '?', ';':
+			// The boundary must be confirmed by following whitespace, which
+			// may not have arrived yet.
+			if end >= len(text) {
+				return 0
+			}
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				continue
+			}
+			if r == '.' && isAbbreviation(text[:i]) {
+				continue
+			}
+			return end
+		}
+	}
+
+	if len(text) > maxChars {
+		if i := strings.LastIndexAny(text[:maxChars], ",;:"); i > minChars {
+			return i + 1
+		}
+		if i := strings.LastIndexByte(text[:maxChars], ' '); i > 0 {
+			return i + 1
+		}
+		// Never split a multi-byte rune.
+		cut := maxChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			_, cut = utf8.DecodeRuneInString(text)
+		}
+		return cut
+	}
+	return 0
+}
+
+func isAbbreviation(before string) bool {
+	i := strings.LastIndexFunc(before, func(r rune) bool { return unicode.IsSpace(r) })
+	word := strings.ToLower(before[i+1:])
+	if len(word) == 1 && unicode.IsLetter(rune(word[0])) {
+		return true // initials like "J."
+	}
+	return abbreviations[word]
+}
+
+// cleanSpeech strips markdown markup that should not be read aloud.
+func cleanSpeech(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "#>")
+		line = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+		line = strings.TrimPrefix(line, "* ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	out := strings.Join(lines, " ")
+	out = strings.NewReplacer("**", "", "__", "", "`", "").Replace(out)
+	return strings.TrimSpace(out)
+}
+
+// SpeechChunks is stream middleware for TTS: it forwards every event from
+// src unchanged and calls onSegment with speakable segments of the text
+// content as soon as they are complete. A nil chunker uses defaults.
+func SpeechChunks(src *AssistantMessageEventStream, chunker *SentenceChunker, onSegment func(string)) *AssistantMessageEventStream {
+	if chunker == nil {
+		chunker = &SentenceChunker{}
+	}
+	return MapStream(src, func(e AssistantMessageEvent) AssistantMessageEvent {
+		switch e.Type {
+		case EventTextDelta:
+			for _, seg := range chunker.Write(e.Delta) {
+				onSegment(seg)
+			}
+		case EventTextEnd, EventDone, EventError:
+			if seg := chunker.Flush(); seg != "" {
+				onSegment(seg)
+			}
+		}
+		return e
+	})
+}
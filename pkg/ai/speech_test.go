@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSentenceChunkerForcedCutKeepsRunes(t *testing.T) {
+	c := &SentenceChunker{MaxChars: 10}
+	text := strings.Repeat("日本語", 10) // no spaces or punctuation
+	var got strings.Builder
+	for _, seg := range c.Write(text) {
+		if !utf8.ValidString(seg) {
+			t.Fatalf("segment %q is not valid UTF-8", seg)
+		}
+		got.WriteString(seg)
+	}
+	got.WriteString(c.Flush())
+	if got.String() != text {
+		t.Errorf("rebuilt %q, want %q", got.String(), text)
+	}
+}
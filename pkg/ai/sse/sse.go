@@ -0,0 +1,182 @@
+// Package sse encodes and decodes Server-Sent Events streams, the framing
+// used to ship assistant events between proxies, servers and clients.
+package sse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Message is a single server-sent event.
+type Message struct {
+	ID    string // "id" field; empty if not sent
+	Event string // "event" field; empty means "message"
+	Data  string // data lines joined with "\n"
+	Retry int    // reconnection delay in ms from the "retry" field; 0 if not sent
+}
+
+// Unmarshal decodes the message data as JSON into v.
+func (m Message) Unmarshal(v any) error {
+	return json.Unmarshal([]byte(m.Data), v)
+}
+
+// ---------------------------------------------------------------------------
+// Encoder
+// ---------------------------------------------------------------------------
+
+// Encoder writes SSE frames. If the underlying writer is an http.Flusher
+// (such as an http.ResponseWriter) it is flushed after every frame.
+type Encoder struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// NewEncoder creates an encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	f, _ := w.(http.Flusher)
+	return &Encoder{w: w, flusher: f}
+}
+
+// SetHeaders sets the response headers an SSE endpoint should send.
+func SetHeaders(h http.Header) {
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+}
+
+// Encode writes v as a JSON data frame, e.g. an ai.AssistantMessageEvent.
+func (e *Encoder) Encode(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.WriteMessage(Message{Data: string(data)})
+}
+
+// WriteMessage writes a full frame. Multi-line data is split into several
+// data fields.
+func (e *Encoder) WriteMessage(m Message) error {
+	var sb strings.Builder
+	if m.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", m.ID)
+	}
+	if m.Event != "" {
+		fmt.Fprintf(&sb, "event: %s\n", m.Event)
+	}
+	if m.Retry > 0 {
+		fmt.Fprintf(&sb, "retry: %d\n", m.Retry)
+	}
+	for _, line := range strings.Split(m.Data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteByte('\n')
+	return e.write(sb.String())
+}
+
+// Comment writes a comment line, commonly used as a keep-alive.
+func (e *Encoder) Comment(text string) error {
+	return e.write(": " + text + "\n\n")
+}
+
+func (e *Encoder) write(s string) error {
+	if _, err := io.WriteString(e.w, s); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Decoder
+// ---------------------------------------------------------------------------
+
+// Decoder reads SSE frames following the WHATWG event-stream rules:
+// multi-line data is joined, comments are skipped, and the last event ID
+// is remembered for reconnection.
+type Decoder struct {
+	r           *bufio.Reader
+	lastEventID string
+}
+
+// NewDecoder creates a decoder reading from r. Lines may be of any length.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// LastEventID returns the most recent "id" field seen, to be sent as the
+// Last-Event-ID header when reconnecting.
+func (d *Decoder) LastEventID() string {
+	return d.lastEventID
+}
+
+// Next returns the next event. It returns io.EOF at the end of the stream.
+// Unlike browsers, it also delivers a trailing event whose terminating
+// blank line never arrived, since many servers close the connection right
+// after the last data line.
+func (d *Decoder) Next() (Message, error) {
+	var m Message
+	var data []string
+	hasData := false
+	for {
+		line, err := d.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return Message{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if err == io.EOF {
+				return d.pending(m, data, hasData)
+			}
+			if !hasData {
+				m = Message{}
+				continue
+			}
+			m.Data = strings.Join(data, "\n")
+			m.ID = d.lastEventID
+			return m, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "event":
+			m.Event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastEventID = value
+			}
+		case "retry":
+			if n, err := strconv.Atoi(value); err == nil {
+				m.Retry = n
+			}
+		}
+		if err == io.EOF {
+			return d.pending(m, data, hasData)
+		}
+	}
+}
+
+// pending returns the event being built when the stream ended, or io.EOF
+// if it has no data.
+func (d *Decoder) pending(m Message, data []string, hasData bool) (Message, error) {
+	if !hasData {
+		return Message{}, io.EOF
+	}
+	m.Data = strings.Join(data, "\n")
+	m.ID = d.lastEventID
+	return m, nil
+}
@@ -0,0 +1,32 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderJoinsDataAndFlushesAtEOF(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(": hi\nid: 1\ndata: a\ndata: b\n\nevent: last\ndata: c"))
+	m, err := dec.Next()
+	if err != nil || m.Data != "a\nb" || m.ID != "1" {
+		t.Fatalf("first = %+v, %v", m, err)
+	}
+	m, err = dec.Next()
+	if err != nil || m.Data != "c" || m.Event != "last" {
+		t.Fatalf("trailing event = %+v, %v", m, err)
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Fatalf("after the last event: %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderDropsTrailingFieldsWithoutData(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("data: a\n\nevent: x\n"))
+	if m, err := dec.Next(); err != nil || m.Data != "a" {
+		t.Fatalf("first = %+v, %v", m, err)
+	}
+	if m, err := dec.Next(); err != io.EOF {
+		t.Fatalf("got %+v, %v; want io.EOF", m, err)
+	}
+}
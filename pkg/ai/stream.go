@@ -1,19 +1,41 @@
 package ai
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
+
+func errNoProvider(api Api) error {
+	return fmt.Errorf("no API provider registered for api: %s", api)
+}
+
+// instrument applies the standard middlewares to a provider stream. Pool
+// models are passed through: the member serving the call is instrumented
+// by its own Stream call.
+func instrument(s *AssistantMessageEventStream, model *Model, llmCtx Context, start time.Time) *AssistantMessageEventStream {
+	if model.Api == ApiPool {
+		return s
+	}
+	return WithTiming(BackfillUsage(s, model, llmCtx), start)
+}
 
 // Stream starts a streaming LLM call using the provider-level API.
-func Stream(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessageEventStream, error) {
-	p := GetApiProvider(model.Api)
+func (r *Registry) Stream(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessageEventStream, error) {
+	if err := CheckModel(model); err != nil {
+		return nil, err
+	}
+	p := r.GetApiProvider(model.Api)
 	if p == nil {
-		return nil, fmt.Errorf("no API provider registered for api: %s", model.Api)
+		return nil, errNoProvider(model.Api)
 	}
-	return p.Stream(model, ctx, opts), nil
+	ctx.Messages = PrepareToolResultImages(model, ctx.Messages)
+	start := time.Now()
+	return instrument(SafeStream(model, func() *AssistantMessageEventStream { return p.Stream(model, ctx, opts) }), model, ctx, start), nil
 }
 
 // Complete performs a streaming call and blocks until the final message.
-func Complete(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessage, error) {
-	s, err := Stream(model, ctx, opts)
+func (r *Registry) Complete(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessage, error) {
+	s, err := r.Stream(model, ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -21,19 +43,44 @@ func Complete(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessage
 }
 
 // StreamSimple starts a streaming call with reasoning options.
-func StreamSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
-	p := GetApiProvider(model.Api)
+func (r *Registry) StreamSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	if err := CheckModel(model); err != nil {
+		return nil, err
+	}
+	p := r.GetApiProvider(model.Api)
 	if p == nil {
-		return nil, fmt.Errorf("no API provider registered for api: %s", model.Api)
+		return nil, errNoProvider(model.Api)
 	}
-	return p.StreamSimple(model, ctx, opts), nil
+	ctx.Messages = PrepareToolResultImages(model, ctx.Messages)
+	start := time.Now()
+	return instrument(SafeStream(model, func() *AssistantMessageEventStream { return p.StreamSimple(model, ctx, opts) }), model, ctx, start), nil
 }
 
 // CompleteSimple performs a simple streaming call and blocks until the final message.
-func CompleteSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessage, error) {
-	s, err := StreamSimple(model, ctx, opts)
+func (r *Registry) CompleteSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessage, error) {
+	s, err := r.StreamSimple(model, ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 	return s.Result(), nil
 }
+
+// Stream starts a streaming call using the default registry.
+func Stream(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.Stream(model, ctx, opts)
+}
+
+// Complete performs a blocking call using the default registry.
+func Complete(model *Model, ctx Context, opts *StreamOptions) (*AssistantMessage, error) {
+	return defaultRegistry.Complete(model, ctx, opts)
+}
+
+// StreamSimple starts a streaming call with reasoning options using the default registry.
+func StreamSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessageEventStream, error) {
+	return defaultRegistry.StreamSimple(model, ctx, opts)
+}
+
+// CompleteSimple performs a blocking call with reasoning options using the default registry.
+func CompleteSimple(model *Model, ctx Context, opts *SimpleStreamOptions) (*AssistantMessage, error) {
+	return defaultRegistry.CompleteSimple(model, ctx, opts)
+}
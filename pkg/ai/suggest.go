@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"sort"
+	"time"
+)
+
+// ModelRequirements describes what an application needs from a model.
+// Zero values impose no constraint.
+type ModelRequirements struct {
+	NeedsVision    bool
+	NeedsTools     bool
+	NeedsReasoning bool
+	NeedsJSONMode  bool
+	MinContext     int     // minimum context window in tokens
+	MaxCostPerMTok float64 // maximum blended (mean of input and output) price per million tokens
+	Limit          int     // maximum number of suggestions; 0 returns all
+}
+
+// blendedCost is the mean of a model's input and output price per million tokens.
+func blendedCost(m *Model) float64 {
+	return (m.Cost.Input + m.Cost.Output) / 2
+}
+
+// meets reports whether m satisfies req.
+func (req ModelRequirements) meets(m *Model) bool {
+	switch {
+	case req.NeedsVision && !m.CanUseImages(),
+		req.NeedsTools && !m.CanUseTools(),
+		req.NeedsReasoning && !m.Reasoning,
+		req.NeedsJSONMode && !m.CanUseJSONMode(),
+		req.MinContext > 0 && m.ContextWindow < req.MinContext,
+		req.MaxCostPerMTok > 0 && blendedCost(m) > req.MaxCostPerMTok:
+		return false
+	}
+	return true
+}
+
+// SuggestModels returns the registered models that satisfy req, skipping
+// deprecated and currently unhealthy ones. Candidates are ranked cheapest
+// first; ties prefer the larger context window, then the newer release.
+func (r *Registry) SuggestModels(req ModelRequirements) []*Model {
+	now := time.Now()
+	var out []*Model
+	for _, p := range r.GetProviders() {
+		for _, m := range r.GetModels(p) {
+			if !req.meets(m) || IsDeprecated(m, now) || !r.IsModelHealthy(m.Provider, m.ID) {
+				continue
+			}
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if ca, cb := blendedCost(a), blendedCost(b); ca != cb {
+			return ca < cb
+		}
+		if a.ContextWindow != b.ContextWindow {
+			return a.ContextWindow > b.ContextWindow
+		}
+		if a.ReleaseDate != b.ReleaseDate {
+			return a.ReleaseDate > b.ReleaseDate // "YYYY-MM-DD" sorts lexically
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.ID < b.ID
+	})
+	if req.Limit > 0 && len(out) > req.Limit {
+		out = out[:req.Limit]
+	}
+	return out
+}
+
+// SuggestModels ranks models in the default registry; see Registry.SuggestModels.
+func SuggestModels(req ModelRequirements) []*Model { return defaultRegistry.SuggestModels(req) }
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ThinkingSummarizer condenses a thinking block into display text.
+type ThinkingSummarizer func(thinking string) string
+
+// DisplayText returns the text a UI should show for the block: Display when
+// set, otherwise Thinking.
+func (t *ThinkingContent) DisplayText() string {
+	if t.Display != "" {
+		return t.Display
+	}
+	return t.Thinking
+}
+
+// boldHeading matches "**Title**" lines, the section style used by
+// provider-written reasoning summaries.
+var boldHeading = regexp.MustCompile(`(?m)^\s*\*\*(.+?)\*\*\s*$`)
+
+// ExtractiveSummarizer returns a local summarizer that needs no model call.
+// It keeps bold section headings when present, otherwise the first sentence
+// of each paragraph, and cuts the result to maxChars (default 280).
+func ExtractiveSummarizer(maxChars int) ThinkingSummarizer {
+	if maxChars <= 0 {
+		maxChars = 280
+	}
+	return func(thinking string) string {
+		var parts []string
+		for _, m := range boldHeading.FindAllStringSubmatch(thinking, -1) {
+			parts = append(parts, strings.TrimSpace(m[1]))
+		}
+		if len(parts) == 0 {
+			for _, para := range strings.Split(thinking, "\n\n") {
+				if s := firstSentence(para); s != "" {
+					parts = append(parts, s)
+				}
+			}
+		}
+		return truncateRunes(strings.Join(parts, " · "), maxChars)
+	}
+}
+
+func firstSentence(para string) string {
+	para = strings.Join(strings.Fields(para), " ")
+	for i, r := range para {
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(para) || para[i+1] == ' ') {
+			return para[:i+1]
+		}
+	}
+	return para
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+// SummarizeThinking fills Display on msg's thinking blocks that are full
+// reasoning (not already a summary) and at least minChars long.
+func SummarizeThinking(msg *AssistantMessage, summarize ThinkingSummarizer, minChars int) {
+	for i, c := range msg.Content {
+		if c.Thinking == nil || c.Thinking.Summary || c.Thinking.Display != "" {
+			continue
+		}
+		if len([]rune(c.Thinking.Thinking)) < minChars {
+			continue
+		}
+		tc := *c.Thinking
+		tc.Display = summarize(tc.Thinking)
+		msg.Content[i] = Content{Thinking: &tc}
+	}
+}
@@ -0,0 +1,66 @@
+package ai
+
+import "time"
+
+// Timing holds latency metrics for one streamed response.
+type Timing struct {
+	TTFTMs             int64   `json:"ttftMs"`             // request start to first content delta
+	DurationMs         int64   `json:"durationMs"`         // request start to terminal event
+	OutputTokensPerSec float64 `json:"outputTokensPerSec"` // output tokens over the generation phase
+}
+
+// WithTiming measures src from start and sets Timing on the final message
+// (unless already set by an inner stream).
+func WithTiming(src *AssistantMessageEventStream, start time.Time) *AssistantMessageEventStream {
+	var first time.Time
+	return MapStream(src, func(e AssistantMessageEvent) AssistantMessageEvent {
+		switch e.Type {
+		case EventTextDelta, EventThinkingDelta, EventToolCallDelta:
+			if first.IsZero() {
+				first = time.Now()
+			}
+		case EventDone, EventError:
+			msg := e.Message
+			if e.Type == EventError {
+				msg = e.Error
+			}
+			if msg != nil && msg.Timing == nil {
+				msg.Timing = newTiming(msg, start, first, time.Now())
+			}
+		}
+		return e
+	})
+}
+
+func newTiming(msg *AssistantMessage, start, first, end time.Time) *Timing {
+	t := &Timing{DurationMs: end.Sub(start).Milliseconds()}
+	if first.IsZero() {
+		return t
+	}
+	t.TTFTMs = first.Sub(start).Milliseconds()
+	output := msg.Usage.Output
+	if output == 0 {
+		output = estimateContentTokens(msg.Content)
+	}
+	if gen := end.Sub(first).Seconds(); gen > 0 {
+		t.OutputTokensPerSec = float64(output) / gen
+	}
+	return t
+}
+
+// estimateContentTokens estimates the tokens of generated content when the
+// provider reported no usage.
+func estimateContentTokens(content []Content) int {
+	n := 0
+	for _, c := range content {
+		switch {
+		case c.Text != nil:
+			n += EstimateTokens(c.Text.Text)
+		case c.Thinking != nil:
+			n += EstimateTokens(c.Thinking.Thinking)
+		case c.ToolCall != nil:
+			n += EstimateTokens(c.ToolCall.Name) + len(c.ToolCall.Arguments)*4
+		}
+	}
+	return n
+}
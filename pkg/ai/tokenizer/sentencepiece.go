@@ -0,0 +1,305 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SentencePiece is a tokenizer loaded from a SentencePiece .model file.
+// Unigram models are segmented with Viterbi search, BPE models by merging
+// the highest-scoring pairs. Normalization is limited to whitespace
+// handling (NFKC rules embedded in the model are not applied).
+type SentencePiece struct {
+	pieces       map[string]int // piece → id
+	scores       []float32
+	unk          int
+	bytes        [256]int // byte-fallback piece ids, -1 if absent
+	byteFallback bool
+	bpe          bool
+	maxLen       int // longest piece in bytes
+
+	addDummyPrefix bool
+	removeExtraWS  bool
+}
+
+// sentencepiece piece types (sentencepiece_model.proto).
+const (
+	spNormal      = 1
+	spUnknown     = 2
+	spControl     = 3
+	spUserDefined = 4
+	spUnused      = 5
+	spByte        = 6
+)
+
+// whitespace is the meta symbol SentencePiece uses for spaces.
+const whitespace = "▁"
+
+// LoadSentencePiece reads a serialized SentencePiece ModelProto.
+func LoadSentencePiece(r io.Reader) (*SentencePiece, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sp := &SentencePiece{pieces: map[string]int{}, unk: -1, addDummyPrefix: true, removeExtraWS: true}
+	for i := range sp.bytes {
+		sp.bytes[i] = -1
+	}
+	err = walkProto(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == 2:
+			return sp.addPiece(b)
+		case field == 2 && wire == 2: // trainer_spec
+			return walkProto(b, func(field int, wire int, v uint64, _ []byte) error {
+				switch {
+				case field == 3 && wire == 0:
+					sp.bpe = v == 2
+				case field == 35 && wire == 0:
+					sp.byteFallback = v != 0
+				}
+				return nil
+			})
+		case field == 3 && wire == 2: // normalizer_spec
+			return walkProto(b, func(field int, wire int, v uint64, _ []byte) error {
+				switch {
+				case field == 3 && wire == 0:
+					sp.addDummyPrefix = v != 0
+				case field == 4 && wire == 0:
+					sp.removeExtraWS = v != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sentencepiece model: %w", err)
+	}
+	if len(sp.scores) == 0 {
+		return nil, fmt.Errorf("sentencepiece model: no pieces")
+	}
+	return sp, nil
+}
+
+func (sp *SentencePiece) addPiece(b []byte) error {
+	id := len(sp.scores)
+	var piece string
+	var score float32
+	typ := uint64(spNormal)
+	err := walkProto(b, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == 2:
+			piece = string(b)
+		case field == 2 && wire == 5:
+			score = math.Float32frombits(uint32(v))
+		case field == 3 && wire == 0:
+			typ = v
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sp.scores = append(sp.scores, score)
+	switch typ {
+	case spUnknown:
+		sp.unk = id
+	case spByte:
+		if len(piece) == 6 && strings.HasPrefix(piece, "<0x") {
+			if n, err := strconv.ParseUint(piece[3:5], 16, 8); err == nil {
+				sp.bytes[n] = id
+			}
+		}
+	case spNormal, spUserDefined:
+		sp.pieces[piece] = id
+		sp.maxLen = max(sp.maxLen, len(piece))
+	}
+	return nil
+}
+
+// Encode returns the token ids of text.
+func (sp *SentencePiece) Encode(text string) []int {
+	var ids []int
+	for _, word := range sp.words(text) {
+		if sp.bpe {
+			ids = sp.encodeBPE(word, ids)
+		} else {
+			ids = sp.encodeUnigram(word, ids)
+		}
+	}
+	return ids
+}
+
+// CountTokens returns the number of tokens in text.
+func (sp *SentencePiece) CountTokens(text string) int {
+	return len(sp.Encode(text))
+}
+
+// words normalizes whitespace and splits text into words, each starting
+// with the whitespace symbol.
+func (sp *SentencePiece) words(text string) []string {
+	if sp.removeExtraWS {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	if text == "" {
+		return nil
+	}
+	if sp.addDummyPrefix {
+		text = " " + text
+	}
+	text = strings.ReplaceAll(text, " ", whitespace)
+	var words []string
+	start := 0
+	for i := 0; i < len(text); i += len(whitespace) {
+		j := strings.Index(text[i:], whitespace)
+		if j < 0 {
+			break
+		}
+		i += j
+		if i > start {
+			words = append(words, text[start:i])
+			start = i
+		}
+	}
+	return append(words, text[start:])
+}
+
+// unknown appends the ids for a rune without a piece.
+func (sp *SentencePiece) unknown(r string, ids []int) []int {
+	if sp.byteFallback {
+		for i := 0; i < len(r); i++ {
+			if id := sp.bytes[r[i]]; id >= 0 {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	return append(ids, sp.unk)
+}
+
+// encodeUnigram segments word with the highest total piece score.
+func (sp *SentencePiece) encodeUnigram(word string, ids []int) []int {
+	n := len(word)
+	best := make([]float64, n+1)
+	prev := make([]int, n+1) // start of the last piece ending here
+	piece := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(-1)
+	}
+	const unkPenalty = -10.0
+	for start := 0; start < n; start++ {
+		if math.IsInf(best[start], -1) || !utf8.RuneStart(word[start]) {
+			continue
+		}
+		matched := false
+		for end := start + 1; end <= n && end-start <= sp.maxLen; end++ {
+			id, ok := sp.pieces[word[start:end]]
+			if !ok {
+				continue
+			}
+			matched = matched || utf8.RuneCountInString(word[start:end]) == 1
+			if s := best[start] + float64(sp.scores[id]); s > best[end] {
+				best[end], prev[end], piece[end] = s, start, id
+			}
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(word[start:])
+			if s := best[start] + unkPenalty; s > best[start+size] {
+				best[start+size], prev[start+size], piece[start+size] = s, start, -1
+			}
+		}
+	}
+	var path []int
+	for end := n; end > 0; end = prev[end] {
+		path = append(path, end)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		end := path[i]
+		if piece[end] < 0 {
+			ids = sp.unknown(word[prev[end]:end], ids)
+		} else {
+			ids = append(ids, piece[end])
+		}
+	}
+	return ids
+}
+
+// encodeBPE merges the adjacent pair whose union has the highest score
+// until no merge is possible.
+func (sp *SentencePiece) encodeBPE(word string, ids []int) []int {
+	var parts []string
+	for _, r := range word {
+		parts = append(parts, string(r))
+	}
+	for len(parts) > 1 {
+		best, bestScore := -1, float32(math.Inf(-1))
+		for i := 0; i+1 < len(parts); i++ {
+			if id, ok := sp.pieces[parts[i]+parts[i+1]]; ok && sp.scores[id] > bestScore {
+				best, bestScore = i, sp.scores[id]
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	for _, p := range parts {
+		if id, ok := sp.pieces[p]; ok {
+			ids = append(ids, id)
+		} else {
+			ids = sp.unknown(p, ids)
+		}
+	}
+	return ids
+}
+
+// walkProto calls fn for each field of a protobuf message. Varint and
+// fixed-width values are passed in v, length-delimited ones in b.
+func walkProto(data []byte, fn func(field int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("bad field key")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+		var v uint64
+		var b []byte
+		switch wire {
+		case 0:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("bad varint in field %d", field)
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return fmt.Errorf("bad length in field %d", field)
+			}
+			b, data = data[n:n+int(l)], data[n+int(l):]
+		case 5:
+			if len(data) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package tokenizer implements exact tokenizers for registering with
+// ai.RegisterTokenizer: byte-level BPE in the tiktoken format (cl100k_base,
+// o200k_base) and SentencePiece models (Llama, Gemma, Mistral and many
+// self-hosted models). Vocabularies are not bundled; load them from the
+// files the model vendors publish.
+//
+//	f, _ := os.Open("o200k_base.tiktoken")
+//	tok, err := tokenizer.LoadTiktoken(f, tokenizer.O200K)
+//	ai.RegisterTokenizer("openai/gpt-4o", tok)
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoding names a tiktoken pre-tokenization scheme.
+type Encoding string
+
+const (
+	CL100K Encoding = "cl100k_base" // GPT-4, GPT-3.5, text-embedding-3
+	O200K  Encoding = "o200k_base"  // GPT-4o, o1, o3, GPT-4.1
+)
+
+// Pre-tokenization patterns. The originals end in `\s+(?!\S)|\s+`; RE2 has
+// no lookahead, so BPE.split emulates that alternative.
+var patterns = map[Encoding]*regexp.Regexp{
+	CL100K: regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`),
+	O200K: regexp.MustCompile(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`),
+}
+
+// BPE is a byte-level BPE tokenizer with tiktoken semantics. Special
+// tokens are not recognized: their text is encoded as ordinary text.
+type BPE struct {
+	ranks   map[string]int
+	decoder map[int]string
+	pattern *regexp.Regexp
+}
+
+// LoadTiktoken reads a .tiktoken vocabulary (one "base64-token rank" pair
+// per line) for the given encoding.
+func LoadTiktoken(r io.Reader, enc Encoding) (*BPE, error) {
+	pattern, ok := patterns[enc]
+	if !ok {
+		return nil, fmt.Errorf("unknown tiktoken encoding %q", enc)
+	}
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("tiktoken line %d: missing rank", line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken line %d: %w", line, err)
+		}
+		ranks[string(b)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewBPE(ranks, pattern), nil
+}
+
+// NewBPE creates a tokenizer from merge ranks (token bytes → id) and a
+// pre-tokenization pattern.
+func NewBPE(ranks map[string]int, pattern *regexp.Regexp) *BPE {
+	decoder := make(map[int]string, len(ranks))
+	for tok, id := range ranks {
+		decoder[id] = tok
+	}
+	return &BPE{ranks: ranks, decoder: decoder, pattern: pattern}
+}
+
+// Encode returns the token ids of text.
+func (t *BPE) Encode(text string) []int {
+	var ids []int
+	t.split(text, func(piece string) {
+		if id, ok := t.ranks[piece]; ok {
+			ids = append(ids, id)
+			return
+		}
+		for _, part := range bytePairMerge(piece, t.ranks) {
+			ids = append(ids, t.ranks[part])
+		}
+	})
+	return ids
+}
+
+// CountTokens returns the number of tokens in text.
+func (t *BPE) CountTokens(text string) int {
+	n := 0
+	t.split(text, func(piece string) {
+		if _, ok := t.ranks[piece]; ok {
+			n++
+			return
+		}
+		n += len(bytePairMerge(piece, t.ranks))
+	})
+	return n
+}
+
+// Decode returns the text of ids; unknown ids are skipped.
+func (t *BPE) Decode(ids []int) string {
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(t.decoder[id])
+	}
+	return sb.String()
+}
+
+// split calls fn with each pre-tokenized piece of text.
+func (t *BPE) split(text string, fn func(string)) {
+	for len(text) > 0 {
+		loc := t.pattern.FindStringIndex(text)
+		if loc == nil {
+			fn(text)
+			return
+		}
+		if loc[0] > 0 {
+			fn(text[:loc[0]])
+		}
+		end := loc[1]
+		if end == loc[0] {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		// Emulate `\s+(?!\S)`: a run of spaces followed by a non-space
+		// leaves its last space to prefix the next piece.
+		if m := text[loc[0]:end]; end < len(text) && utf8.RuneCountInString(m) > 1 && isPlainSpace(m) {
+			_, size := utf8.DecodeLastRuneInString(m)
+			end -= size
+		}
+		fn(text[loc[0]:end])
+		text = text[end:]
+	}
+}
+
+// isPlainSpace reports whether s is all whitespace without line breaks.
+func isPlainSpace(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\r' || r == '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// bytePairMerge splits piece into vocabulary tokens by repeatedly merging
+// the adjacent pair with the lowest rank.
+func bytePairMerge(piece string, ranks map[string]int) []string {
+	// bounds[i] is the start of part i; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	rank := func(i int) int {
+		if i+2 >= len(bounds) {
+			return math.MaxInt
+		}
+		if r, ok := ranks[piece[bounds[i]:bounds[i+2]]]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if r := rank(i); r < bestRank {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	parts := make([]string, len(bounds)-1)
+	for i := range parts {
+		parts[i] = piece[bounds[i]:bounds[i+1]]
+	}
+	return parts
+}
@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Tokenizer counts tokens for a model's vocabulary.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts an ordinary function to a Tokenizer.
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f(text).
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// imageTokenEstimate is the flat token cost assumed per image.
+const imageTokenEstimate = 1200
+
+var (
+	tokenizers       = map[string]Tokenizer{} // model pattern → tokenizer
+	defaultTokenizer Tokenizer
+	tokenizersMu     sync.RWMutex
+)
+
+// RegisterTokenizer associates a tokenizer with the models matching
+// pattern. A pattern containing "/" is matched against "provider/modelID",
+// otherwise against the model ID alone; "*" matches any run of characters,
+// and a pattern without "*" matches as a prefix. The most specific pattern
+// wins (most literal characters in the model part, provider-qualified on a
+// tie, then fewest wildcards, then the smaller pattern), so self-hosted models with custom vocabularies can override a
+// broader registration:
+//
+//	ai.RegisterTokenizer("gpt-4o", o200k)
+//	ai.RegisterTokenizer("openai/gpt-4*", cl100k)
+//	ai.RegisterTokenizer("ollama/my-finetune", custom)
+func RegisterTokenizer(pattern string, tok Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	tokenizers[pattern] = tok
+}
+
+// UnregisterTokenizer removes the tokenizer registered for pattern.
+func UnregisterTokenizer(pattern string) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	delete(tokenizers, pattern)
+}
+
+// SetDefaultTokenizer sets the tokenizer used by EstimateTokens and for
+// models without a registered tokenizer; nil restores the chars/4
+// heuristic.
+func SetDefaultTokenizer(tok Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	defaultTokenizer = tok
+}
+
+// GetTokenizer returns the tokenizer registered for a model, or nil.
+func GetTokenizer(model *Model) Tokenizer {
+	if model == nil {
+		return nil
+	}
+	qualified := string(model.Provider) + "/" + model.ID
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	var best Tokenizer
+	var bestPattern string
+	bestScore := -1
+	for pattern, tok := range tokenizers {
+		name, score := model.ID, 0
+		if i := strings.Index(pattern, "/"); i >= 0 {
+			name, score = qualified, 1
+			score += 2 * len(strings.ReplaceAll(pattern[i+1:], "*", ""))
+		} else {
+			score += 2 * len(strings.ReplaceAll(pattern, "*", ""))
+		}
+		if !matchModelPattern(pattern, name) {
+			continue
+		}
+		// Ties go to fewer wildcards, then to the smaller pattern, so the
+		// result does not depend on map order.
+		if score > bestScore || score == bestScore && lessSpecific(bestPattern, pattern) {
+			best, bestPattern, bestScore = tok, pattern, score
+		}
+	}
+	return best
+}
+
+// lessSpecific reports whether pattern a loses a tie against b.
+func lessSpecific(a, b string) bool {
+	if na, nb := strings.Count(a, "*"), strings.Count(b, "*"); na != nb {
+		return na > nb
+	}
+	return a > b
+}
+
+// matchModelPattern matches name against a tokenizer pattern: "*" is a
+// wildcard and patterns without one match as a prefix.
+func matchModelPattern(pattern, name string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.HasPrefix(name, pattern)
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, last)
+}
+
+// TokenizerFor returns the tokenizer for model: the registered one, else
+// the default tokenizer, else the chars/4 heuristic. It never returns nil.
+func TokenizerFor(model *Model) Tokenizer {
+	if tok := GetTokenizer(model); tok != nil {
+		return tok
+	}
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	if defaultTokenizer != nil {
+		return defaultTokenizer
+	}
+	return TokenizerFunc(heuristicTokens)
+}
+
+// EstimateTokens counts tokens with the default tokenizer (see
+// SetDefaultTokenizer), approximating characters / 4 when none is set.
+func EstimateTokens(text string) int {
+	tokenizersMu.RLock()
+	tok := defaultTokenizer
+	tokenizersMu.RUnlock()
+	if tok != nil {
+		return tok.CountTokens(text)
+	}
+	return heuristicTokens(text)
+}
+
+func heuristicTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// CountTokens estimates the prompt size of ctx for model using
+// TokenizerFor(model). Images are counted at a flat rate.
+func CountTokens(model *Model, ctx Context) int {
+	count := TokenizerFor(model).CountTokens
+
+	total := count(ctx.SystemPrompt)
+	for _, m := range ctx.Messages {
+		total += countMessageTokens(m, count)
+	}
+	for _, t := range ctx.Tools {
+		total += count(t.Name) + count(t.Description)
+		if params, err := json.Marshal(t.Parameters); err == nil {
+			total += count(string(params))
+		}
+	}
+	return total
+}
+
+func countMessageTokens(m Message, count func(string) int) int {
+	var content []Content
+	switch {
+	case m.User != nil:
+		content = m.User.Content
+	case m.Assistant != nil:
+		content = m.Assistant.Content
+	case m.ToolResult != nil:
+		content = m.ToolResult.Content
+	}
+	total := 0
+	for _, c := range content {
+		switch {
+		case c.Text != nil:
+			total += count(c.Text.Text)
+		case c.Thinking != nil:
+			total += count(c.Thinking.Thinking)
+		case c.Image != nil:
+			total += imageTokenEstimate
+		case c.ToolCall != nil:
+			total += count(c.ToolCall.Name)
+			if args, err := json.Marshal(c.ToolCall.Arguments); err == nil {
+				total += count(string(args))
+			}
+		}
+	}
+	return total
+}
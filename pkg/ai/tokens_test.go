@@ -0,0 +1,29 @@
+package ai
+
+import "testing"
+
+func TestGetTokenizerBreaksTiesDeterministically(t *testing.T) {
+	count := func(n int) Tokenizer { return TokenizerFunc(func(string) int { return n }) }
+	patterns := map[string]int{"gpt-4*": 1, "*gpt-4": 2, "gpt*-4": 3, "gpt-4": 4}
+	for p, n := range patterns {
+		RegisterTokenizer(p, count(n))
+	}
+	defer func() {
+		for p := range patterns {
+			UnregisterTokenizer(p)
+		}
+	}()
+
+	m := &Model{ID: "gpt-4", Provider: "openai"}
+	for range 50 {
+		if n := GetTokenizer(m).CountTokens(""); n != 4 {
+			t.Fatalf("GetTokenizer picked pattern %d, want the one without a wildcard", n)
+		}
+	}
+	UnregisterTokenizer("gpt-4")
+	for range 50 {
+		if n := GetTokenizer(m).CountTokens(""); n != 2 {
+			t.Fatalf("GetTokenizer picked pattern %d, want the smallest of the tied patterns, *gpt-4", n)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+)
+
+// HoistToolResultImages moves images out of tool results, for provider
+// formats whose tool results hold only text (e.g. OpenAI chat
+// completions). Each image is replaced by a placeholder, and the images of
+// a run of consecutive tool results are appended as one user message
+// after the run, so tool results still directly follow their assistant
+// message. Messages without such images are returned unchanged.
+func HoistToolResultImages(messages []Message) []Message {
+	var out []Message
+	var pending []Content
+	flush := func() {
+		if len(pending) > 0 {
+			out = append(out, Message{User: &UserMessage{Role: RoleUser, Content: pending, Timestamp: time.Now().UnixMilli()}})
+			pending = nil
+		}
+	}
+	changed := false
+	for _, m := range messages {
+		if m.ToolResult == nil {
+			flush()
+			out = append(out, m)
+			continue
+		}
+		if !hasImage(m.ToolResult.Content) {
+			out = append(out, m)
+			continue
+		}
+		changed = true
+		tr := *m.ToolResult
+		tr.Content = make([]Content, 0, len(m.ToolResult.Content))
+		label := fmt.Sprintf("Image from tool %s (call %s):", tr.ToolName, tr.ToolCallID)
+		for _, c := range m.ToolResult.Content {
+			if c.Image == nil {
+				tr.Content = append(tr.Content, c)
+				continue
+			}
+			tr.Content = append(tr.Content, NewTextContent("(image attached in the next message)"))
+			pending = append(pending, NewTextContent(label), c)
+		}
+		out = append(out, Message{ToolResult: &tr})
+	}
+	flush()
+	if !changed {
+		return messages
+	}
+	return out
+}
+
+// PrepareToolResultImages applies HoistToolResultImages when model cannot
+// take images inside tool results (see Model.CanUseToolResultImages).
+// Registry.Stream, StreamSimple and their Ctx variants apply it to every
+// request, and the agent loop applies it for custom stream functions, so
+// that screenshots and other tool images reach every provider. Applying it
+// twice is harmless.
+func PrepareToolResultImages(model *Model, messages []Message) []Message {
+	if model == nil || !model.CanUseImages() || model.CanUseToolResultImages() {
+		return messages
+	}
+	return HoistToolResultImages(messages)
+}
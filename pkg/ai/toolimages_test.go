@@ -0,0 +1,29 @@
+package ai
+
+import "testing"
+
+func TestStreamHoistsToolResultImages(t *testing.T) {
+	r := NewRegistry()
+	var got Context
+	r.RegisterApiProvider(&ApiProvider{
+		Api: ApiOpenAICompletions,
+		Stream: func(model *Model, ctx Context, _ *StreamOptions) *AssistantMessageEventStream {
+			got = ctx
+			s := NewAssistantMessageEventStream()
+			s.End(&AssistantMessage{})
+			return s
+		},
+	}, "test")
+	model := &Model{ID: "m", Api: ApiOpenAICompletions, Provider: "test", Input: []string{"text", "image"}}
+	img := Content{Image: &ImageContent{Data: "png", MimeType: "image/png"}}
+	ctx := Context{Messages: []Message{{ToolResult: &ToolResultMessage{Role: RoleToolResult, ToolCallID: "c1", ToolName: "shot", Content: []Content{img}}}}}
+
+	s, err := r.Stream(model, ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Result()
+	if len(got.Messages) != 2 || hasImage(got.Messages[0].ToolResult.Content) || got.Messages[1].User == nil {
+		t.Errorf("provider received %+v, want the image hoisted into a user message", got.Messages)
+	}
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Transport performs HTTP requests on behalf of providers and the proxy
+// client. Environments without usable net/http defaults (WASM hosts, custom
+// mTLS stacks, tests) supply their own. The response body is read as a
+// stream, so implementations must not buffer it.
+type Transport interface {
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// TransportFunc adapts an ordinary function to a Transport.
+type TransportFunc func(ctx context.Context, req *http.Request) (*http.Response, error)
+
+// Do calls f(ctx, req).
+func (f TransportFunc) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return f(ctx, req)
+}
+
+// HTTPTransport is a Transport backed by an *http.Client.
+type HTTPTransport struct {
+	Client *http.Client
+}
+
+// Do sends req with ctx attached using the wrapped client.
+func (t HTTPTransport) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req.WithContext(ctx))
+}
+
+var (
+	defaultTransport   Transport = HTTPTransport{}
+	defaultTransportMu sync.RWMutex
+)
+
+// SetDefaultTransport replaces the transport used when StreamOptions.Transport
+// is nil.
+func SetDefaultTransport(t Transport) {
+	defaultTransportMu.Lock()
+	defer defaultTransportMu.Unlock()
+	if t == nil {
+		t = HTTPTransport{}
+	}
+	defaultTransport = t
+}
+
+// GetTransport returns t, or the default transport if t is nil. When a
+// NetworkPolicy is active the result rejects requests to disallowed hosts.
+func GetTransport(t Transport) Transport {
+	if t == nil {
+		defaultTransportMu.RLock()
+		t = defaultTransport
+		defaultTransportMu.RUnlock()
+	}
+	if GetNetworkPolicy() != nil {
+		return policyTransport{next: t}
+	}
+	return t
+}
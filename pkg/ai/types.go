@@ -86,6 +86,17 @@ type StreamOptions struct {
 	SessionID       string            `json:"sessionId,omitempty"`
 	Headers         map[string]string `json:"headers,omitempty"`
 	MaxRetryDelayMs *int              `json:"maxRetryDelayMs,omitempty"`
+	IdleTimeoutMs   *int              `json:"idleTimeoutMs,omitempty"` // abort if no event arrives for this long
+	Transport       Transport         `json:"-"` // nil uses the default transport
+	Buffer          *BufferOptions    `json:"-"` // event buffering for the returned stream
+}
+
+// IdleTimeout returns IdleTimeoutMs as a duration, or 0 if unset.
+func (o *StreamOptions) IdleTimeout() time.Duration {
+	if o == nil || o.IdleTimeoutMs == nil {
+		return 0
+	}
+	return time.Duration(*o.IdleTimeoutMs) * time.Millisecond
 }
 
 // SimpleStreamOptions extends StreamOptions with reasoning controls.
@@ -121,6 +132,8 @@ type ThinkingContent struct {
 	Type              ContentType `json:"type"` // always "thinking"
 	Thinking          string      `json:"thinking"`
 	ThinkingSignature string      `json:"thinkingSignature,omitempty"`
+	Summary           bool        `json:"summary,omitempty"` // Thinking is a provider-written summary, not the full reasoning
+	Display           string      `json:"display,omitempty"` // concise text for UIs, see DisplayText
 }
 
 // ImageContent is a base64-encoded image in a message.
@@ -216,6 +229,12 @@ func NewThinkingContent(thinking string) Content {
 	return Content{Thinking: &ThinkingContent{Type: ContentThinking, Thinking: thinking}}
 }
 
+// NewThinkingSummaryContent is used by providers that only return a
+// summary of the model's reasoning.
+func NewThinkingSummaryContent(summary string) Content {
+	return Content{Thinking: &ThinkingContent{Type: ContentThinking, Thinking: summary, Summary: true}}
+}
+
 func NewImageContent(data, mimeType string) Content {
 	return Content{Image: &ImageContent{Type: ContentImage, Data: data, MimeType: mimeType}}
 }
@@ -245,6 +264,7 @@ type Usage struct {
 	CacheWrite  int  `json:"cacheWrite"`
 	TotalTokens int  `json:"totalTokens"`
 	Cost        Cost `json:"cost"`
+	Estimated   bool `json:"estimated,omitempty"` // estimated locally because the provider reported none
 }
 
 // StopReason indicates why the model stopped generating.
@@ -256,6 +276,7 @@ const (
 	StopReasonToolUse StopReason = "toolUse"
 	StopReasonError   StopReason = "error"
 	StopReasonAborted StopReason = "aborted"
+	StopReasonLimit   StopReason = "limit" // set by the agent loop when a run limit cut it off
 )
 
 // ---------------------------------------------------------------------------
@@ -288,6 +309,10 @@ type AssistantMessage struct {
 	Usage        Usage       `json:"usage"`
 	StopReason   StopReason  `json:"stopReason"`
 	ErrorMessage string      `json:"errorMessage,omitempty"`
+	FallbackFrom string      `json:"fallbackFrom,omitempty"` // requested model ID when a fallback answered
+	RoutedVia    string      `json:"routedVia,omitempty"`    // virtual pool model that routed this call
+	Recovered    bool        `json:"recovered,omitempty"`    // stitched together after a mid-stream disconnect
+	Timing       *Timing     `json:"timing,omitempty"`
 	Timestamp    int64       `json:"timestamp"` // Unix ms
 }
 
@@ -389,6 +414,12 @@ type Tool struct {
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
 	Parameters  ToolSchema `json:"parameters"`
+
+	// Strict asks providers that support it (e.g. OpenAI structured
+	// function calling) to constrain arguments to Parameters exactly. The
+	// agent loop also validates a Strict tool's arguments without coercion
+	// and rejects properties Parameters does not declare.
+	Strict bool `json:"strict,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -427,6 +458,73 @@ type Model struct {
 	ContextWindow int               `json:"contextWindow"`
 	MaxTokens     int               `json:"maxTokens"`
 	Headers       map[string]string `json:"headers,omitempty"`
+	FallbackIDs   []string          `json:"fallbackIds,omitempty"` // same-provider models to try when this one fails
+
+	// Capability flags. nil means unknown; see the Model.Can* helpers for
+	// how each is defaulted.
+	SupportsTools            *bool `json:"supportsTools,omitempty"`
+	SupportsJSONMode         *bool `json:"supportsJsonMode,omitempty"`
+	SupportsCaching          *bool `json:"supportsCaching,omitempty"`
+	SupportsAudio            *bool `json:"supportsAudio,omitempty"`
+	SupportsToolResultImages *bool `json:"supportsToolResultImages,omitempty"`
+	MaxImagesPerRequest      int   `json:"maxImagesPerRequest,omitempty"` // 0 = no limit
+
+	// Lifecycle metadata, as "YYYY-MM-DD" dates.
+	KnowledgeCutoff string `json:"knowledgeCutoff,omitempty"`
+	ReleaseDate     string `json:"releaseDate,omitempty"`
+	DeprecationDate string `json:"deprecationDate,omitempty"` // deprecated from this day on
+	SunsetDate      string `json:"sunsetDate,omitempty"`      // no longer served from this day on
+
+	Deprecated bool   `json:"deprecated,omitempty"` // deprecated regardless of DeprecationDate
+	Successor  string `json:"successor,omitempty"`  // same-provider model ID to migrate to
+}
+
+// CanUseTools reports whether tools may be attached. Unknown means yes.
+func (m *Model) CanUseTools() bool {
+	return m.SupportsTools == nil || *m.SupportsTools
+}
+
+// CanUseJSONMode reports whether the model supports JSON mode. Unknown means no.
+func (m *Model) CanUseJSONMode() bool {
+	return m.SupportsJSONMode != nil && *m.SupportsJSONMode
+}
+
+// CanUseCaching reports whether the model supports prompt caching. Unknown means no.
+func (m *Model) CanUseCaching() bool {
+	return m.SupportsCaching != nil && *m.SupportsCaching
+}
+
+// CanUseAudio reports whether the model accepts audio input. Unknown means no.
+func (m *Model) CanUseAudio() bool {
+	return m.SupportsAudio != nil && *m.SupportsAudio
+}
+
+// CanUseToolResultImages reports whether images may stay inside tool
+// results. Unknown means yes for the Anthropic, Bedrock and Google APIs,
+// whose tool results carry image blocks, and no for the OpenAI APIs.
+func (m *Model) CanUseToolResultImages() bool {
+	if m.SupportsToolResultImages != nil {
+		return *m.SupportsToolResultImages
+	}
+	switch m.Api {
+	case ApiAnthropicMessages, ApiBedrockConverseStream, ApiGoogleGenerativeAI, ApiGoogleGeminiCLI, ApiGoogleVertex:
+		return true
+	}
+	return false
+}
+
+// CanUseImages reports whether Input lists "image". Unknown (nil Input)
+// means yes.
+func (m *Model) CanUseImages() bool {
+	if m.Input == nil {
+		return true
+	}
+	for _, in := range m.Input {
+		if in == "image" {
+			return true
+		}
+	}
+	return false
 }
 
 // ---------------------------------------------------------------------------
@@ -452,6 +550,8 @@ const (
 )
 
 // AssistantMessageEvent is a single event from a streaming LLM response.
+// Its JSON encoding is a wire contract shared by the SSE transports and
+// agent events; fields are only ever added, never renamed.
 type AssistantMessageEvent struct {
 	Type         AssistantMessageEventType `json:"type"`
 	ContentIndex int                       `json:"contentIndex,omitempty"`
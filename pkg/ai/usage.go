@@ -0,0 +1,70 @@
+package ai
+
+import "context"
+
+// BackfillUsage ensures that aborted and failed responses carry usage:
+// when the terminal message reports no tokens at all, input is estimated
+// from llmCtx and output from the content generated so far, cost is
+// computed from the model's pricing, and Usage.Estimated is set. Usage
+// reported by the provider is never overwritten. Failures before the
+// response started (auth and rate-limit errors, refused connections,
+// policy denials) are not charged: nothing was billed.
+func BackfillUsage(src *AssistantMessageEventStream, model *Model, llmCtx Context) *AssistantMessageEventStream {
+	started := false
+	return MapStream(src, func(e AssistantMessageEvent) AssistantMessageEvent {
+		switch {
+		case e.Type == EventStart:
+			started = true
+		case e.Type == EventError && e.Error != nil && (started || len(e.Error.Content) > 0):
+			EstimateUsage(e.Error, model, llmCtx)
+		}
+		return e
+	})
+}
+
+// EstimateUsage fills msg.Usage with estimates if it is empty.
+func EstimateUsage(msg *AssistantMessage, model *Model, llmCtx Context) {
+	u := &msg.Usage
+	if u.Input != 0 || u.Output != 0 || u.CacheRead != 0 || u.CacheWrite != 0 {
+		return
+	}
+	u.Input = CountTokens(model, llmCtx)
+	u.Output = estimateContentTokens(msg.Content)
+	u.TotalTokens = u.Input + u.Output
+	u.Estimated = true
+	CalculateCost(model, u)
+}
+
+// AddUsage returns the sum of two usage records.
+func AddUsage(a, b Usage) Usage {
+	a.Input += b.Input
+	a.Output += b.Output
+	a.CacheRead += b.CacheRead
+	a.CacheWrite += b.CacheWrite
+	a.TotalTokens += b.TotalTokens
+	a.Cost.Input += b.Cost.Input
+	a.Cost.Output += b.Cost.Output
+	a.Cost.CacheRead += b.Cost.CacheRead
+	a.Cost.CacheWrite += b.Cost.CacheWrite
+	a.Cost.Total += b.Cost.Total
+	a.Estimated = a.Estimated || b.Estimated
+	return a
+}
+
+type usageHookKey struct{}
+
+// WithUsageHook returns ctx carrying fn, which ReportUsage calls with the
+// usage of model calls made with the context, so that callers can bill or
+// budget calls they do not make themselves (captions, moderation, ...).
+func WithUsageHook(ctx context.Context, fn func(Usage)) context.Context {
+	return context.WithValue(ctx, usageHookKey{}, fn)
+}
+
+// ReportUsage passes u to the hook installed with WithUsageHook, if any.
+// Helpers that call models on the caller's behalf report every response,
+// failed ones included.
+func ReportUsage(ctx context.Context, u Usage) {
+	if fn, ok := ctx.Value(usageHookKey{}).(func(Usage)); ok && fn != nil {
+		fn(u)
+	}
+}
@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCaptionAndModerationReportUsage(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterApiProvider(&ApiProvider{
+		Api: ApiOpenAICompletions,
+		Stream: func(model *Model, ctx Context, _ *StreamOptions) *AssistantMessageEventStream {
+			s := NewAssistantMessageEventStream()
+			s.End(&AssistantMessage{Content: []Content{NewTextContent(`{"flagged": false}`)}, Usage: Usage{Input: 7, TotalTokens: 7}})
+			return s
+		},
+	}, "test")
+	model := &Model{ID: "m", Api: ApiOpenAICompletions, Provider: "test", Input: []string{"text", "image"}}
+	var total Usage
+	ctx := WithUsageHook(context.Background(), func(u Usage) { total = AddUsage(total, u) })
+	img := &ImageContent{Data: "png", MimeType: "image/png"}
+
+	if _, err := (&ImageCaptioner{Model: model, Registry: r}).Caption(ctx, img); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&VisionClassifier{Model: model, Registry: r}).Classify(ctx, img); err != nil {
+		t.Fatal(err)
+	}
+	if total.TotalTokens != 14 {
+		t.Errorf("reported %d tokens, want 14", total.TotalTokens)
+	}
+}
@@ -56,3 +56,141 @@ func ValidateToolArguments(tool *Tool, tc ToolCall) (map[string]any, error) {
 
 	return args, nil
 }
+
+// ValidatePartialToolArguments checks arguments that are still streaming
+// for violations that later deltas cannot fix: a property whose value has
+// the wrong JSON type, or an unknown property when the schema sets
+// additionalProperties to false. Missing required properties are not
+// reported, as they may still arrive.
+func ValidatePartialToolArguments(tool *Tool, args map[string]any) error {
+	props, _ := tool.Parameters["properties"].(map[string]any)
+	closed := tool.Parameters["additionalProperties"] == false
+	var problems []string
+	for name, v := range args {
+		prop, ok := props[name].(map[string]any)
+		if !ok {
+			if closed {
+				problems = append(problems, fmt.Sprintf("unknown property %q", name))
+			}
+			continue
+		}
+		if want := schemaTypes(prop["type"]); len(want) > 0 && !matchesType(v, want) {
+			problems = append(problems, fmt.Sprintf("%q must be %s, got %s", name, strings.Join(want, " or "), jsonType(v)))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("validation failed for tool %q:\n  - %s", tool.Name, strings.Join(problems, "\n  - "))
+}
+
+// schemaTypes returns the allowed types of a JSON-Schema "type" value.
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func matchesType(v any, want []string) bool {
+	got := jsonType(v)
+	for _, w := range want {
+		if w == got || (w == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON-Schema type of a decoded JSON value.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ValidateValue checks a decoded JSON value against a JSON-Schema subset:
+// type, enum, required, properties, additionalProperties: false, and
+// items. It reports every problem found, each prefixed with its path.
+func ValidateValue(schema ToolSchema, v any) error {
+	var problems []string
+	validateValue(schema, v, "$", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("schema validation failed:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+func validateValue(schema map[string]any, v any, path string, problems *[]string) {
+	if schema == nil {
+		return
+	}
+	if want := schemaTypes(schema["type"]); len(want) > 0 && !matchesType(v, want) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s, got %s", path, strings.Join(want, " or "), jsonType(v)))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*problems = append(*problems, fmt.Sprintf("%s must be one of %v", path, enum))
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaTypes(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		for name, val := range v {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					*problems = append(*problems, fmt.Sprintf("%s.%s is not allowed", path, name))
+				}
+				continue
+			}
+			validateValue(prop, val, path+"."+name, problems)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, val := range v {
+			validateValue(items, val, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
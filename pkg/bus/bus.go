@@ -0,0 +1,63 @@
+// Package bus implements agent.MessageBus over shared brokers so that
+// steering and follow-up messages reach the gateway replica running a
+// session. Adapters for Redis pub/sub and NATS are included; both speak the
+// wire protocol directly and need no client library. The Redis adapter is
+// also an agent.LeaseStore, so that replicas sharing it can decide which
+// of them runs a session.
+//
+// Like agent.LocalBus, delivery is at most once: a message published while
+// no replica subscribes to its session, or while a subscriber is
+// reconnecting, is lost.
+package bus
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	dialTimeout   = 10 * time.Second
+	maxRedialWait = 5 * time.Second
+)
+
+// dialer returns dial, or a TCP dialer for addr if dial is nil.
+func dialer(dial func(ctx context.Context) (net.Conn, error), addr string) func(ctx context.Context) (net.Conn, error) {
+	if dial != nil {
+		return dial
+	}
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// withDeadline runs fn with conn's deadline bound to ctx: fn's reads and
+// writes fail once ctx is done.
+func withDeadline(ctx context.Context, conn net.Conn, fn func() error) error {
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	err := fn()
+	if !stop() {
+		err = ctx.Err()
+	}
+	conn.SetDeadline(time.Time{})
+	return err
+}
+
+// redial calls connect with growing pauses until it succeeds or stopped
+// reports true.
+func redial(connect func(ctx context.Context) error, stopped func() bool) {
+	wait := 100 * time.Millisecond
+	for !stopped() {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		err := connect(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		time.Sleep(wait)
+		wait = min(2*wait, maxRedialWait)
+	}
+}
@@ -0,0 +1,53 @@
+package bus
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// The lease scripts run atomically on the server, so a lease cannot change
+// hands between checking its owner and updating it.
+const (
+	acquireScript = `local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`
+	renewScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+	releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// Acquire implements agent.LeaseStore. The lease is the key
+// Prefix+"lease:"+key, holding the owner and expiring after ttl.
+func (b *Redis) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return b.lease(ctx, acquireScript, key, owner, ttl)
+}
+
+// Renew implements agent.LeaseStore.
+func (b *Redis) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return b.lease(ctx, renewScript, key, owner, ttl)
+}
+
+// Release implements agent.LeaseStore.
+func (b *Redis) Release(ctx context.Context, key, owner string) error {
+	_, err := b.lease(ctx, releaseScript, key, owner, 0)
+	return err
+}
+
+// lease runs a lease script and reports whether it succeeded.
+func (b *Redis) lease(ctx context.Context, script, key, owner string, ttl time.Duration) (bool, error) {
+	ms := max(ttl.Milliseconds(), 1)
+	reply, err := b.do(ctx, "EVAL", script, "1", b.channel("lease:"+key), owner, strconv.FormatInt(ms, 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
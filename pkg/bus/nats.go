@@ -0,0 +1,301 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// NATS is an agent.MessageBus over core NATS. Each session is the subject
+// Prefix+sessionID, so session IDs must not contain whitespace or the
+// wildcards "*" and ">". One connection, opened on first use, carries both
+// directions; it reconnects and resubscribes if it drops.
+type NATS struct {
+	Addr     string // host:port, default "localhost:4222"
+	User     string // optional
+	Password string // optional
+	Token    string // optional auth token
+	Prefix   string // subject prefix, default "pi.bus."
+
+	// Dial opens a connection; nil dials Addr over TCP. A TLS connection
+	// needs a server with handshake_first set: upgrading to TLS after the
+	// INFO greeting is not supported.
+	Dial func(ctx context.Context) (net.Conn, error)
+
+	mu      sync.Mutex
+	conn    net.Conn
+	w       *bufio.Writer
+	subs    map[int]natsSub // by subscription ID
+	nextSID int
+	closed  bool
+}
+
+// natsConnect is the body of the CONNECT handshake.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Lang      string `json:"lang"`
+	Name      string `json:"name"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+type natsSub struct {
+	subject string
+	fn      func(agent.BusMessage)
+}
+
+// Publish implements agent.MessageBus. Core NATS does not acknowledge
+// messages, so a nil error only means the message was written.
+func (b *NATS) Publish(ctx context.Context, msg agent.BusMessage) error {
+	subject, err := b.subject(msg.SessionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.ensure(ctx); err != nil {
+		return err
+	}
+	err = withDeadline(ctx, b.conn, func() error {
+		fmt.Fprintf(b.w, "PUB %s %d\r\n", subject, len(data))
+		b.w.Write(data)
+		b.w.WriteString("\r\n")
+		return b.w.Flush()
+	})
+	if err != nil {
+		b.drop(b.conn)
+		return fmt.Errorf("nats: publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe implements agent.MessageBus. fn runs on the connection's
+// reader goroutine and should not block.
+func (b *NATS) Subscribe(ctx context.Context, sessionID string, fn func(agent.BusMessage)) (func(), error) {
+	subject, err := b.subject(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.ensure(ctx); err != nil {
+		return nil, err
+	}
+	sid := b.nextSID
+	b.nextSID++
+	if err := b.send("SUB %s %d\r\n", subject, sid); err != nil {
+		b.drop(b.conn)
+		return nil, fmt.Errorf("nats: subscribe: %w", err)
+	}
+	if b.subs == nil {
+		b.subs = map[int]natsSub{}
+	}
+	b.subs[sid] = natsSub{subject: subject, fn: fn}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sid]; !ok {
+			return
+		}
+		delete(b.subs, sid)
+		if b.conn != nil {
+			b.send("UNSUB %d\r\n", sid)
+		}
+	}, nil
+}
+
+// Close closes the connection. Subscriptions end and later calls fail.
+func (b *NATS) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.conn != nil {
+		b.drop(b.conn)
+	}
+	return nil
+}
+
+func (b *NATS) subject(sessionID string) (string, error) {
+	if sessionID == "" || strings.ContainsAny(sessionID, " \t\r\n*>") {
+		return "", fmt.Errorf("nats: session ID %q is not a valid subject token", sessionID)
+	}
+	if b.Prefix == "" {
+		return "pi.bus." + sessionID, nil
+	}
+	return b.Prefix + sessionID, nil
+}
+
+// send writes a protocol line. Called with mu held and a connection up.
+func (b *NATS) send(format string, args ...any) error {
+	fmt.Fprintf(b.w, format, args...)
+	return b.w.Flush()
+}
+
+// drop closes conn if it is the current connection. Called with mu held.
+func (b *NATS) drop(conn net.Conn) {
+	if b.conn == conn {
+		b.conn.Close()
+		b.conn, b.w = nil, nil
+	}
+}
+
+// ensure connects, completes the handshake, resubscribes every
+// subscription and starts the reader unless a connection is up. Called
+// with mu held.
+func (b *NATS) ensure(ctx context.Context) error {
+	if b.closed {
+		return errors.New("nats: bus closed")
+	}
+	if b.conn != nil {
+		return nil
+	}
+	addr := b.Addr
+	if addr == "" {
+		addr = "localhost:4222"
+	}
+	conn, err := dialer(b.Dial, addr)(ctx)
+	if err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	err = withDeadline(ctx, conn, func() error {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "INFO ") {
+			return fmt.Errorf("unexpected greeting %q", line)
+		}
+		connect, _ := json.Marshal(natsConnect{Lang: "go", Name: "pi-go", User: b.User, Pass: b.Password, AuthToken: b.Token})
+		fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connect)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		for {
+			line, err := readLine(r)
+			switch {
+			case err != nil:
+				return err
+			case line == "PONG":
+				return nil
+			case strings.HasPrefix(line, "-ERR"):
+				return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			}
+		}
+	})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("nats: connect: %w", err)
+	}
+	b.conn, b.w = conn, w
+	for sid, s := range b.subs {
+		if err := b.send("SUB %s %d\r\n", s.subject, sid); err != nil {
+			b.drop(conn)
+			return fmt.Errorf("nats: subscribe: %w", err)
+		}
+	}
+	go b.read(conn, r)
+	return nil
+}
+
+// read handles the messages arriving on conn until it fails, then
+// reconnects while there are subscriptions.
+func (b *NATS) read(conn net.Conn, r *bufio.Reader) {
+loop:
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			break
+		}
+		switch {
+		case line == "PING":
+			b.mu.Lock()
+			if b.conn == conn {
+				b.send("PONG\r\n")
+			}
+			b.mu.Unlock()
+		case strings.HasPrefix(line, "MSG "):
+			sid, payload, err := readMsg(r, line)
+			if err != nil {
+				break loop
+			}
+			var msg agent.BusMessage
+			if json.Unmarshal(payload, &msg) != nil {
+				continue
+			}
+			b.mu.Lock()
+			s, ok := b.subs[sid]
+			b.mu.Unlock()
+			if ok {
+				s.fn(msg)
+			}
+		}
+	}
+	b.mu.Lock()
+	b.drop(conn)
+	b.mu.Unlock()
+	redial(func(ctx context.Context) error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.idle() {
+			return nil
+		}
+		return b.ensure(ctx)
+	}, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.idle()
+	})
+}
+
+// idle reports whether the connection needs no reconnecting: the bus is
+// closed, another connection is up or nobody subscribes. Publish connects
+// on demand. Called with mu held.
+func (b *NATS) idle() bool {
+	return b.closed || b.conn != nil || len(b.subs) == 0
+}
+
+// readMsg reads the payload announced by a MSG line:
+// MSG <subject> <sid> [reply-to] <#bytes>.
+func readMsg(r *bufio.Reader, line string) (sid int, payload []byte, err error) {
+	f := strings.Fields(line)
+	if len(f) != 4 && len(f) != 5 {
+		return 0, nil, fmt.Errorf("malformed %q", line)
+	}
+	if sid, err = strconv.Atoi(f[2]); err != nil {
+		return 0, nil, err
+	}
+	n, err := strconv.Atoi(f[len(f)-1])
+	if err != nil || n < 0 {
+		return 0, nil, fmt.Errorf("malformed %q", line)
+	}
+	payload = make([]byte, n+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return sid, payload[:n], nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,143 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// fakeNATS implements the core NATS protocol without wildcards or queues.
+type fakeNATS struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns map[net.Conn]map[string]string // sid → subject per connection
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATS{ln: ln, conns: map[net.Conn]map[string]string{}}
+	t.Cleanup(func() { ln.Close(); s.drop() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = map[string]string{}
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		switch f[0] {
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			s.conns[conn][f[2]] = f[1]
+		case "UNSUB":
+			delete(s.conns[conn], f[1])
+		case "PUB":
+			s.mu.Unlock()
+			_, payload, err := readMsg(r, "MSG "+f[1]+" 0 "+f[len(f)-1])
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			for sub, sids := range s.conns {
+				for sid, subject := range sids {
+					if subject == f[1] {
+						fmt.Fprintf(sub, "MSG %s %s %d\r\n%s\r\n", subject, sid, len(payload), payload)
+					}
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *fakeNATS) subscribers(subject string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sids := range s.conns {
+		for _, sub := range sids {
+			if sub == subject {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (s *fakeNATS) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+func TestNATSDeliversAndResubscribes(t *testing.T) {
+	srv := newFakeNATS(t)
+	b := &NATS{Addr: srv.ln.Addr().String()}
+	defer b.Close()
+	ctx := context.Background()
+
+	got := make(chan agent.BusMessage, 1)
+	unsubscribe, err := b.Subscribe(ctx, "s1", func(m agent.BusMessage) { got <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "subscription", func() bool { return srv.subscribers("pi.bus.s1") == 1 })
+
+	steer := agent.NewAgentMessageFromMessage(ai.NewUserMessage("stop"))
+	if err := agent.PublishSteer(ctx, b, "s1", steer); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, got); m.Kind != agent.BusSteering || m.SessionID != "s1" || m.Message.User.Content[0].Text.Text != "stop" {
+		t.Errorf("received %+v", m)
+	}
+
+	srv.drop()
+	waitFor(t, "resubscription", func() bool { return srv.subscribers("pi.bus.s1") == 1 })
+	if err := agent.PublishFollowUp(ctx, b, "s1", steer); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, got); m.Kind != agent.BusFollowUp {
+		t.Errorf("received %+v after reconnecting", m)
+	}
+
+	unsubscribe()
+	waitFor(t, "unsubscription", func() bool { return srv.subscribers("pi.bus.s1") == 0 })
+
+	if _, err := b.Subscribe(ctx, "a b", func(agent.BusMessage) {}); err == nil {
+		t.Error("subscribed to a session ID with whitespace")
+	}
+}
@@ -0,0 +1,335 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// Redis is an agent.MessageBus over Redis pub/sub. Each session is the
+// channel Prefix+sessionID. Publishing uses one connection and subscribing
+// another, both opened on first use; the subscriber reconnects and
+// resubscribes if its connection drops.
+type Redis struct {
+	Addr     string // host:port, default "localhost:6379"
+	Username string // ACL user (Redis 6+), optional
+	Password string // AUTH password, optional
+	Prefix   string // channel prefix, default "pi:bus:"
+
+	// Dial opens a connection, e.g. over TLS; nil dials Addr over TCP.
+	Dial func(ctx context.Context) (net.Conn, error)
+
+	pubMu sync.Mutex
+	pub   *respConn
+
+	subMu  sync.Mutex
+	sub    *respConn
+	subs   map[string]map[int]func(agent.BusMessage) // channel → subscribers
+	nextID int
+	closed bool
+}
+
+// Publish implements agent.MessageBus. A publish that fails on a reused
+// connection, e.g. one the server closed while idle, is retried once on a
+// new connection.
+func (b *Redis) Publish(ctx context.Context, msg agent.BusMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	_, err = b.do(ctx, "PUBLISH", b.channel(msg.SessionID), string(data))
+	return err
+}
+
+// do sends a command on the publishing connection and returns its reply.
+// A command that fails on a reused connection is retried once on a new
+// connection, so commands must be idempotent.
+func (b *Redis) do(ctx context.Context, args ...string) (any, error) {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	var reply any
+	var err error
+	for attempt := 0; ; attempt++ {
+		reused := b.pub != nil
+		if !reused {
+			c, err := b.connect(ctx)
+			if err != nil {
+				return nil, err
+			}
+			b.pub = c
+		}
+		err = withDeadline(ctx, b.pub.conn, func() error {
+			if err := b.pub.write(args...); err != nil {
+				return err
+			}
+			var err error
+			reply, err = b.pub.read()
+			return err
+		})
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			break
+		}
+		b.pub.conn.Close()
+		b.pub = nil
+		if !reused || attempt > 0 || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: %s: %w", strings.ToLower(args[0]), err)
+	}
+	return reply, nil
+}
+
+// Subscribe implements agent.MessageBus. fn runs on the subscriber
+// connection's reader goroutine and should not block.
+func (b *Redis) Subscribe(ctx context.Context, sessionID string, fn func(agent.BusMessage)) (func(), error) {
+	channel := b.channel(sessionID)
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if b.closed {
+		return nil, errors.New("redis: bus closed")
+	}
+	if b.sub == nil {
+		if err := b.connectSub(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.subs[channel]) == 0 {
+		if err := b.sub.write("SUBSCRIBE", channel); err != nil {
+			return nil, fmt.Errorf("redis: subscribe: %w", err)
+		}
+	}
+	if b.subs == nil {
+		b.subs = map[string]map[int]func(agent.BusMessage){}
+	}
+	if b.subs[channel] == nil {
+		b.subs[channel] = map[int]func(agent.BusMessage){}
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[channel][id] = fn
+	return func() {
+		b.subMu.Lock()
+		defer b.subMu.Unlock()
+		if _, ok := b.subs[channel][id]; !ok {
+			return
+		}
+		delete(b.subs[channel], id)
+		if len(b.subs[channel]) == 0 {
+			delete(b.subs, channel)
+			if b.sub != nil {
+				b.sub.write("UNSUBSCRIBE", channel)
+			}
+		}
+	}, nil
+}
+
+// Close closes both connections. Subscriptions end and later calls fail.
+func (b *Redis) Close() error {
+	b.subMu.Lock()
+	b.closed = true
+	if b.sub != nil {
+		b.sub.conn.Close()
+		b.sub = nil
+	}
+	b.subMu.Unlock()
+	b.pubMu.Lock()
+	if b.pub != nil {
+		b.pub.conn.Close()
+		b.pub = nil
+	}
+	b.pubMu.Unlock()
+	return nil
+}
+
+func (b *Redis) channel(sessionID string) string {
+	if b.Prefix == "" {
+		return "pi:bus:" + sessionID
+	}
+	return b.Prefix + sessionID
+}
+
+// connect dials and authenticates a connection.
+func (b *Redis) connect(ctx context.Context) (*respConn, error) {
+	addr := b.Addr
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	conn, err := dialer(b.Dial, addr)(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if b.Password != "" {
+		args := []string{"AUTH", b.Password}
+		if b.Username != "" {
+			args = []string{"AUTH", b.Username, b.Password}
+		}
+		err = withDeadline(ctx, conn, func() error {
+			if err := c.write(args...); err != nil {
+				return err
+			}
+			_, err := c.read()
+			return err
+		})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis: auth: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// connectSub opens the subscriber connection, subscribes it to every
+// channel that has subscribers and starts its reader. Called with subMu
+// held.
+func (b *Redis) connectSub(ctx context.Context) error {
+	c, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	for channel := range b.subs {
+		if err := c.write("SUBSCRIBE", channel); err != nil {
+			c.conn.Close()
+			return fmt.Errorf("redis: subscribe: %w", err)
+		}
+	}
+	b.sub = c
+	go b.readSub(c)
+	return nil
+}
+
+// readSub delivers the messages arriving on c until it fails, then
+// reconnects unless the bus was closed.
+func (b *Redis) readSub(c *respConn) {
+	for {
+		reply, err := c.read()
+		if err != nil {
+			break
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue // subscribe and unsubscribe confirmations
+		}
+		channel, _ := parts[1].(string)
+		payload, _ := parts[2].(string)
+		var msg agent.BusMessage
+		if json.Unmarshal([]byte(payload), &msg) != nil {
+			continue
+		}
+		b.subMu.Lock()
+		fns := make([]func(agent.BusMessage), 0, len(b.subs[channel]))
+		for _, fn := range b.subs[channel] {
+			fns = append(fns, fn)
+		}
+		b.subMu.Unlock()
+		for _, fn := range fns {
+			fn(msg)
+		}
+	}
+	c.conn.Close()
+	b.subMu.Lock()
+	if b.sub == c {
+		b.sub = nil
+	}
+	b.subMu.Unlock()
+	redial(func(ctx context.Context) error {
+		b.subMu.Lock()
+		defer b.subMu.Unlock()
+		if b.idle() {
+			return nil
+		}
+		return b.connectSub(ctx)
+	}, func() bool {
+		b.subMu.Lock()
+		defer b.subMu.Unlock()
+		return b.idle()
+	})
+}
+
+// idle reports whether the subscriber connection needs no reconnecting:
+// the bus is closed, another connection is up or nobody subscribes.
+// Called with subMu held.
+func (b *Redis) idle() bool {
+	return b.closed || b.sub != nil || len(b.subs) == 0
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// respConn speaks RESP, the Redis serialization protocol.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// write sends a command.
+func (c *respConn) write(args ...string) error {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return c.w.Flush()
+}
+
+// read returns the next reply: a string, an int64, nil, a []any or, for
+// error replies, a redisError as the error.
+func (c *respConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				var replyErr redisError
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = replyErr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", kind)
+}
@@ -0,0 +1,231 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// fakeRedis implements the pub/sub subset of the Redis protocol, and EVAL
+// for the lease scripts.
+type fakeRedis struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	conns  map[net.Conn]map[string]bool // subscribed channels per connection
+	leases map[string]fakeLease
+}
+
+type fakeLease struct {
+	owner   string
+	expires time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{ln: ln, conns: map[net.Conn]map[string]bool{}, leases: map[string]fakeLease{}}
+	t.Cleanup(func() { ln.Close(); s.drop() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = map[string]bool{}
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	for {
+		cmd, err := c.read()
+		if err != nil {
+			return
+		}
+		args := cmd.([]any)
+		s.mu.Lock()
+		switch args[0] {
+		case "SUBSCRIBE":
+			s.conns[conn][args[1].(string)] = true
+			fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1].(string)), args[1])
+		case "UNSUBSCRIBE":
+			delete(s.conns[conn], args[1].(string))
+			fmt.Fprintf(conn, "*3\r\n$11\r\nunsubscribe\r\n$%d\r\n%s\r\n:0\r\n", len(args[1].(string)), args[1])
+		case "PUBLISH":
+			channel, payload := args[1].(string), args[2].(string)
+			n := 0
+			for sub, channels := range s.conns {
+				if channels[channel] {
+					fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(payload), payload)
+					n++
+				}
+			}
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		case "EVAL":
+			fmt.Fprintf(conn, ":%d\r\n", s.eval(args[1].(string), args[3].(string), args[4].(string), args[5].(string)))
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command\r\n")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// eval runs a lease script on key.
+func (s *fakeRedis) eval(script, key, owner, ms string) int {
+	ttl, _ := strconv.Atoi(ms)
+	l, ok := s.leases[key]
+	if ok && time.Now().After(l.expires) {
+		delete(s.leases, key)
+		ok = false
+	}
+	switch script {
+	case acquireScript:
+		if ok && l.owner != owner {
+			return 0
+		}
+	case renewScript:
+		if !ok || l.owner != owner {
+			return 0
+		}
+	case releaseScript:
+		if !ok || l.owner != owner {
+			return 0
+		}
+		delete(s.leases, key)
+		return 1
+	}
+	s.leases[key] = fakeLease{owner: owner, expires: time.Now().Add(time.Duration(ttl) * time.Millisecond)}
+	return 1
+}
+
+// subscribers returns the number of connections subscribed to channel.
+func (s *fakeRedis) subscribers(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, channels := range s.conns {
+		if channels[channel] {
+			n++
+		}
+	}
+	return n
+}
+
+// drop closes every client connection.
+func (s *fakeRedis) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, got <-chan agent.BusMessage) agent.BusMessage {
+	t.Helper()
+	select {
+	case m := <-got:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered")
+		return agent.BusMessage{}
+	}
+}
+
+func TestRedisDeliversAndResubscribes(t *testing.T) {
+	srv := newFakeRedis(t)
+	b := &Redis{Addr: srv.ln.Addr().String()}
+	defer b.Close()
+	ctx := context.Background()
+
+	got := make(chan agent.BusMessage, 1)
+	unsubscribe, err := b.Subscribe(ctx, "s1", func(m agent.BusMessage) { got <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "subscription", func() bool { return srv.subscribers("pi:bus:s1") == 1 })
+
+	steer := agent.NewAgentMessageFromMessage(ai.NewUserMessage("stop"))
+	if err := agent.PublishSteer(ctx, b, "s1", steer); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, got); m.Kind != agent.BusSteering || m.SessionID != "s1" || m.Message.User.Content[0].Text.Text != "stop" {
+		t.Errorf("received %+v", m)
+	}
+
+	srv.drop()
+	waitFor(t, "resubscription", func() bool { return srv.subscribers("pi:bus:s1") == 1 })
+	if err := agent.PublishFollowUp(ctx, b, "s1", steer); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, got); m.Kind != agent.BusFollowUp {
+		t.Errorf("received %+v after reconnecting", m)
+	}
+
+	unsubscribe()
+	waitFor(t, "unsubscription", func() bool { return srv.subscribers("pi:bus:s1") == 0 })
+}
+
+func TestRedisLeases(t *testing.T) {
+	srv := newFakeRedis(t)
+	a := &Redis{Addr: srv.ln.Addr().String()}
+	b := &Redis{Addr: srv.ln.Addr().String()}
+	defer a.Close()
+	defer b.Close()
+	ctx := context.Background()
+
+	if ok, err := a.Acquire(ctx, "s1", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("a.Acquire = %v, %v", ok, err)
+	}
+	if ok, _ := b.Acquire(ctx, "s1", "b", time.Minute); ok {
+		t.Error("b acquired a lease held by a")
+	}
+	if ok, _ := b.Renew(ctx, "s1", "b", time.Minute); ok {
+		t.Error("b renewed a lease held by a")
+	}
+	srv.mu.Lock()
+	owner := srv.leases["pi:bus:lease:s1"].owner
+	srv.mu.Unlock()
+	if owner != "a" {
+		t.Errorf("lease key held by %q, want a", owner)
+	}
+	if err := a.Release(ctx, "s1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Acquire(ctx, "s1", "b", time.Millisecond); !ok {
+		t.Error("b could not acquire a released lease")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := b.Renew(ctx, "s1", "b", time.Minute); ok {
+		t.Error("b renewed an expired lease")
+	}
+	if ok, _ := a.Acquire(ctx, "s1", "a", time.Minute); !ok {
+		t.Error("a could not acquire an expired lease")
+	}
+}
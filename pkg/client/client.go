@@ -0,0 +1,138 @@
+// Package client drives agents hosted by a pkg/gateway server. A Session
+// offers the familiar Agent API (Prompt, Steer, FollowUp, Abort,
+// Subscribe) but runs remotely, so frontends need no provider code.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/gateway"
+)
+
+// Client is a connection to a gateway server.
+type Client struct {
+	baseURL string
+	token   string
+
+	// Transport sends the HTTP requests; nil uses ai's default transport.
+	Transport ai.Transport
+}
+
+// New creates a client for the gateway at baseURL, authenticating with
+// token when it is non-empty.
+func New(baseURL, token string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+// NewSession creates a remote session with a fresh agent.
+func (c *Client) NewSession(ctx context.Context) (*Session, error) {
+	return c.NewSessionWithKey(ctx, "")
+}
+
+// NewSessionWithKey creates a session whose traffic-split arm is pinned by
+// routingKey (e.g. a user ID); see gateway.Server.SetTrafficSplit.
+func (c *Client) NewSessionWithKey(ctx context.Context, routingKey string) (*Session, error) {
+	var body any
+	if routingKey != "" {
+		body = gateway.CreateRequest{RoutingKey: routingKey}
+	}
+	var out struct {
+		ID  string `json:"id"`
+		Arm string `json:"arm"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/sessions", body, &out); err != nil {
+		return nil, err
+	}
+	sess := c.Session(out.ID)
+	sess.Arm = out.Arm
+	return sess, nil
+}
+
+// Arms fetches the server's traffic-split metrics.
+func (c *Client) Arms(ctx context.Context) ([]gateway.ArmMetrics, error) {
+	var out []gateway.ArmMetrics
+	err := c.do(ctx, http.MethodGet, "/api/arms", nil, &out)
+	return out, err
+}
+
+// Stages fetches the server's turn stage latency percentiles.
+func (c *Client) Stages(ctx context.Context) ([]agent.StageLatency, error) {
+	var out []agent.StageLatency
+	err := c.do(ctx, http.MethodGet, "/api/stages", nil, &out)
+	return out, err
+}
+
+// Session returns a handle to an existing session.
+func (c *Client) Session(id string) *Session {
+	return &Session{ID: id, c: c, listeners: map[int]func(Event){}}
+}
+
+// request builds an authenticated request for path.
+func (c *Client) request(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do performs a JSON request, decoding the response into out if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	req, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := ai.GetTransport(c.Transport).Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// responseError converts a failed response into an *ai.APIError.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &ai.APIError{Provider: "gateway", StatusCode: resp.StatusCode, Message: msg}
+}
+
+// State is a snapshot of a remote agent.
+type State = gateway.State
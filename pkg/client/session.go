@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/ai/sse"
+	"github.com/badlogic/pi-go/pkg/gateway"
+)
+
+// Event is an agent event received from the server.
+type Event = agent.AgentEvent
+
+const (
+	// reconnectDelay is the pause before re-opening a dropped event stream.
+	reconnectDelay = time.Second
+	// subscribeTimeout bounds how long Subscribe waits for the stream.
+	subscribeTimeout = 10 * time.Second
+)
+
+// Session is a remote agent session.
+type Session struct {
+	ID  string
+	Arm string // traffic-split arm assigned at creation, if any
+	c   *Client
+
+	mu             sync.Mutex
+	listeners      map[int]func(Event)
+	nextListenerID int
+	cancel         context.CancelFunc // stops the event stream
+	lastEventID    string
+}
+
+func (s *Session) path(suffix string) string {
+	return "/api/sessions/" + s.ID + suffix
+}
+
+// Prompt sends a text prompt. Like Agent.Prompt it returns once the run has
+// started; follow progress with Subscribe.
+func (s *Session) Prompt(ctx context.Context, text string, images ...ai.ImageContent) error {
+	return s.PromptWithKey(ctx, "", text, images...)
+}
+
+// PromptWithKey sends a prompt with an idempotency key, so it can be retried
+// after a network failure without starting a second run.
+func (s *Session) PromptWithKey(ctx context.Context, key, text string, images ...ai.ImageContent) error {
+	return s.c.do(ctx, http.MethodPost, s.path("/prompt"), gateway.PromptRequest{Text: text, Images: images, RequestID: key}, nil)
+}
+
+// Steer queues a steering message.
+func (s *Session) Steer(ctx context.Context, text string) error {
+	return s.c.do(ctx, http.MethodPost, s.path("/steer"), gateway.MessageRequest{Text: text}, nil)
+}
+
+// FollowUp queues a follow-up message.
+func (s *Session) FollowUp(ctx context.Context, text string) error {
+	return s.c.do(ctx, http.MethodPost, s.path("/follow-up"), gateway.MessageRequest{Text: text}, nil)
+}
+
+// Abort cancels the current run.
+func (s *Session) Abort(ctx context.Context) error {
+	return s.c.do(ctx, http.MethodPost, s.path("/abort"), nil, nil)
+}
+
+// Feedback rates a message of the session, like Agent.RecordFeedback.
+func (s *Session) Feedback(ctx context.Context, messageID string, rating agent.FeedbackRating, comment string) error {
+	return s.c.do(ctx, http.MethodPost, s.path("/feedback"), gateway.FeedbackRequest{MessageID: messageID, Rating: rating, Comment: comment}, nil)
+}
+
+// State fetches a snapshot of the remote agent.
+func (s *Session) State(ctx context.Context) (State, error) {
+	var st State
+	err := s.c.do(ctx, http.MethodGet, s.path(""), nil, &st)
+	return st, err
+}
+
+// Close stops the event stream and deletes the remote session.
+func (s *Session) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.mu.Unlock()
+	return s.c.do(ctx, http.MethodDelete, s.path(""), nil, nil)
+}
+
+// Subscribe registers a listener for the session's events and returns an
+// unsubscribe function. The event stream is opened with the first listener
+// and reconnects (resuming after the last event seen) until the last
+// listener unsubscribes or the session is gone. The first listener waits
+// until the server has accepted the stream, so it sees every event of a
+// Prompt sent after Subscribe returns.
+func (s *Session) Subscribe(fn func(Event)) func() {
+	s.mu.Lock()
+	id := s.nextListenerID
+	s.nextListenerID++
+	s.listeners[id] = fn
+	var ready chan struct{}
+	if s.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		ready = make(chan struct{})
+		go s.stream(ctx, ready)
+	}
+	s.mu.Unlock()
+	if ready != nil {
+		select {
+		case <-ready:
+		case <-time.After(subscribeTimeout):
+		}
+	}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.listeners, id)
+		if len(s.listeners) == 0 && s.cancel != nil {
+			s.cancel()
+			s.cancel = nil
+		}
+	}
+}
+
+// WaitForIdle blocks until the remote agent is not running or ctx ends.
+func (s *Session) WaitForIdle(ctx context.Context) error {
+	idle := make(chan struct{}, 1)
+	unsubscribe := s.Subscribe(func(e Event) {
+		if e.Type == agent.AgentEventEnd {
+			select {
+			case idle <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+	for {
+		st, err := s.State(ctx)
+		if err != nil {
+			return err
+		}
+		if !st.IsStreaming {
+			return nil
+		}
+		select {
+		case <-idle:
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stream reads the SSE event stream until ctx is cancelled or the server
+// refuses it for good. ready is closed once the first attempt has
+// connected or failed.
+func (s *Session) stream(ctx context.Context, ready chan struct{}) {
+	for ctx.Err() == nil {
+		if !s.readEvents(ctx, ready) {
+			return
+		}
+		ready = nil
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// readEvents delivers the events of one connection. It returns false if
+// reconnecting is pointless, e.g. because the session was deleted.
+func (s *Session) readEvents(ctx context.Context, ready chan struct{}) bool {
+	connected := func() {
+		if ready != nil {
+			close(ready)
+			ready = nil
+		}
+	}
+	defer connected()
+	req, err := s.c.request(ctx, http.MethodGet, s.path("/events"), nil)
+	if err != nil {
+		return true
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	s.mu.Lock()
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+	s.mu.Unlock()
+	resp, err := ai.GetTransport(s.c.Transport).Do(ctx, req)
+	if err != nil {
+		return true
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// Client errors such as 404 (session deleted) or 401 do not go away
+		// by retrying; timeouts and rate limits might.
+		switch resp.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return resp.StatusCode >= 500
+	}
+	connected()
+	dec := sse.NewDecoder(resp.Body)
+	for {
+		msg, err := dec.Next()
+		if err != nil {
+			return true
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(msg.Data), &e); err != nil {
+			continue
+		}
+		s.mu.Lock()
+		s.lastEventID = msg.ID
+		listeners := make([]func(Event), 0, len(s.listeners))
+		for _, fn := range s.listeners {
+			listeners = append(listeners, fn)
+		}
+		s.mu.Unlock()
+		for _, fn := range listeners {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/gateway"
+)
+
+func echoAgent() *agent.Agent {
+	a := agent.NewAgent(agent.AgentOptions{
+		StreamFn: func(model *ai.Model, _ ai.Context, _ *ai.SimpleStreamOptions) *ai.AssistantMessageEventStream {
+			msg := &ai.AssistantMessage{Role: ai.RoleAssistant, Model: model.ID, StopReason: ai.StopReasonStop, Content: []ai.Content{ai.NewTextContent("ok")}}
+			out := ai.NewAssistantMessageEventStream()
+			go func() {
+				partial := *msg
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventStart, Partial: &partial})
+				out.Push(ai.AssistantMessageEvent{Type: ai.EventDone, Reason: msg.StopReason, Message: msg})
+			}()
+			return out
+		},
+	})
+	a.SetModel(&ai.Model{ID: "test"})
+	return a
+}
+
+func TestSubscribeSeesEventsOfTheNextPrompt(t *testing.T) {
+	srv := gateway.NewServer(echoAgent, "")
+	defer srv.Close()
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+	ctx := context.Background()
+
+	sess, err := New(hs.URL, "").NewSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var types []agent.AgentEventType
+	ended := make(chan struct{})
+	unsubscribe := sess.Subscribe(func(e Event) {
+		mu.Lock()
+		types = append(types, e.Type)
+		mu.Unlock()
+		if e.Type == agent.AgentEventEnd {
+			close(ended)
+		}
+	})
+	defer unsubscribe()
+
+	if err := sess.Prompt(ctx, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no agent_end")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(types) == 0 || types[0] != agent.AgentEventStart {
+		t.Errorf("events %v, want them from agent_start on", types)
+	}
+}
+
+func TestSubscribeStopsOnDeletedSession(t *testing.T) {
+	var requests atomic.Int32
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+	}))
+	defer hs.Close()
+
+	sess := New(hs.URL, "").Session("gone")
+	unsubscribe := sess.Subscribe(func(Event) {})
+	defer unsubscribe()
+	time.Sleep(reconnectDelay + 500*time.Millisecond)
+	if n := requests.Load(); n != 1 {
+		t.Errorf("event stream requested %d times, want no retries after 404", n)
+	}
+}
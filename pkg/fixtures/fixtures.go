@@ -0,0 +1,330 @@
+// Package fixtures generates JSON fixtures of the wire types (messages,
+// stream events, agent events, LLM contexts, saved sessions) from the Go
+// types themselves.
+// The checked-in copies under testdata/ are the golden files; sibling
+// implementations in other languages load them to verify that they read
+// and write the same format.
+//
+// Regenerate after an intentional wire change with go generate; verify with
+//
+//	go run ./cmd/fixtures -check
+package fixtures
+
+//go:generate go run ../../cmd/fixtures -dir testdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Fixture is one named sample value.
+type Fixture struct {
+	Name  string // file name without the .json extension
+	Value any    // the fixture is the indented JSON encoding of Value
+}
+
+// Encode returns the fixture's file contents.
+func (f Fixture) Encode() ([]byte, error) {
+	data, err := json.MarshalIndent(f.Value, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Write writes every fixture to dir, creating it if needed.
+func Write(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range All() {
+		data, err := f.Encode()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, f.Name+".json"), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the fixture files in dir against the current Go types:
+// each file must equal the fresh encoding, and decoding it into the Go type
+// and encoding again must reproduce it. All mismatches are reported.
+func Verify(dir string) error {
+	var errs []error
+	for _, f := range All() {
+		want, err := f.Encode()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got, err := os.ReadFile(filepath.Join(dir, f.Name+".json"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			errs = append(errs, fmt.Errorf("fixture %s: file differs from the Go encoding (regenerate with go generate ./pkg/fixtures)", f.Name))
+			continue
+		}
+		if err := roundTrip(f, got); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// roundTrip decodes data into a fresh value of the fixture's type and
+// checks that re-encoding it yields the same JSON (key order aside:
+// untyped payloads decode into maps).
+func roundTrip(f Fixture, data []byte) error {
+	v := reflect.New(reflect.TypeOf(f.Value))
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return fmt.Errorf("fixture %s: decode: %w", f.Name, err)
+	}
+	again, err := json.Marshal(v.Elem().Interface())
+	if err != nil {
+		return fmt.Errorf("fixture %s: encode: %w", f.Name, err)
+	}
+	var want, got any
+	if err := json.Unmarshal(data, &want); err != nil {
+		return fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+	if err := json.Unmarshal(again, &got); err != nil {
+		return fmt.Errorf("fixture %s: %w", f.Name, err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("fixture %s: does not survive a decode/encode round trip", f.Name)
+	}
+	return nil
+}
+
+// All returns the fixtures in a fixed order. Values are deterministic so
+// regenerating without a wire change produces identical files.
+func All() []Fixture {
+	return []Fixture{
+		{"messages", messages()},
+		{"context", llmContext()},
+		{"assistant_events", assistantEvents()},
+		{"agent_events", agentEvents()},
+		{"agent_events_v0", legacyAgentEvents()},
+		{"session", savedSession()},
+	}
+}
+
+// timestamp is the fixed Unix ms time used by all fixtures.
+const timestamp int64 = 1700000000000
+
+func assistantMessage() *ai.AssistantMessage {
+	return &ai.AssistantMessage{
+		Role: ai.RoleAssistant,
+		Content: []ai.Content{
+			ai.NewThinkingContent("The user wants the weather."),
+			ai.NewTextContent("Let me check."),
+			ai.NewToolCallContent("call_1", "get_weather", map[string]any{"city": "Berlin"}),
+		},
+		Api:      "openai-completions",
+		Provider: "openai",
+		Model:    "gpt-4o",
+		Usage: ai.Usage{
+			Input: 120, Output: 30, CacheRead: 20, TotalTokens: 170,
+			Cost: ai.Cost{Input: 0.0003, Output: 0.0003, CacheRead: 0.000025, Total: 0.000625},
+		},
+		StopReason: ai.StopReasonToolUse,
+		Timing:     &ai.Timing{TTFTMs: 350, DurationMs: 1200, OutputTokensPerSec: 35.3},
+		Timestamp:  timestamp + 1000,
+	}
+}
+
+func toolResult() *ai.ToolResultMessage {
+	return &ai.ToolResultMessage{
+		Role:       ai.RoleToolResult,
+		ToolCallID: "call_1",
+		ToolName:   "get_weather",
+		Content:    []ai.Content{ai.NewTextContent("12°C, light rain")},
+		Details:    map[string]any{"celsius": 12.0},
+		Timestamp:  timestamp + 2000,
+	}
+}
+
+func messages() []ai.Message {
+	user := &ai.UserMessage{
+		Role: ai.RoleUser,
+		Content: []ai.Content{
+			ai.NewTextContent("What's the weather in Berlin? Here is a photo."),
+			ai.NewImageContent("iVBORw0KGgo=", "image/png"),
+		},
+		Timestamp: timestamp,
+	}
+	failed := &ai.AssistantMessage{
+		Role:         ai.RoleAssistant,
+		Content:      []ai.Content{},
+		Api:          "anthropic-messages",
+		Provider:     "anthropic",
+		Model:        "claude-sonnet-4-5",
+		StopReason:   ai.StopReasonError,
+		ErrorMessage: "overloaded",
+		Timestamp:    timestamp + 3000,
+	}
+	return []ai.Message{
+		{User: user},
+		{Assistant: assistantMessage()},
+		{ToolResult: toolResult()},
+		{Assistant: failed},
+	}
+}
+
+func llmContext() ai.Context {
+	return ai.Context{
+		SystemPrompt: "You are a helpful assistant.",
+		Messages:     messages()[:3],
+		Tools: []ai.Tool{{
+			Name:        "get_weather",
+			Description: "Current weather for a city",
+			Parameters: ai.ToolSchema{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				"required":   []any{"city"},
+			},
+		}},
+	}
+}
+
+func assistantEvents() []ai.AssistantMessageEvent {
+	final := assistantMessage()
+	partial := *final
+	partial.Content = nil
+	partial.StopReason = ""
+	partial.Usage = ai.Usage{}
+	partial.Timing = nil
+	call := final.Content[2].ToolCall
+	return []ai.AssistantMessageEvent{
+		{Type: ai.EventStart, Partial: &partial},
+		{Type: ai.EventThinkingStart, ContentIndex: 0},
+		{Type: ai.EventThinkingDelta, ContentIndex: 0, Delta: "The user wants the weather."},
+		{Type: ai.EventThinkingEnd, ContentIndex: 0, Content: "The user wants the weather."},
+		{Type: ai.EventTextStart, ContentIndex: 1},
+		{Type: ai.EventTextDelta, ContentIndex: 1, Delta: "Let me check."},
+		{Type: ai.EventTextEnd, ContentIndex: 1, Content: "Let me check."},
+		{Type: ai.EventToolCallStart, ContentIndex: 2, ToolCallData: &ai.ToolCall{Type: ai.ContentToolCall, ID: call.ID, Name: call.Name}},
+		{Type: ai.EventToolCallDelta, ContentIndex: 2, Delta: `{"city":"Berlin"}`},
+		{Type: ai.EventToolCallEnd, ContentIndex: 2, ToolCallData: call},
+		{Type: ai.EventDone, Reason: ai.StopReasonToolUse, Message: final},
+	}
+}
+
+func agentEvents() []agent.AgentEvent {
+	msgs := messages()
+	user := &agent.AgentMessage{Message: msgs[0]}
+	reply := &agent.AgentMessage{Message: msgs[1]}
+	result := &agent.AgentMessage{Message: msgs[2]}
+	delta := ai.AssistantMessageEvent{Type: ai.EventTextDelta, ContentIndex: 1, Delta: "Let me check."}
+	args := map[string]any{"city": "Berlin"}
+	toolRes := agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent("12°C, light rain")}, Details: map[string]any{"celsius": 12.0}}
+	return []agent.AgentEvent{
+		{Type: agent.AgentEventStart},
+		{Type: agent.TurnEventStart},
+		{Type: agent.MessageEventStart, Message: user},
+		{Type: agent.MessageEventEnd, Message: user},
+		{Type: agent.MessageEventStart, Message: reply},
+		{Type: agent.MessageEventUpdate, Message: reply, AssistantMessageEvent: &delta},
+		{Type: agent.MessageEventEnd, Message: reply},
+		{Type: agent.ToolCallInvalidEvent, ToolCallID: "call_2", ToolName: "get_weather", Args: map[string]any{"city": 7.0}, ValidationError: "city: expected string"},
+		{Type: agent.ToolApprovalRequestedEvent, ToolCallID: "call_1", ToolName: "get_weather", Args: args},
+		{Type: agent.ToolCacheHitEvent, ToolCallID: "call_1", ToolName: "get_weather", Args: args},
+		agent.ToolExecutionStart{ToolCallID: "call_1", ToolName: "get_weather", Args: args}.Event(),
+		agent.ToolExecutionUpdate{ToolCallID: "call_1", ToolName: "get_weather", Args: args, PartialResult: agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent("fetching")}}}.Event(),
+		agent.ToolExecutionEnd{ToolCallID: "call_1", ToolName: "get_weather", Result: toolRes}.Event(),
+		{Type: agent.MessageEventStart, Message: result},
+		{Type: agent.MessageEventEnd, Message: result},
+		{Type: agent.TurnEventEnd, Message: reply, ToolResults: []ai.ToolResultMessage{*toolResult()}},
+		{Type: agent.ContextUsageEvent, ContextUsage: &agent.ContextUsage{Tokens: 115200, ContextWindow: 128000, Fraction: 0.9, Crossed: 0.85}},
+		{Type: agent.WarningEvent, Warning: "context is 90% full"},
+		{Type: agent.CompactionEvent, Compaction: &agent.Compaction{Summary: "The user asked for the weather in Berlin; it is 12°C with light rain.", Replaced: 3, FirstKept: 3, TokensBefore: 115200, TokensAfter: 2400, Model: "gpt-4o-mini"}},
+		{Type: agent.ImageModerationEvent, ToolCallID: "call_1", ToolName: "get_weather", ImageModeration: &ai.ImageModeration{Content: 1, Role: ai.RoleToolResult, MimeType: "image/png", Hash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Verdict: ai.ImageVerdict{Flagged: true, Categories: []string{"violence"}, Scores: map[string]float64{"violence": 0.91}}, Action: ai.ModerationBlur}},
+		{Type: agent.SubAgentEvent, ToolCallID: "call_2", ToolName: "task", Nested: &agent.NestedEvent{Label: "task", Event: agent.AgentEvent{Type: agent.WarningEvent, Warning: "context is 90% full"}}},
+		{Type: agent.BudgetExceededEvent, BudgetExceeded: &agent.BudgetExceeded{Scope: agent.BudgetRun, Budget: agent.Budget{MaxCost: 0.5}, Usage: ai.Usage{Input: 61200, Output: 4100, TotalTokens: 65300, Cost: ai.Cost{Input: 0.1836, Output: 0.0615, Total: 0.2451}}}},
+		{Type: agent.FeedbackEventRecorded, Feedback: &agent.Feedback{MessageID: "m1", Rating: agent.FeedbackPositive, Comment: "helpful", Timestamp: timestamp + 5000}},
+		{Type: agent.AgentEventEnd, Messages: []agent.AgentMessage{*user, *reply, *result}},
+	}
+}
+
+// legacyEventTypes are the event types that existed in version 0. The
+// legacy fixture is frozen: events added since are left out of it.
+var legacyEventTypes = map[agent.AgentEventType]bool{
+	agent.AgentEventStart:          true,
+	agent.AgentEventEnd:            true,
+	agent.TurnEventStart:           true,
+	agent.TurnEventEnd:             true,
+	agent.MessageEventStart:        true,
+	agent.MessageEventUpdate:       true,
+	agent.MessageEventEnd:          true,
+	agent.ToolExecutionEventStart:  true,
+	agent.ToolExecutionEventUpdate: true,
+	agent.ToolExecutionEventEnd:    true,
+}
+
+// legacyAgentEvents holds the version 0 events of agentEvents in the
+// version 0 encoding, for consumers that still read it.
+func legacyAgentEvents() []json.RawMessage {
+	var out []json.RawMessage
+	for _, e := range agentEvents() {
+		if !legacyEventTypes[e.Type] {
+			continue
+		}
+		data, err := agent.EncodeAgentEvent(e, 0)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, data)
+	}
+	return out
+}
+
+// savedSession is a SaveSession envelope whose messages carry IDs,
+// metadata and an untyped Custom payload.
+func savedSession() agent.SavedSession {
+	var msgs []agent.AgentMessage
+	for i, m := range messages() {
+		am := agent.AgentMessage{Message: m, ID: fmt.Sprintf("m%d", i+1)}
+		if m.User != nil {
+			am.SetMetadata(agent.MetadataRequestID, "req-1")
+		}
+		msgs = append(msgs, am)
+	}
+	msgs = append(msgs, agent.AgentMessage{ID: "note", Custom: map[string]any{"kind": "bookmark", "label": "weather checked"}})
+	return agent.SavedSession{
+		Version:       agent.SessionFormatVersion,
+		SavedAt:       timestamp + 10000,
+		SystemPrompt:  "You are a helpful assistant.",
+		Model:         model(),
+		ThinkingLevel: ai.ThinkingLow,
+		Language:      "en",
+		Messages:      msgs,
+		Usage:         agent.TotalUsage(msgs),
+	}
+}
+
+func model() *ai.Model {
+	return &ai.Model{
+		ID:            "gpt-4o",
+		Name:          "GPT-4o",
+		Api:           "openai-completions",
+		Provider:      "openai",
+		BaseURL:       "https://api.openai.com/v1",
+		Input:         []string{"text", "image"},
+		Cost:          ai.ModelCost{Input: 2.5, Output: 10, CacheRead: 1.25},
+		ContextWindow: 128000,
+		MaxTokens:     16384,
+	}
+}
@@ -0,0 +1,9 @@
+package fixtures
+
+import "testing"
+
+func TestFixtures(t *testing.T) {
+	if err := Verify("testdata"); err != nil {
+		t.Fatalf("%v (run go generate ./pkg/fixtures after an intended wire change)", err)
+	}
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Arm is one configuration in a traffic split, e.g. the current system
+// prompt or model and a candidate being rolled out.
+type Arm struct {
+	Name    string
+	Percent float64 // share of new sessions; shares are normalized over all arms
+
+	// Configure adjusts a fresh agent from newAgent for this arm (set the
+	// system prompt, model, tools, ...). nil leaves it unchanged.
+	Configure func(*agent.Agent)
+}
+
+// ArmMetrics aggregates the sessions assigned to an arm.
+type ArmMetrics struct {
+	Name             string  `json:"name"`
+	Percent          float64 `json:"percent"`
+	Sessions         int     `json:"sessions"`
+	Runs             int     `json:"runs"`
+	Errors           int     `json:"errors"` // assistant messages that ended in error
+	Cost             float64 `json:"cost"`
+	MeanLatencyMs    float64 `json:"meanLatencyMs"` // prompt to agent_end
+	P95LatencyMs     float64 `json:"p95LatencyMs"`
+	PositiveFeedback int     `json:"positiveFeedback"`
+	NegativeFeedback int     `json:"negativeFeedback"`
+}
+
+// latencyWindow is the number of recent run latencies kept per arm.
+const latencyWindow = 1000
+
+type armStats struct {
+	ArmMetrics
+	latencies []time.Duration
+}
+
+// canary holds the traffic split and per-arm metrics.
+type canary struct {
+	mu    sync.Mutex
+	arms  []Arm
+	stats map[string]*armStats
+}
+
+// SetTrafficSplit routes new sessions between arms by Percent. Sessions
+// created with a routing key are assigned deterministically, so a user
+// stays on one arm. Metrics of arms that keep their name are preserved;
+// existing sessions keep their arm. Calling it with no arms disables the
+// split.
+func (s *Server) SetTrafficSplit(arms ...Arm) error {
+	total := 0.0
+	seen := map[string]bool{}
+	for _, a := range arms {
+		if a.Name == "" || seen[a.Name] {
+			return fmt.Errorf("arm names must be unique and non-empty")
+		}
+		if a.Percent < 0 {
+			return fmt.Errorf("arm %s: negative percent", a.Name)
+		}
+		seen[a.Name] = true
+		total += a.Percent
+	}
+	if len(arms) > 0 && total == 0 {
+		return fmt.Errorf("arm percentages sum to zero")
+	}
+	c := &s.canary
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.arms = slices.Clone(arms)
+	if c.stats == nil {
+		c.stats = map[string]*armStats{}
+	}
+	for _, a := range arms {
+		if c.stats[a.Name] == nil {
+			c.stats[a.Name] = &armStats{ArmMetrics: ArmMetrics{Name: a.Name}}
+		}
+	}
+	return nil
+}
+
+// ArmMetrics returns metrics for the arms of the current split, in order.
+func (s *Server) ArmMetrics() []ArmMetrics {
+	c := &s.canary
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0.0
+	for _, a := range c.arms {
+		total += a.Percent
+	}
+	out := make([]ArmMetrics, 0, len(c.arms))
+	for _, a := range c.arms {
+		st := c.stats[a.Name]
+		m := st.ArmMetrics
+		m.Percent = 100 * a.Percent / total
+		if n := len(st.latencies); n > 0 {
+			var sum time.Duration
+			for _, d := range st.latencies {
+				sum += d
+			}
+			sorted := slices.Sorted(slices.Values(st.latencies))
+			m.MeanLatencyMs = float64(sum.Milliseconds()) / float64(n)
+			m.P95LatencyMs = float64(sorted[(n*95-1)/100].Milliseconds())
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// pick chooses an arm for a new session, or nil when there is no split.
+func (c *canary) pick(key string) *Arm {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.arms) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, a := range c.arms {
+		total += a.Percent
+	}
+	x := rand.Float64()
+	if key != "" {
+		sum := sha256.Sum256([]byte(key))
+		x = float64(binary.BigEndian.Uint64(sum[:])>>11) / (1 << 53)
+	}
+	x *= total
+	for i := range c.arms {
+		if x < c.arms[i].Percent {
+			arm := c.arms[i]
+			c.stats[arm.Name].Sessions++
+			return &arm
+		}
+		x -= c.arms[i].Percent
+	}
+	arm := c.arms[len(c.arms)-1]
+	c.stats[arm.Name].Sessions++
+	return &arm
+}
+
+// rejoin returns the named arm of the current split and counts a session
+// for it, or returns nil if the arm no longer exists.
+func (c *canary) rejoin(name string) *Arm {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.arms {
+		if c.arms[i].Name == name {
+			arm := c.arms[i]
+			c.stats[name].Sessions++
+			return &arm
+		}
+	}
+	return nil
+}
+
+// observer returns an event handler that records a session's runs in the
+// arm's metrics.
+func (c *canary) observer(arm string) func(agent.AgentEvent) {
+	var start time.Time
+	return func(e agent.AgentEvent) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		st := c.stats[arm]
+		switch e.Type {
+		case agent.AgentEventStart:
+			start = time.Now()
+			st.Runs++
+		case agent.AgentEventEnd:
+			if !start.IsZero() {
+				st.latencies = append(st.latencies, time.Since(start))
+				if len(st.latencies) > latencyWindow {
+					st.latencies = st.latencies[len(st.latencies)-latencyWindow:]
+				}
+			}
+		case agent.MessageEventEnd:
+			if m := e.Message; m != nil && m.Assistant != nil {
+				st.Cost += m.Assistant.Usage.Cost.Total
+				if m.Assistant.StopReason == ai.StopReasonError {
+					st.Errors++
+				}
+			}
+		case agent.FeedbackEventRecorded:
+			if e.Feedback != nil && e.Feedback.Rating > 0 {
+				st.PositiveFeedback++
+			} else if e.Feedback != nil {
+				st.NegativeFeedback++
+			}
+		}
+	}
+}
+
+func (s *Server) arms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.ArmMetrics())
+}
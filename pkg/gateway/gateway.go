@@ -0,0 +1,352 @@
+// Package gateway serves agents over HTTP so that thin frontends (see
+// pkg/client) can drive them remotely. Each session owns one agent.Agent;
+// commands are JSON POSTs and events stream back as Server-Sent Events in
+// the agent event wire format (agent.EventSchemaVersion).
+//
+// Routes, relative to the mount point:
+//
+//	POST   /api/sessions                   create a session (CreateRequest, optional) → {"id", "arm"}
+//	GET    /api/sessions/{id}              state → State
+//	DELETE /api/sessions/{id}              close the session
+//	POST   /api/sessions/{id}/prompt       PromptRequest
+//	POST   /api/sessions/{id}/steer        MessageRequest
+//	POST   /api/sessions/{id}/follow-up    MessageRequest
+//	POST   /api/sessions/{id}/abort
+//	POST   /api/sessions/{id}/feedback     FeedbackRequest
+//	GET    /api/sessions/{id}/events       SSE; honours Last-Event-ID
+//	GET    /api/arms                       traffic split metrics → []ArmMetrics
+//	GET    /api/stages                     turn stage latencies → []agent.StageLatency
+//	GET    /api/snapshot                   export all sessions (see Export)
+//	POST   /api/snapshot                   import an exported archive → {"imported"}
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/ai/sse"
+)
+
+// PromptRequest is the body of a prompt command.
+type PromptRequest struct {
+	Text      string            `json:"text"`
+	Images    []ai.ImageContent `json:"images,omitempty"`
+	RequestID string            `json:"requestId,omitempty"` // idempotency key
+}
+
+// CreateRequest is the optional body of a create command.
+type CreateRequest struct {
+	// RoutingKey, e.g. a user ID, pins the session's traffic-split arm so
+	// the same key always lands on the same arm.
+	RoutingKey string `json:"routingKey,omitempty"`
+}
+
+// FeedbackRequest is the body of a feedback command.
+type FeedbackRequest struct {
+	MessageID string               `json:"messageId"`
+	Rating    agent.FeedbackRating `json:"rating"`
+	Comment   string               `json:"comment,omitempty"`
+}
+
+// MessageRequest is the body of steer and follow-up commands.
+type MessageRequest struct {
+	Text string `json:"text"`
+}
+
+// State is a snapshot of a session's agent.
+type State struct {
+	Messages    []agent.AgentMessage `json:"messages"`
+	IsStreaming bool                 `json:"isStreaming"`
+	Error       string               `json:"error,omitempty"`
+	Arm         string               `json:"arm,omitempty"` // traffic-split arm
+}
+
+// historySize is the number of events kept per session for reconnecting
+// subscribers.
+const historySize = 1024
+
+// maxRequestBody bounds command bodies; prompts may carry base64 images.
+const maxRequestBody = 32 << 20
+
+// DefaultMaxSessions is the session limit of a Server whose MaxSessions is
+// zero.
+const DefaultMaxSessions = 1000
+
+// Server is an http.Handler hosting agent sessions.
+type Server struct {
+	// Registry is the model registry captured by Export and populated by
+	// Import; nil means the default registry.
+	Registry *ai.Registry
+	// MaxSessions caps the open sessions; creating or importing more fails
+	// with 503 until some are deleted. Default DefaultMaxSessions.
+	MaxSessions int
+
+	newAgent func() *agent.Agent
+	token    string
+	mux      *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*session
+
+	canary  canary
+	latency agent.LatencyRecorder
+}
+
+// NewServer creates a server that builds one agent per session with
+// newAgent. If token is non-empty, requests must carry it as a bearer token.
+func NewServer(newAgent func() *agent.Agent, token string) *Server {
+	s := &Server{newAgent: newAgent, token: token, sessions: map[string]*session{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/sessions", s.create)
+	mux.HandleFunc("GET /api/sessions/{id}", s.withSession(s.state))
+	mux.HandleFunc("DELETE /api/sessions/{id}", s.delete)
+	mux.HandleFunc("POST /api/sessions/{id}/prompt", s.withSession(s.prompt))
+	mux.HandleFunc("POST /api/sessions/{id}/steer", s.withSession(s.steer))
+	mux.HandleFunc("POST /api/sessions/{id}/follow-up", s.withSession(s.followUp))
+	mux.HandleFunc("POST /api/sessions/{id}/abort", s.withSession(s.abort))
+	mux.HandleFunc("POST /api/sessions/{id}/feedback", s.withSession(s.feedback))
+	mux.HandleFunc("GET /api/sessions/{id}/events", s.withSession(s.events))
+	mux.HandleFunc("GET /api/arms", s.arms)
+	mux.HandleFunc("GET /api/stages", s.stages)
+	mux.HandleFunc("GET /api/snapshot", s.exportSnapshot)
+	mux.HandleFunc("POST /api/snapshot", s.importSnapshot)
+	s.mux = mux
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close closes every session.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = map[string]*session{}
+	s.mu.Unlock()
+	var errs []error
+	for _, sess := range sessions {
+		errs = append(errs, sess.close())
+	}
+	return errors.Join(errs...)
+}
+
+// StageLatency returns latency percentiles per turn stage across all
+// sessions.
+func (s *Server) StageLatency() []agent.StageLatency {
+	return s.latency.Stats()
+}
+
+// observer combines the server-wide event hooks with an arm's observer,
+// which may be nil.
+func (s *Server) observer(arm func(agent.AgentEvent)) func(agent.AgentEvent) {
+	return func(e agent.AgentEvent) {
+		s.latency.Observe(e)
+		if arm != nil {
+			arm(e)
+		}
+	}
+}
+
+func (s *Server) stages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.StageLatency())
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+	a := s.newAgent()
+	var sess *session
+	if arm := s.canary.pick(req.RoutingKey); arm != nil {
+		if arm.Configure != nil {
+			arm.Configure(a)
+		}
+		sess = newSession(a, arm.Name, s.observer(s.canary.observer(arm.Name)))
+	} else {
+		sess = newSession(a, "", s.observer(nil))
+	}
+	s.mu.Lock()
+	if len(s.sessions) >= s.maxSessions() {
+		s.mu.Unlock()
+		sess.close()
+		writeError(w, http.StatusServiceUnavailable, errTooManySessions)
+		return
+	}
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, map[string]string{"id": sess.id, "arm": sess.arm})
+}
+
+var errTooManySessions = errors.New("too many sessions")
+
+func (s *Server) maxSessions() int {
+	if s.MaxSessions > 0 {
+		return s.MaxSessions
+	}
+	return DefaultMaxSessions
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	sess := s.sessions[r.PathValue("id")]
+	delete(s.sessions, r.PathValue("id"))
+	s.mu.Unlock()
+	if sess == nil {
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	if err := sess.close(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) withSession(h func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		sess := s.sessions[r.PathValue("id")]
+		s.mu.Unlock()
+		if sess == nil {
+			writeError(w, http.StatusNotFound, errors.New("session not found"))
+			return
+		}
+		h(w, r, sess)
+	}
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request, sess *session) {
+	st := sess.agent.State()
+	writeJSON(w, http.StatusOK, State{Messages: st.Messages, IsStreaming: st.IsStreaming, Error: st.Error, Arm: sess.arm})
+}
+
+func (s *Server) prompt(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req PromptRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := sess.agent.PromptWithKey(req.RequestID, req.Text, req.Images...); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) steer(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req MessageRequest
+	if readJSON(w, r, &req) {
+		sess.agent.Steer(userMessage(req.Text))
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) followUp(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req MessageRequest
+	if readJSON(w, r, &req) {
+		sess.agent.FollowUp(userMessage(req.Text))
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) feedback(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req FeedbackRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := sess.agent.RecordFeedback(req.MessageID, req.Rating, req.Comment); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) abort(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.agent.Abort()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request, sess *session) {
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	ch, backlog, unsubscribe := sess.subscribe(after)
+	defer unsubscribe()
+
+	sse.SetHeaders(w.Header())
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush() // the headers tell the client it is subscribed
+	}
+	enc := sse.NewEncoder(w)
+	send := func(e seqEvent) bool {
+		data, err := json.Marshal(e.event)
+		if err != nil {
+			return true
+		}
+		return enc.WriteMessage(sse.Message{ID: strconv.Itoa(e.seq), Event: string(e.event.Type), Data: string(data)}) == nil
+	}
+	for _, e := range backlog {
+		if !send(e) {
+			return
+		}
+	}
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok || !send(e) {
+				return
+			}
+		case <-keepAlive.C:
+			if enc.Comment("keep-alive") != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func userMessage(text string) agent.AgentMessage {
+	return agent.NewAgentMessageFromMessage(ai.Message{User: &ai.UserMessage{
+		Role:      ai.RoleUser,
+		Content:   []ai.Content{ai.NewTextContent(text)},
+		Timestamp: time.Now().UnixMilli(),
+	}})
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
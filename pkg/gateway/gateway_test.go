@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+func do(t *testing.T, h http.Handler, method, path, auth, body string) int {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestServerLimits(t *testing.T) {
+	s := NewServer(func() *agent.Agent { return agent.NewAgent(agent.AgentOptions{}) }, "secret")
+	s.MaxSessions = 1
+	defer s.Close()
+
+	if code := do(t, s, "POST", "/api/sessions", "Bearer wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d", code)
+	}
+	if code := do(t, s, "POST", "/api/sessions", "Bearer secret", ""); code != http.StatusCreated {
+		t.Fatalf("create: %d", code)
+	}
+	if code := do(t, s, "POST", "/api/sessions", "Bearer secret", ""); code != http.StatusServiceUnavailable {
+		t.Errorf("create beyond MaxSessions: %d", code)
+	}
+	huge := `{"routingKey":"` + strings.Repeat("x", maxRequestBody) + `"}`
+	if code := do(t, s, "POST", "/api/sessions", "Bearer secret", huge); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: %d", code)
+	}
+}
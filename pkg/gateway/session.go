@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// seqEvent is an agent event numbered for SSE replay.
+type seqEvent struct {
+	seq   int
+	event agent.AgentEvent
+}
+
+// session is one hosted agent and the fan-out of its events.
+type session struct {
+	id          string
+	agent       *agent.Agent
+	arm         string                 // traffic-split arm, if any
+	observe     func(agent.AgentEvent) // metrics hook, may be nil
+	unsubscribe func()
+
+	mu      sync.Mutex
+	seq     int
+	history []seqEvent
+	subs    map[chan seqEvent]struct{}
+}
+
+func newSession(a *agent.Agent, arm string, observe func(agent.AgentEvent)) *session {
+	s := &session{id: agent.NewMessageID(), agent: a, arm: arm, observe: observe, subs: map[chan seqEvent]struct{}{}}
+	s.unsubscribe = a.Subscribe(s.publish)
+	return s
+}
+
+// publish records an event and forwards it to subscribers. Subscribers
+// that fall too far behind are dropped; they can reconnect and resume from
+// the history.
+func (s *session) publish(e agent.AgentEvent) {
+	if s.observe != nil {
+		s.observe(e)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	se := seqEvent{seq: s.seq, event: e}
+	s.history = append(s.history, se)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- se:
+		default:
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the events after seq still in history and a channel
+// for later ones.
+func (s *session) subscribe(after int) (<-chan seqEvent, []seqEvent, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var backlog []seqEvent
+	if after > 0 {
+		for _, e := range s.history {
+			if e.seq > after {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	ch := make(chan seqEvent, 256)
+	s.subs[ch] = struct{}{}
+	return ch, backlog, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *session) close() error {
+	s.unsubscribe()
+	s.mu.Lock()
+	for ch := range s.subs {
+		close(ch)
+	}
+	s.subs = map[chan seqEvent]struct{}{}
+	s.mu.Unlock()
+	return s.agent.Close()
+}
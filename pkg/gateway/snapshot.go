@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// SnapshotVersion is the archive format written by Export.
+const SnapshotVersion = 1
+
+// Size limits of an imported archive: maxSnapshotUpload bounds the
+// compressed POST /api/snapshot body, maxSnapshotSize the archive after
+// decompression.
+const (
+	maxSnapshotUpload = 64 << 20
+	maxSnapshotSize   = 512 << 20
+)
+
+// Manifest describes a snapshot archive (manifest.json).
+type Manifest struct {
+	Version   int      `json:"version"`
+	CreatedAt string   `json:"createdAt"` // RFC 3339
+	Sessions  []string `json:"sessions"`
+}
+
+// SessionSnapshot is one session's agent definition and history
+// (sessions/<id>.json). Tools are not serializable; they come from the
+// server's newAgent and the session's arm on import.
+type SessionSnapshot struct {
+	ID            string               `json:"id"`
+	Arm           string               `json:"arm,omitempty"`
+	SystemPrompt  string               `json:"systemPrompt"`
+	Model         *ai.Model            `json:"model,omitempty"`
+	ModelAlias    string               `json:"modelAlias,omitempty"`
+	ThinkingLevel ai.ThinkingLevel     `json:"thinkingLevel,omitempty"`
+	Language      string               `json:"language,omitempty"`
+	Tools         []string             `json:"tools,omitempty"` // names, for reference
+	Messages      []agent.AgentMessage `json:"messages"`
+	Usage         ai.Usage             `json:"usage"` // summed over assistant messages
+}
+
+// RegistrySnapshot holds the overrides of the server's registry
+// (registry.json): the aliases set on it. Model definitions are not part
+// of a snapshot; they come from the code that populates the registry.
+type RegistrySnapshot struct {
+	Aliases map[ai.Provider]map[string]string `json:"aliases,omitempty"`
+}
+
+// Export writes every session, the registry's aliases, and the
+// traffic-split metrics (usage.json) to w as a gzipped tar archive, for
+// backups, migration between environments, and reproducible demos.
+// Sessions that are streaming are captured as of their last committed
+// message.
+func (s *Server) Export(w io.Writer) error {
+	s.mu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].id < sessions[j].id })
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := Manifest{Version: SnapshotVersion, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	for _, sess := range sessions {
+		snap := snapshotSession(sess)
+		manifest.Sessions = append(manifest.Sessions, snap.ID)
+		if err := writeTarJSON(tw, "sessions/"+snap.ID+".json", snap); err != nil {
+			return err
+		}
+	}
+	if err := writeTarJSON(tw, "registry.json", snapshotRegistry(ai.RegistryOrDefault(s.Registry))); err != nil {
+		return err
+	}
+	if err := writeTarJSON(tw, "usage.json", s.ArmMetrics()); err != nil {
+		return err
+	}
+	if err := writeTarJSON(tw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Import restores an archive written by Export: registry aliases are set
+// when their target model is registered, and each session is recreated
+// with newAgent (plus its arm's Configure, if the arm still exists) and
+// then given its saved prompt, model, and history. Models are only looked
+// up in the server's registry, never taken from the archive, so an archive
+// cannot point a session at another endpoint; a session whose model is not
+// registered, or whose ID is already in use, is an error. Arm metrics are
+// not restored. It returns the number of sessions imported.
+func (s *Server) Import(r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("import: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	var registry *RegistrySnapshot
+	var snaps []SessionSnapshot
+	tr := tar.NewReader(&cappedReader{r: gz, n: maxSnapshotSize})
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("import: %w", err)
+		}
+		dec := json.NewDecoder(tr)
+		switch {
+		case hdr.Name == "manifest.json":
+			manifest = &Manifest{}
+			err = dec.Decode(manifest)
+		case hdr.Name == "registry.json":
+			registry = &RegistrySnapshot{}
+			err = dec.Decode(registry)
+		case strings.HasPrefix(hdr.Name, "sessions/"):
+			var snap SessionSnapshot
+			err = dec.Decode(&snap)
+			snaps = append(snaps, snap)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("import %s: %w", hdr.Name, err)
+		}
+	}
+	if manifest == nil {
+		return 0, fmt.Errorf("import: archive has no manifest.json")
+	}
+	if manifest.Version > SnapshotVersion {
+		return 0, fmt.Errorf("import: unsupported snapshot version %d", manifest.Version)
+	}
+
+	reg := ai.RegistryOrDefault(s.Registry)
+	models := make([]*ai.Model, len(snaps))
+	for i, snap := range snaps {
+		if snap.Model == nil {
+			continue
+		}
+		if models[i] = reg.GetModel(snap.Model.Provider, snap.Model.ID); models[i] == nil {
+			return 0, fmt.Errorf("import: session %s uses model %s/%s, which is not registered", snap.ID, snap.Model.Provider, snap.Model.ID)
+		}
+	}
+
+	s.mu.Lock()
+	if len(s.sessions)+len(snaps) > s.maxSessions() {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("import: %w", errTooManySessions)
+	}
+	for _, snap := range snaps {
+		if _, ok := s.sessions[snap.ID]; ok {
+			s.mu.Unlock()
+			return 0, fmt.Errorf("import: session %s already exists", snap.ID)
+		}
+	}
+	s.mu.Unlock()
+
+	if registry != nil {
+		for provider, aliases := range registry.Aliases {
+			for alias, target := range aliases {
+				if reg.GetModel(provider, target) != nil {
+					reg.RegisterAlias(provider, alias, target)
+				}
+			}
+		}
+	}
+
+	for i, snap := range snaps {
+		sess := s.restoreSession(snap, models[i])
+		s.mu.Lock()
+		s.sessions[sess.id] = sess
+		s.mu.Unlock()
+	}
+	return len(snaps), nil
+}
+
+func (s *Server) restoreSession(snap SessionSnapshot, model *ai.Model) *session {
+	a := s.newAgent()
+	var observe func(agent.AgentEvent)
+	if arm := s.canary.rejoin(snap.Arm); arm != nil {
+		if arm.Configure != nil {
+			arm.Configure(a)
+		}
+		observe = s.canary.observer(arm.Name)
+	}
+	a.SetSystemPrompt(snap.SystemPrompt)
+	if model != nil {
+		a.SetModel(model)
+		if snap.ModelAlias != "" {
+			_ = a.SetModelAlias(model.Provider, snap.ModelAlias)
+		}
+	}
+	a.SetThinkingLevel(snap.ThinkingLevel)
+	if snap.Language != "" {
+		a.SetLanguage(snap.Language)
+	}
+	a.ReplaceMessages(snap.Messages)
+
+	arm := ""
+	if observe != nil {
+		arm = snap.Arm
+	}
+	sess := newSession(a, arm, s.observer(observe))
+	sess.id = snap.ID
+	return sess
+}
+
+func snapshotSession(sess *session) SessionSnapshot {
+	st := sess.agent.State()
+	snap := SessionSnapshot{
+		ID:            sess.id,
+		Arm:           sess.arm,
+		SystemPrompt:  st.SystemPrompt,
+		Model:         st.Model,
+		ModelAlias:    st.ModelAlias,
+		ThinkingLevel: st.ThinkingLevel,
+		Language:      st.Language,
+		Messages:      st.Messages,
+	}
+	for _, t := range st.Tools {
+		snap.Tools = append(snap.Tools, t.Name)
+	}
+	snap.Usage = agent.TotalUsage(st.Messages)
+	return snap
+}
+
+func snapshotRegistry(reg *ai.Registry) RegistrySnapshot {
+	snap := RegistrySnapshot{Aliases: map[ai.Provider]map[string]string{}}
+	for _, p := range reg.GetProviders() {
+		if aliases := reg.Aliases(p); len(aliases) > 0 {
+			snap.Aliases[p] = aliases
+		}
+	}
+	return snap
+}
+
+func writeTarJSON(tw *tar.Writer, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("export %s: %w", name, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func (s *Server) exportSnapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="snapshot.tar.gz"`)
+	_ = s.Export(w)
+}
+
+func (s *Server) importSnapshot(w http.ResponseWriter, r *http.Request) {
+	n, err := s.Import(http.MaxBytesReader(w, r.Body, maxSnapshotUpload))
+	if errors.Is(err, errTooManySessions) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"imported": n})
+}
+
+// cappedReader fails once more than n bytes have been read, so that a
+// small archive cannot decompress without bound.
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		return 0, fmt.Errorf("archive exceeds %d bytes", int64(maxSnapshotSize))
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/ai/sse"
+)
+
+// HTTPTransport implements the streamable HTTP transport: every message is
+// POSTed to one endpoint, and the server answers with either a JSON body or
+// an SSE stream carrying the response.
+type HTTPTransport struct {
+	URL     string
+	Headers map[string]string // extra request headers, e.g. Authorization
+
+	// Transport sends the HTTP requests; nil uses ai's default transport
+	// (and so honours the network policy).
+	Transport ai.Transport
+
+	mu        sync.Mutex
+	sessionID string // Mcp-Session-Id assigned by the server
+}
+
+// NewHTTPTransport creates a transport for the server endpoint url.
+func NewHTTPTransport(url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{URL: url, Headers: headers}
+}
+
+func (t *HTTPTransport) post(ctx context.Context, msg any) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := ai.GetTransport(t.Transport).Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &ai.APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(text))}
+	}
+	return resp, nil
+}
+
+// Call implements Transport.
+func (t *HTTPTransport) Call(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var r Response
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &r, nil
+	}
+
+	// The stream may carry server notifications before our response.
+	dec := sse.NewDecoder(resp.Body)
+	for {
+		msg, err := dec.Next()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("read response stream: %w", err)
+		}
+		var r Response
+		if msg.Unmarshal(&r) != nil || r.ID == nil || *r.ID != *req.ID {
+			continue
+		}
+		return &r, nil
+	}
+}
+
+// Notify implements Transport.
+func (t *HTTPTransport) Notify(ctx context.Context, req *Request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Close ends the server session, if one was assigned.
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	id := t.sessionID
+	t.sessionID = ""
+	t.mu.Unlock()
+	if id == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", id)
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := ai.GetTransport(t.Transport).Do(context.Background(), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ConnectHTTP initializes a client for a streamable HTTP server.
+func ConnectHTTP(ctx context.Context, url string, headers map[string]string) (*Client, error) {
+	c := NewClient(NewHTTPTransport(url, headers))
+	if err := c.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	var mu sync.Mutex
+	sessions := map[string]string{} // method → Mcp-Session-Id it was sent with
+	var deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = r.Header.Get("Mcp-Session-Id")
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sessions[req.Method] = r.Header.Get("Mcp-Session-Id")
+		switch req.Method {
+		case "initialize":
+			// A JSON response assigning the session.
+			w.Header().Set("Mcp-Session-Id", "session-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"serverInfo":{"name":"fake","version":"1"}}}`, *req.ID)
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			// An SSE response with a notification ahead of the answer.
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":{\"tools\":[{\"name\":\"echo\"}]}}\n\n", *req.ID)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"unknown method"}}`, *req.ID)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if _, err := ConnectHTTP(ctx, srv.URL, nil); err == nil {
+		t.Error("connecting without the Authorization header succeeded")
+	}
+	c, err := ConnectHTTP(ctx, srv.URL, map[string]string{"Authorization": "Bearer token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.Name != "fake" {
+		t.Errorf("server = %+v", c.Server)
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Errorf("tools = %+v", tools)
+	}
+	if err := c.Ping(ctx); err == nil {
+		t.Error("Ping succeeded, want the server's RPC error")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{"initialize": "", "notifications/initialized": "session-1", "tools/list": "session-1", "ping": "session-1"}
+	for method, id := range want {
+		if sessions[method] != id {
+			t.Errorf("%s sent session %q, want %q", method, sessions[method], id)
+		}
+	}
+	if deleted != "session-1" {
+		t.Errorf("Close deleted session %q, want session-1", deleted)
+	}
+}
@@ -0,0 +1,195 @@
+// Package mcp is a client for Model Context Protocol servers. It speaks
+// JSON-RPC 2.0 over a stdio-spawned process or the streamable HTTP
+// transport and exposes the server's tools as agent.AgentTool values.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP revision the client requests.
+const ProtocolVersion = "2025-03-26"
+
+// Request is a JSON-RPC request or, without ID, a notification.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: %s (code %d)", e.Message, e.Code)
+}
+
+// Transport carries JSON-RPC messages to a server.
+type Transport interface {
+	// Call sends a request and waits for its response.
+	Call(ctx context.Context, req *Request) (*Response, error)
+	// Notify sends a notification.
+	Notify(ctx context.Context, req *Request) error
+	// Close releases the connection (and stops a spawned server).
+	Close() error
+}
+
+// Implementation identifies a client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Tool is a tool advertised by a server.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// Content is one item of a tool result.
+type Content struct {
+	Type     string          `json:"type"` // "text", "image", "audio" or "resource"
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"` // base64 for image and audio
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// CallToolResult is the result of tools/call.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Client is a connection to one MCP server.
+type Client struct {
+	t      Transport
+	nextID atomic.Int64
+
+	// Info holds the client identity sent in initialize.
+	Info Implementation
+
+	// Server and ServerCapabilities are filled in by Initialize.
+	Server             Implementation
+	ServerCapabilities map[string]any
+	Instructions       string
+}
+
+// NewClient wraps a transport. Call Initialize before any other method;
+// the Connect helpers do this for you.
+func NewClient(t Transport) *Client {
+	return &Client{t: t, Info: Implementation{Name: "pi-go", Version: "0.1.0"}}
+}
+
+// call performs a request and decodes its result into out (if non-nil).
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	id := c.nextID.Add(1)
+	req := &Request{JSONRPC: "2.0", ID: &id, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("mcp: encode %s params: %w", method, err)
+		}
+		req.Params = raw
+	}
+	resp, err := c.t.Call(ctx, req)
+	if err != nil {
+		return fmt.Errorf("mcp: %s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("mcp: decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// Initialize performs the protocol handshake.
+func (c *Client) Initialize(ctx context.Context) error {
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		Capabilities    map[string]any `json:"capabilities"`
+		ServerInfo      Implementation `json:"serverInfo"`
+		Instructions    string         `json:"instructions"`
+	}
+	err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      c.Info,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	c.Server = result.ServerInfo
+	c.ServerCapabilities = result.Capabilities
+	c.Instructions = result.Instructions
+	return c.t.Notify(ctx, &Request{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool. A result with IsError set is returned without
+// an error; err reports protocol and transport failures.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Ping checks that the server is responsive.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "ping", nil, nil)
+}
+
+// Close shuts down the connection.
+func (c *Client) Close() error {
+	return c.t.Close()
+}
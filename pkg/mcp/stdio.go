@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// StdioTransport talks to a server process over its stdin and stdout using
+// newline-delimited JSON-RPC messages.
+type StdioTransport struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *Response
+	err     error         // set once the read loop stops
+	done    chan struct{} // closed when the read loop stops
+}
+
+// NewStdioTransport starts command with args. env, if non-nil, is appended
+// to the current environment. The server's stderr is passed through.
+func NewStdioTransport(command string, args []string, env []string) (*StdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: stdin pipe: %w", err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp: start %s: %w", command, err)
+	}
+	t := NewStreamTransport(in, out)
+	t.cmd = cmd
+	return t, nil
+}
+
+// NewStreamTransport speaks the stdio protocol over an existing pair of
+// streams, e.g. an in-process server connected with io.Pipe.
+func NewStreamTransport(w io.WriteCloser, r io.Reader) *StdioTransport {
+	t := &StdioTransport{
+		in:      w,
+		pending: map[int64]chan *Response{},
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(r)
+	return t
+}
+
+func (t *StdioTransport) readLoop(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg struct {
+			Response
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		if msg.Method != "" {
+			t.handleServerMessage(msg.ID, msg.Method)
+			continue
+		}
+		if msg.ID == nil {
+			continue
+		}
+		t.mu.Lock()
+		ch := t.pending[*msg.ID]
+		delete(t.pending, *msg.ID)
+		t.mu.Unlock()
+		if ch != nil {
+			resp := msg.Response
+			ch <- &resp
+		}
+	}
+	err := sc.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = fmt.Errorf("server closed the connection: %w", err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// handleServerMessage answers server-initiated requests. Only ping is
+// supported; notifications are ignored.
+func (t *StdioTransport) handleServerMessage(id *int64, method string) {
+	if id == nil {
+		return
+	}
+	resp := Response{JSONRPC: "2.0", ID: id}
+	if method == "ping" {
+		resp.Result = json.RawMessage(`{}`)
+	} else {
+		resp.Error = &RPCError{Code: -32601, Message: "method not found: " + method}
+	}
+	_ = t.write(resp)
+}
+
+func (t *StdioTransport) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.in.Write(append(data, '\n'))
+	return err
+}
+
+// Call implements Transport.
+func (t *StdioTransport) Call(ctx context.Context, req *Request) (*Response, error) {
+	ch := make(chan *Response, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[*req.ID] = ch
+	t.mu.Unlock()
+	forget := func() {
+		t.mu.Lock()
+		delete(t.pending, *req.ID)
+		t.mu.Unlock()
+	}
+
+	if err := t.write(req); err != nil {
+		forget()
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		forget()
+		return nil, ctx.Err()
+	case <-t.done:
+		forget()
+		return nil, t.err
+	}
+}
+
+// Notify implements Transport.
+func (t *StdioTransport) Notify(ctx context.Context, req *Request) error {
+	return t.write(req)
+}
+
+// closeTimeout is how long Close waits for a spawned server to exit after
+// its stdin is closed before killing it.
+const closeTimeout = 5 * time.Second
+
+// Close closes the server's stdin and waits for the process to exit,
+// killing it if it does not exit in time.
+func (t *StdioTransport) Close() error {
+	err := t.in.Close()
+	if t.cmd == nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- t.cmd.Wait() }()
+	select {
+	case werr := <-exited:
+		if err == nil {
+			err = werr
+		}
+	case <-time.After(closeTimeout):
+		_ = t.cmd.Process.Kill()
+		<-exited
+	}
+	return err
+}
+
+// ConnectStdio spawns a server and initializes a client for it.
+func ConnectStdio(ctx context.Context, command string, args []string, env []string) (*Client, error) {
+	t, err := NewStdioTransport(command, args, env)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(t)
+	if err := c.Initialize(ctx); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+// serveStdio is a minimal in-process MCP server speaking newline-delimited
+// JSON-RPC on r and w. Before answering tools/call it pings the client.
+func serveStdio(t *testing.T, r io.Reader, w io.WriteCloser, notified chan<- string) {
+	// Writes are queued like in a pipe buffer: io.Pipe is unbuffered, and
+	// the client answers the ping from its read loop.
+	out := make(chan []byte, 16)
+	go func() {
+		defer w.Close()
+		for line := range out {
+			w.Write(line)
+		}
+	}()
+	defer close(out)
+	enc := json.NewEncoder(chanWriter(out))
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var req struct {
+			Request
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			t.Errorf("server got %q: %v", sc.Bytes(), err)
+			return
+		}
+		if req.ID == nil {
+			notified <- req.Method
+			continue
+		}
+		var result any
+		switch req.Method {
+		case "": // the client's answer to our ping
+			if *req.ID != 99 || string(req.Result) != "{}" {
+				t.Errorf("ping answer = %s", sc.Bytes())
+			}
+			continue
+		case "initialize":
+			result = map[string]any{"protocolVersion": ProtocolVersion, "serverInfo": map[string]any{"name": "fake", "version": "1"}}
+		case "tools/list":
+			var params struct {
+				Cursor string `json:"cursor"`
+			}
+			json.Unmarshal(req.Params, &params)
+			if params.Cursor == "" {
+				result = map[string]any{"tools": []Tool{{Name: "echo"}}, "nextCursor": "2"}
+			} else {
+				result = map[string]any{"tools": []Tool{{Name: "add"}}}
+			}
+		case "tools/call":
+			id := int64(99)
+			enc.Encode(Request{JSONRPC: "2.0", ID: &id, Method: "ping"})
+			var params struct {
+				Arguments map[string]any `json:"arguments"`
+			}
+			json.Unmarshal(req.Params, &params)
+			result = CallToolResult{Content: []Content{{Type: "text", Text: params.Arguments["text"].(string)}}}
+		default:
+			enc.Encode(Response{JSONRPC: "2.0", ID: req.ID, Error: &RPCError{Code: -32601, Message: "unknown method"}})
+			continue
+		}
+		raw, _ := json.Marshal(result)
+		enc.Encode(Response{JSONRPC: "2.0", ID: req.ID, Result: raw})
+	}
+}
+
+// chanWriter sends each write to a channel.
+type chanWriter chan<- []byte
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func TestStreamTransportRoundTrip(t *testing.T) {
+	serverR, clientW := io.Pipe()
+	clientR, serverW := io.Pipe()
+	notified := make(chan string, 1)
+	go serveStdio(t, serverR, serverW, notified)
+
+	ctx := context.Background()
+	c := NewClient(NewStreamTransport(clientW, clientR))
+	if err := c.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.Name != "fake" {
+		t.Errorf("server = %+v", c.Server)
+	}
+	if m := <-notified; m != "notifications/initialized" {
+		t.Errorf("notification = %q", m)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "add" {
+		t.Errorf("tools = %+v, want both pages", tools)
+	}
+
+	res, err := c.CallTool(ctx, "echo", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Content) != 1 || res.Content[0].Text != "hello" {
+		t.Errorf("result = %+v", res)
+	}
+
+	var rpcErr *RPCError
+	if err := c.Ping(ctx); err == nil || !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Errorf("Ping error = %v, want the server's RPC error", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTools(ctx); err == nil {
+		t.Error("call after the server closed succeeded")
+	}
+}
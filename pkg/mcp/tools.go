@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// AgentTools lists the server's tools and converts them into agent tools
+// whose Execute proxies a tools/call request. prefix, if non-empty, is
+// prepended to every tool name (e.g. "github_") to avoid collisions
+// between servers; the server still sees the original name.
+func (c *Client) AgentTools(ctx context.Context, prefix string) ([]agent.AgentTool, error) {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]agent.AgentTool, 0, len(tools))
+	for _, t := range tools {
+		schema := t.InputSchema
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out = append(out, agent.AgentTool{
+			Tool: ai.Tool{
+				Name:        prefix + t.Name,
+				Description: t.Description,
+				Parameters:  schema,
+			},
+			Label:   t.Name,
+			Execute: c.executor(t.Name),
+		})
+	}
+	return out, nil
+}
+
+func (c *Client) executor(name string) func(context.Context, string, map[string]any, agent.AgentToolUpdateCallback) (agent.AgentToolResult, error) {
+	return func(ctx context.Context, _ string, args map[string]any, _ agent.AgentToolUpdateCallback) (agent.AgentToolResult, error) {
+		res, err := c.CallTool(ctx, name, args)
+		if err != nil {
+			return agent.AgentToolResult{}, err
+		}
+		content := convertContent(res.Content)
+		if res.IsError {
+			return agent.AgentToolResult{}, errors.New(textOf(content))
+		}
+		return agent.AgentToolResult{Content: content, Details: res}, nil
+	}
+}
+
+// convertContent maps MCP result content to ai content. Audio and embedded
+// resources are summarised as text.
+func convertContent(in []Content) []ai.Content {
+	out := make([]ai.Content, 0, len(in))
+	for _, c := range in {
+		switch c.Type {
+		case "text":
+			out = append(out, ai.NewTextContent(c.Text))
+		case "image":
+			out = append(out, ai.Content{Image: &ai.ImageContent{Type: ai.ContentImage, Data: c.Data, MimeType: c.MimeType}})
+		case "resource":
+			out = append(out, ai.NewTextContent(string(c.Resource)))
+		default:
+			out = append(out, ai.NewTextContent("["+c.Type+" content omitted]"))
+		}
+	}
+	return out
+}
+
+func textOf(content []ai.Content) string {
+	var parts []string
+	for _, c := range content {
+		if c.Text != nil {
+			parts = append(parts, c.Text.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "tool call failed"
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Toolset returns the server's tools as an agent.Toolset named name. The
+// prefix is applied by the toolset, so callers may change it before adding.
+func (c *Client) Toolset(ctx context.Context, name, prefix string) (agent.Toolset, error) {
+	tools, err := c.AgentTools(ctx, "")
+	if err != nil {
+		return agent.Toolset{}, err
+	}
+	return agent.Toolset{Name: name, Prefix: prefix, Tools: tools}, nil
+}
+
+// Attach adds the server's tools to a and closes the client when a is
+// closed, tying the connection's lifetime to the agent.
+func (c *Client) Attach(ctx context.Context, a *agent.Agent, prefix string) error {
+	tools, err := c.AgentTools(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	a.SetTools(append(append([]agent.AgentTool{}, a.State().Tools...), tools...))
+	a.AddCloser(c)
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package prompt assembles user messages from mixed sources — literal
+// text, files matched by globs, stdin, the clipboard and URLs — within
+// per-source and overall token budgets.
+//
+//	b := &prompt.Builder{Budget: 50000}
+//	msg, err := b.Message(ctx,
+//		prompt.Text("Review these changes:"),
+//		prompt.Files("pkg/**/*.go").WithBudget(30000),
+//		prompt.Stdin(),
+//	)
+//	err = a.PromptMessages([]agent.AgentMessage{msg})
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+	"github.com/badlogic/pi-go/pkg/textsplit"
+	"github.com/badlogic/pi-go/pkg/tools/web"
+)
+
+// Builder assembles prompts. The zero value is ready to use.
+type Builder struct {
+	// Budget caps the tokens of all text parts together; 0 is unlimited.
+	// Sources are filled in order, so later ones are cut first.
+	Budget int
+
+	// CountTokens counts text tokens (default ai.EstimateTokens).
+	CountTokens func(string) int
+
+	// Dir is the base directory for relative file globs (default: the
+	// working directory).
+	Dir string
+
+	// Fetch configures URL sources.
+	Fetch web.Options
+}
+
+// Source is one input of a prompt.
+type Source struct {
+	name   string
+	budget int
+	load   func(ctx context.Context, b *Builder) ([]part, error)
+}
+
+// part is one piece of loaded content: text or an image.
+type part struct {
+	label  string // heading printed before the text, if any
+	fenced bool   // wrap the text in a code fence
+	lang   string // code fence language
+	text   string
+	image  *ai.ImageContent
+}
+
+// WithBudget limits the tokens this source may contribute.
+func (s Source) WithBudget(tokens int) Source {
+	s.budget = tokens
+	return s
+}
+
+// Text is a literal text source.
+func Text(text string) Source {
+	return Source{name: "text", load: func(context.Context, *Builder) ([]part, error) {
+		return []part{{text: text}}, nil
+	}}
+}
+
+// URL fetches a web page (converted to markdown), text document or image.
+func URL(rawURL string) Source {
+	return Source{name: rawURL, load: func(ctx context.Context, b *Builder) ([]part, error) {
+		res, err := web.FetchTool(b.Fetch).Execute(ctx, "", map[string]any{"url": rawURL}, nil)
+		if err != nil {
+			return nil, err
+		}
+		var parts []part
+		for _, c := range res.Content {
+			switch {
+			case c.Image != nil:
+				parts = append(parts, part{image: c.Image})
+			case c.Text != nil:
+				parts = append(parts, part{label: rawURL, text: c.Text.Text})
+			}
+		}
+		return parts, nil
+	}}
+}
+
+func (b *Builder) count(text string) int {
+	if b.CountTokens != nil {
+		return b.CountTokens(text)
+	}
+	return ai.EstimateTokens(text)
+}
+
+// Build loads every source and returns the assembled content: text parts
+// are joined into text blocks and images kept as image content.
+func (b *Builder) Build(ctx context.Context, sources ...Source) ([]ai.Content, error) {
+	remaining := b.Budget
+	var content []ai.Content
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			content = append(content, ai.NewTextContent(strings.TrimRight(text.String(), "\n")))
+			text.Reset()
+		}
+	}
+
+	for _, src := range sources {
+		parts, err := src.load(ctx, b)
+		if err != nil {
+			return nil, fmt.Errorf("prompt source %s: %w", src.name, err)
+		}
+		budget := src.budget
+		if b.Budget > 0 && (budget <= 0 || budget > remaining) {
+			budget = remaining
+		}
+		limited := budget > 0 || b.Budget > 0
+		for _, p := range parts {
+			if p.image != nil {
+				flush()
+				content = append(content, ai.Content{Image: p.image})
+				continue
+			}
+			body := p.text
+			if limited {
+				var used int
+				body, used = b.truncate(body, budget)
+				budget -= used
+				if b.Budget > 0 {
+					remaining -= used
+				}
+			}
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(format(p, body))
+		}
+	}
+	flush()
+	return content, nil
+}
+
+// Message builds a user message from sources.
+func (b *Builder) Message(ctx context.Context, sources ...Source) (agent.AgentMessage, error) {
+	content, err := b.Build(ctx, sources...)
+	if err != nil {
+		return agent.AgentMessage{}, err
+	}
+	return agent.NewAgentMessageFromMessage(ai.Message{User: &ai.UserMessage{
+		Role:      ai.RoleUser,
+		Content:   content,
+		Timestamp: time.Now().UnixMilli(),
+	}}), nil
+}
+
+// Prompt builds a message from sources and sends it to a.
+func (b *Builder) Prompt(ctx context.Context, a *agent.Agent, sources ...Source) error {
+	msg, err := b.Message(ctx, sources...)
+	if err != nil {
+		return err
+	}
+	return a.PromptMessages([]agent.AgentMessage{msg})
+}
+
+// minPartTokens is the smallest useful excerpt; with less budget left a
+// part is omitted instead of cut.
+const minPartTokens = 32
+
+// truncate cuts text to at most budget tokens, returning the kept text
+// (with a marker when cut) and the tokens it uses.
+func (b *Builder) truncate(text string, budget int) (string, int) {
+	n := b.count(text)
+	if n <= budget {
+		return text, n
+	}
+	if budget < minPartTokens {
+		return "[omitted: token budget exhausted]", 0
+	}
+	// Keep the first chunk within budget, cut at the most natural boundary.
+	kept := ""
+	if chunks := textsplit.ByTokens(text, textsplit.Options{MaxTokens: budget, CountTokens: b.count}); len(chunks) > 0 {
+		kept = chunks[0].Text
+	}
+	return fmt.Sprintf("%s\n[truncated: about %d tokens omitted]", kept, n-b.count(kept)), b.count(kept)
+}
+
+// format renders a text part with its label and code fence.
+func format(p part, body string) string {
+	var sb strings.Builder
+	if p.label != "" {
+		sb.WriteString(p.label + ":\n")
+	}
+	if p.fenced {
+		fence := "```"
+		for strings.Contains(body, fence) {
+			fence += "`"
+		}
+		sb.WriteString(fence + p.lang + "\n" + strings.TrimRight(body, "\n") + "\n" + fence + "\n")
+		return sb.String()
+	}
+	sb.WriteString(body)
+	if !strings.HasSuffix(body, "\n") {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,191 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	osexec "os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// languages maps file extensions to code fence labels.
+var languages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".mjs": "javascript", ".jsx": "jsx",
+	".ts": "typescript", ".tsx": "tsx", ".rs": "rust", ".java": "java", ".kt": "kotlin",
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".rb": "ruby", ".php": "php", ".swift": "swift", ".scala": "scala", ".sh": "bash",
+	".bash": "bash", ".zsh": "zsh", ".ps1": "powershell", ".sql": "sql", ".html": "html",
+	".css": "css", ".scss": "scss", ".json": "json", ".yaml": "yaml", ".yml": "yaml",
+	".toml": "toml", ".xml": "xml", ".md": "markdown", ".proto": "protobuf", ".lua": "lua",
+	".dockerfile": "dockerfile", ".tf": "hcl", ".vue": "vue", ".svelte": "svelte",
+}
+
+// Language returns the code fence label for a file name, or "".
+func Language(name string) string {
+	if strings.EqualFold(filepath.Base(name), "Dockerfile") {
+		return "dockerfile"
+	}
+	if strings.EqualFold(filepath.Base(name), "Makefile") {
+		return "makefile"
+	}
+	return languages[strings.ToLower(filepath.Ext(name))]
+}
+
+// Files includes every file matching the glob patterns ("**" matches any
+// number of directories), each in a code fence labelled with its path and
+// language. Binary files are skipped; a pattern matching nothing is an
+// error.
+func Files(patterns ...string) Source {
+	return Source{name: strings.Join(patterns, " "), load: func(ctx context.Context, b *Builder) ([]part, error) {
+		base := b.Dir
+		if base == "" {
+			base = "."
+		}
+		seen := map[string]bool{}
+		var parts []part
+		for _, pattern := range patterns {
+			matches, err := glob(ctx, base, pattern)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", pattern)
+			}
+			for _, m := range matches {
+				if seen[m] {
+					continue
+				}
+				seen[m] = true
+				data, err := os.ReadFile(filepath.Join(base, m))
+				if err != nil {
+					return nil, err
+				}
+				if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+					parts = append(parts, part{label: m, text: "(binary file omitted)"})
+					continue
+				}
+				parts = append(parts, part{label: m, fenced: true, lang: Language(m), text: string(data)})
+			}
+		}
+		return parts, nil
+	}}
+}
+
+// glob returns the slash-separated paths under base matching pattern,
+// sorted.
+func glob(ctx context.Context, base, pattern string) ([]string, error) {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+	}
+	var out []string
+	err := filepath.WalkDir(base, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(base, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") && !strings.Contains(pattern, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchPath(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			out = append(out, rel)
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
+
+// matchPath matches path segments against pattern segments, where a "**"
+// segment matches zero or more path segments.
+func matchPath(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchPath(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// Reader includes everything read from r in a code fence labelled name.
+func Reader(name string, r io.Reader) Source {
+	return Source{name: name, load: func(context.Context, *Builder) ([]part, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, nil
+		}
+		return []part{{label: name, fenced: true, text: string(data)}}, nil
+	}}
+}
+
+// Stdin includes standard input when it is piped or redirected; it
+// contributes nothing when stdin is a terminal.
+func Stdin() Source {
+	return Source{name: "stdin", load: func(ctx context.Context, b *Builder) ([]part, error) {
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return nil, nil
+		}
+		return Reader("stdin", os.Stdin).load(ctx, b)
+	}}
+}
+
+// clipboardCommands are tried in order to read the clipboard.
+var clipboardCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+// Clipboard includes the system clipboard's text, read with pbpaste,
+// wl-paste, xclip, xsel or PowerShell, whichever is available.
+func Clipboard() Source {
+	return Source{name: "clipboard", load: func(ctx context.Context, b *Builder) ([]part, error) {
+		for _, argv := range clipboardCommands {
+			bin, err := osexec.LookPath(argv[0])
+			if err != nil {
+				continue
+			}
+			out, err := osexec.CommandContext(ctx, bin, argv[1:]...).Output()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", argv[0], err)
+			}
+			if len(bytes.TrimSpace(out)) == 0 {
+				return nil, nil
+			}
+			return []part{{label: "clipboard", fenced: true, text: string(out)}}, nil
+		}
+		return nil, errors.New("no clipboard command found (pbpaste, wl-paste, xclip, xsel)")
+	}}
+}
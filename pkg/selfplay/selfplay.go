@@ -0,0 +1,181 @@
+// Package selfplay runs two agents against each other: each side receives
+// the other's replies as user input. It is meant for negotiation
+// simulations, adversarial testing, and synthetic data generation.
+package selfplay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Player is one side of a match.
+type Player struct {
+	Name  string // label used in the transcript, e.g. "buyer"
+	Agent *agent.Agent
+}
+
+// Turn is one reply in a match.
+type Turn struct {
+	Index   int                // 0-based turn number
+	Speaker string             // Player.Name of the side that replied
+	Input   string             // text the speaker was prompted with
+	Message agent.AgentMessage // the speaker's final assistant message
+	Text    string             // text content of Message
+}
+
+// Verdict is a referee's decision after a turn.
+type Verdict struct {
+	Stop   bool
+	Reason string // why the match ended; recorded in Result.Reason
+	Winner string // optional Player.Name
+
+	// Note, if set, is appended to the next speaker's input, e.g. a
+	// reminder of the rules.
+	Note string
+}
+
+// Referee inspects the transcript after every turn. The last element of
+// transcript is the turn just played.
+type Referee func(ctx context.Context, transcript []Turn) (Verdict, error)
+
+// Stop reasons set by Match itself.
+const (
+	ReasonMaxTurns   = "max_turns"
+	ReasonEmptyReply = "empty_reply"
+)
+
+// Match wires two players together.
+type Match struct {
+	A, B     Player // A speaks first
+	MaxTurns int    // total replies across both sides (default 10)
+	Referee  Referee
+	OnTurn   func(Turn) // called after every turn, before the referee
+}
+
+// Result is the outcome of a match.
+type Result struct {
+	Transcript []Turn
+	Reason     string
+	Winner     string
+}
+
+// Run prompts A with opening and then alternates between the players
+// until MaxTurns is reached, a player replies with no text, or the referee
+// stops the match. Cancelling ctx aborts the active agent. The partial
+// result is returned alongside any error.
+func (m *Match) Run(ctx context.Context, opening string) (*Result, error) {
+	if m.A.Agent == nil || m.B.Agent == nil {
+		return nil, fmt.Errorf("selfplay: both players need an agent")
+	}
+	maxTurns := m.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 10
+	}
+	players := [2]Player{m.A, m.B}
+	for i, name := range []string{"A", "B"} {
+		if players[i].Name == "" {
+			players[i].Name = name
+		}
+	}
+
+	res := &Result{}
+	input := opening
+	for i := 0; i < maxTurns; i++ {
+		p := players[i%2]
+		msg, err := play(ctx, p.Agent, input)
+		if err != nil {
+			return res, fmt.Errorf("selfplay: turn %d (%s): %w", i, p.Name, err)
+		}
+		turn := Turn{Index: i, Speaker: p.Name, Input: input, Message: msg, Text: assistantText(msg)}
+		res.Transcript = append(res.Transcript, turn)
+		if m.OnTurn != nil {
+			m.OnTurn(turn)
+		}
+		if strings.TrimSpace(turn.Text) == "" {
+			res.Reason = ReasonEmptyReply
+			return res, nil
+		}
+
+		input = turn.Text
+		if m.Referee != nil {
+			v, err := m.Referee(ctx, res.Transcript)
+			if err != nil {
+				return res, fmt.Errorf("selfplay: referee: %w", err)
+			}
+			if v.Stop {
+				res.Reason, res.Winner = v.Reason, v.Winner
+				return res, nil
+			}
+			if v.Note != "" {
+				input += "\n\n" + v.Note
+			}
+		}
+	}
+	res.Reason = ReasonMaxTurns
+	return res, nil
+}
+
+// play prompts a and waits for its run to finish, returning the final
+// assistant message.
+func play(ctx context.Context, a *agent.Agent, input string) (agent.AgentMessage, error) {
+	if err := a.Prompt(input); err != nil {
+		return agent.AgentMessage{}, err
+	}
+	idle := make(chan struct{})
+	go func() {
+		a.WaitForIdle()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		a.Abort()
+		<-idle
+		return agent.AgentMessage{}, ctx.Err()
+	}
+
+	state := a.State()
+	if state.Error != "" {
+		return agent.AgentMessage{}, fmt.Errorf("%s", state.Error)
+	}
+	for i := len(state.Messages) - 1; i >= 0; i-- {
+		if msg := state.Messages[i]; msg.Assistant != nil {
+			if msg.Assistant.StopReason == ai.StopReasonError {
+				return msg, fmt.Errorf("%s", msg.Assistant.ErrorMessage)
+			}
+			return msg, nil
+		}
+	}
+	return agent.AgentMessage{}, fmt.Errorf("no assistant reply")
+}
+
+func assistantText(msg agent.AgentMessage) string {
+	if msg.Assistant == nil {
+		return ""
+	}
+	var parts []string
+	for _, c := range msg.Assistant.Content {
+		if c.Text != nil {
+			parts = append(parts, c.Text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// StopOnPhrase returns a referee that ends the match when a reply contains
+// phrase (case-insensitive), e.g. "DEAL" in a negotiation. The speaker is
+// recorded as the winner.
+func StopOnPhrase(phrase string) Referee {
+	phrase = strings.ToLower(phrase)
+	return func(_ context.Context, transcript []Turn) (Verdict, error) {
+		last := transcript[len(transcript)-1]
+		if strings.Contains(strings.ToLower(last.Text), phrase) {
+			return Verdict{Stop: true, Reason: "phrase: " + phrase, Winner: last.Speaker}, nil
+		}
+		return Verdict{}, nil
+	}
+}
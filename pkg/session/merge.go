@@ -0,0 +1,281 @@
+// Package session persists and manipulates conversation histories outside
+// a running agent: an append-only JSONL store with branches (Store), and
+// merging of parallel branches into one canonical history (Merge).
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Branch is one line of a conversation together with the agent settings
+// it ran under. Branches that forked from the same history share a prefix
+// of messages.
+type Branch struct {
+	Name          string // used in conflict reports; defaults to "a" / "b"
+	SystemPrompt  string
+	Model         *ai.Model
+	ThinkingLevel ai.ThinkingLevel
+	Messages      []agent.AgentMessage
+}
+
+// Strategy selects how the messages after the shared prefix are combined.
+type Strategy int
+
+const (
+	// Concat appends all of branch A's new messages, then all of B's.
+	Concat Strategy = iota
+	// Interleave orders the new turns of both branches by timestamp. A turn
+	// (a user message and the replies up to the next user message) is never
+	// split, so tool calls stay next to their results.
+	Interleave
+)
+
+// ConflictKind classifies a conflict found while merging.
+type ConflictKind string
+
+const (
+	ConflictToolCallID ConflictKind = "tool_call_id" // B reused a tool call ID of A; B's was renamed
+	ConflictMessageID  ConflictKind = "message_id"   // same message ID, different content; B's got a new ID
+	ConflictState      ConflictKind = "state"        // the branches ran with different settings; A's were kept
+)
+
+// Conflict describes one conflict and how it was resolved.
+type Conflict struct {
+	Kind   ConflictKind
+	Detail string
+}
+
+// MergeResult is the merged branch and the conflicts resolved on the way.
+// Branch A always wins: its messages and settings are kept verbatim and B
+// is adjusted around them.
+type MergeResult struct {
+	Branch
+	Shared    int // length of the common prefix
+	Conflicts []Conflict
+}
+
+// Merge consolidates two branches into one history. Messages B shares with
+// A (by ID, or by content for messages without one) are kept once; B's
+// tool call IDs and message IDs that collide with A's are renamed.
+func Merge(a, b Branch, strategy Strategy) (*MergeResult, error) {
+	if strategy != Concat && strategy != Interleave {
+		return nil, fmt.Errorf("session: unknown merge strategy %d", strategy)
+	}
+	nameA, nameB := a.Name, b.Name
+	if nameA == "" {
+		nameA = "a"
+	}
+	if nameB == "" {
+		nameB = "b"
+	}
+
+	res := &MergeResult{Branch: Branch{
+		Name:          nameA,
+		SystemPrompt:  a.SystemPrompt,
+		Model:         a.Model,
+		ThinkingLevel: a.ThinkingLevel,
+	}}
+	res.Conflicts = stateConflicts(a, b, nameA, nameB)
+
+	shared := 0
+	for shared < len(a.Messages) && shared < len(b.Messages) && sameMessage(a.Messages[shared], b.Messages[shared]) {
+		shared++
+	}
+	res.Shared = shared
+	tailA := a.Messages[shared:]
+	tailB, conflicts := reconcile(a.Messages, tailA, b.Messages[shared:], nameB)
+	res.Conflicts = append(res.Conflicts, conflicts...)
+
+	msgs := slices.Clone(a.Messages[:shared])
+	switch strategy {
+	case Concat:
+		msgs = append(msgs, tailA...)
+		msgs = append(msgs, tailB...)
+	case Interleave:
+		msgs = append(msgs, interleave(turns(tailA), turns(tailB))...)
+	}
+	res.Messages = msgs
+	return res, nil
+}
+
+// stateConflicts reports settings the branches disagree on.
+func stateConflicts(a, b Branch, nameA, nameB string) []Conflict {
+	var out []Conflict
+	add := func(what string, va, vb any) {
+		out = append(out, Conflict{Kind: ConflictState, Detail: fmt.Sprintf("%s differs: kept %v from %s, dropped %v from %s", what, va, nameA, vb, nameB)})
+	}
+	if a.SystemPrompt != b.SystemPrompt {
+		add("system prompt", fmt.Sprintf("%q", a.SystemPrompt), fmt.Sprintf("%q", b.SystemPrompt))
+	}
+	if modelID(a.Model) != modelID(b.Model) {
+		add("model", modelID(a.Model), modelID(b.Model))
+	}
+	if a.ThinkingLevel != b.ThinkingLevel {
+		add("thinking level", a.ThinkingLevel, b.ThinkingLevel)
+	}
+	return out
+}
+
+func modelID(m *ai.Model) string {
+	if m == nil {
+		return "<none>"
+	}
+	return string(m.Provider) + "/" + m.ID
+}
+
+// sameMessage reports whether x and y are the same message: equal IDs when
+// both have one, equal content otherwise.
+func sameMessage(x, y agent.AgentMessage) bool {
+	if x.ID != "" && y.ID != "" {
+		return x.ID == y.ID
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// reconcile returns B's new messages with duplicates of A's messages
+// dropped and colliding IDs renamed. Messages without an ID are matched by
+// content against A's messages after the shared prefix, each at most once,
+// so a message repeated in B is only dropped as often as A has it. The
+// input messages are not modified.
+func reconcile(msgsA, tailA, tailB []agent.AgentMessage, nameB string) ([]agent.AgentMessage, []Conflict) {
+	msgIDs := map[string]agent.AgentMessage{}
+	callIDs := map[string]bool{}
+	for _, m := range msgsA {
+		if m.ID != "" {
+			msgIDs[m.ID] = m
+		}
+		for _, id := range toolCallIDs(m) {
+			callIDs[id] = true
+		}
+	}
+	var unmatched []agent.AgentMessage // A's new messages without an ID
+	for _, m := range tailA {
+		if m.ID == "" {
+			unmatched = append(unmatched, m)
+		}
+	}
+
+	var out []agent.AgentMessage
+	var conflicts []Conflict
+	renamed := map[string]string{} // B's tool call ID → new ID
+	for _, m := range tailB {
+		if m.ID != "" {
+			if prev, ok := msgIDs[m.ID]; ok {
+				if reflect.DeepEqual(prev, m) {
+					continue // already in A
+				}
+				newID := agent.NewMessageID()
+				conflicts = append(conflicts, Conflict{Kind: ConflictMessageID, Detail: fmt.Sprintf("message %s in %s differs from the one kept; renamed to %s", m.ID, nameB, newID)})
+				m.ID = newID
+			}
+		} else if i := slices.IndexFunc(unmatched, func(u agent.AgentMessage) bool { return reflect.DeepEqual(u, m) }); i >= 0 {
+			unmatched = slices.Delete(unmatched, i, i+1)
+			continue // already in A
+		}
+		switch {
+		case m.Assistant != nil:
+			var msg *ai.AssistantMessage
+			for i, c := range m.Assistant.Content {
+				if c.ToolCall == nil || !callIDs[c.ToolCall.ID] {
+					continue
+				}
+				if msg == nil {
+					clone := *m.Assistant
+					clone.Content = slices.Clone(m.Assistant.Content)
+					msg = &clone
+				}
+				tc := *c.ToolCall
+				newID := uniqueID(tc.ID, callIDs)
+				callIDs[newID] = true
+				conflicts = append(conflicts, Conflict{Kind: ConflictToolCallID, Detail: fmt.Sprintf("tool call %s (%s) in %s renamed to %s", tc.ID, tc.Name, nameB, newID)})
+				renamed[tc.ID] = newID
+				tc.ID = newID
+				msg.Content[i] = ai.Content{ToolCall: &tc}
+			}
+			if msg != nil {
+				m.Message = ai.Message{Assistant: msg}
+			}
+		case m.ToolResult != nil:
+			if newID, ok := renamed[m.ToolResult.ToolCallID]; ok {
+				tr := *m.ToolResult
+				tr.ToolCallID = newID
+				m.Message = ai.Message{ToolResult: &tr}
+			}
+		}
+		for _, id := range toolCallIDs(m) {
+			callIDs[id] = true
+		}
+		out = append(out, m)
+	}
+	return out, conflicts
+}
+
+// uniqueID derives an ID from id that is not in used.
+func uniqueID(id string, used map[string]bool) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", id, n)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+func toolCallIDs(m agent.AgentMessage) []string {
+	if m.Assistant == nil {
+		return nil
+	}
+	var ids []string
+	for _, c := range m.Assistant.Content {
+		if c.ToolCall != nil {
+			ids = append(ids, c.ToolCall.ID)
+		}
+	}
+	return ids
+}
+
+// turns splits msgs at user messages.
+func turns(msgs []agent.AgentMessage) [][]agent.AgentMessage {
+	var out [][]agent.AgentMessage
+	for i, m := range msgs {
+		if i == 0 || m.User != nil {
+			out = append(out, nil)
+		}
+		out[len(out)-1] = append(out[len(out)-1], m)
+	}
+	return out
+}
+
+// interleave merges two turn lists by the timestamp of each turn's first
+// timestamped message; ties go to a.
+func interleave(a, b [][]agent.AgentMessage) []agent.AgentMessage {
+	var out []agent.AgentMessage
+	for len(a) > 0 || len(b) > 0 {
+		if len(b) == 0 || (len(a) > 0 && turnTime(a[0]) <= turnTime(b[0])) {
+			out = append(out, a[0]...)
+			a = a[1:]
+		} else {
+			out = append(out, b[0]...)
+			b = b[1:]
+		}
+	}
+	return out
+}
+
+func turnTime(turn []agent.AgentMessage) int64 {
+	for _, m := range turn {
+		switch {
+		case m.User != nil:
+			return m.User.Timestamp
+		case m.Assistant != nil:
+			return m.Assistant.Timestamp
+		case m.ToolResult != nil:
+			return m.ToolResult.Timestamp
+		}
+	}
+	return 0
+}
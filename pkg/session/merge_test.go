@@ -0,0 +1,99 @@
+package session
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func userAt(text string, ts int64) agent.AgentMessage {
+	return agent.NewAgentMessageFromMessage(ai.Message{User: &ai.UserMessage{Role: ai.RoleUser, Content: []ai.Content{ai.NewTextContent(text)}, Timestamp: ts}})
+}
+
+func callAt(callID, tool string, ts int64) agent.AgentMessage {
+	return agent.NewAgentMessageFromMessage(ai.Message{Assistant: &ai.AssistantMessage{
+		Role:       ai.RoleAssistant,
+		Content:    []ai.Content{ai.NewToolCallContent(callID, tool, map[string]any{})},
+		StopReason: ai.StopReasonToolUse,
+		Timestamp:  ts,
+	}})
+}
+
+func resultAt(callID, text string, ts int64) agent.AgentMessage {
+	return agent.NewAgentMessageFromMessage(ai.Message{ToolResult: &ai.ToolResultMessage{
+		Role:       ai.RoleToolResult,
+		ToolCallID: callID,
+		Content:    []ai.Content{ai.NewTextContent(text)},
+		Timestamp:  ts,
+	}})
+}
+
+func texts(msgs []agent.AgentMessage) []string {
+	var out []string
+	for _, m := range msgs {
+		switch {
+		case m.User != nil:
+			out = append(out, "user:"+m.User.Content[0].Text.Text)
+		case m.Assistant != nil:
+			out = append(out, "call:"+m.Assistant.Content[0].ToolCall.ID)
+		case m.ToolResult != nil:
+			out = append(out, "result:"+m.ToolResult.ToolCallID)
+		}
+	}
+	return out
+}
+
+func TestMergeDropsContentDuplicatesAfterThePrefix(t *testing.T) {
+	start := userAt("start", 1)
+	again := userAt("again", 5)
+	a := Branch{Messages: []agent.AgentMessage{start, userAt("a", 2), again}}
+	b := Branch{Messages: []agent.AgentMessage{start, again, userAt("b", 3), again}}
+
+	res, err := Merge(a, b, Concat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// B's first "again" duplicates A's; its second one is new.
+	want := []string{"user:start", "user:a", "user:again", "user:b", "user:again"}
+	if got := texts(res.Messages); !slices.Equal(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+}
+
+func TestMergeRenamesCollidingToolCalls(t *testing.T) {
+	start := userAt("start", 1)
+	a := Branch{Name: "main", Messages: []agent.AgentMessage{start, callAt("call_1", "read", 2), resultAt("call_1", "a", 3)}}
+	b := Branch{Name: "side", Messages: []agent.AgentMessage{start, callAt("call_1", "write", 4), resultAt("call_1", "b", 5)}}
+
+	res, err := Merge(a, b, Concat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"user:start", "call:call_1", "result:call_1", "call:call_1_2", "result:call_1_2"}
+	if got := texts(res.Messages); !slices.Equal(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+	if len(res.Conflicts) != 1 || res.Conflicts[0].Kind != ConflictToolCallID {
+		t.Errorf("conflicts = %+v", res.Conflicts)
+	}
+	if b.Messages[1].Assistant.Content[0].ToolCall.ID != "call_1" {
+		t.Error("Merge modified its input")
+	}
+}
+
+func TestMergeInterleavesWholeTurns(t *testing.T) {
+	start := userAt("start", 1)
+	a := Branch{Messages: []agent.AgentMessage{start, userAt("a1", 10), callAt("ca", "read", 40), resultAt("ca", "x", 41), userAt("a2", 30)}}
+	b := Branch{Messages: []agent.AgentMessage{start, userAt("b1", 20), userAt("b2", 50)}}
+
+	res, err := Merge(a, b, Interleave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"user:start", "user:a1", "call:ca", "result:ca", "user:b1", "user:a2", "user:b2"}
+	if got := texts(res.Messages); !slices.Equal(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,384 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// StoreVersion is the file format written by Store.
+const StoreVersion = 1
+
+// EntryType discriminates the lines of a session file.
+type EntryType string
+
+const (
+	EntrySession EntryType = "session" // first line: format version and creation time
+	EntryMessage EntryType = "message" // a committed AgentMessage; moves the leaf to it
+	EntryEvent   EntryType = "event"   // an AgentEvent, attached to the leaf at the time
+	EntryBranch  EntryType = "branch"  // moves the leaf back to ParentID
+)
+
+// Entry is one line of a session file. Message entries form a tree through
+// ParentID; the path from the root to a leaf is one branch of the
+// conversation.
+type Entry struct {
+	Type      EntryType           `json:"type"`
+	ID        string              `json:"id"`
+	ParentID  string              `json:"parentId,omitempty"`
+	Timestamp int64               `json:"timestamp"`         // Unix ms
+	Version   int                 `json:"version,omitempty"` // session entries
+	Message   *agent.AgentMessage `json:"message,omitempty"`
+	Event     *agent.AgentEvent   `json:"event,omitempty"`
+}
+
+// Store is an append-only JSONL session file holding a tree of messages,
+// the way coding-agent CLIs persist sessions: every message is appended
+// under the current leaf, Branch moves the leaf to an earlier message so
+// the next message starts a new branch, and nothing is ever rewritten
+// except by Compact. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	entries []Entry
+	byID    map[string]int // index into entries
+	leaf    string         // ID of the current message entry; "" before the first
+}
+
+// load reads the entries in data and returns the length of its valid
+// prefix. An undecodable final line is a write torn by a crash and is
+// skipped; an undecodable line anywhere else is an error.
+func (s *Store) load(data []byte) (int, error) {
+	valid := 0
+	for line, rest := 1, data; len(rest) > 0; line++ {
+		raw, next, complete := bytes.Cut(rest, []byte{'\n'})
+		rest = next
+		if len(bytes.TrimSpace(raw)) == 0 {
+			valid = len(data) - len(rest)
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			if !complete || len(bytes.TrimSpace(rest)) == 0 {
+				return valid, nil
+			}
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Type == EntrySession && e.Version > StoreVersion {
+			return 0, fmt.Errorf("session format version %d is newer than supported version %d", e.Version, StoreVersion)
+		}
+		s.add(e)
+		valid = len(data) - len(rest)
+	}
+	return valid, nil
+}
+
+// add records e in memory and updates the leaf.
+func (s *Store) add(e Entry) {
+	s.byID[e.ID] = len(s.entries)
+	s.entries = append(s.entries, e)
+	switch e.Type {
+	case EntryMessage:
+		s.leaf = e.ID
+	case EntryBranch:
+		s.leaf = e.ParentID
+	}
+}
+
+func (s *Store) appendLocked(e Entry) error {
+	if s.f == nil {
+		return fmt.Errorf("session %s is closed", s.path)
+	}
+	if e.ID == "" {
+		e.ID = agent.NewMessageID()
+	}
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().UnixMilli()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode session entry: %w", err)
+	}
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write session %s: %w", s.path, err)
+	}
+	s.add(e)
+	return nil
+}
+
+// Append adds a message under the current leaf and makes it the new leaf.
+// It returns the entry ID.
+func (s *Store) Append(m agent.AgentMessage) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.appendLocked(Entry{Type: EntryMessage, ParentID: s.leaf, Message: &m}); err != nil {
+		return "", err
+	}
+	return s.leaf, nil
+}
+
+// AppendEvent records an event under the current leaf.
+func (s *Store) AppendEvent(e agent.AgentEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(Entry{Type: EntryEvent, ParentID: s.leaf, Event: &e})
+}
+
+// Handle records agent events; register it with agent.Subscribe. Committed
+// messages (message_end) become message entries; streaming updates and
+// agent_end, which only repeat messages, are skipped; other events are
+// stored as event entries. Write errors are dropped; use Append and
+// AppendEvent directly to see them.
+func (s *Store) Handle(e agent.AgentEvent) {
+	switch e.Type {
+	case agent.MessageEventEnd:
+		if e.Message != nil {
+			_, _ = s.Append(*e.Message)
+		}
+	case agent.MessageEventStart, agent.MessageEventUpdate, agent.AgentEventEnd:
+	default:
+		_ = s.AppendEvent(e)
+	}
+}
+
+// Branch moves the leaf to the message entry id (or to before the first
+// message if id is ""), so the next Append starts a new branch there.
+func (s *Store) Branch(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkMessageLocked(id); err != nil {
+		return err
+	}
+	return s.appendLocked(Entry{Type: EntryBranch, ParentID: id})
+}
+
+// checkMessageLocked reports an error unless id is "" or a message entry.
+func (s *Store) checkMessageLocked(id string) error {
+	if id == "" {
+		return nil
+	}
+	if i, ok := s.byID[id]; !ok || s.entries[i].Type != EntryMessage {
+		return fmt.Errorf("session %s: no message entry %q", s.path, id)
+	}
+	return nil
+}
+
+// Fork appends msgs as a new branch under the message entry parentID
+// ("" for a new root) and returns the new branch's leaf. The current leaf
+// does not change, so a side branch, e.g. an agent.TurnReplay, can be
+// recorded for comparison without leaving the main line.
+func (s *Store) Fork(parentID string, msgs ...agent.AgentMessage) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkMessageLocked(parentID); err != nil {
+		return "", err
+	}
+	back := s.leaf
+	if err := s.appendLocked(Entry{Type: EntryBranch, ParentID: parentID}); err != nil {
+		return "", err
+	}
+	for _, m := range msgs {
+		if err := s.appendLocked(Entry{Type: EntryMessage, ParentID: s.leaf, Message: &m}); err != nil {
+			return "", err
+		}
+	}
+	leaf := s.leaf
+	if err := s.appendLocked(Entry{Type: EntryBranch, ParentID: back}); err != nil {
+		return "", err
+	}
+	return leaf, nil
+}
+
+// Leaf returns the ID of the current leaf message entry.
+func (s *Store) Leaf() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leaf
+}
+
+// Leaves returns the tips of every branch, oldest first.
+func (s *Store) Leaves() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parents := map[string]bool{}
+	for _, e := range s.entries {
+		if e.Type == EntryMessage && e.ParentID != "" {
+			parents[e.ParentID] = true
+		}
+	}
+	var out []string
+	for _, e := range s.entries {
+		if e.Type == EntryMessage && !parents[e.ID] {
+			out = append(out, e.ID)
+		}
+	}
+	return out
+}
+
+// Path returns the message entries from the root to leaf.
+func (s *Store) Path(leaf string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pathLocked(leaf)
+}
+
+func (s *Store) pathLocked(leaf string) []Entry {
+	var out []Entry
+	for id := leaf; id != ""; {
+		i, ok := s.byID[id]
+		if !ok {
+			break
+		}
+		out = append(out, s.entries[i])
+		id = s.entries[i].ParentID
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// Messages returns the messages of the current branch.
+func (s *Store) Messages() []agent.AgentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []agent.AgentMessage
+	for _, e := range s.pathLocked(s.leaf) {
+		out = append(out, *e.Message)
+	}
+	return out
+}
+
+// Entries returns every entry in file order.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.entries)
+}
+
+// Replay loads the current branch into a, replacing its messages.
+func (s *Store) Replay(a *agent.Agent) {
+	a.ReplaceMessages(s.Messages())
+}
+
+// Compact rewrites the file keeping only the branches ending in the
+// current leaf and in keep, with their events; entries of dead branches
+// are dropped. The file is replaced atomically.
+func (s *Store) Compact(keep ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return fmt.Errorf("session %s is closed", s.path)
+	}
+
+	live := map[string]bool{}
+	for _, leaf := range append([]string{s.leaf}, keep...) {
+		for _, e := range s.pathLocked(leaf) {
+			live[e.ID] = true
+		}
+	}
+	// Events recorded with no message yet (ParentID "") belong to the
+	// root-level branch whose first message follows them, so walk the file
+	// backwards tracking whether that message is live.
+	keepEntry := make([]bool, len(s.entries))
+	rootLive := s.leaf == ""
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		switch e.Type {
+		case EntrySession:
+			keepEntry[i] = true
+		case EntryMessage:
+			keepEntry[i] = live[e.ID]
+			rootLive = e.ParentID == "" && live[e.ID]
+		case EntryEvent:
+			if e.ParentID == "" {
+				keepEntry[i] = rootLive
+			} else {
+				keepEntry[i] = live[e.ParentID]
+			}
+		case EntryBranch:
+			rootLive = false
+		}
+	}
+	var kept []Entry
+	for i, e := range s.entries {
+		if keepEntry[i] {
+			kept = append(kept, e)
+		}
+	}
+	// Restore the current leaf, which may not be the last message kept.
+	if n := len(kept); n > 0 && s.leaf != lastMessageID(kept) {
+		kept = append(kept, Entry{Type: EntryBranch, ID: agent.NewMessageID(), ParentID: s.leaf, Timestamp: time.Now().UnixMilli()})
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("compact session %s: %w", s.path, err)
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range kept {
+		data, err := json.Marshal(e)
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("compact session %s: %w", s.path, err)
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("compact session %s: %w", s.path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact session %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact session %s: %w", s.path, err)
+	}
+
+	s.f.Close()
+	s.f, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		s.f = nil
+		return fmt.Errorf("compact session %s: %w", s.path, err)
+	}
+	leaf := s.leaf
+	s.entries, s.byID = nil, map[string]int{}
+	for _, e := range kept {
+		s.add(e)
+	}
+	s.leaf = leaf
+	return nil
+}
+
+func lastMessageID(entries []Entry) string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Type == EntryMessage {
+			return entries[i].ID
+		}
+	}
+	return ""
+}
+
+// Close closes the file. The store cannot be written afterwards.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build js
+
+package session
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Open fails under js: browsers and edge runtimes have no file system for
+// session files. Persist AgentState with Agent.Save instead.
+func Open(path string) (*Store, error) {
+	return nil, fmt.Errorf("open session %s: %w", path, errors.ErrUnsupported)
+}
@@ -0,0 +1,48 @@
+//go:build !js
+
+package session
+
+import (
+	"fmt"
+	"os"
+)
+
+// Open opens the session file at path, creating it if it does not exist.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, byID: map[string]int{}}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open session %s: %w", path, err)
+	}
+	valid := len(data)
+	if len(data) > 0 {
+		if valid, err = s.load(data); err != nil {
+			return nil, fmt.Errorf("open session %s: %w", path, err)
+		}
+	}
+	s.f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open session %s: %w", path, err)
+	}
+	// Drop a final line torn by a crash mid-write, so that the next entry
+	// does not get glued onto it.
+	if valid < len(data) {
+		if err := s.f.Truncate(int64(valid)); err != nil {
+			s.f.Close()
+			return nil, fmt.Errorf("open session %s: %w", path, err)
+		}
+	} else if valid > 0 && data[valid-1] != '\n' {
+		// The last entry is whole but lost its newline.
+		if _, err := s.f.Write([]byte{'\n'}); err != nil {
+			s.f.Close()
+			return nil, fmt.Errorf("open session %s: %w", path, err)
+		}
+	}
+	if len(s.entries) == 0 {
+		if err := s.appendLocked(Entry{Type: EntrySession, Version: StoreVersion}); err != nil {
+			s.f.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
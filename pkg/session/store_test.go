@@ -0,0 +1,81 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func userMessage(text string) agent.AgentMessage {
+	return agent.NewAgentMessageFromMessage(ai.NewUserMessage(text))
+}
+
+func TestOpenToleratesTornFinalLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(userMessage("one")); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"message","id":"torn","mess`)
+	f.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, err := s.Append(userMessage("two")); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen after append: %v", err)
+	}
+	defer s.Close()
+	if msgs := s.Messages(); len(msgs) != 2 {
+		t.Errorf("got %d messages, want 2", len(msgs))
+	}
+}
+
+func TestCompactDropsRootEventsOfDeadBranches(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "s.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.AppendEvent(agent.AgentEvent{Type: agent.AgentEventStart}) // dead root branch
+	s.Append(userMessage("dead"))
+	s.Branch("")
+	s.AppendEvent(agent.AgentEvent{Type: agent.TurnEventStart}) // live root branch
+	s.Append(userMessage("live"))
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []agent.AgentEventType
+	for _, e := range s.Entries() {
+		if e.Type == EntryEvent {
+			events = append(events, e.Event.Type)
+		}
+	}
+	if len(events) != 1 || events[0] != agent.TurnEventStart {
+		t.Errorf("kept events %v, want only the live branch's", events)
+	}
+	if msgs := s.Messages(); len(msgs) != 1 {
+		t.Errorf("got %d messages, want 1", len(msgs))
+	}
+}
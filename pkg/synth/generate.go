@@ -0,0 +1,101 @@
+package synth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// errInvalid marks a sample whose output never passed validation.
+var errInvalid = errors.New("output failed validation")
+
+// generate produces one record for j, retrying with feedback when the
+// output does not match the schema. cost covers every attempt.
+func (g *Generator) generate(ctx context.Context, j job) (rec Record, cost float64, err error) {
+	attempts := g.MaxAttempts
+	if attempts <= 0 {
+		attempts = 2
+	}
+	llmCtx := ai.Context{SystemPrompt: j.system, Messages: []ai.Message{ai.NewUserMessage(j.prompt)}}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		s, err := ai.RegistryOrDefault(g.Registry).StreamCtx(ctx, j.model, llmCtx, g.Options)
+		if err != nil {
+			return Record{}, cost, err
+		}
+		msg := s.Result()
+		if msg == nil {
+			return Record{}, cost, fmt.Errorf("call returned no message")
+		}
+		cost += msg.Usage.Cost.Total
+		if msg.StopReason == ai.StopReasonError || msg.StopReason == ai.StopReasonAborted {
+			return Record{}, cost, fmt.Errorf("call failed: %s", msg.ErrorMessage)
+		}
+		text := strings.TrimSpace(assistantText(msg))
+		if text == "" {
+			return Record{}, cost, fmt.Errorf("empty output")
+		}
+
+		rec = Record{Input: j.input, Provider: j.model.Provider, Model: j.model.ID}
+		if j.system != "" {
+			rec.Messages = append(rec.Messages, Message{Role: "system", Content: j.system})
+		}
+		rec.Messages = append(rec.Messages, Message{Role: "user", Content: j.prompt}, Message{Role: "assistant", Content: text})
+		if g.Schema == nil {
+			rec.Cost = cost
+			return rec, cost, nil
+		}
+
+		label, verr := parseLabel(text, g.Schema)
+		if verr == nil {
+			rec.Label = label
+			rec.Messages[len(rec.Messages)-1].Content = string(label)
+			rec.Cost = cost
+			return rec, cost, nil
+		}
+		llmCtx.Messages = append(llmCtx.Messages,
+			ai.Message{Assistant: msg},
+			ai.NewUserMessage(fmt.Sprintf("That reply was invalid: %v\nReply again with only the corrected JSON.", verr)))
+	}
+	return Record{}, cost, errInvalid
+}
+
+// parseLabel extracts JSON from text and validates it against schema,
+// returning it in compact form.
+func parseLabel(text string, schema ai.ToolSchema) (json.RawMessage, error) {
+	text = stripFence(text)
+	var v any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return nil, fmt.Errorf("not valid JSON: %w", err)
+	}
+	if err := ai.ValidateValue(schema, v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// stripFence removes a surrounding markdown code fence.
+func stripFence(text string) string {
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+func assistantText(msg *ai.AssistantMessage) string {
+	var sb strings.Builder
+	for _, c := range msg.Content {
+		if c.Text != nil {
+			sb.WriteString(c.Text.Text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,230 @@
+// Package synth batch-generates labeled datasets from prompt templates
+// across one or more models. Outputs can be validated against a JSON
+// schema, duplicates are dropped, spending is capped, and records are
+// written as JSONL for fine-tuning or eval sets.
+package synth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Task describes what to generate. System and Prompt are text/template
+// sources executed with each input; referencing a missing key is an error.
+type Task struct {
+	System  string
+	Prompt  string
+	Inputs  []map[string]any
+	Samples int // generations per input per model (default 1)
+}
+
+// Format selects the JSONL record layout.
+type Format int
+
+const (
+	// FormatFull writes every Record field.
+	FormatFull Format = iota
+	// FormatChat writes only {"messages": [...]}, the layout most
+	// fine-tuning APIs accept.
+	FormatChat
+)
+
+// Generator runs a Task against Models.
+type Generator struct {
+	Models   []*ai.Model
+	Registry *ai.Registry      // nil uses the default registry
+	Options  *ai.StreamOptions // API key, temperature, max tokens, ...
+
+	// Schema, when set, requires each output to be JSON matching it
+	// (markdown code fences are stripped). The parsed value is stored in
+	// Record.Label.
+	Schema ai.ToolSchema
+	// MaxAttempts bounds calls per sample when validation fails; the
+	// validation error is fed back to the model on retry (default 2).
+	MaxAttempts int
+
+	// MaxCost stops the batch once this much has been spent (in the
+	// currency of Model.Cost); 0 means unlimited. Calls already in flight
+	// finish, so the cap can be exceeded by up to Concurrency calls.
+	MaxCost float64
+
+	// KeepDuplicates disables deduplication. By default outputs whose
+	// normalized text was already generated are dropped.
+	KeepDuplicates bool
+
+	Concurrency int // parallel calls (default 4)
+	Format      Format
+	OnRecord    func(Record) // called for every record written
+}
+
+// Message is one chat turn of a Record.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Record is one generated example.
+type Record struct {
+	Messages []Message       `json:"messages"`
+	Label    json.RawMessage `json:"label,omitempty"`
+	Input    map[string]any  `json:"input,omitempty"`
+	Provider ai.Provider     `json:"provider"`
+	Model    string          `json:"model"`
+	Cost     float64         `json:"cost"`
+}
+
+// Stats summarizes a run.
+type Stats struct {
+	Written    int
+	Duplicates int
+	Invalid    int // outputs still failing validation after MaxAttempts
+	Failed     int // calls that errored
+	Cost       float64
+	CostCapped bool // stopped early because MaxCost was reached
+}
+
+type job struct {
+	model  *ai.Model
+	input  map[string]any
+	system string
+	prompt string
+}
+
+// Run generates the dataset and writes it to w as JSONL. Individual call
+// failures are counted in Stats; an error is returned only for template
+// errors, write errors, or cancellation.
+func (g *Generator) Run(ctx context.Context, task Task, w io.Writer) (Stats, error) {
+	if len(g.Models) == 0 {
+		return Stats{}, fmt.Errorf("synth: no models")
+	}
+	systemTmpl, err := template.New("system").Option("missingkey=error").Parse(task.System)
+	if err != nil {
+		return Stats{}, fmt.Errorf("synth: system template: %w", err)
+	}
+	promptTmpl, err := template.New("prompt").Option("missingkey=error").Parse(task.Prompt)
+	if err != nil {
+		return Stats{}, fmt.Errorf("synth: prompt template: %w", err)
+	}
+	samples := max(task.Samples, 1)
+
+	var jobs []job
+	for i, input := range task.Inputs {
+		system, err := render(systemTmpl, input)
+		if err != nil {
+			return Stats{}, fmt.Errorf("synth: input %d: %w", i, err)
+		}
+		prompt, err := render(promptTmpl, input)
+		if err != nil {
+			return Stats{}, fmt.Errorf("synth: input %d: %w", i, err)
+		}
+		for _, m := range g.Models {
+			for range samples {
+				jobs = append(jobs, job{model: m, input: input, system: system, prompt: prompt})
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		stats    Stats
+		seen     = map[string]bool{}
+		enc      = json.NewEncoder(w)
+		writeErr error
+	)
+	enc.SetEscapeHTML(false)
+
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for range max(g.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				mu.Lock()
+				capped := g.MaxCost > 0 && stats.Cost >= g.MaxCost
+				stats.CostCapped = stats.CostCapped || capped
+				mu.Unlock()
+				if capped {
+					continue
+				}
+				rec, cost, err := g.generate(ctx, j)
+
+				mu.Lock()
+				stats.Cost += cost
+				switch {
+				case err == errInvalid:
+					stats.Invalid++
+				case err != nil:
+					stats.Failed++
+				case !g.KeepDuplicates && seen[dedupKey(rec)]:
+					stats.Duplicates++
+				default:
+					seen[dedupKey(rec)] = true
+					if err := g.write(enc, rec); err != nil {
+						writeErr = err
+						cancel()
+					} else {
+						stats.Written++
+						if g.OnRecord != nil {
+							g.OnRecord(rec)
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if writeErr != nil {
+		return stats, fmt.Errorf("synth: write: %w", writeErr)
+	}
+	return stats, ctx.Err()
+}
+
+func (g *Generator) write(enc *json.Encoder, rec Record) error {
+	if g.Format == FormatChat {
+		return enc.Encode(struct {
+			Messages []Message `json:"messages"`
+		}{rec.Messages})
+	}
+	return enc.Encode(rec)
+}
+
+func render(t *template.Template, data map[string]any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// dedupKey hashes the normalized output text of rec.
+func dedupKey(rec Record) string {
+	out := rec.Messages[len(rec.Messages)-1].Content
+	norm := strings.Join(strings.Fields(strings.ToLower(out)), " ")
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
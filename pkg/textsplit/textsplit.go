@@ -0,0 +1,287 @@
+// Package textsplit splits text into token-bounded chunks. It is shared by
+// context compaction (transcripts too large for the summary model) and
+// prompt.Builder (sources cut to their token budget), and is meant for
+// retrieval helpers too, so that every component chunks text the same way.
+package textsplit
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Options configures a splitter.
+type Options struct {
+	MaxTokens   int              // maximum tokens per chunk (default 512)
+	Overlap     int              // tokens repeated from the end of the previous chunk
+	CountTokens func(string) int // token counter (default ai.EstimateTokens, see ai.SetDefaultTokenizer)
+}
+
+// Chunk is a contiguous slice of the source text.
+type Chunk struct {
+	Text    string
+	Start   int    // byte offset in the source
+	End     int    // byte offset in the source (exclusive)
+	Tokens  int    // token count of Text
+	Heading string // markdown heading path or code symbol, if any
+}
+
+// proseSeparators are tried in order when a span is too large.
+var proseSeparators = []string{"\n\n", "\n", ". ", " "}
+
+// lineSeparators are used to split code symbols.
+var lineSeparators = []string{"\n\n", "\n", " "}
+
+func (o Options) withDefaults() Options {
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = 512
+	}
+	if o.Overlap < 0 || o.Overlap >= o.MaxTokens {
+		o.Overlap = 0
+	}
+	if o.CountTokens == nil {
+		o.CountTokens = ai.EstimateTokens
+	}
+	return o
+}
+
+type span struct{ start, end int }
+
+// ByTokens splits text at the most natural boundary available (paragraph,
+// line, sentence, word, then raw characters) so that no chunk exceeds
+// MaxTokens.
+func ByTokens(text string, opts Options) []Chunk {
+	opts = opts.withDefaults()
+	return splitSpan(text, 0, len(text), proseSeparators, "", opts)
+}
+
+// splitSpan chunks text[start:end] and labels every chunk with heading.
+func splitSpan(text string, start, end int, seps []string, heading string, opts Options) []Chunk {
+	if start >= end {
+		return nil
+	}
+	pieces := splitPieces(text, start, end, seps, opts)
+	return mergePieces(text, pieces, heading, opts)
+}
+
+// splitPieces breaks text[start:end] into spans that each fit MaxTokens.
+func splitPieces(text string, start, end int, seps []string, opts Options) []span {
+	if opts.CountTokens(text[start:end]) <= opts.MaxTokens {
+		return []span{{start, end}}
+	}
+	if len(seps) == 0 {
+		return hardSplit(text, start, end, opts)
+	}
+
+	var out []span
+	sep := seps[0]
+	pos := start
+	for pos < end {
+		i := strings.Index(text[pos:end], sep)
+		next := end
+		if i >= 0 {
+			next = pos + i + len(sep)
+		}
+		out = append(out, splitPieces(text, pos, next, seps[1:], opts)...)
+		pos = next
+	}
+	return out
+}
+
+// hardSplit cuts text[start:end] at rune boundaries when no separator helps.
+func hardSplit(text string, start, end int, opts Options) []span {
+	var out []span
+	for start < end {
+		cut := end
+		for opts.CountTokens(text[start:cut]) > opts.MaxTokens {
+			n := cut - start
+			cut = start + n*3/4
+			for cut > start && !runeStart(text[cut]) {
+				cut--
+			}
+			if cut <= start {
+				cut = start + 1
+				for cut < end && !runeStart(text[cut]) {
+					cut++
+				}
+				break
+			}
+		}
+		out = append(out, span{start, cut})
+		start = cut
+	}
+	return out
+}
+
+func runeStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// mergePieces greedily packs adjacent spans into chunks of at most
+// MaxTokens, carrying Overlap tokens of trailing spans into the next chunk.
+func mergePieces(text string, pieces []span, heading string, opts Options) []Chunk {
+	var out []Chunk
+	emit := func(first, last int) {
+		s, e := pieces[first].start, pieces[last].end
+		chunkText := text[s:e]
+		if strings.TrimSpace(chunkText) == "" {
+			return
+		}
+		out = append(out, Chunk{
+			Text:    chunkText,
+			Start:   s,
+			End:     e,
+			Tokens:  opts.CountTokens(chunkText),
+			Heading: heading,
+		})
+	}
+
+	first := 0
+	for first < len(pieces) {
+		last := first
+		for last+1 < len(pieces) &&
+			opts.CountTokens(text[pieces[first].start:pieces[last+1].end]) <= opts.MaxTokens {
+			last++
+		}
+		emit(first, last)
+		if last+1 >= len(pieces) {
+			break
+		}
+
+		// Start the next chunk with trailing pieces that fit in Overlap,
+		// but only as many as leave room for the next new piece, so that
+		// no chunk consists of overlap alone.
+		next := last + 1
+		for opts.Overlap > 0 && next-1 > first &&
+			opts.CountTokens(text[pieces[next-1].start:pieces[last].end]) <= opts.Overlap &&
+			opts.CountTokens(text[pieces[next-1].start:pieces[last+1].end]) <= opts.MaxTokens {
+			next--
+		}
+		first = next
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// Markdown
+// ---------------------------------------------------------------------------
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// ByMarkdown splits text into sections at headings (ignoring headings inside
+// fenced code blocks). Each chunk's Heading is the path of enclosing
+// headings, e.g. "Install > Linux". Sections larger than MaxTokens are split
+// further with ByTokens rules.
+func ByMarkdown(text string, opts Options) []Chunk {
+	opts = opts.withDefaults()
+
+	var out []Chunk
+	var path []string
+	sectionStart := 0
+	heading := ""
+	inFence := false
+
+	flush := func(end int) {
+		out = append(out, splitSpan(text, sectionStart, end, proseSeparators, heading, opts)...)
+	}
+
+	pos := 0
+	for pos < len(text) {
+		lineEnd := strings.IndexByte(text[pos:], '\n')
+		next := len(text)
+		if lineEnd >= 0 {
+			next = pos + lineEnd + 1
+		}
+		line := strings.TrimRight(text[pos:next], "\r\n")
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			if m := headingPattern.FindStringSubmatch(line); m != nil {
+				flush(pos)
+				level := len(m[1])
+				if level-1 < len(path) {
+					path = path[:level-1]
+				}
+				for len(path) < level-1 {
+					path = append(path, "")
+				}
+				path = append(path, m[2])
+				heading = joinHeadings(path)
+				sectionStart = pos
+			}
+		}
+		pos = next
+	}
+	flush(len(text))
+	return out
+}
+
+func joinHeadings(path []string) string {
+	parts := make([]string, 0, len(path))
+	for _, p := range path {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// ---------------------------------------------------------------------------
+// Code
+// ---------------------------------------------------------------------------
+
+// symbolPattern matches lines that start a top-level declaration in common
+// languages (Go, Python, JS/TS, Rust, Java/C#, C-like).
+var symbolPattern = regexp.MustCompile(`^(?:export\s+)?(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:default\s+)?(?:abstract\s+|final\s+|static\s+|public\s+|private\s+|protected\s+)*(?:func|type|const|var|def|class|interface|enum|struct|trait|impl|fn|mod|function|let)\b`)
+
+// commentPattern matches lines that belong to the following declaration.
+var commentPattern = regexp.MustCompile(`^\s*(?://|#|/\*|\*|--|@)`)
+
+// ByCode splits source code at top-level symbol boundaries. Doc comments and
+// decorators directly above a symbol stay with it; each chunk's Heading is
+// the symbol's first line. Symbols larger than MaxTokens are split at blank
+// lines and then line boundaries.
+func ByCode(text string, opts Options) []Chunk {
+	opts = opts.withDefaults()
+
+	type line struct{ start, end int }
+	var lines []line
+	for pos := 0; pos < len(text); {
+		i := strings.IndexByte(text[pos:], '\n')
+		next := len(text)
+		if i >= 0 {
+			next = pos + i + 1
+		}
+		lines = append(lines, line{pos, next})
+		pos = next
+	}
+
+	var out []Chunk
+	sectionStart := 0
+	heading := ""
+	for i, l := range lines {
+		content := strings.TrimRight(text[l.start:l.end], "\r\n")
+		if !symbolPattern.MatchString(content) {
+			continue
+		}
+		// Pull preceding comment/decorator lines into this symbol.
+		start := l.start
+		for j := i - 1; j >= 0; j-- {
+			prev := strings.TrimRight(text[lines[j].start:lines[j].end], "\r\n")
+			if !commentPattern.MatchString(prev) {
+				break
+			}
+			start = lines[j].start
+		}
+		if start > sectionStart {
+			out = append(out, splitSpan(text, sectionStart, start, lineSeparators, heading, opts)...)
+			sectionStart = start
+		}
+		heading = strings.TrimSpace(content)
+	}
+	out = append(out, splitSpan(text, sectionStart, len(text), lineSeparators, heading, opts)...)
+	return out
+}
@@ -0,0 +1,87 @@
+package textsplit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// words counts whitespace-separated words, which keeps the tests
+// independent of the default tokenizer.
+func words(s string) int { return len(strings.Fields(s)) }
+
+func sentences(n int) string {
+	var sb strings.Builder
+	for i := range n {
+		fmt.Fprintf(&sb, "Sentence %d has five words. ", i)
+	}
+	return sb.String()
+}
+
+func TestByTokensRespectsMaxTokens(t *testing.T) {
+	text := sentences(40)
+	chunks := ByTokens(text, Options{MaxTokens: 12, CountTokens: words})
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	var rebuilt strings.Builder
+	for i, c := range chunks {
+		if c.Tokens > 12 {
+			t.Errorf("chunk %d has %d tokens", i, c.Tokens)
+		}
+		if text[c.Start:c.End] != c.Text {
+			t.Errorf("chunk %d offsets do not match its text", i)
+		}
+		if !strings.HasSuffix(c.Text, ". ") {
+			t.Errorf("chunk %d %q is not cut at a sentence", i, c.Text)
+		}
+		rebuilt.WriteString(c.Text)
+	}
+	if rebuilt.String() != text {
+		t.Error("chunks without overlap do not rebuild the text")
+	}
+}
+
+func TestByTokensOverlapNeverRepeatsAlone(t *testing.T) {
+	// Pieces of very different sizes: a short piece followed by one that
+	// only fits on its own used to produce a chunk of pure overlap.
+	text := "a b c d e f g h. c d e f g h. k l. m n o p q r s t. u."
+	chunks := ByTokens(text, Options{MaxTokens: 9, Overlap: 2, CountTokens: words})
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].End <= chunks[i-1].End {
+			t.Errorf("chunk %d %q adds nothing after %q", i, chunks[i].Text, chunks[i-1].Text)
+		}
+		if chunks[i].Start >= chunks[i-1].End {
+			continue
+		}
+		if overlap := words(text[chunks[i].Start:chunks[i-1].End]); overlap > 2 {
+			t.Errorf("chunk %d repeats %d tokens", i, overlap)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.End != len(text) {
+		t.Errorf("text not covered: last chunk ends at %d of %d", last.End, len(text))
+	}
+}
+
+func TestByMarkdownHeadings(t *testing.T) {
+	text := "# Install\nintro\n## Linux\napt install\n```\n# not a heading\n```\n## macOS\nbrew install\n"
+	var got []string
+	for _, c := range ByMarkdown(text, Options{CountTokens: words}) {
+		got = append(got, c.Heading)
+	}
+	want := []string{"Install", "Install > Linux", "Install > macOS"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("headings = %q, want %q", got, want)
+	}
+}
+
+func TestByCodeKeepsCommentsWithSymbols(t *testing.T) {
+	text := "package p\n\n// A does a.\nfunc A() {}\n\n// B does b.\nfunc B() {}\n"
+	chunks := ByCode(text, Options{CountTokens: words})
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[2].Text, "// B does b.") || chunks[2].Heading != "func B() {}" {
+		t.Errorf("last chunk = %+v", chunks[2])
+	}
+}
@@ -0,0 +1,179 @@
+// Package exec provides a shell tool for agents. Commands run with a
+// timeout, a filtered environment and truncated output; a Sandbox lets
+// hosts wrap every command in container, bubblewrap or seatbelt isolation.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"sync"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Options configures the shell tool.
+type Options struct {
+	// Shell is the interpreter and flags the command string is appended
+	// to. Defaults to ["bash", "-c"], or ["sh", "-c"] without bash.
+	Shell []string
+
+	// Dir is the working directory; commands may pick a subdirectory with
+	// the cwd argument. Defaults to the process working directory.
+	Dir string
+
+	// Timeout is the default limit per command (default 2 minutes).
+	// MaxTimeout caps the timeout the model may request (default 10
+	// minutes).
+	Timeout    time.Duration
+	MaxTimeout time.Duration
+
+	// MaxOutputBytes truncates combined stdout/stderr, keeping the start
+	// and the end (default 30000).
+	MaxOutputBytes int
+
+	// EnvAllowlist names the host environment variables passed through;
+	// nil uses DefaultEnvAllowlist. Env adds or overrides variables.
+	EnvAllowlist []string
+	Env          map[string]string
+
+	// Sandbox, when set, builds the command so it runs isolated.
+	Sandbox Sandbox
+}
+
+// DefaultEnvAllowlist is passed through when Options.EnvAllowlist is nil.
+var DefaultEnvAllowlist = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TMPDIR", "SHELL"}
+
+// updateInterval throttles streamed output updates.
+const updateInterval = 200 * time.Millisecond
+
+func (o Options) shell() []string {
+	if len(o.Shell) > 0 {
+		return o.Shell
+	}
+	if _, err := osexec.LookPath("bash"); err == nil {
+		return []string{"bash", "-c"}
+	}
+	return []string{"sh", "-c"}
+}
+
+func (o Options) timeout(requestedSec *int) time.Duration {
+	d := o.Timeout
+	if d <= 0 {
+		d = 2 * time.Minute
+	}
+	if requestedSec != nil && *requestedSec > 0 {
+		d = time.Duration(*requestedSec) * time.Second
+	}
+	limit := o.MaxTimeout
+	if limit <= 0 {
+		limit = 10 * time.Minute
+	}
+	return min(d, limit)
+}
+
+func (o Options) maxOutput() int {
+	if o.MaxOutputBytes > 0 {
+		return o.MaxOutputBytes
+	}
+	return 30000
+}
+
+// environ builds the command environment from the allowlist and Env.
+func (o Options) environ() []string {
+	allow := o.EnvAllowlist
+	if allow == nil {
+		allow = DefaultEnvAllowlist
+	}
+	var env []string
+	for _, k := range allow {
+		if _, override := o.Env[k]; override {
+			continue
+		}
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+	for k, v := range o.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// Args are the arguments of the bash tool.
+type Args struct {
+	Command string  `json:"command" jsonschema:"description=Shell command to run"`
+	Timeout *int    `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds"`
+	Cwd     *string `json:"cwd,omitempty" jsonschema:"description=Working directory\\, relative to the default one"`
+}
+
+// Details describes a successful command.
+type Details struct {
+	Truncated  bool  `json:"truncated,omitempty"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Tool returns the bash tool. Output is streamed through the update
+// callback while the command runs; a non-zero exit status or timeout
+// yields an error result that still contains the output.
+func Tool(opts Options) agent.AgentTool {
+	return agent.NewStreamingTool("bash", "Run a shell command and return its combined stdout and stderr.",
+		func(ctx context.Context, args Args, onUpdate agent.AgentToolUpdateCallback) (agent.AgentToolResult, error) {
+			return run(ctx, opts, args, onUpdate)
+		})
+}
+
+// outputBuffer collects command output, keeping the first and last
+// limit/2 bytes, and streams the tail through onUpdate.
+type outputBuffer struct {
+	mu       sync.Mutex
+	limit    int
+	head     []byte
+	tail     []byte
+	dropped  int
+	onUpdate agent.AgentToolUpdateCallback
+	lastSent time.Time
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if room := b.limit/2 - len(b.head); room > 0 {
+		k := min(room, len(p))
+		b.head = append(b.head, p[:k]...)
+		p = p[k:]
+	}
+	b.tail = append(b.tail, p...)
+	if over := len(b.tail) - b.limit/2; over > 0 {
+		b.dropped += over
+		b.tail = append(b.tail[:0], b.tail[over:]...)
+	}
+	if b.onUpdate != nil && time.Since(b.lastSent) >= updateInterval {
+		b.lastSent = time.Now()
+		text, _ := b.textLocked()
+		b.onUpdate(agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent(text)}})
+	}
+	return n, nil
+}
+
+func (b *outputBuffer) result() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.textLocked()
+}
+
+func (b *outputBuffer) textLocked() (string, bool) {
+	if b.dropped == 0 {
+		return string(b.head) + string(b.tail), false
+	}
+	var sb bytes.Buffer
+	sb.Write(b.head)
+	fmt.Fprintf(&sb, "\n\n[... %d bytes truncated ...]\n\n", b.dropped)
+	sb.Write(b.tail)
+	return sb.String(), true
+}
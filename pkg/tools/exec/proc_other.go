@@ -0,0 +1,9 @@
+//go:build !unix
+
+package exec
+
+import osexec "os/exec"
+
+// killProcessGroup is a no-op where process groups are unavailable; the
+// default cancellation kills only the shell.
+func killProcessGroup(cmd *osexec.Cmd) {}
@@ -0,0 +1,83 @@
+//go:build !js && !wasip1
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func run(ctx context.Context, opts Options, args Args, onUpdate agent.AgentToolUpdateCallback) (agent.AgentToolResult, error) {
+	if strings.TrimSpace(args.Command) == "" {
+		return agent.AgentToolResult{}, fmt.Errorf("command must not be empty")
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if args.Cwd != nil && *args.Cwd != "" {
+		if filepath.IsAbs(*args.Cwd) {
+			dir = *args.Cwd
+		} else {
+			dir = filepath.Join(dir, *args.Cwd)
+		}
+	}
+
+	timeout := opts.timeout(args.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	spec := CommandSpec{Command: args.Command, Shell: opts.shell(), Dir: dir, Env: opts.environ()}
+	var cmd *osexec.Cmd
+	if opts.Sandbox != nil {
+		var err error
+		if cmd, err = opts.Sandbox.Command(ctx, spec); err != nil {
+			return agent.AgentToolResult{}, fmt.Errorf("sandbox: %w", err)
+		}
+	} else {
+		cmd = spec.Cmd(ctx)
+	}
+	killProcessGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
+
+	out := &outputBuffer{limit: opts.maxOutput(), onUpdate: onUpdate}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	start := time.Now()
+	err := cmd.Run()
+	text, truncated := out.result()
+
+	var exitErr *osexec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return agent.AgentToolResult{}, fmt.Errorf("%sCommand timed out after %s", withNewline(text), timeout)
+	case ctx.Err() != nil:
+		return agent.AgentToolResult{}, fmt.Errorf("%sCommand aborted", withNewline(text))
+	case errors.As(err, &exitErr):
+		return agent.AgentToolResult{}, fmt.Errorf("%sCommand exited with code %d", withNewline(text), exitErr.ExitCode())
+	case err != nil:
+		return agent.AgentToolResult{}, err
+	}
+	if text == "" {
+		text = "(no output)"
+	}
+	details := Details{Truncated: truncated, DurationMs: time.Since(start).Milliseconds()}
+	return agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent(text)}, Details: details}, nil
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
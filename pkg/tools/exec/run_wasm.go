@@ -0,0 +1,18 @@
+//go:build js || wasip1
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// run fails: WebAssembly hosts cannot spawn processes. Register a tool
+// backed by the host (e.g. a server endpoint) instead.
+func run(ctx context.Context, opts Options, args Args, onUpdate agent.AgentToolUpdateCallback) (agent.AgentToolResult, error) {
+	return agent.AgentToolResult{}, fmt.Errorf("bash is not available on %s/%s: %w", runtime.GOOS, runtime.GOARCH, errors.ErrUnsupported)
+}
@@ -0,0 +1,80 @@
+package exec
+
+import (
+	"context"
+	osexec "os/exec"
+)
+
+// CommandSpec describes a command to run.
+type CommandSpec struct {
+	Command string   // shell command text
+	Shell   []string // interpreter and flags, e.g. ["bash", "-c"]
+	Dir     string   // working directory
+	Env     []string // environment, "KEY=value"
+}
+
+// Cmd builds the unsandboxed command.
+func (s CommandSpec) Cmd(ctx context.Context) *osexec.Cmd {
+	return s.wrap(ctx, nil)
+}
+
+// wrap builds a command running prefix followed by the shell invocation.
+func (s CommandSpec) wrap(ctx context.Context, prefix []string) *osexec.Cmd {
+	argv := append(append(append([]string{}, prefix...), s.Shell...), s.Command)
+	cmd := osexec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = s.Dir
+	cmd.Env = s.Env
+	return cmd
+}
+
+// Sandbox builds commands that run in isolation. Implementations usually
+// wrap the shell invocation in a launcher (docker exec, bwrap,
+// sandbox-exec) and must honour ctx cancellation.
+type Sandbox interface {
+	Command(ctx context.Context, spec CommandSpec) (*osexec.Cmd, error)
+}
+
+// SandboxFunc adapts a function to a Sandbox.
+type SandboxFunc func(ctx context.Context, spec CommandSpec) (*osexec.Cmd, error)
+
+// Command calls f(ctx, spec).
+func (f SandboxFunc) Command(ctx context.Context, spec CommandSpec) (*osexec.Cmd, error) {
+	return f(ctx, spec)
+}
+
+// Bubblewrap runs commands under bwrap (Linux) with a read-only view of
+// the filesystem, the working directory writable, and no network unless
+// enabled.
+type Bubblewrap struct {
+	Path          string   // bwrap binary (default "bwrap")
+	WritablePaths []string // extra paths mounted read-write
+	Network       bool     // share the host network namespace
+}
+
+// Command implements Sandbox.
+func (b Bubblewrap) Command(ctx context.Context, spec CommandSpec) (*osexec.Cmd, error) {
+	path := b.Path
+	if path == "" {
+		path = "bwrap"
+	}
+	bin, err := osexec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{bin,
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--bind", spec.Dir, spec.Dir,
+	}
+	for _, p := range b.WritablePaths {
+		args = append(args, "--bind", p, p)
+	}
+	args = append(args, "--unshare-all", "--die-with-parent", "--chdir", spec.Dir)
+	if b.Network {
+		args = append(args, "--share-net")
+	}
+	args = append(args, "--")
+	return spec.wrap(ctx, args), nil
+}
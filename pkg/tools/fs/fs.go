@@ -0,0 +1,189 @@
+// Package fs provides filesystem tools for agents: line-ranged reads,
+// atomic writes, string-replacement edits, glob and regex search. Set
+// Options.Root to jail every path inside one directory. Writes and edits
+// hold the "file:<path>" lock (see agent.LockResources) so parallel calls
+// cannot interleave on one file.
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Options configures the filesystem tools.
+type Options struct {
+	// Root, when set, is the directory all paths are resolved against and
+	// confined to; paths (including symlink targets) that leave it are
+	// rejected. When empty, relative paths resolve against the working
+	// directory and any path is allowed.
+	Root string
+
+	// MaxReadLines is the default number of lines returned by read
+	// (default 2000).
+	MaxReadLines int
+
+	// MaxResults caps glob and grep output (default 500).
+	MaxResults int
+}
+
+// maxLineLength truncates very long lines in read and grep output.
+const maxLineLength = 2000
+
+func (o Options) maxReadLines() int {
+	if o.MaxReadLines > 0 {
+		return o.MaxReadLines
+	}
+	return 2000
+}
+
+func (o Options) maxResults() int {
+	if o.MaxResults > 0 {
+		return o.MaxResults
+	}
+	return 500
+}
+
+// Tools returns all filesystem tools.
+func Tools(opts Options) []agent.AgentTool {
+	return []agent.AgentTool{
+		ReadTool(opts),
+		WriteTool(opts),
+		EditTool(opts),
+		GlobTool(opts),
+		GrepTool(opts),
+	}
+}
+
+// ReadOnlyTools returns the tools that never modify files (read, glob, grep).
+func ReadOnlyTools(opts Options) []agent.AgentTool {
+	return []agent.AgentTool{ReadTool(opts), GlobTool(opts), GrepTool(opts)}
+}
+
+// resolve turns a tool-supplied path into an absolute path, enforcing the
+// root jail.
+func (o Options) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	if o.Root == "" {
+		return filepath.Abs(path)
+	}
+	root, err := filepath.Abs(o.Root)
+	if err != nil {
+		return "", err
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	p := path
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	p = filepath.Clean(p)
+	if !within(root, p) {
+		return "", fmt.Errorf("path %s is outside the allowed root %s", path, o.Root)
+	}
+	// Resolve symlinks in the longest existing prefix so links cannot
+	// point out of the jail.
+	existing, rest := p, ""
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !within(root, real) {
+				return "", fmt.Errorf("path %s resolves outside the allowed root %s", path, o.Root)
+			}
+			return filepath.Join(real, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return p, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// within reports whether p is root or inside it.
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// display returns path relative to the root (or working directory) for
+// tool output.
+func (o Options) display(path string) string {
+	base := o.Root
+	if base == "" {
+		base, _ = os.Getwd()
+	} else if real, err := filepath.EvalSymlinks(base); err == nil {
+		base = real
+	}
+	if abs, err := filepath.Abs(base); err == nil {
+		if rel, err := filepath.Rel(abs, path); err == nil && within(abs, path) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, keeping the existing file's permissions.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isBinary reports whether data looks like a binary file.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func textResult(text string, details any) agent.AgentToolResult {
+	return agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent(text)}, Details: details}
+}
@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// ReadArgs are the arguments of the read tool.
+type ReadArgs struct {
+	Path   string `json:"path" jsonschema:"description=File to read"`
+	Offset *int   `json:"offset,omitempty" jsonschema:"description=First line to return (1-based)"`
+	Limit  *int   `json:"limit,omitempty" jsonschema:"description=Maximum number of lines to return"`
+}
+
+// ReadDetails describes what a read returned.
+type ReadDetails struct {
+	Path       string `json:"path"`
+	StartLine  int    `json:"startLine"`
+	EndLine    int    `json:"endLine"`
+	TotalLines int    `json:"totalLines"`
+}
+
+// ReadTool returns the read tool, which prints a file's lines with line
+// numbers, optionally limited to a range.
+func ReadTool(opts Options) agent.AgentTool {
+	tool := agent.NewTool("read", "Read a text file. Lines are numbered; use offset and limit to page through large files.",
+		func(ctx context.Context, args ReadArgs) (agent.AgentToolResult, error) {
+			path, err := opts.resolve(args.Path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			if isBinary(data) {
+				return agent.AgentToolResult{}, fmt.Errorf("%s is a binary file", args.Path)
+			}
+
+			lines := strings.Split(string(data), "\n")
+			if len(lines) > 0 && lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			start := 1
+			if args.Offset != nil && *args.Offset > 1 {
+				start = *args.Offset
+			}
+			limit := opts.maxReadLines()
+			if args.Limit != nil && *args.Limit > 0 {
+				limit = *args.Limit
+			}
+			if start > len(lines) && len(lines) > 0 {
+				return agent.AgentToolResult{}, fmt.Errorf("offset %d is past the end of %s (%d lines)", start, args.Path, len(lines))
+			}
+			end := min(start-1+limit, len(lines))
+
+			var sb strings.Builder
+			for i := start - 1; i < end; i++ {
+				line := lines[i]
+				if len(line) > maxLineLength {
+					line = line[:maxLineLength] + "…"
+				}
+				fmt.Fprintf(&sb, "%6d\t%s\n", i+1, line)
+			}
+			if end < len(lines) {
+				fmt.Fprintf(&sb, "\n(%d more lines; continue with offset %d)\n", len(lines)-end, end+1)
+			}
+			if len(lines) == 0 {
+				sb.WriteString("(empty file)\n")
+			}
+			return textResult(sb.String(), ReadDetails{Path: opts.display(path), StartLine: start, EndLine: end, TotalLines: len(lines)}), nil
+		})
+	tool.Parallelizable = true
+	return tool
+}
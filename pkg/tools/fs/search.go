@@ -0,0 +1,259 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// skipDirs are never descended into by glob and grep.
+var skipDirs = map[string]bool{".git": true, "node_modules": true, ".hg": true, ".svn": true}
+
+// maxGrepFileSize skips larger files during grep.
+const maxGrepFileSize = 10 << 20
+
+// GlobArgs are the arguments of the glob tool.
+type GlobArgs struct {
+	Pattern string `json:"pattern" jsonschema:"description=Glob pattern such as **/*.go or src/*.{ts\\,tsx}"`
+	Path    string `json:"path,omitempty" jsonschema:"description=Directory to search (default: the root)"`
+}
+
+// GlobTool returns the glob tool, which lists files matching a pattern
+// ("**" matches any number of directories, "{a,b}" alternatives),
+// most recently modified first.
+func GlobTool(opts Options) agent.AgentTool {
+	tool := agent.NewTool("glob", "Find files by glob pattern (supports ** and {a,b}). Results are sorted by modification time, newest first.",
+		func(ctx context.Context, args GlobArgs) (agent.AgentToolResult, error) {
+			re, err := globRegexp(args.Pattern)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			dir, err := opts.resolve(args.Path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			type match struct {
+				path    string
+				modTime int64
+			}
+			var matches []match
+			err = walkFiles(ctx, dir, func(path string, d iofs.DirEntry) error {
+				rel, _ := filepath.Rel(dir, path)
+				if !re.MatchString(filepath.ToSlash(rel)) {
+					return nil
+				}
+				var mod int64
+				if info, err := d.Info(); err == nil {
+					mod = info.ModTime().UnixNano()
+				}
+				matches = append(matches, match{path, mod})
+				return nil
+			})
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			sort.SliceStable(matches, func(i, j int) bool { return matches[i].modTime > matches[j].modTime })
+
+			if len(matches) == 0 {
+				return textResult("No files found", nil), nil
+			}
+			limit := opts.maxResults()
+			var sb strings.Builder
+			for i, m := range matches {
+				if i == limit {
+					fmt.Fprintf(&sb, "(%d more files not shown)\n", len(matches)-limit)
+					break
+				}
+				sb.WriteString(opts.display(m.path))
+				sb.WriteByte('\n')
+			}
+			return textResult(sb.String(), nil), nil
+		})
+	tool.Parallelizable = true
+	return tool
+}
+
+// GrepArgs are the arguments of the grep tool.
+type GrepArgs struct {
+	Pattern    string `json:"pattern" jsonschema:"description=Regular expression (RE2 syntax)"`
+	Path       string `json:"path,omitempty" jsonschema:"description=File or directory to search (default: the root)"`
+	Glob       string `json:"glob,omitempty" jsonschema:"description=Only search files whose path matches this glob"`
+	IgnoreCase bool   `json:"ignore_case,omitempty" jsonschema:"description=Case-insensitive match"`
+}
+
+// GrepTool returns the grep tool, which searches file contents with a
+// regular expression and prints matching lines as path:line:text.
+func GrepTool(opts Options) agent.AgentTool {
+	tool := agent.NewTool("grep", "Search file contents with a regular expression. Prints matching lines as path:line:text.",
+		func(ctx context.Context, args GrepArgs) (agent.AgentToolResult, error) {
+			pattern := args.Pattern
+			if args.IgnoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return agent.AgentToolResult{}, fmt.Errorf("invalid pattern: %w", err)
+			}
+			var filter *regexp.Regexp
+			if args.Glob != "" {
+				if filter, err = globRegexp(args.Glob); err != nil {
+					return agent.AgentToolResult{}, err
+				}
+			}
+			root, err := opts.resolve(args.Path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+
+			limit := opts.maxResults()
+			var sb strings.Builder
+			count := 0
+			errLimit := fmt.Errorf("limit reached")
+			err = walkFiles(ctx, root, func(path string, d iofs.DirEntry) error {
+				if filter != nil {
+					rel, _ := filepath.Rel(root, path)
+					if !filter.MatchString(filepath.ToSlash(rel)) && !filter.MatchString(d.Name()) {
+						return nil
+					}
+				}
+				if info, err := d.Info(); err != nil || info.Size() > maxGrepFileSize {
+					return nil
+				}
+				data, err := os.ReadFile(path)
+				if err != nil || isBinary(data) {
+					return nil
+				}
+				scanner := bufio.NewScanner(bytes.NewReader(data))
+				scanner.Buffer(nil, maxGrepFileSize)
+				for n := 1; scanner.Scan(); n++ {
+					line := scanner.Text()
+					if !re.MatchString(line) {
+						continue
+					}
+					if count == limit {
+						return errLimit
+					}
+					count++
+					if len(line) > maxLineLength {
+						line = line[:maxLineLength] + "…"
+					}
+					fmt.Fprintf(&sb, "%s:%d:%s\n", opts.display(path), n, line)
+				}
+				return nil
+			})
+			if err == errLimit {
+				fmt.Fprintf(&sb, "(stopped after %d matches)\n", limit)
+			} else if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			if count == 0 {
+				return textResult("No matches found", nil), nil
+			}
+			return textResult(sb.String(), nil), nil
+		})
+	tool.Parallelizable = true
+	return tool
+}
+
+// walkFiles calls fn for every regular file under root (or root itself if
+// it is a file), skipping VCS and dependency directories.
+func walkFiles(ctx context.Context, root string, fn func(path string, d iofs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // unreadable entries are skipped
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
+
+// globRegexp compiles a glob pattern into a regular expression over
+// slash-separated relative paths. A pattern without a slash matches the
+// file name at any depth.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty glob pattern")
+	}
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	var sb strings.Builder
+	sb.WriteByte('^')
+	if !strings.Contains(pattern, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+	depth := 0 // inside {...}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			j := strings.IndexByte(pattern[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated [ in glob %q", pattern)
+			}
+			class := pattern[i+1 : i+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += j
+		case '{':
+			depth++
+			sb.WriteString("(?:")
+		case '}':
+			if depth == 0 {
+				return nil, fmt.Errorf("unbalanced } in glob %q", pattern)
+			}
+			depth--
+			sb.WriteByte(')')
+		case ',':
+			if depth > 0 {
+				sb.WriteByte('|')
+			} else {
+				sb.WriteByte(',')
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced { in glob %q", pattern)
+	}
+	sb.WriteByte('$')
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+)
+
+// WriteArgs are the arguments of the write tool.
+type WriteArgs struct {
+	Path    string `json:"path" jsonschema:"description=File to create or overwrite"`
+	Content string `json:"content" jsonschema:"description=The complete new file content"`
+}
+
+// WriteTool returns the write tool, which atomically replaces a file's
+// content, creating parent directories as needed.
+func WriteTool(opts Options) agent.AgentTool {
+	return agent.NewTool("write", "Create or overwrite a file with the given content.",
+		func(ctx context.Context, args WriteArgs) (agent.AgentToolResult, error) {
+			path, err := opts.resolve(args.Path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			unlock, err := agent.LockResources(ctx, "file:"+path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			defer unlock()
+			_, statErr := os.Stat(path)
+			if err := writeFileAtomic(path, []byte(args.Content)); err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			verb := "Updated"
+			if errors.Is(statErr, os.ErrNotExist) {
+				verb = "Created"
+			}
+			return textResult(fmt.Sprintf("%s %s (%d bytes)", verb, opts.display(path), len(args.Content)), nil), nil
+		})
+}
+
+// EditArgs are the arguments of the edit tool.
+type EditArgs struct {
+	Path       string `json:"path" jsonschema:"description=File to edit"`
+	OldString  string `json:"old_string" jsonschema:"description=Exact text to replace"`
+	NewString  string `json:"new_string" jsonschema:"description=Replacement text"`
+	ReplaceAll bool   `json:"replace_all,omitempty" jsonschema:"description=Replace every occurrence instead of requiring exactly one"`
+}
+
+// EditDetails describes an applied edit.
+type EditDetails struct {
+	Path         string `json:"path"`
+	Replacements int    `json:"replacements"`
+}
+
+// EditTool returns the edit tool, which replaces an exact string in a file.
+// Unless replace_all is set the string must occur exactly once, so the
+// model cannot silently edit the wrong spot.
+func EditTool(opts Options) agent.AgentTool {
+	return agent.NewTool("edit", "Replace an exact string in a file. old_string must match exactly once unless replace_all is true; include surrounding lines to make it unique.",
+		func(ctx context.Context, args EditArgs) (agent.AgentToolResult, error) {
+			if args.OldString == "" {
+				return agent.AgentToolResult{}, fmt.Errorf("old_string must not be empty")
+			}
+			if args.OldString == args.NewString {
+				return agent.AgentToolResult{}, fmt.Errorf("old_string and new_string are identical")
+			}
+			path, err := opts.resolve(args.Path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			unlock, err := agent.LockResources(ctx, "file:"+path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			defer unlock()
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			content := string(data)
+			n := strings.Count(content, args.OldString)
+			switch {
+			case n == 0:
+				return agent.AgentToolResult{}, fmt.Errorf("old_string not found in %s", args.Path)
+			case n > 1 && !args.ReplaceAll:
+				return agent.AgentToolResult{}, fmt.Errorf("old_string occurs %d times in %s; add context to make it unique or set replace_all", n, args.Path)
+			}
+			if args.ReplaceAll {
+				content = strings.ReplaceAll(content, args.OldString, args.NewString)
+			} else {
+				content = strings.Replace(content, args.OldString, args.NewString, 1)
+			}
+			if err := writeFileAtomic(path, []byte(content)); err != nil {
+				return agent.AgentToolResult{}, err
+			}
+			return textResult(fmt.Sprintf("Edited %s (%d replacement(s))", opts.display(path), n), EditDetails{Path: opts.display(path), Replacements: n}), nil
+		})
+}
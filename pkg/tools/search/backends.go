@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Brave queries the Brave Search API.
+type Brave struct {
+	APIKey    string       // default: $BRAVE_API_KEY
+	BaseURL   string       // default "https://api.search.brave.com/res/v1/web/search"
+	Transport ai.Transport // nil uses the default transport
+}
+
+// Search implements Backend.
+func (b *Brave) Search(ctx context.Context, q Query) ([]Result, error) {
+	key := b.APIKey
+	if key == "" {
+		key = os.Getenv("BRAVE_API_KEY")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("brave: no API key (set BRAVE_API_KEY)")
+	}
+	base := b.BaseURL
+	if base == "" {
+		base = "https://api.search.brave.com/res/v1/web/search"
+	}
+	params := url.Values{"q": {q.Text}}
+	if q.Count > 0 {
+		params.Set("count", strconv.Itoa(min(q.Count, 20)))
+	}
+	if q.Language != "" {
+		params.Set("search_lang", q.Language)
+	}
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				Age         string `json:"age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	header := http.Header{"X-Subscription-Token": {key}, "Accept": {"application/json"}}
+	if err := getJSON(ctx, b.Transport, base+"?"+params.Encode(), header, &resp); err != nil {
+		return nil, fmt.Errorf("brave: %w", err)
+	}
+	out := make([]Result, 0, len(resp.Web.Results))
+	for _, r := range resp.Web.Results {
+		out = append(out, Result{Title: cleanSnippet(r.Title), URL: r.URL, Snippet: cleanSnippet(r.Description), Published: r.Age})
+	}
+	return out, nil
+}
+
+// SearXNG queries a SearXNG instance, which must have the JSON output
+// format enabled.
+type SearXNG struct {
+	BaseURL    string       // instance URL, e.g. "https://searx.example.org"
+	Categories string       // optional, e.g. "general,news"
+	Transport  ai.Transport // nil uses the default transport
+}
+
+// Search implements Backend.
+func (s *SearXNG) Search(ctx context.Context, q Query) ([]Result, error) {
+	if s.BaseURL == "" {
+		return nil, fmt.Errorf("searxng: no BaseURL")
+	}
+	params := url.Values{"q": {q.Text}, "format": {"json"}}
+	if q.Language != "" {
+		params.Set("language", q.Language)
+	}
+	if s.Categories != "" {
+		params.Set("categories", s.Categories)
+	}
+	var resp struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+	}
+	endpoint := strings.TrimSuffix(s.BaseURL, "/") + "/search?" + params.Encode()
+	if err := getJSON(ctx, s.Transport, endpoint, http.Header{"Accept": {"application/json"}}, &resp); err != nil {
+		return nil, fmt.Errorf("searxng: %w", err)
+	}
+	out := make([]Result, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		if q.Count > 0 && len(out) == q.Count {
+			break
+		}
+		out = append(out, Result{Title: cleanSnippet(r.Title), URL: r.URL, Snippet: cleanSnippet(r.Content), Published: r.PublishedDate})
+	}
+	return out, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into out.
+func getJSON(ctx context.Context, t ai.Transport, endpoint string, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := ai.GetTransport(t).Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
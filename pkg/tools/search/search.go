@@ -0,0 +1,114 @@
+// Package search exposes web search to agents through a pluggable
+// Backend. Adapters for the Brave Search API and SearXNG are included.
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Query is a search request.
+type Query struct {
+	Text     string
+	Count    int    // results wanted; backends may return fewer
+	Language string // ISO 639-1 code, optional
+}
+
+// Result is one search hit.
+type Result struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Snippet   string `json:"snippet"`
+	Published string `json:"published,omitempty"` // as reported by the backend
+}
+
+// Backend runs web searches.
+type Backend interface {
+	Search(ctx context.Context, q Query) ([]Result, error)
+}
+
+// BackendFunc adapts a function to a Backend.
+type BackendFunc func(ctx context.Context, q Query) ([]Result, error)
+
+// Search calls f(ctx, q).
+func (f BackendFunc) Search(ctx context.Context, q Query) ([]Result, error) {
+	return f(ctx, q)
+}
+
+// Options configures the search tool.
+type Options struct {
+	DefaultCount int // results per query (default 5)
+	MaxCount     int // cap on the count the model may request (default 10)
+	Language     string
+}
+
+// Args are the arguments of the web_search tool.
+type Args struct {
+	Query string `json:"query" jsonschema:"description=Search query"`
+	Count *int   `json:"count,omitempty" jsonschema:"description=Number of results"`
+}
+
+// Tool returns the web_search tool backed by b. Results are numbered so
+// the model can cite them as [n].
+func Tool(b Backend, opts Options) agent.AgentTool {
+	if opts.DefaultCount <= 0 {
+		opts.DefaultCount = 5
+	}
+	if opts.MaxCount <= 0 {
+		opts.MaxCount = 10
+	}
+	tool := agent.NewTool("web_search", "Search the web. Returns numbered results with title, URL and snippet; cite them as [n].",
+		func(ctx context.Context, args Args) (agent.AgentToolResult, error) {
+			if strings.TrimSpace(args.Query) == "" {
+				return agent.AgentToolResult{}, fmt.Errorf("query must not be empty")
+			}
+			count := opts.DefaultCount
+			if args.Count != nil && *args.Count > 0 {
+				count = min(*args.Count, opts.MaxCount)
+			}
+			results, err := b.Search(ctx, Query{Text: args.Query, Count: count, Language: opts.Language})
+			if err != nil {
+				return agent.AgentToolResult{}, fmt.Errorf("search failed: %w", err)
+			}
+			if len(results) > count {
+				results = results[:count]
+			}
+			return agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent(Format(args.Query, results))}, Details: results}, nil
+		})
+	tool.Parallelizable = true
+	tool.Cacheable = true
+	return tool
+}
+
+// Format renders results as a numbered, citable list.
+func Format(query string, results []Result) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No results for %q.", query)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Results for %q:\n", query)
+	for i, r := range results {
+		fmt.Fprintf(&sb, "\n[%d] %s\n%s\n", i+1, r.Title, r.URL)
+		if r.Published != "" {
+			fmt.Fprintf(&sb, "Published: %s\n", r.Published)
+		}
+		if r.Snippet != "" {
+			sb.WriteString(r.Snippet + "\n")
+		}
+	}
+	return sb.String()
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanSnippet strips the highlighting markup backends put in snippets.
+func cleanSnippet(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,278 @@
+// Package web provides a fetch tool that lets agents read web pages:
+// HTML is converted to markdown, text formats are returned as is and
+// images as image content. Requests go through ai.GetTransport, so an
+// ai.NetworkPolicy applies to them too.
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/badlogic/pi-go/pkg/agent"
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+// Options configures the fetch tool.
+type Options struct {
+	Timeout       time.Duration // per request (default 30s)
+	MaxBytes      int64         // response body limit (default 5 MiB)
+	MaxChars      int           // text returned to the model (default 50000)
+	MaxRedirects  int           // default 10
+	UserAgent     string        // default "pi-go-fetch/1"
+	Transport     ai.Transport  // nil uses the default http.Client; see get for redirects
+	ImageMaxBytes int64         // larger images are refused (default 4 MiB)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = 5 << 20
+	}
+	if o.MaxChars <= 0 {
+		o.MaxChars = 50000
+	}
+	if o.MaxRedirects <= 0 {
+		o.MaxRedirects = 10
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = "pi-go-fetch/1"
+	}
+	if o.ImageMaxBytes <= 0 {
+		o.ImageMaxBytes = 4 << 20
+	}
+	return o
+}
+
+// FetchArgs are the arguments of the fetch tool.
+type FetchArgs struct {
+	URL string `json:"url" jsonschema:"description=http or https URL to fetch"`
+	Raw bool   `json:"raw,omitempty" jsonschema:"description=Return HTML source instead of markdown"`
+}
+
+// FetchDetails describes a fetched resource.
+type FetchDetails struct {
+	URL         string `json:"url"` // final URL after redirects
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Title       string `json:"title,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// FetchTool returns the fetch tool.
+func FetchTool(opts Options) agent.AgentTool {
+	opts = opts.withDefaults()
+	tool := agent.NewTool("fetch", "Fetch a URL. HTML pages are converted to markdown; images are returned as images.",
+		func(ctx context.Context, args FetchArgs) (agent.AgentToolResult, error) {
+			return fetch(ctx, opts, args)
+		})
+	tool.Parallelizable = true
+	tool.Cacheable = true
+	return tool
+}
+
+func fetch(ctx context.Context, opts Options, args FetchArgs) (agent.AgentToolResult, error) {
+	u, err := url.Parse(strings.TrimSpace(args.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return agent.AgentToolResult{}, fmt.Errorf("invalid URL %q: only http and https are supported", args.URL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	resp, err := get(ctx, opts, u)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return agent.AgentToolResult{}, fmt.Errorf("fetching %s timed out after %s", u, opts.Timeout)
+		}
+		return agent.AgentToolResult{}, err
+	}
+	defer resp.Body.Close()
+
+	details := FetchDetails{URL: resp.Request.URL.String(), Status: resp.StatusCode}
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	details.ContentType = mediaType
+
+	limit := opts.MaxBytes
+	if strings.HasPrefix(mediaType, "image/") {
+		limit = opts.ImageMaxBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return agent.AgentToolResult{}, fmt.Errorf("reading %s: %w", u, err)
+	}
+	if int64(len(body)) > limit {
+		if strings.HasPrefix(mediaType, "image/") {
+			return agent.AgentToolResult{}, fmt.Errorf("image at %s exceeds %d bytes", u, limit)
+		}
+		body = body[:limit]
+		details.Truncated = true
+	}
+	if resp.StatusCode >= 400 {
+		return agent.AgentToolResult{}, fmt.Errorf("HTTP %d fetching %s: %s", resp.StatusCode, u, snippet(body))
+	}
+
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, params, _ = mime.ParseMediaType(mediaType)
+		details.ContentType = mediaType
+	}
+	var text string
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return agent.AgentToolResult{
+			Content: []ai.Content{
+				ai.NewTextContent(fmt.Sprintf("Image from %s (%s, %d bytes)", details.URL, mediaType, len(body))),
+				ai.NewImageContent(base64.StdEncoding.EncodeToString(body), mediaType),
+			},
+			Details: details,
+		}, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		text = decodeText(body, params["charset"])
+		if !args.Raw {
+			base := resp.Request.URL
+			text, details.Title = HTMLToMarkdown(text, base)
+			if details.Title != "" {
+				text = "# " + details.Title + "\n\n" + text
+			}
+		}
+	case isText(mediaType):
+		text = decodeText(body, params["charset"])
+	default:
+		return agent.AgentToolResult{}, fmt.Errorf("unsupported content type %q at %s", mediaType, details.URL)
+	}
+
+	if len(text) > opts.MaxChars {
+		cut := opts.MaxChars
+		for cut > 0 && !utf8RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "\n\n[content truncated]"
+		details.Truncated = true
+	}
+	return agent.AgentToolResult{Content: []ai.Content{ai.NewTextContent(text)}, Details: details}, nil
+}
+
+// get performs the request, following up to MaxRedirects redirects. The
+// tool follows redirects itself so that each hop is checked, including
+// against an ai.NetworkPolicy: an ai.HTTPTransport's client is copied with
+// redirects turned off. Other transports may follow redirects on their
+// own; the hops they took are counted and checked after the fact.
+func get(ctx context.Context, opts Options, u *url.URL) (*http.Response, error) {
+	var transport ai.Transport
+	switch t := opts.Transport.(type) {
+	case nil:
+		transport = ai.HTTPTransport{Client: noRedirects(nil)}
+	case ai.HTTPTransport:
+		transport = ai.HTTPTransport{Client: noRedirects(t.Client)}
+	case *ai.HTTPTransport:
+		transport = ai.HTTPTransport{Client: noRedirects(t.Client)}
+	default:
+		transport = t
+	}
+	transport = ai.GetTransport(transport)
+	for redirects := 0; ; redirects++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", opts.UserAgent)
+		req.Header.Set("Accept", "text/html,text/markdown,text/plain,application/json,image/*;q=0.8,*/*;q=0.5")
+		resp, err := transport.Do(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if redirects, err = followedRedirects(resp, redirects, opts.MaxRedirects); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		loc := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || loc == "" {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if redirects >= opts.MaxRedirects {
+			return nil, fmt.Errorf("too many redirects fetching %s", u)
+		}
+		next, err := u.Parse(loc)
+		if err != nil {
+			return nil, fmt.Errorf("bad redirect from %s: %w", u, err)
+		}
+		if next.Scheme != "http" && next.Scheme != "https" {
+			return nil, fmt.Errorf("refusing redirect to %s", next)
+		}
+		u = next
+	}
+}
+
+// noRedirects returns a copy of c (or of the default client) that returns
+// redirect responses instead of following them.
+func noRedirects(c *http.Client) *http.Client {
+	out := &http.Client{}
+	if c != nil {
+		*out = *c
+	}
+	out.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return out
+}
+
+// followedRedirects adds the redirects a transport followed on its own to
+// redirects, walking resp's request chain, and fails if there are too many
+// or one left http and https.
+func followedRedirects(resp *http.Response, redirects, max int) (int, error) {
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return redirects, fmt.Errorf("refusing redirect to %s", req.URL)
+		}
+		redirects++
+		if redirects > max {
+			return redirects, fmt.Errorf("too many redirects fetching %s", req.Response.Request.URL)
+		}
+	}
+	return redirects, nil
+}
+
+func isText(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml", "application/yaml", "application/rss+xml", "application/atom+xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// decodeText converts body to UTF-8. Only UTF-8 and Latin-1 are decoded;
+// other charsets are passed through.
+func decodeText(body []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(body))
+		for i, b := range body {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(body), "�")
+}
+
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func snippet(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > 200 {
+		s = s[:200] + "…"
+	}
+	return s
+}
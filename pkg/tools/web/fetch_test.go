@@ -0,0 +1,47 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/badlogic/pi-go/pkg/ai"
+)
+
+func TestFetchLimitsRedirectsWithCustomTransports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n < 5 {
+			http.Redirect(w, r, fmt.Sprintf("/%d", n+1), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "arrived")
+	}))
+	defer srv.Close()
+
+	following := &http.Client{} // follows up to 10 redirects itself
+	transports := map[string]ai.Transport{
+		"HTTPTransport": ai.HTTPTransport{Client: following},
+		"TransportFunc": ai.TransportFunc(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return following.Do(req.WithContext(ctx))
+		}),
+	}
+	for name, transport := range transports {
+		_, err := fetch(context.Background(), Options{Transport: transport, MaxRedirects: 3}.withDefaults(), FetchArgs{URL: srv.URL + "/0"})
+		if err == nil || !strings.Contains(err.Error(), "too many redirects") {
+			t.Errorf("%s: err = %v, want too many redirects", name, err)
+		}
+		res, err := fetch(context.Background(), Options{Transport: transport, MaxRedirects: 5}.withDefaults(), FetchArgs{URL: srv.URL + "/0"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if d := res.Details.(FetchDetails); !strings.HasSuffix(d.URL, "/5") {
+			t.Errorf("%s: final URL %s", name, d.URL)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package web
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// htmlToken is a start tag, end tag or text run.
+type htmlToken struct {
+	kind        tokenKind
+	tag         string // lower-case tag name
+	attrs       map[string]string
+	text        string // unescaped text
+	selfClosing bool
+}
+
+type tokenKind int
+
+const (
+	textToken tokenKind = iota
+	startTag
+	endTag
+)
+
+// rawTextTags hold unparsed text up to their end tag.
+var rawTextTags = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// voidTags never have content or an end tag.
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true, "input": true, "meta": true, "link": true, "area": true, "base": true, "col": true, "embed": true, "source": true, "wbr": true}
+
+// skipTags are dropped together with their content.
+var skipTags = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "svg": true, "head": true, "iframe": true, "object": true, "canvas": true}
+
+// tokenizeHTML is a lenient HTML tokenizer: comments, doctypes and
+// processing instructions are dropped and malformed markup degrades to
+// text.
+func tokenizeHTML(s string) []htmlToken {
+	var out []htmlToken
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			out = append(out, htmlToken{kind: textToken, text: html.UnescapeString(s)})
+			break
+		}
+		if lt > 0 {
+			out = append(out, htmlToken{kind: textToken, text: html.UnescapeString(s[:lt])})
+			s = s[lt:]
+		}
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				return out
+			}
+			s = s[end+3:]
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return out
+			}
+			s = s[end+1:]
+			continue
+		}
+		tok, rest, ok := parseTag(s)
+		if !ok {
+			out = append(out, htmlToken{kind: textToken, text: "<"})
+			s = s[1:]
+			continue
+		}
+		out = append(out, tok)
+		s = rest
+		if tok.kind == startTag && rawTextTags[tok.tag] && !tok.selfClosing {
+			end := indexFold(s, "</"+tok.tag)
+			if end < 0 {
+				end = len(s)
+			}
+			text := s[:end]
+			if tok.tag == "title" || tok.tag == "textarea" {
+				text = html.UnescapeString(text)
+			}
+			out = append(out, htmlToken{kind: textToken, text: text}, htmlToken{kind: endTag, tag: tok.tag})
+			s = s[end:]
+			if gt := strings.IndexByte(s, '>'); gt >= 0 {
+				s = s[gt+1:]
+			}
+		}
+	}
+	return out
+}
+
+// parseTag parses the tag at the start of s ("<...>").
+func parseTag(s string) (htmlToken, string, bool) {
+	i := 1
+	tok := htmlToken{kind: startTag}
+	if i < len(s) && s[i] == '/' {
+		tok.kind = endTag
+		i++
+	}
+	start := i
+	for i < len(s) && isNameByte(s[i]) {
+		i++
+	}
+	if i == start {
+		return tok, s, false
+	}
+	tok.tag = strings.ToLower(s[start:i])
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return tok, s[i+1:], true
+		}
+		if s[i] == '/' {
+			tok.selfClosing = true
+			i++
+			continue
+		}
+		nameStart := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[nameStart:i])
+		value := ""
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return tok, s, false
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				vs := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[vs:i]
+			}
+		}
+		if name != "" {
+			if tok.attrs == nil {
+				tok.attrs = map[string]string{}
+			}
+			tok.attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return tok, s, false
+}
+
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == ':'
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// mdWriter accumulates markdown, tracking block separation.
+type mdWriter struct {
+	sb       strings.Builder
+	newlines int  // trailing newlines already written
+	space    bool // a collapsed space is pending
+}
+
+func (w *mdWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	if w.space && w.newlines == 0 && w.sb.Len() > 0 {
+		w.sb.WriteByte(' ')
+	}
+	w.space = false
+	w.sb.WriteString(s)
+	w.newlines = len(s) - len(strings.TrimRight(s, "\n"))
+}
+
+// inline writes an inline marker: opening markers take any pending space
+// before them, closing ones leave it for after.
+func (w *mdWriter) inline(marker string, open bool) {
+	if open {
+		w.raw(marker)
+		return
+	}
+	w.sb.WriteString(marker)
+	w.newlines = 0
+}
+
+// block ends the current block with a blank line.
+func (w *mdWriter) block() { w.breakLines(2) }
+
+// line ends the current line.
+func (w *mdWriter) line() { w.breakLines(1) }
+
+func (w *mdWriter) breakLines(n int) {
+	w.space = false
+	if w.sb.Len() == 0 {
+		return
+	}
+	for w.newlines < n {
+		w.sb.WriteByte('\n')
+		w.newlines++
+	}
+}
+
+// text writes inline text with whitespace collapsed.
+func (w *mdWriter) text(s string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			w.space = true
+		}
+		return
+	}
+	if isSpace(s[0]) {
+		w.space = true
+	}
+	w.raw(strings.Join(fields, " "))
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToMarkdown converts an HTML document to markdown, resolving links
+// and image sources against base (which may be nil). It also returns the
+// document title.
+func HTMLToMarkdown(src string, base *url.URL) (markdown, title string) {
+	var w mdWriter
+	type list struct {
+		ordered bool
+		n       int
+	}
+	var lists []list
+	var links []string // pending link targets, "" when the link is dropped
+	skip, pre := 0, 0
+	inTitle := false
+	tableRow, headerCells := 0, 0
+
+	resolve := func(ref string) string {
+		ref = strings.TrimSpace(ref)
+		if base == nil || ref == "" {
+			return ref
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return u.String()
+	}
+
+	for _, t := range tokenizeHTML(src) {
+		if t.tag == "title" {
+			inTitle = t.kind == startTag
+			continue
+		}
+		if t.kind == textToken {
+			switch {
+			case inTitle:
+				title = strings.Join(strings.Fields(t.text), " ")
+			case skip > 0:
+			case pre > 0:
+				w.raw(t.text)
+			default:
+				w.text(t.text)
+			}
+			continue
+		}
+		if skipTags[t.tag] {
+			if t.kind == startTag && !t.selfClosing {
+				skip++
+			} else if t.kind == endTag && skip > 0 {
+				skip--
+			}
+			continue
+		}
+		if skip > 0 {
+			continue
+		}
+
+		start := t.kind == startTag
+		switch t.tag {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			w.block()
+			if start {
+				w.raw(strings.Repeat("#", int(t.tag[1]-'0')) + " ")
+			}
+		case "p", "div", "section", "article", "header", "footer", "main", "aside", "nav", "figure", "form", "dl", "address":
+			w.block()
+		case "br":
+			w.line()
+		case "hr":
+			w.block()
+			w.raw("---")
+			w.block()
+		case "pre":
+			if start {
+				w.block()
+				w.raw("```\n")
+				pre++
+			} else if pre > 0 {
+				w.line()
+				w.raw("```")
+				pre--
+				w.block()
+			}
+		case "code", "kbd", "samp":
+			if pre == 0 {
+				w.inline("`", start)
+			}
+		case "strong", "b":
+			w.inline("**", start)
+		case "em", "i":
+			w.inline("_", start)
+		case "del", "s", "strike":
+			w.inline("~~", start)
+		case "blockquote":
+			w.block()
+			if start {
+				w.raw("> ")
+			}
+		case "ul", "ol":
+			if start {
+				if len(lists) == 0 {
+					w.block()
+				}
+				lists = append(lists, list{ordered: t.tag == "ol"})
+			} else if len(lists) > 0 {
+				lists = lists[:len(lists)-1]
+				if len(lists) == 0 {
+					w.block()
+				}
+			}
+		case "li":
+			if !start {
+				continue
+			}
+			w.line()
+			marker := "- "
+			if len(lists) > 0 {
+				l := &lists[len(lists)-1]
+				if l.ordered {
+					l.n++
+					marker = strconv.Itoa(l.n) + ". "
+				}
+				w.raw(strings.Repeat("  ", len(lists)-1))
+			}
+			w.raw(marker)
+		case "dt":
+			w.line()
+		case "dd":
+			w.line()
+			w.raw(": ")
+		case "a":
+			if start {
+				href := t.attrs["href"]
+				if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") || strings.HasPrefix(href, "#") {
+					links = append(links, "")
+					continue
+				}
+				links = append(links, resolve(href))
+				w.raw("[")
+			} else if len(links) > 0 {
+				href := links[len(links)-1]
+				links = links[:len(links)-1]
+				if href != "" {
+					w.inline("]("+href+")", false)
+				}
+			}
+		case "img":
+			if src := t.attrs["src"]; src != "" && !strings.HasPrefix(src, "data:") {
+				w.raw("![" + t.attrs["alt"] + "](" + resolve(src) + ")")
+			}
+		case "table":
+			w.block()
+			tableRow, headerCells = 0, 0
+		case "tr":
+			if start {
+				w.line()
+				w.raw("|")
+			} else {
+				tableRow++
+				if tableRow == 1 && headerCells > 0 {
+					w.line()
+					w.raw("|" + strings.Repeat(" --- |", headerCells))
+				}
+			}
+		case "th", "td":
+			if start {
+				if t.tag == "th" && tableRow == 0 {
+					headerCells++
+				}
+				w.raw(" ")
+			} else {
+				w.raw(" |")
+			}
+		}
+	}
+	md := blankLines.ReplaceAllString(w.sb.String(), "\n\n")
+	return strings.TrimSpace(md), title
+}